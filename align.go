@@ -0,0 +1,132 @@
+package xmlsurf
+
+// alignLists renumbers the instances of repeated elements in right so that the
+// longest common subsequence of identical subtrees keeps the indices of left.
+// Between two aligned instances, the remaining instances are paired in order;
+// instances inserted on the right are numbered after the instances of left.
+func alignLists(left, right XMLMap) XMLMap {
+	leftTree, _ := left.structuralTree()
+	rightTree, elementOf := right.structuralTree()
+	return right.renamed(elementOf, leftTree, rightTree, pairByAlignment)
+}
+
+// pairByAlignment pairs the instances of a common subsequence of identical subtrees
+// and, in each gap between them, the instances of both sides in order
+func pairByAlignment(left, right []*structuralNode) []*structuralNode {
+	partners := make([]*structuralNode, len(right))
+	matches := commonSubsequence(len(left), len(right), func(i, j int) bool {
+		return left[i].sequence == right[j].sequence
+	})
+	// A final match closes the last gap
+	matches = append(matches, [2]int{len(left), len(right)})
+
+	i, j := 0, 0
+	for _, match := range matches {
+		for ; i < match[0] && j < match[1]; i, j = i+1, j+1 {
+			partners[j] = left[i]
+		}
+		if match[1] < len(right) {
+			partners[match[1]] = left[match[0]]
+		}
+		i, j = match[0]+1, match[1]+1
+	}
+	return partners
+}
+
+// commonSubsequence returns the index pairs of a longest common subsequence of two
+// lists of length n and m, in increasing order, using Myers' O((n+m)d) algorithm
+// where d is the number of insertions and deletions
+func commonSubsequence(n, m int, equal func(i, j int) bool) [][2]int {
+	// Common prefix and suffix need no search
+	prefix := 0
+	for prefix < n && prefix < m && equal(prefix, prefix) {
+		prefix++
+	}
+	suffix := 0
+	for suffix < n-prefix && suffix < m-prefix && equal(n-1-suffix, m-1-suffix) {
+		suffix++
+	}
+
+	matches := make([][2]int, 0, min(n, m))
+	for k := 0; k < prefix; k++ {
+		matches = append(matches, [2]int{k, k})
+	}
+	middle := myersMatches(n-prefix-suffix, m-prefix-suffix, func(i, j int) bool {
+		return equal(prefix+i, prefix+j)
+	})
+	for _, match := range middle {
+		matches = append(matches, [2]int{prefix + match[0], prefix + match[1]})
+	}
+	for k := suffix; k > 0; k-- {
+		matches = append(matches, [2]int{n - k, m - k})
+	}
+	return matches
+}
+
+// myersMatches finds the diagonal moves of a shortest edit script between two lists
+func myersMatches(n, m int, equal func(i, j int) bool) [][2]int {
+	if n == 0 || m == 0 {
+		return nil
+	}
+
+	offset := n + m
+	v := make([]int, 2*offset+2)
+	// Step d only reads the diagonals -d..d of the previous step, so only those are
+	// kept, with diagonal k at index k+d; the trace grows with d² rather than d(n+m)
+	var trace [][]int
+	found := false
+	for d := 0; d <= n+m && !found; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && equal(x, y) {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	// Walk the edit script back from the end, collecting the diagonal moves
+	var matches [][2]int
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		if d == 0 {
+			// The first snake starts at the origin
+			for x > 0 && y > 0 {
+				x, y = x-1, y-1
+				matches = append(matches, [2]int{x, y})
+			}
+			break
+		}
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[d+k-1] < v[d+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[d+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			matches = append(matches, [2]int{x, y})
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(matches)-1; i < j; i, j = i+1, j-1 {
+		matches[i], matches[j] = matches[j], matches[i]
+	}
+	return matches
+}
@@ -0,0 +1,142 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithListAlignment(t *testing.T) {
+	tests := []struct {
+		name  string
+		left  XMLMap
+		right XMLMap
+		want  []Diff
+	}{
+		{
+			name:  "insertion in the middle",
+			left:  XMLMap{"/r/item[1]": "a", "/r/item[2]": "b", "/r/item[3]": "c", "/r/item[4]": "d"},
+			right: XMLMap{"/r/item[1]": "a", "/r/item[2]": "x", "/r/item[3]": "b", "/r/item[4]": "c", "/r/item[5]": "d"},
+			want: []Diff{
+				{Path: "/r/item[5]", RightValue: "x", Type: DiffMissing},
+			},
+		},
+		{
+			name:  "deletion in the middle",
+			left:  XMLMap{"/r/item[1]": "a", "/r/item[2]": "b", "/r/item[3]": "c"},
+			right: XMLMap{"/r/item[1]": "a", "/r/item[2]": "c"},
+			want: []Diff{
+				{Path: "/r/item[2]", LeftValue: "b", Type: DiffExtra},
+			},
+		},
+		{
+			name:  "changed element is paired in its gap",
+			left:  XMLMap{"/r/item[1]/@id": "1", "/r/item[1]/qty": "1", "/r/item[2]/@id": "2", "/r/item[2]/qty": "2", "/r/item[3]/@id": "3", "/r/item[3]/qty": "3"},
+			right: XMLMap{"/r/item[1]/@id": "1", "/r/item[1]/qty": "1", "/r/item[2]/@id": "2", "/r/item[2]/qty": "5", "/r/item[3]/@id": "3", "/r/item[3]/qty": "3"},
+			want: []Diff{
+				{Path: "/r/item[2]/qty", LeftValue: "2", RightValue: "5", Type: DiffValue},
+			},
+		},
+		{
+			name: "insertion in a nested list",
+			left: XMLMap{
+				"/r/order[1]/line[1]": "a", "/r/order[1]/line[2]": "b",
+				"/r/order[2]/line[1]": "c", "/r/order[2]/line[2]": "d",
+			},
+			right: XMLMap{
+				"/r/order[1]/line[1]": "a", "/r/order[1]/line[2]": "b",
+				"/r/order[2]/line[1]": "x", "/r/order[2]/line[2]": "c", "/r/order[2]/line[3]": "d",
+			},
+			want: []Diff{
+				{Path: "/r/order[2]/line[3]", RightValue: "x", Type: DiffMissing},
+			},
+		},
+		{
+			name:  "single element becomes a list",
+			left:  XMLMap{"/r/item": "a"},
+			right: XMLMap{"/r/item[1]": "x", "/r/item[2]": "a"},
+			want: []Diff{
+				{Path: "/r/item[2]", RightValue: "x", Type: DiffMissing},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.left.Diffs(tt.right, WithListAlignment(true)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diffs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommonSubsequence(t *testing.T) {
+	tests := []struct {
+		left, right string
+		want        int
+	}{
+		{"", "", 0},
+		{"abc", "", 0},
+		{"abc", "abc", 3},
+		{"abcabba", "cbabac", 4},
+		{"axbyc", "abc", 3},
+		{"abc", "xyz", 0},
+		{"aaaa", "aa", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.left+"/"+tt.right, func(t *testing.T) {
+			got := commonSubsequence(len(tt.left), len(tt.right), func(i, j int) bool {
+				return tt.left[i] == tt.right[j]
+			})
+			if len(got) != tt.want {
+				t.Fatalf("commonSubsequence() = %v, want %d matches", got, tt.want)
+			}
+			for k, match := range got {
+				if tt.left[match[0]] != tt.right[match[1]] {
+					t.Errorf("match %v pairs different elements", match)
+				}
+				if k > 0 && (match[0] <= got[k-1][0] || match[1] <= got[k-1][1]) {
+					t.Errorf("matches %v are not increasing", got)
+				}
+			}
+		})
+	}
+}
+
+func TestCommonSubsequenceLength(t *testing.T) {
+	// Compare with the length found by dynamic programming on pseudo-random lists
+	seed := uint32(1)
+	random := func(n int) string {
+		b := make([]byte, n)
+		for i := range b {
+			seed = seed*1664525 + 1013904223
+			b[i] = 'a' + byte(seed>>28)%3
+		}
+		return string(b)
+	}
+	for round := 0; round < 200; round++ {
+		left, right := random(round%17), random(round%13)
+		lengths := make([][]int, len(left)+1)
+		for i := range lengths {
+			lengths[i] = make([]int, len(right)+1)
+		}
+		for i := len(left) - 1; i >= 0; i-- {
+			for j := len(right) - 1; j >= 0; j-- {
+				if left[i] == right[j] {
+					lengths[i][j] = lengths[i+1][j+1] + 1
+				} else {
+					lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+				}
+			}
+		}
+		got := commonSubsequence(len(left), len(right), func(i, j int) bool { return left[i] == right[j] })
+		if len(got) != lengths[0][0] {
+			t.Errorf("commonSubsequence(%q, %q) = %v, want %d matches", left, right, got, lengths[0][0])
+		}
+		for _, match := range got {
+			if left[match[0]] != right[match[1]] {
+				t.Errorf("commonSubsequence(%q, %q): match %v pairs different elements", left, right, match)
+			}
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package xmlsurf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// checkAllowedPaths reports the paths of the map matching none of the patterns of
+// WithAllowedPaths, to the handler of WithUnknownPathHandler or as an error
+func (m XMLMap) checkAllowedPaths(options *WriteOptions) error {
+	if len(options.AllowedPaths) == 0 {
+		return nil
+	}
+	patterns := compilePathPatterns(options.AllowedPaths)
+	var unknown []string
+	for path := range m {
+		if !matchesPathOrAncestor(patterns, path) {
+			unknown = append(unknown, path)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sortByPath(unknown, func(path string) string { return path })
+
+	if options.UnknownPath == nil {
+		return fmt.Errorf("%w %s", ErrUnknownPath, strings.Join(unknown, ", "))
+	}
+	for _, path := range unknown {
+		if err := options.UnknownPath(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package xmlsurf
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithAllowedPaths(t *testing.T) {
+	allowed := WithAllowedPaths("/order/@id", "/order/item[*]/sku", "/order/item[*]/qty", "/order/meta")
+
+	tests := []struct {
+		name    string
+		m       XMLMap
+		wantErr string
+	}{
+		{
+			name: "allowed",
+			m: XMLMap{
+				"/order/@id":         "42",
+				"/order/item[1]/sku": "a",
+				"/order/item[2]/qty": "2",
+				"/order/meta/source": "web",
+				"/order/meta!/doc/x": "1",
+			},
+		},
+		{
+			name: "typos",
+			m: XMLMap{
+				"/order/@id":          "42",
+				"/order/item[1]/skus": "a",
+				"/order/itme/qty":     "2",
+			},
+			wantErr: "unknown path /order/item[1]/skus, /order/itme/qty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := tt.m.WriteXML(&buf, allowed)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("WriteXML() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrUnknownPath) || err.Error() != tt.wantErr {
+				t.Errorf("WriteXML() error = %v, want %q", err, tt.wantErr)
+			}
+			if buf.Len() != 0 {
+				t.Errorf("WriteXML() wrote %q despite the error", buf.String())
+			}
+			if _, err := tt.m.Tokens(allowed); !errors.Is(err, ErrUnknownPath) {
+				t.Errorf("Tokens() error = %v, want ErrUnknownPath", err)
+			}
+		})
+	}
+}
+
+func TestWithUnknownPathHandler(t *testing.T) {
+	m := XMLMap{"/order/b": "2", "/order/a": "1", "/order/id": "42"}
+
+	var warned []string
+	var buf bytes.Buffer
+	err := m.WriteXML(&buf, WithAllowedPaths("/order/id"), WithUnknownPathHandler(func(path string) error {
+		warned = append(warned, path)
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("WriteXML() error = %v", err)
+	}
+	if want := []string{"/order/a", "/order/b"}; !reflect.DeepEqual(warned, want) {
+		t.Errorf("handler called with %v, want %v", warned, want)
+	}
+	if !strings.Contains(buf.String(), "<a>1</a>") {
+		t.Errorf("WriteXML() = %q, want the unknown paths written", buf.String())
+	}
+
+	stop := errors.New("stop")
+	err = m.WriteXML(&buf, WithAllowedPaths("/order/id"), WithUnknownPathHandler(func(string) error { return stop }))
+	if !errors.Is(err, stop) {
+		t.Errorf("WriteXML() error = %v, want the handler error", err)
+	}
+}
@@ -0,0 +1,128 @@
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/bmcszk/xmlsurf"
+)
+
+// sizes are the element counts of the benchmarked documents
+var sizes = []int{100, 10000}
+
+func BenchmarkParse(b *testing.B) {
+	for _, shape := range Shapes() {
+		for _, size := range sizes {
+			input := Generate(shape, size)
+			b.Run(fmt.Sprintf("%s/%d", shape, size), func(b *testing.B) {
+				b.SetBytes(int64(len(input)))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := xmlsurf.ParseToMap(bytes.NewReader(input)); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkWrite(b *testing.B) {
+	for _, shape := range Shapes() {
+		for _, size := range sizes {
+			m := parse(b, shape, size)
+			b.Run(fmt.Sprintf("%s/%d", shape, size), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if err := m.WriteXML(io.Discard); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkDiff(b *testing.B) {
+	for _, shape := range Shapes() {
+		for _, size := range sizes {
+			m := parse(b, shape, size)
+			modified := Modify(m)
+			b.Run(fmt.Sprintf("%s/%d", shape, size), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					m.Diffs(modified)
+				}
+			})
+			b.Run(fmt.Sprintf("%s/%d/ignore-order", shape, size), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					m.DiffsIgnoreOrder(modified)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkDiffHuge compares maps with a million paths, the size of full catalog exports
+func BenchmarkDiffHuge(b *testing.B) {
+	m := parse(b, ManySiblings, 500000)
+	modified := Modify(m)
+	b.Run("ordered", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			m.Diffs(modified)
+		}
+	})
+	b.Run("ignore-order", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			m.DiffsIgnoreOrder(modified)
+		}
+	})
+}
+
+// parse returns the generated document of the shape and size as a map
+func parse(tb testing.TB, shape Shape, size int) xmlsurf.XMLMap {
+	tb.Helper()
+	m, err := xmlsurf.ParseToMap(bytes.NewReader(Generate(shape, size)))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return m
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		shape     Shape
+		wantPaths int
+		wantPath  string
+	}{
+		{shape: Wide, wantPaths: 50, wantPath: "/root/field49"},
+		{shape: Deep, wantPaths: 1, wantPath: "/root/level0/level1/level2/level3/level4/level5/level6/level7/level8/level9/level0/level1/level2/level3/level4/level5/level6/level7/level8/level9/level0/level1/level2/level3/level4/level5/level6/level7/level8/level9/level0/level1/level2/level3/level4/level5/level6/level7/level8/level9/level0/level1/level2/level3/level4/level5/level6/level7/level8/level9"},
+		{shape: ManySiblings, wantPaths: 100, wantPath: "/root/item[50]/name"},
+		{shape: AttributeHeavy, wantPaths: 50 * attributesPerElement, wantPath: "/root/item[50]/@attr9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.shape.String(), func(t *testing.T) {
+			m := parse(t, tt.shape, 50)
+			if len(m) != tt.wantPaths {
+				t.Errorf("Generate() has %d paths, want %d", len(m), tt.wantPaths)
+			}
+			if _, ok := m[tt.wantPath]; !ok {
+				t.Errorf("Generate() has no path %s", tt.wantPath)
+			}
+			if !bytes.Equal(Generate(tt.shape, 50), Generate(tt.shape, 50)) {
+				t.Error("Generate() is not deterministic")
+			}
+
+			modified := Modify(m)
+			if got, want := len(m.Diffs(modified)), (len(m)+9)/10; got != want {
+				t.Errorf("Modify() changed %d values, want %d", got, want)
+			}
+		})
+	}
+}
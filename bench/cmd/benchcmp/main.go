@@ -0,0 +1,65 @@
+// Command benchcmp compares two outputs of go test -bench and exits with status 1
+// if a benchmark got slower or allocates more by more than the threshold.
+//
+// Usage:
+//
+//	benchcmp [-threshold percent] old.txt new.txt
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bmcszk/xmlsurf/bench"
+)
+
+func main() {
+	threshold := flag.Float64("threshold", 10, "percentage of slowdown or extra allocations reported as a regression")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: benchcmp [-threshold percent] old.txt new.txt")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	old, err := readResults(flag.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+	current, err := readResults(flag.Arg(1))
+	if err != nil {
+		fatal(err)
+	}
+
+	comparisons := bench.Compare(old, current)
+	if err := bench.WriteComparisons(os.Stdout, comparisons, *threshold); err != nil {
+		fatal(err)
+	}
+	if regressions := bench.Regressions(comparisons, *threshold); len(regressions) > 0 {
+		fmt.Fprintf(os.Stderr, "%d benchmarks regressed by more than %.0f%%\n", len(regressions), *threshold)
+		os.Exit(1)
+	}
+}
+
+// readResults parses the benchmark output in the named file
+func readResults(name string) (map[string]bench.Result, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	results, err := bench.ParseResults(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return results, nil
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "benchcmp:", err)
+	os.Exit(2)
+}
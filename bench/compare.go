@@ -0,0 +1,162 @@
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Result holds the measurements of a benchmark, averaged over its runs
+type Result struct {
+	Name        string  // Benchmark name without the GOMAXPROCS suffix
+	Runs        int     // Number of runs averaged, e.g. with -count
+	NsPerOp     float64 // Time per operation
+	BytesPerOp  float64 // Allocated bytes per operation, 0 without -benchmem
+	AllocsPerOp float64 // Allocations per operation, 0 without -benchmem
+}
+
+// ParseResults reads the output of go test -bench and returns the results by
+// benchmark name. Lines that are not benchmark results are ignored.
+func ParseResults(r io.Reader) (map[string]Result, error) {
+	results := make(map[string]Result)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+
+		name := trimProcs(fields[0])
+		result := results[name]
+		result.Name = name
+		n := float64(result.Runs)
+		for i := 2; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("benchmark %s: %w", name, err)
+			}
+			// Running averages keep the results of earlier runs
+			switch fields[i+1] {
+			case "ns/op":
+				result.NsPerOp = (result.NsPerOp*n + value) / (n + 1)
+			case "B/op":
+				result.BytesPerOp = (result.BytesPerOp*n + value) / (n + 1)
+			case "allocs/op":
+				result.AllocsPerOp = (result.AllocsPerOp*n + value) / (n + 1)
+			}
+		}
+		result.Runs++
+		results[name] = result
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// trimProcs removes the GOMAXPROCS suffix of a benchmark name, e.g. -8
+func trimProcs(name string) string {
+	i := strings.LastIndexByte(name, '-')
+	if i == -1 {
+		return name
+	}
+	if _, err := strconv.Atoi(name[i+1:]); err != nil {
+		return name
+	}
+	return name[:i]
+}
+
+// Comparison is the change of a benchmark between two runs
+type Comparison struct {
+	Name     string
+	Old, New Result
+	// Delta is the relative change of the time per operation in percent,
+	// positive when the new run is slower
+	Delta float64
+	// AllocsDelta is the relative change of allocations per operation in percent
+	AllocsDelta float64
+}
+
+// Compare returns the comparisons of the benchmarks present in both the old and the
+// current run, sorted by name
+func Compare(old, current map[string]Result) []Comparison {
+	comparisons := make([]Comparison, 0, len(current))
+	for name, n := range current {
+		o, ok := old[name]
+		if !ok {
+			continue
+		}
+		comparisons = append(comparisons, Comparison{
+			Name:        name,
+			Old:         o,
+			New:         n,
+			Delta:       percentChange(o.NsPerOp, n.NsPerOp),
+			AllocsDelta: percentChange(o.AllocsPerOp, n.AllocsPerOp),
+		})
+	}
+	sort.Slice(comparisons, func(i, j int) bool {
+		return comparisons[i].Name < comparisons[j].Name
+	})
+	return comparisons
+}
+
+// percentChange returns the change from old to current in percent, 0 if old is 0
+func percentChange(old, current float64) float64 {
+	if old == 0 {
+		return 0
+	}
+	return (current - old) / old * 100
+}
+
+// Regressions returns the comparisons whose time or allocations per operation
+// grew by more than threshold percent
+func Regressions(comparisons []Comparison, threshold float64) []Comparison {
+	regressions := make([]Comparison, 0)
+	for _, c := range comparisons {
+		if c.Delta > threshold || c.AllocsDelta > threshold {
+			regressions = append(regressions, c)
+		}
+	}
+	return regressions
+}
+
+// WriteComparisons writes the comparisons as an aligned table, marking changes
+// above threshold percent
+func WriteComparisons(w io.Writer, comparisons []Comparison, threshold float64) error {
+	width := len("name")
+	for _, c := range comparisons {
+		width = max(width, len(c.Name))
+	}
+	if _, err := fmt.Fprintf(w, "%-*s  %12s  %12s  %8s  %10s  %10s  %8s\n", width,
+		"name", "old ns/op", "new ns/op", "delta", "old allocs", "new allocs", "delta"); err != nil {
+		return err
+	}
+	for _, c := range comparisons {
+		mark := ""
+		if c.Delta > threshold || c.AllocsDelta > threshold {
+			mark = "  REGRESSION"
+		}
+		if _, err := fmt.Fprintf(w, "%-*s  %12.0f  %12.0f  %+7.1f%%  %10.0f  %10.0f  %+7.1f%%%s\n", width,
+			c.Name, c.Old.NsPerOp, c.New.NsPerOp, round(c.Delta),
+			c.Old.AllocsPerOp, c.New.AllocsPerOp, round(c.AllocsDelta), mark); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// round rounds a percentage to one decimal, avoiding a printed -0.0
+func round(percent float64) float64 {
+	rounded := math.Round(percent*10) / 10
+	if rounded == 0 {
+		return 0
+	}
+	return rounded
+}
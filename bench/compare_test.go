@@ -0,0 +1,82 @@
+package bench
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const oldOutput = `goos: linux
+goarch: amd64
+pkg: github.com/bmcszk/xmlsurf/bench
+BenchmarkParse/wide/100-8         	   10000	    100000 ns/op	  12.50 MB/s	   50000 B/op	     900 allocs/op
+BenchmarkParse/wide/100-8         	   10000	    120000 ns/op	  10.40 MB/s	   50000 B/op	     900 allocs/op
+BenchmarkDiff/deep/100-8          	  100000	      2000 ns/op	    1000 B/op	      10 allocs/op
+BenchmarkRemoved-8                	  100000	      2000 ns/op
+PASS
+ok  	github.com/bmcszk/xmlsurf/bench	12.345s
+`
+
+const newOutput = `BenchmarkParse/wide/100-16        	   10000	    110000 ns/op	  11.40 MB/s	   50000 B/op	     900 allocs/op
+BenchmarkDiff/deep/100-16         	  100000	      2000 ns/op	    1000 B/op	      12 allocs/op
+BenchmarkAdded-16                 	  100000	      2000 ns/op
+`
+
+func TestParseResults(t *testing.T) {
+	results, err := ParseResults(strings.NewReader(oldOutput))
+	if err != nil {
+		t.Fatalf("ParseResults() error = %v", err)
+	}
+	want := map[string]Result{
+		"BenchmarkParse/wide/100": {Name: "BenchmarkParse/wide/100", Runs: 2, NsPerOp: 110000, BytesPerOp: 50000, AllocsPerOp: 900},
+		"BenchmarkDiff/deep/100":  {Name: "BenchmarkDiff/deep/100", Runs: 1, NsPerOp: 2000, BytesPerOp: 1000, AllocsPerOp: 10},
+		"BenchmarkRemoved":        {Name: "BenchmarkRemoved", Runs: 1, NsPerOp: 2000},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("ParseResults() = %+v, want %+v", results, want)
+	}
+
+	if _, err := ParseResults(strings.NewReader("BenchmarkBad-8 10 fast ns/op\n")); err == nil {
+		t.Error("ParseResults() of a malformed value error = nil")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	old, err := ParseResults(strings.NewReader(oldOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := ParseResults(strings.NewReader(newOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comparisons := Compare(old, current)
+	var names []string
+	for _, c := range comparisons {
+		names = append(names, c.Name)
+	}
+	if want := []string{"BenchmarkDiff/deep/100", "BenchmarkParse/wide/100"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("Compare() names = %v, want %v", names, want)
+	}
+	if got := comparisons[0].AllocsDelta; got != 20 {
+		t.Errorf("allocs delta = %v, want 20", got)
+	}
+	if got := comparisons[1].Delta; got != 0 {
+		t.Errorf("time delta = %v, want 0", got)
+	}
+
+	regressions := Regressions(comparisons, 10)
+	if len(regressions) != 1 || regressions[0].Name != "BenchmarkDiff/deep/100" {
+		t.Errorf("Regressions() = %+v, want the diff benchmark", regressions)
+	}
+
+	var out strings.Builder
+	if err := WriteComparisons(&out, comparisons, 10); err != nil {
+		t.Fatalf("WriteComparisons() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], "+20.0%  REGRESSION") || strings.Contains(lines[2], "REGRESSION") {
+		t.Errorf("WriteComparisons() =\n%s", out.String())
+	}
+}
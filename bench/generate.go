@@ -0,0 +1,106 @@
+// Package bench generates synthetic XML documents for benchmarking xmlsurf and
+// compares the results of benchmark runs, so performance regressions between
+// releases become visible.
+//
+// Run the benchmarks and compare two runs with:
+//
+//	go test ./bench -run '^$' -bench . -benchmem -count 5 > old.txt
+//	go test ./bench -run '^$' -bench . -benchmem -count 5 > new.txt
+//	go run ./bench/cmd/benchcmp -threshold 10 old.txt new.txt
+package bench
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bmcszk/xmlsurf"
+)
+
+func init() {
+	xmlsurf.RegisterModule("bench")
+}
+
+// Shape is the structure of a synthetic document
+type Shape int
+
+const (
+	// Wide documents have many differently named children under the root
+	Wide Shape = iota
+	// Deep documents nest elements size levels deep
+	Deep
+	// ManySiblings documents repeat the same element, so every path is indexed
+	ManySiblings
+	// AttributeHeavy documents have elements with many attributes each
+	AttributeHeavy
+)
+
+// Shapes returns all shapes
+func Shapes() []Shape {
+	return []Shape{Wide, Deep, ManySiblings, AttributeHeavy}
+}
+
+// String returns the name of the shape used in benchmark names
+func (s Shape) String() string {
+	switch s {
+	case Wide:
+		return "wide"
+	case Deep:
+		return "deep"
+	case ManySiblings:
+		return "siblings"
+	case AttributeHeavy:
+		return "attributes"
+	default:
+		return fmt.Sprintf("Shape(%d)", int(s))
+	}
+}
+
+// attributesPerElement is the number of attributes of AttributeHeavy elements
+const attributesPerElement = 10
+
+// Generate returns a document of the shape with about size elements. The output
+// only depends on its arguments, so runs of different releases parse the same input.
+func Generate(shape Shape, size int) []byte {
+	var b strings.Builder
+	b.WriteString("<root>")
+	switch shape {
+	case Wide:
+		for i := 0; i < size; i++ {
+			fmt.Fprintf(&b, "<field%d>value %d</field%d>", i, i, i)
+		}
+	case Deep:
+		for i := 0; i < size; i++ {
+			fmt.Fprintf(&b, "<level%d>", i%10)
+		}
+		b.WriteString("leaf")
+		for i := size - 1; i >= 0; i-- {
+			fmt.Fprintf(&b, "</level%d>", i%10)
+		}
+	case ManySiblings:
+		for i := 0; i < size; i++ {
+			fmt.Fprintf(&b, "<item><id>%d</id><name>item %d</name></item>", i, i)
+		}
+	case AttributeHeavy:
+		for i := 0; i < size; i++ {
+			b.WriteString("<item")
+			for j := 0; j < attributesPerElement; j++ {
+				fmt.Fprintf(&b, ` attr%d="%d-%d"`, j, i, j)
+			}
+			b.WriteString("/>")
+		}
+	}
+	b.WriteString("</root>")
+	return []byte(b.String())
+}
+
+// Modify returns a copy of m with every tenth value changed, as the other side
+// of a diff benchmark
+func Modify(m xmlsurf.XMLMap) xmlsurf.XMLMap {
+	modified := m.Clone()
+	for i, pair := range m.ToPairs() {
+		if i%10 == 0 {
+			modified[pair.Path] = pair.Value + " changed"
+		}
+	}
+	return modified
+}
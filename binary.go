@@ -0,0 +1,101 @@
+package xmlsurf
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GetBytes returns the base64 decoded value at path, such as the content of an
+// xs:base64Binary element. Whitespace in the value is ignored. It returns an error
+// wrapping ErrPathNotFound if the map holds no value at path.
+func (m XMLMap) GetBytes(path string) ([]byte, error) {
+	value, ok := m[path]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return data, nil
+}
+
+// BinarySink returns the writer receiving the decoded content of an element selected
+// with WithBinaryPaths. path is the index-free path the element was selected by and
+// index counts the elements at that path in document order, starting at 1.
+type BinarySink func(path string, index int) (io.Writer, error)
+
+// binaryValue decodes the base64 text of an element to a writer as it is parsed
+type binaryValue struct {
+	path    string
+	w       io.Writer
+	pending []byte // Encoded bytes that do not form a full quantum yet
+	buf     []byte
+}
+
+// write decodes the complete quanta of the text and keeps the rest for the next text
+func (v *binaryValue) write(text []byte) error {
+	for _, c := range text {
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			v.pending = append(v.pending, c)
+		}
+	}
+	full := len(v.pending) / 4 * 4
+	if full == 0 {
+		return nil
+	}
+	if err := v.decode(v.pending[:full]); err != nil {
+		return err
+	}
+	v.pending = append(v.pending[:0], v.pending[full:]...)
+	return nil
+}
+
+// decode writes the decoded quanta to the writer
+func (v *binaryValue) decode(quanta []byte) error {
+	if cap(v.buf) < base64.StdEncoding.DecodedLen(len(quanta)) {
+		v.buf = make([]byte, base64.StdEncoding.DecodedLen(len(quanta)))
+	}
+	n, err := base64.StdEncoding.Decode(v.buf[:cap(v.buf)], quanta)
+	if err != nil {
+		return fmt.Errorf("binary value at %s: %w", v.path, err)
+	}
+	_, err = v.w.Write(v.buf[:n])
+	return err
+}
+
+// finish decodes the remaining text at the end tag and closes the writer if it is an io.Closer
+func (v *binaryValue) finish() error {
+	err := v.close()
+	if len(v.pending) > 0 {
+		err = fmt.Errorf("binary value at %s: %w", v.path, base64.CorruptInputError(len(v.pending)))
+	}
+	return err
+}
+
+// close closes the writer if it is an io.Closer
+func (v *binaryValue) close() error {
+	if closer, ok := v.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// openBinary returns the decoder for the element at the index-free path basePath,
+// or nil if WithBinaryPaths does not select it
+func (b *documentBuilder) openBinary(basePath string) (*binaryValue, error) {
+	if !b.options.BinaryPaths[basePath] {
+		return nil, nil
+	}
+	if b.binaryCounts == nil {
+		b.binaryCounts = make(map[string]int)
+	}
+	b.binaryCounts[basePath]++
+	w, err := b.options.BinarySink(basePath, b.binaryCounts[basePath])
+	if err != nil {
+		return nil, err
+	}
+	return &binaryValue{path: basePath, w: w}, nil
+}
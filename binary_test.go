@@ -0,0 +1,132 @@
+package xmlsurf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetBytes(t *testing.T) {
+	m := XMLMap{
+		"/upload/data":    "aGVs\n  bG8=",
+		"/upload/empty":   "",
+		"/upload/corrupt": "aGVsbG8",
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    []byte
+		wantErr error
+	}{
+		{name: "whitespace ignored", path: "/upload/data", want: []byte("hello")},
+		{name: "empty value", path: "/upload/empty", want: []byte{}},
+		{name: "missing path", path: "/upload/missing", wantErr: ErrPathNotFound},
+		{name: "corrupt value", path: "/upload/corrupt", wantErr: base64.CorruptInputError(4)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.GetBytes(tt.path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetBytes() error = %v, want %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("GetBytes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// closingBuffer records whether it was closed
+type closingBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *closingBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestWithBinaryPaths(t *testing.T) {
+	first := bytes.Repeat([]byte{0, 1, 2, 0xff}, 1000)
+	encoded := base64.StdEncoding.EncodeToString(first)
+	input := `<upload><file id="1"><name>a.bin</name><data>` + encoded[:101] + "\n" + encoded[101:] + `</data></file>` +
+		`<file id="2"><name>b.bin</name><data><![CDATA[aGVs]]>bG8=</data></file>` +
+		`<note><data>dGV4dA==</data></note></upload>`
+
+	sinks := make(map[string]*closingBuffer)
+	sink := func(path string, index int) (io.Writer, error) {
+		buf := &closingBuffer{}
+		sinks[fmt.Sprintf("%s %d", path, index)] = buf
+		return buf, nil
+	}
+
+	m, err := ParseToMap(strings.NewReader(input), WithBinaryPaths(sink, "/upload/file/data"))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+	want := XMLMap{
+		"/upload/file[1]/@id": "1", "/upload/file[1]/name": "a.bin", "/upload/file[1]/data": "",
+		"/upload/file[2]/@id": "2", "/upload/file[2]/name": "b.bin", "/upload/file[2]/data": "",
+		"/upload/note/data": "dGV4dA==",
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("ParseToMap() = %v, want %v", m, want)
+	}
+
+	if got := sinks["/upload/file/data 1"]; got == nil || !bytes.Equal(got.Bytes(), first) || !got.closed {
+		t.Errorf("first sink = %+v, want the decoded content, closed", got)
+	}
+	if got := sinks["/upload/file/data 2"]; got == nil || got.String() != "hello" || !got.closed {
+		t.Errorf("second sink = %+v, want hello, closed", got)
+	}
+	if len(sinks) != 2 {
+		t.Errorf("sinks = %v, want two", sinks)
+	}
+}
+
+func TestWithBinaryPathsErrors(t *testing.T) {
+	errSink := errors.New("disk full")
+
+	tests := []struct {
+		name    string
+		xml     string
+		sinkErr error
+		wantErr error
+	}{
+		{name: "corrupt content", xml: `<upload><data>aGV*bG8=</data></upload>`, wantErr: base64.CorruptInputError(3)},
+		{name: "incomplete content", xml: `<upload><data>aGVsbG8</data></upload>`, wantErr: base64.CorruptInputError(3)},
+		{name: "sink error", xml: `<upload><data>aGVsbG8=</data></upload>`, sinkErr: errSink, wantErr: errSink},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &closingBuffer{}
+			sink := func(string, int) (io.Writer, error) { return buf, tt.sinkErr }
+			_, err := ParseToMap(strings.NewReader(tt.xml), WithBinaryPaths(sink, "/upload/data"))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseToMap() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.sinkErr == nil && !buf.closed {
+				t.Error("writer not closed")
+			}
+		})
+	}
+
+	// A writer left open by malformed XML is closed
+	buf := &closingBuffer{}
+	sink := func(string, int) (io.Writer, error) { return buf, nil }
+	if _, err := ParseToMap(strings.NewReader(`<upload><data>aGVs`), WithBinaryPaths(sink, "/upload/data")); err == nil {
+		t.Error("ParseToMap() error = nil, want a syntax error")
+	}
+	if !buf.closed {
+		t.Error("writer not closed after a syntax error")
+	}
+}
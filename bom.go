@@ -0,0 +1,157 @@
+package xmlsurf
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Byte order marks of UTF-16 input
+const (
+	bomUTF16LE = "\xFF\xFE"
+	bomUTF16BE = "\xFE\xFF"
+)
+
+// newDecoder returns a decoder for r that detects a byte order mark. UTF-8 input is
+// read as it is; the decoder treats a UTF-8 BOM as text in front of the root element.
+// UTF-16 input is converted to UTF-8, so offsets reported for it count bytes of the
+// converted input, and its encoding declaration is accepted.
+// With AutoDecompress, compressed input is decompressed first.
+func newDecoder(r io.Reader, options *ParseOptions) *xml.Decoder {
+	return newBOMDecoder(&bomReader{source: r, decompress: options.AutoDecompress})
+}
+
+// newBOMDecoder returns a decoder for the input, accepting the encoding declaration
+// of UTF-16 input
+func newBOMDecoder(input *bomReader) *xml.Decoder {
+	decoder := xml.NewDecoder(input)
+	decoder.CharsetReader = func(label string, charsetInput io.Reader) (io.Reader, error) {
+		if input.utf16 {
+			switch strings.ToLower(label) {
+			case "utf-16", "utf-16le", "utf-16be", "utf16":
+				return charsetInput, nil
+			}
+		}
+		return nil, fmt.Errorf("encoding %q is not supported", label)
+	}
+	return decoder
+}
+
+// bomReader detects a UTF-16 byte order mark on the first read, so creating a decoder
+// does not block on input that is not available yet, such as a network stream
+type bomReader struct {
+	source     io.Reader
+	decompress bool      // Whether compressed input is detected before the byte order mark
+	reader     io.Reader // Input after the byte order mark, nil before the first read
+	utf16      bool
+	// converted reports whether the input was decompressed or converted from UTF-16,
+	// so offsets of the decoder do not count bytes of the source
+	converted bool
+}
+
+// Read returns the input, converted to UTF-8 if it starts with a UTF-16 byte order mark
+func (b *bomReader) Read(p []byte) (int, error) {
+	if b.reader == nil {
+		buffered := bufio.NewReader(b.source)
+		if b.decompress {
+			source, err := decompressed(buffered)
+			if err != nil {
+				return 0, err
+			}
+			if source != io.Reader(buffered) {
+				b.converted = true
+			}
+			buffered = bufio.NewReader(source)
+		}
+		bom, _ := buffered.Peek(2)
+		switch string(bom) {
+		case bomUTF16LE, bomUTF16BE:
+			buffered.Discard(2)
+			b.reader = &utf16Reader{r: buffered, bigEndian: string(bom) == bomUTF16BE}
+			b.utf16, b.converted = true, true
+		default:
+			b.reader = buffered
+		}
+	}
+	return b.reader.Read(p)
+}
+
+// utf16Reader converts UTF-16 to UTF-8. Unpaired surrogates and a trailing odd byte
+// become U+FFFD.
+type utf16Reader struct {
+	r         *bufio.Reader
+	bigEndian bool
+	pending   []byte // Converted bytes not returned yet
+	err       error
+}
+
+// Read returns converted UTF-8 bytes
+func (u *utf16Reader) Read(p []byte) (int, error) {
+	for len(u.pending) == 0 {
+		if u.err != nil {
+			return 0, u.err
+		}
+		u.fill()
+	}
+	n := copy(p, u.pending)
+	u.pending = u.pending[n:]
+	return n, nil
+}
+
+// fill converts the buffered input into pending bytes
+func (u *utf16Reader) fill() {
+	if _, err := u.r.Peek(2); err != nil {
+		// At most one byte is left
+		if _, err := u.r.ReadByte(); err == nil {
+			u.pending = utf8.AppendRune(u.pending[:0], utf8.RuneError)
+		}
+		u.err = io.EOF
+		if err != io.EOF {
+			u.err = err
+		}
+		return
+	}
+
+	u.pending = u.pending[:0]
+	for u.r.Buffered() >= 2 && len(u.pending) < 4096 {
+		unit := u.unit()
+		if !utf16.IsSurrogate(rune(unit)) {
+			u.pending = utf8.AppendRune(u.pending, rune(unit))
+			continue
+		}
+		if u.r.Buffered() < 2 {
+			if _, err := u.r.Peek(2); err != nil {
+				u.pending = utf8.AppendRune(u.pending, utf8.RuneError)
+				continue
+			}
+		}
+		next, _ := u.r.Peek(2)
+		low := u.order(next)
+		if r := utf16.DecodeRune(rune(unit), rune(low)); r != utf8.RuneError {
+			u.r.Discard(2)
+			u.pending = utf8.AppendRune(u.pending, r)
+			continue
+		}
+		u.pending = utf8.AppendRune(u.pending, utf8.RuneError)
+	}
+}
+
+// unit reads the next UTF-16 code unit
+func (u *utf16Reader) unit() uint16 {
+	var b [2]byte
+	b[0], _ = u.r.ReadByte()
+	b[1], _ = u.r.ReadByte()
+	return u.order(b[:])
+}
+
+// order combines two bytes in the byte order of the input
+func (u *utf16Reader) order(b []byte) uint16 {
+	if u.bigEndian {
+		return uint16(b[0])<<8 | uint16(b[1])
+	}
+	return uint16(b[1])<<8 | uint16(b[0])
+}
@@ -0,0 +1,125 @@
+package xmlsurf
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// encodeUTF16 encodes s as UTF-16 with a byte order mark
+func encodeUTF16(s string, bigEndian bool) string {
+	var b strings.Builder
+	for _, unit := range utf16.Encode([]rune("\ufeff" + s)) {
+		if bigEndian {
+			b.WriteByte(byte(unit >> 8))
+			b.WriteByte(byte(unit))
+		} else {
+			b.WriteByte(byte(unit))
+			b.WriteByte(byte(unit >> 8))
+		}
+	}
+	return b.String()
+}
+
+func TestParseWithBOM(t *testing.T) {
+	document := "<?xml version=\"1.0\" encoding=\"UTF-16\"?>\r\n<root><name>Zoë 😀</name><item id=\"1\"/></root>"
+	want := XMLMap{"/root/name": "Zoë 😀", "/root/item/@id": "1"}
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "UTF-8 BOM",
+			input: "\xEF\xBB\xBF" + strings.Replace(document, "UTF-16", "UTF-8", 1),
+		},
+		{
+			name:  "UTF-16 little endian",
+			input: encodeUTF16(document, false),
+		},
+		{
+			name:  "UTF-16 big endian",
+			input: encodeUTF16(document, true),
+		},
+		{
+			name:  "UTF-16 without declaration",
+			input: encodeUTF16("<root><name>Zoë 😀</name><item id=\"1\"/></root>", false),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseToMap(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("ParseToMap() error = %v", err)
+			}
+			if !reflect.DeepEqual(m, want) {
+				t.Errorf("ParseToMap() = %v, want %v", m, want)
+			}
+
+			record, err := NewStream(strings.NewReader(tt.input)).Next()
+			if err != nil {
+				t.Fatalf("Next() error = %v", err)
+			}
+			if record.Path != "/root/name[1]" || record.Map["/name"] != "Zoë 😀" {
+				t.Errorf("Next() = %+v", record)
+			}
+
+			report, err := Fidelity(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Fidelity() error = %v", err)
+			}
+			if len(report.Diffs) != 0 {
+				t.Errorf("Fidelity() diffs = %v", report.Diffs)
+			}
+		})
+	}
+}
+
+func TestUnwrapNestedWithBOM(t *testing.T) {
+	m, err := ParseToMap(strings.NewReader(`<root><payload>&#xFEFF;&lt;a&gt;1&lt;/a&gt;</payload></root>`), WithUnwrapNested(true))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+	if want := (XMLMap{"/root/payload!/a": "1"}); !reflect.DeepEqual(m, want) {
+		t.Errorf("ParseToMap() = %v, want %v", m, want)
+	}
+}
+
+func TestParseWithBOMErrors(t *testing.T) {
+	// A UTF-16 byte order mark contradicts a declared single-byte encoding
+	input := encodeUTF16(`<?xml version="1.0" encoding="ISO-8859-1"?><root>1</root>`, false)
+	if _, err := ParseToMap(strings.NewReader(input)); err == nil {
+		t.Error("ParseToMap() error = nil, want an encoding error")
+	}
+}
+
+func TestUTF16Reader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "surrogate pair", input: "\x3D\xD8\x00\xDE", want: "😀"},
+		{name: "unpaired high surrogate", input: "\x3D\xD8a\x00", want: "\ufffda"},
+		{name: "unpaired low surrogate", input: "\x00\xDEa\x00", want: "\ufffda"},
+		{name: "high surrogate at the end", input: "a\x00\x3D\xD8", want: "a\ufffd"},
+		{name: "odd trailing byte", input: "a\x00b", want: "a\ufffd"},
+		{name: "pairs across reads", input: strings.Repeat("a\x00\x3D\xD8\x00\xDE", 3000), want: strings.Repeat("a😀", 3000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := io.ReadAll(&utf16Reader{r: bufio.NewReader(strings.NewReader(tt.input))})
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("converted = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package xmlsurf
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// modulePath is the import path of this module
+const modulePath = "github.com/bmcszk/xmlsurf"
+
+// LibraryCapabilities describes the library linked into the running program,
+// for logging which behaviors were in effect when an artifact was produced
+type LibraryCapabilities struct {
+	// Version is the module version from the build info, "(devel)" for a local
+	// build, or "unknown" if the program was built without module support
+	Version string `json:"version"`
+	// DiffSemantics is the comparison behavior used by default
+	DiffSemantics DiffSemantics `json:"diffSemantics"`
+	// ParseOptions, CompareOptions and WriteOptions name the supported options
+	ParseOptions   []string `json:"parseOptions"`
+	CompareOptions []string `json:"compareOptions"`
+	WriteOptions   []string `json:"writeOptions"`
+	// Modules lists the optional subpackages linked into the program, e.g. "soap"
+	Modules []string `json:"modules"`
+}
+
+// String returns a one-line summary of the capabilities
+func (c LibraryCapabilities) String() string {
+	modules := "none"
+	if len(c.Modules) > 0 {
+		modules = strings.Join(c.Modules, ",")
+	}
+	return fmt.Sprintf("xmlsurf %s (diff semantics v%d, modules: %s)", c.Version, c.DiffSemantics, modules)
+}
+
+// Option names reported by Capabilities
+var (
+	parseOptionNames = []string{
+		"WithAllowFragment", "WithAutoDecompress", "WithBinaryPaths", "WithCollectedValues", "WithDefaultNamespacePrefix",
+		"WithEmptyElements", "WithIndexBase", "WithIndexStyle", "WithMetrics", "WithNamespaces", "WithOverwritePolicy",
+		"WithProgress", "WithRedactPatterns", "WithSizeHint", "WithSkipSubtrees", "WithSpillover", "WithStopAfter", "WithStopAfterElements", "WithTraceHook", "WithTrimValues", "WithUnwrapNested",
+		"WithValueTransform",
+	}
+	compareOptionNames = []string{
+		"WithDiffSemantics", "WithIgnorePaths", "WithListAlignment", "WithMatchKey", "WithNestedDocuments",
+		"WithStructuralOrder",
+	}
+	writeOptionNames = []string{
+		"WithAllowedPaths", "WithControlChars", "WithDeclaration", "WithDocumentOrder", "WithElementOrder", "WithIndent",
+		"WithNamespaceDeclarations", "WithNewline", "WithRawValues", "WithSelfClosing", "WithSequence", "WithSpecialElementOrder",
+		"WithUnknownPathHandler",
+	}
+)
+
+var (
+	modulesMu sync.Mutex
+	modules   = make(map[string]bool)
+)
+
+// RegisterModule records an optional subpackage of this library as linked into the
+// program. The subpackages call it from init; other code has no reason to.
+func RegisterModule(name string) {
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+	modules[name] = true
+}
+
+// Capabilities reports the version, default diff semantics, supported options and
+// linked optional modules of the library
+func Capabilities() LibraryCapabilities {
+	modulesMu.Lock()
+	linked := make([]string, 0, len(modules))
+	for name := range modules {
+		linked = append(linked, name)
+	}
+	modulesMu.Unlock()
+	sort.Strings(linked)
+
+	return LibraryCapabilities{
+		Version:        moduleVersion(),
+		DiffSemantics:  DiffSemanticsDefault,
+		ParseOptions:   append([]string(nil), parseOptionNames...),
+		CompareOptions: append([]string(nil), compareOptionNames...),
+		WriteOptions:   append([]string(nil), writeOptionNames...),
+		Modules:        linked,
+	}
+}
+
+// moduleVersion returns the version of this module from the build info
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,81 @@
+package xmlsurf
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	RegisterModule("test")
+	t.Cleanup(func() {
+		modulesMu.Lock()
+		delete(modules, "test")
+		modulesMu.Unlock()
+	})
+	c := Capabilities()
+
+	if c.Version == "" {
+		t.Error("Version is empty")
+	}
+	if c.DiffSemantics != DiffSemanticsDefault {
+		t.Errorf("DiffSemantics = %d, want %d", c.DiffSemantics, DiffSemanticsDefault)
+	}
+	if !reflect.DeepEqual(c.Modules, []string{"test"}) {
+		t.Errorf("Modules = %v, want [test]", c.Modules)
+	}
+	if got := c.String(); !strings.Contains(got, "modules: test") {
+		t.Errorf("String() = %q", got)
+	}
+
+	// The reported options must match the option constructors of the package
+	want := optionConstructors(t)
+	for kind, got := range map[string][]string{
+		"Option":        c.ParseOptions,
+		"CompareOption": c.CompareOptions,
+		"WriteOption":   c.WriteOptions,
+	} {
+		if !reflect.DeepEqual(got, want[kind]) {
+			t.Errorf("%s names = %v, want %v", kind, got, want[kind])
+		}
+	}
+}
+
+// optionConstructors returns the sorted names of the functions returning each option type
+func optionConstructors(t *testing.T) map[string][]string {
+	t.Helper()
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := make(map[string][]string)
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || !fn.Name.IsExported() || fn.Type.Results == nil || len(fn.Type.Results.List) != 1 {
+				continue
+			}
+			if ident, ok := fn.Type.Results.List[0].Type.(*ast.Ident); ok {
+				result[ident.Name] = append(result[ident.Name], fn.Name.Name)
+			}
+		}
+	}
+	for _, names := range result {
+		sort.Strings(names)
+	}
+	return result
+}
@@ -0,0 +1,62 @@
+package xmlsurf
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+// TestXMLMapConcurrentReads exercises the read APIs on a shared map from many
+// goroutines. Run with -race to verify that none of them write to the map.
+func TestXMLMapConcurrentReads(t *testing.T) {
+	shared := XMLMap{
+		"/root/@version":         "1",
+		"/root/items/item[1]":    "a",
+		"/root/items/item[2]":    "b",
+		"/root/items/item[3]":    "c",
+		"/root/meta/created":     "2024-01-01",
+		"/root/payload":          "<inner><x>1</x></inner>",
+		"/root/payload!/inner/x": "1",
+	}
+	other := XMLMap{
+		"/root/@version":      "2",
+		"/root/items/item[1]": "c",
+		"/root/items/item[2]": "b",
+		"/root/items/item[3]": "a",
+		"/root/meta/created":  "2024-01-02",
+	}
+
+	var expected bytes.Buffer
+	if err := shared.ToXML(&expected, true); err != nil {
+		t.Fatalf("ToXML() error = %v", err)
+	}
+
+	const goroutines = 16
+	var wg sync.WaitGroup
+	errs := make(chan string, goroutines)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var buf bytes.Buffer
+			if err := shared.ToXML(&buf, true); err != nil || buf.String() != expected.String() {
+				errs <- "ToXML() output differs between goroutines"
+				return
+			}
+			shared.Diffs(other, WithIgnorePaths("/root/meta"), WithNestedDocuments(true))
+			shared.DiffsIgnoreOrder(other)
+			shared.Equal(other)
+			shared.Contains(other)
+			shared.Clone()
+			shared.UnwrapNested()
+			GroupDiffs(shared.Diffs(other))
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for msg := range errs {
+		t.Error(msg)
+	}
+}
@@ -0,0 +1,46 @@
+package xmlsurf
+
+import (
+	"sort"
+)
+
+// Contains returns true if every path of subset exists in m with the same value.
+// Paths present only in m are ignored.
+func (m XMLMap) Contains(subset XMLMap, opts ...CompareOption) bool {
+	return len(m.ContainsDiffs(subset, opts...)) == 0
+}
+
+// ContainsDiffs returns the differences for paths present in subset.
+// A path of subset missing from m is reported as DiffMissing and a different
+// value as DiffValue; paths present only in m are never reported.
+func (m XMLMap) ContainsDiffs(subset XMLMap, opts ...CompareOption) []Diff {
+	m, subset = prepareCompare(m, subset, newCompareOptions(opts))
+
+	diffs := make([]Diff, 0)
+	for path, expected := range subset {
+		actual, exists := m[path]
+		if !exists {
+			diffs = append(diffs, Diff{
+				Path:       path,
+				RightValue: expected,
+				Type:       DiffMissing,
+			})
+		} else if actual != expected {
+			diffs = append(diffs, Diff{
+				Path:       path,
+				LeftValue:  actual,
+				RightValue: expected,
+				Type:       DiffValue,
+			})
+		}
+	}
+
+	// Sort diffs by path for consistent output
+	if len(diffs) > 0 {
+		sort.Slice(diffs, func(i, j int) bool {
+			return diffs[i].Path < diffs[j].Path
+		})
+	}
+
+	return diffs
+}
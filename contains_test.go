@@ -0,0 +1,67 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestXMLMapContains(t *testing.T) {
+	actual := XMLMap{
+		"/root/id":            "42",
+		"/root/status":        "OK",
+		"/root/item[1]/name":  "a",
+		"/root/item[2]/name":  "b",
+		"/root/meta/ts":       "2024-01-01",
+		"/root/meta/@version": "3",
+	}
+
+	tests := []struct {
+		name     string
+		subset   XMLMap
+		opts     []CompareOption
+		expected []Diff
+	}{
+		{
+			name: "subset present",
+			subset: XMLMap{
+				"/root/id":           "42",
+				"/root/item[2]/name": "b",
+			},
+			expected: []Diff{},
+		},
+		{
+			name: "missing path and different value",
+			subset: XMLMap{
+				"/root/id":     "43",
+				"/root/status": "OK",
+				"/root/code":   "1",
+			},
+			expected: []Diff{
+				{Path: "/root/code", RightValue: "1", Type: DiffMissing},
+				{Path: "/root/id", LeftValue: "42", RightValue: "43", Type: DiffValue},
+			},
+		},
+		{
+			name: "ignored subtree",
+			subset: XMLMap{
+				"/root/id":            "42",
+				"/root/meta/ts":       "2025-01-01",
+				"/root/meta/@version": "4",
+			},
+			opts:     []CompareOption{WithIgnorePaths("/root/meta")},
+			expected: []Diff{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs := actual.ContainsDiffs(tt.subset, tt.opts...)
+			if !reflect.DeepEqual(diffs, tt.expected) {
+				t.Errorf("ContainsDiffs() = %v, want %v", diffs, tt.expected)
+			}
+			if contains := actual.Contains(tt.subset, tt.opts...); contains != (len(tt.expected) == 0) {
+				t.Errorf("Contains() = %v, want %v", contains, len(tt.expected) == 0)
+			}
+		})
+	}
+}
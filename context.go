@@ -0,0 +1,82 @@
+package xmlsurf
+
+import (
+	"context"
+	"io"
+)
+
+// cancelCheckInterval is the number of steps between two context checks
+const cancelCheckInterval = 1024
+
+// cancelCheck polls a context for cancellation during long running loops.
+// A nil *cancelCheck never reports cancellation.
+type cancelCheck struct {
+	ctx   context.Context
+	steps int
+}
+
+// newCancelCheck returns a check for ctx, or nil if ctx can never be canceled
+func newCancelCheck(ctx context.Context) *cancelCheck {
+	if ctx.Done() == nil {
+		return nil
+	}
+	return &cancelCheck{ctx: ctx}
+}
+
+// err counts a step and returns the context error every cancelCheckInterval steps
+func (c *cancelCheck) err() error {
+	return c.add(1)
+}
+
+// add counts n steps at once, e.g. for sorting n items, and returns the context
+// error when the steps pass a multiple of cancelCheckInterval
+func (c *cancelCheck) add(n int) error {
+	if c == nil {
+		return nil
+	}
+	before := c.steps
+	c.steps += n
+	if c.steps/cancelCheckInterval == before/cancelCheckInterval {
+		return nil
+	}
+	return c.ctx.Err()
+}
+
+// DiffsContext is like Diffs but stops and returns the context error once ctx is done
+func (m XMLMap) DiffsContext(ctx context.Context, other XMLMap, opts ...CompareOption) ([]Diff, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	check := newCancelCheck(ctx)
+	left, right, err := prepareCompareContext(m, other, newCompareOptions(opts), check)
+	if err != nil {
+		return nil, err
+	}
+	return left.findDiffs(right, check)
+}
+
+// DiffsIgnoreOrderContext is like DiffsIgnoreOrder but stops and returns the context
+// error once ctx is done
+func (m XMLMap) DiffsIgnoreOrderContext(ctx context.Context, other XMLMap, opts ...CompareOption) ([]Diff, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.diffsIgnoreOrder(other, newCompareOptions(opts), newCancelCheck(ctx))
+}
+
+// ToXMLContext is like ToXML but stops and returns the context error once ctx is done.
+// Nothing is written to w when serialization is canceled.
+func (m XMLMap) ToXMLContext(ctx context.Context, w io.Writer, indent bool) error {
+	if indent {
+		return m.WriteXMLContext(ctx, w, WithIndent("", "  "))
+	}
+	return m.WriteXMLContext(ctx, w)
+}
+
+// WriteXMLContext is like WriteXML but stops and returns the context error once ctx is done
+func (m XMLMap) WriteXMLContext(ctx context.Context, w io.Writer, opts ...WriteOption) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.writeXML(w, newCancelCheck(ctx), opts)
+}
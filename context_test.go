@@ -0,0 +1,119 @@
+package xmlsurf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// cancelAfterFirstCheck is a context that reports cancellation from its second Err call,
+// so cancellation is only noticed by the periodic checks
+type cancelAfterFirstCheck struct {
+	context.Context
+	calls int
+}
+
+func (c *cancelAfterFirstCheck) Err() error {
+	c.calls++
+	if c.calls > 1 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func largeMap(n int, value string) XMLMap {
+	m := make(XMLMap, n)
+	for i := 1; i <= n; i++ {
+		m[fmt.Sprintf("/root/item[%d]", i)] = value
+	}
+	return m
+}
+
+func TestXMLMapDiffsContext(t *testing.T) {
+	left := largeMap(5000, "a")
+	right := largeMap(5000, "b")
+
+	diffs, err := left.DiffsContext(context.Background(), right)
+	if err != nil {
+		t.Fatalf("DiffsContext() error = %v", err)
+	}
+	if len(diffs) != 5000 {
+		t.Errorf("DiffsContext() returned %d diffs, want 5000", len(diffs))
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := left.DiffsContext(canceled, right); !errors.Is(err, context.Canceled) {
+		t.Errorf("DiffsContext() with canceled context error = %v, want %v", err, context.Canceled)
+	}
+
+	ctx := &cancelAfterFirstCheck{Context: canceled}
+	if _, err := left.DiffsContext(ctx, right); !errors.Is(err, context.Canceled) {
+		t.Errorf("DiffsContext() canceled during comparison error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestXMLMapDiffsIgnoreOrderContext(t *testing.T) {
+	left := largeMap(5000, "a")
+	right := largeMap(5000, "b")
+
+	for _, opts := range [][]CompareOption{nil, {WithStructuralOrder(true)}, {WithIgnorePaths("/root/none")}} {
+		diffs, err := left.DiffsIgnoreOrderContext(context.Background(), right, opts...)
+		if err != nil {
+			t.Fatalf("DiffsIgnoreOrderContext() error = %v", err)
+		}
+		if expected := left.DiffsIgnoreOrder(right, opts...); len(diffs) != len(expected) {
+			t.Errorf("DiffsIgnoreOrderContext() returned %d diffs, want %d", len(diffs), len(expected))
+		}
+
+		canceled, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := left.DiffsIgnoreOrderContext(canceled, right, opts...); !errors.Is(err, context.Canceled) {
+			t.Errorf("DiffsIgnoreOrderContext() with canceled context error = %v, want %v", err, context.Canceled)
+		}
+		ctx := &cancelAfterFirstCheck{Context: canceled}
+		if _, err := left.DiffsIgnoreOrderContext(ctx, right, opts...); !errors.Is(err, context.Canceled) {
+			t.Errorf("DiffsIgnoreOrderContext() canceled during comparison error = %v, want %v", err, context.Canceled)
+		}
+	}
+}
+
+func TestXMLMapToXMLContextTreeBuild(t *testing.T) {
+	// A single element with many children: the tree build and the sort of the
+	// children take most of the time, so cancellation is noticed before writing
+	m := largeMap(5000, "a")
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	check := newCancelCheck(&cancelAfterFirstCheck{Context: canceled})
+	if _, err := m.writeTree(DefaultWriteOptions(), check); !errors.Is(err, context.Canceled) {
+		t.Errorf("writeTree() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestXMLMapToXMLContext(t *testing.T) {
+	m := largeMap(5000, "a")
+
+	var expected, actual bytes.Buffer
+	if err := m.ToXML(&expected, true); err != nil {
+		t.Fatalf("ToXML() error = %v", err)
+	}
+	if err := m.ToXMLContext(context.Background(), &actual, true); err != nil {
+		t.Fatalf("ToXMLContext() error = %v", err)
+	}
+	if actual.String() != expected.String() {
+		t.Errorf("ToXMLContext() output differs from ToXML()")
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	ctx := &cancelAfterFirstCheck{Context: canceled}
+	var buf bytes.Buffer
+	if err := m.ToXMLContext(ctx, &buf, false); !errors.Is(err, context.Canceled) {
+		t.Errorf("ToXMLContext() error = %v, want %v", err, context.Canceled)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("ToXMLContext() wrote %d bytes after cancellation", buf.Len())
+	}
+}
@@ -0,0 +1,80 @@
+package xmlsurf
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ToCSV writes the repeated elements at recordPath as CSV rows, one per element.
+// recordPath is given without an index, e.g. "/orders/order", and matches both
+// /orders/order and /orders/order[n]. Columns are paths relative to a record,
+// e.g. "@id" or "customer/name"; "" or "." selects the record's own value.
+// The first row holds the column names. Missing values are written as empty fields.
+func (m XMLMap) ToCSV(w io.Writer, recordPath string, columns []string) (err error) {
+	defer recoverPanic("write CSV", &err)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+
+	row := make([]string, len(columns))
+	for _, record := range m.recordPaths(recordPath) {
+		for i, column := range columns {
+			path := record
+			if column != "" && column != "." {
+				path = record + "/" + strings.TrimPrefix(column, "/")
+			}
+			row[i] = m[path]
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// recordPaths returns the paths of all elements at the index-free recordPath, ordered by index
+func (m XMLMap) recordPaths(recordPath string) []string {
+	indices := make(map[string]int)
+	for path := range m {
+		if record, ok := recordOf(path, recordPath); ok {
+			index := lastPathSegment(record).index
+			indices[record] = index
+		}
+	}
+
+	records := make([]string, 0, len(indices))
+	for record := range indices {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return indices[records[i]] < indices[records[j]]
+	})
+	return records
+}
+
+// recordOf returns the path of the element at the index-free recordPath that
+// contains path, e.g. /orders/order[2] for /orders/order[2]/total
+func recordOf(path, recordPath string) (string, bool) {
+	if !strings.HasPrefix(path, recordPath) {
+		return "", false
+	}
+
+	// recordPath must end with the segment holding its end, or before its index
+	record, ok := "", false
+	forEachSegment(path, func(segment pathSegment) bool {
+		if segment.end < len(recordPath) {
+			return true
+		}
+		if segment.end == len(recordPath) || segment.start+len(segment.base) == len(recordPath) {
+			record, ok = path[:segment.end], true
+		}
+		return false
+	})
+	return record, ok
+}
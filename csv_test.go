@@ -0,0 +1,66 @@
+package xmlsurf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestXMLMapToCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        XMLMap
+		record   string
+		columns  []string
+		expected string
+	}{
+		{
+			name: "repeated records",
+			m: XMLMap{
+				"/orders/order[1]/@id":           "1",
+				"/orders/order[1]/customer/name": "Alice",
+				"/orders/order[1]/total":         "10.50",
+				"/orders/order[2]/@id":           "2",
+				"/orders/order[2]/customer/name": "Bob, Jr.",
+				"/orders/order[10]/@id":          "10",
+				"/orders/orderCount":             "3",
+			},
+			record:  "/orders/order",
+			columns: []string{"@id", "customer/name", "total"},
+			expected: "@id,customer/name,total\n" +
+				"1,Alice,10.50\n" +
+				"2,\"Bob, Jr.\",\n" +
+				"10,,\n",
+		},
+		{
+			name: "single record with own value",
+			m: XMLMap{
+				"/list/item":      "only",
+				"/list/item/@sku": "A1",
+			},
+			record:   "/list/item",
+			columns:  []string{".", "@sku"},
+			expected: ".,@sku\nonly,A1\n",
+		},
+		{
+			name: "no records",
+			m: XMLMap{
+				"/list/other": "x",
+			},
+			record:   "/list/item",
+			columns:  []string{"name"},
+			expected: "name\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.m.ToCSV(&buf, tt.record, tt.columns); err != nil {
+				t.Fatalf("ToCSV() error = %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("ToCSV() =\n%s\nwant\n%s", buf.String(), tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package xmlsurf
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+)
+
+// decompressed returns the decompressed input if it starts with the magic bytes of a
+// gzip or zlib stream, and the input itself otherwise. XML cannot start with either,
+// so uncompressed documents are read unchanged.
+func decompressed(r *bufio.Reader) (io.Reader, error) {
+	magic, _ := r.Peek(2)
+	if len(magic) < 2 {
+		return r, nil
+	}
+	switch {
+	case magic[0] == 0x1f && magic[1] == 0x8b:
+		return gzip.NewReader(r)
+	case isZlibHeader(magic[0], magic[1]):
+		return zlib.NewReader(r)
+	}
+	return r, nil
+}
+
+// isZlibHeader reports whether the bytes are a zlib header: deflate with a window of at
+// most 32 KiB and a header checksum that is a multiple of 31
+func isZlibHeader(cmf, flg byte) bool {
+	return cmf&0x0f == 8 && cmf>>4 <= 7 && (uint16(cmf)<<8|uint16(flg))%31 == 0
+}
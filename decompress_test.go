@@ -0,0 +1,105 @@
+package xmlsurf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// compress compresses data with a gzip or zlib writer
+func compress(t *testing.T, data string, newWriter func(io.Writer) io.WriteCloser) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := newWriter(&buf)
+	if _, err := io.WriteString(w, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestWithAutoDecompress(t *testing.T) {
+	document := `<?xml version="1.0"?><feed><entry>1</entry><entry>2</entry></feed>`
+	gzipWriter := func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }
+	zlibWriter := func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }
+	want := XMLMap{"/feed/entry[1]": "1", "/feed/entry[2]": "2"}
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "uncompressed", input: document},
+		{name: "gzip", input: compress(t, document, gzipWriter)},
+		{name: "zlib", input: compress(t, document, zlibWriter)},
+		{name: "gzip with UTF-16 byte order mark", input: compress(t, encodeUTF16(document, false), gzipWriter)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseToMap(strings.NewReader(tt.input), WithAutoDecompress(true))
+			if err != nil {
+				t.Fatalf("ParseToMap() error = %v", err)
+			}
+			if !reflect.DeepEqual(m, want) {
+				t.Errorf("ParseToMap() = %v, want %v", m, want)
+			}
+
+			record, err := NewStream(strings.NewReader(tt.input), WithAutoDecompress(true)).Next()
+			if err != nil {
+				t.Fatalf("Next() error = %v", err)
+			}
+			if record.Map["/entry"] != "1" {
+				t.Errorf("Next() = %+v", record)
+			}
+		})
+	}
+
+	// Without the option compressed input is malformed XML
+	var syntaxErr *SyntaxError
+	if _, err := ParseToMap(strings.NewReader(tests[1].input)); !errors.As(err, &syntaxErr) {
+		t.Errorf("ParseToMap() of gzip without WithAutoDecompress error = %v, want a *SyntaxError", err)
+	}
+}
+
+func TestWithAutoDecompressErrors(t *testing.T) {
+	gzipped := compress(t, `<feed><entry>1</entry></feed>`, func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })
+
+	tests := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{name: "truncated header", input: gzipped[:5], want: io.ErrUnexpectedEOF},
+		{name: "truncated data", input: gzipped[:len(gzipped)-4], want: io.ErrUnexpectedEOF},
+		{name: "corrupt checksum", input: gzipped[:len(gzipped)-8] + "xxxx" + gzipped[len(gzipped)-4:], want: gzip.ErrChecksum},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseToMap(strings.NewReader(tt.input), WithAutoDecompress(true))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ParseToMap() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsZlibHeader(t *testing.T) {
+	for _, header := range []string{"\x78\x01", "\x78\x5e", "\x78\x9c", "\x78\xda"} {
+		if !isZlibHeader(header[0], header[1]) {
+			t.Errorf("isZlibHeader(%q) = false, want true", header)
+		}
+	}
+	for _, header := range []string{"<?", "<r", "\xef\xbb", "\xff\xfe", "\x78\x00"} {
+		if isZlibHeader(header[0], header[1]) {
+			t.Errorf("isZlibHeader(%q) = true, want false", header)
+		}
+	}
+}
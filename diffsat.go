@@ -0,0 +1,36 @@
+package xmlsurf
+
+import (
+	"strings"
+)
+
+// DiffsAt compares the subtree of the map at prefix with the subtree of other at
+// otherPrefix, e.g. the SOAP Body elements of two differently wrapped requests.
+// Paths outside the subtrees are ignored. Diffs report paths relative to the prefixes:
+// "order/id" and "@version" below the subtree element and "." for the element itself.
+// Paths given to WithIgnorePaths are relative in the same way.
+func (m XMLMap) DiffsAt(prefix string, other XMLMap, otherPrefix string, opts ...CompareOption) []Diff {
+	return m.subtreeAt(prefix).Diffs(other.subtreeAt(otherPrefix), opts...)
+}
+
+// subtreeAt returns the paths at or below prefix, relative to it
+func (m XMLMap) subtreeAt(prefix string) XMLMap {
+	result := make(XMLMap)
+	for path, value := range m {
+		if !isPathOrBelow(path, prefix) {
+			continue
+		}
+		rest := path[len(prefix):]
+		switch {
+		case rest == "":
+			rest = "."
+		case strings.HasPrefix(rest, "/"):
+			rest = rest[1:]
+		default:
+			// An embedded document in the subtree element itself
+			rest = "." + rest
+		}
+		result[rest] = value
+	}
+	return result
+}
@@ -0,0 +1,51 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffsAt(t *testing.T) {
+	left := XMLMap{
+		"/soap:Envelope/soap:Header/auth":       "token-a",
+		"/soap:Envelope/soap:Body/order/@id":    "1",
+		"/soap:Envelope/soap:Body/order/total":  "10",
+		"/soap:Envelope/soap:Body/order/note":   "left only",
+		"/soap:Envelope/soap:Body/order/status": "open",
+	}
+	right := XMLMap{
+		"/s:Envelope/s:Header/auth":         "token-b",
+		"/s:Envelope/s:Body":                "",
+		"/s:Envelope/s:Body/order/@id":      "1",
+		"/s:Envelope/s:Body/order/total":    "12",
+		"/s:Envelope/s:Body/order/status":   "open",
+		"/s:Envelope/s:Body/order/currency": "EUR",
+		"/s:Envelope/s:Bodyguard/order/@id": "not in the subtree",
+	}
+
+	got := left.DiffsAt("/soap:Envelope/soap:Body", right, "/s:Envelope/s:Body")
+	want := []Diff{
+		{Path: ".", RightValue: "", Type: DiffMissing},
+		{Path: "order/currency", RightValue: "EUR", Type: DiffMissing},
+		{Path: "order/note", LeftValue: "left only", Type: DiffExtra},
+		{Path: "order/total", LeftValue: "10", RightValue: "12", Type: DiffValue},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffsAt() = %v, want %v", got, want)
+	}
+
+	// Ignored paths are relative to the prefixes
+	got = left.DiffsAt("/soap:Envelope/soap:Body", right, "/s:Envelope/s:Body", WithIgnorePaths(".", "order/note", "order/currency"))
+	want = []Diff{{Path: "order/total", LeftValue: "10", RightValue: "12", Type: DiffValue}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffsAt() with ignored paths = %v, want %v", got, want)
+	}
+}
+
+func TestSubtreeAtNested(t *testing.T) {
+	m := XMLMap{"/a/b!/x/y": "1", "/a/b/c": "2", "/a/bc": "3"}
+	want := XMLMap{".!/x/y": "1", "c": "2"}
+	if got := m.subtreeAt("/a/b"); !reflect.DeepEqual(got, want) {
+		t.Errorf("subtreeAt() = %v, want %v", got, want)
+	}
+}
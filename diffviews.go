@@ -0,0 +1,23 @@
+package xmlsurf
+
+// SplitDiffs separates diffs into side-by-side views: paths only present on the left
+// (DiffExtra) with their left values, paths only present on the right (DiffMissing)
+// with their right values, and changed paths (DiffValue) with their left and right values.
+// The returned maps are never nil.
+func SplitDiffs(diffs []Diff) (onlyLeft, onlyRight XMLMap, changed map[string][2]string) {
+	onlyLeft = make(XMLMap)
+	onlyRight = make(XMLMap)
+	changed = make(map[string][2]string)
+
+	for _, diff := range diffs {
+		switch diff.Type {
+		case DiffExtra:
+			onlyLeft[diff.Path] = diff.LeftValue
+		case DiffMissing:
+			onlyRight[diff.Path] = diff.RightValue
+		case DiffValue:
+			changed[diff.Path] = [2]string{diff.LeftValue, diff.RightValue}
+		}
+	}
+	return onlyLeft, onlyRight, changed
+}
@@ -0,0 +1,28 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitDiffs(t *testing.T) {
+	left := XMLMap{"/root/a": "1", "/root/b": "2", "/root/c/@id": "x"}
+	right := XMLMap{"/root/a": "1", "/root/b": "3", "/root/d": "4", "/root/e": "5"}
+
+	onlyLeft, onlyRight, changed := SplitDiffs(left.Diffs(right))
+
+	if want := (XMLMap{"/root/c/@id": "x"}); !reflect.DeepEqual(onlyLeft, want) {
+		t.Errorf("onlyLeft = %v, want %v", onlyLeft, want)
+	}
+	if want := (XMLMap{"/root/d": "4", "/root/e": "5"}); !reflect.DeepEqual(onlyRight, want) {
+		t.Errorf("onlyRight = %v, want %v", onlyRight, want)
+	}
+	if want := map[string][2]string{"/root/b": {"2", "3"}}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+
+	onlyLeft, onlyRight, changed = SplitDiffs(nil)
+	if onlyLeft == nil || onlyRight == nil || changed == nil {
+		t.Error("SplitDiffs(nil) returned a nil map")
+	}
+}
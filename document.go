@@ -0,0 +1,86 @@
+package xmlsurf
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Metadata describes where an XMLMap came from
+type Metadata struct {
+	Source    string    // Origin of the document, e.g. an environment or file name
+	Timestamp time.Time // When the document was captured
+	Version   string    // Version of the system that produced the document
+}
+
+// String returns a compact description like "prod (v1.2, 2024-01-02T15:04:05Z)"
+func (md Metadata) String() string {
+	details := make([]string, 0, 2)
+	if md.Version != "" {
+		details = append(details, md.Version)
+	}
+	if !md.Timestamp.IsZero() {
+		details = append(details, md.Timestamp.Format(time.RFC3339))
+	}
+
+	source := md.Source
+	if source == "" {
+		source = "unknown"
+	}
+	if len(details) == 0 {
+		return source
+	}
+	return fmt.Sprintf("%s (%s)", source, strings.Join(details, ", "))
+}
+
+// Document is an XMLMap annotated with metadata
+type Document struct {
+	Map  XMLMap
+	Meta Metadata
+}
+
+// DiffReport holds the differences between two documents together with their metadata
+type DiffReport struct {
+	Left  Metadata
+	Right Metadata
+	Diffs []Diff
+}
+
+// String returns the report with a header identifying both sides, one diff per line
+func (r DiffReport) String() string {
+	var builder strings.Builder
+	builder.WriteString("--- left: ")
+	builder.WriteString(r.Left.String())
+	builder.WriteString("\n+++ right: ")
+	builder.WriteString(r.Right.String())
+	for _, diff := range r.Diffs {
+		builder.WriteString("\n")
+		builder.WriteString(diff.String())
+	}
+	return builder.String()
+}
+
+// Diffs compares the document with another document and returns a report carrying both metadata.
+// Options are applied as in XMLMap.Diffs.
+func (d Document) Diffs(other Document, opts ...CompareOption) DiffReport {
+	return DiffReport{
+		Left:  d.Meta,
+		Right: other.Meta,
+		Diffs: d.Map.Diffs(other.Map, opts...),
+	}
+}
+
+// DiffNDocuments compares every pair of documents like DiffN and keeps their
+// metadata in the result, in the same order as the documents
+func DiffNDocuments(docs []Document, opts ...CompareOption) MultiDiff {
+	maps := make([]XMLMap, len(docs))
+	meta := make([]Metadata, len(docs))
+	for i, doc := range docs {
+		maps[i] = doc.Map
+		meta[i] = doc.Meta
+	}
+
+	result := DiffN(maps, opts...)
+	result.Meta = meta
+	return result
+}
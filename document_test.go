@@ -0,0 +1,49 @@
+package xmlsurf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDocumentDiffs(t *testing.T) {
+	left := Document{
+		Map: XMLMap{"/root/a": "1"},
+		Meta: Metadata{
+			Source:    "prod",
+			Version:   "v1.2",
+			Timestamp: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+	}
+	right := Document{
+		Map:  XMLMap{"/root/a": "2"},
+		Meta: Metadata{Source: "staging"},
+	}
+
+	report := left.Diffs(right)
+	if report.Left.Source != "prod" || report.Right.Source != "staging" || len(report.Diffs) != 1 {
+		t.Errorf("Diffs() = %+v", report)
+	}
+
+	expected := "--- left: prod (v1.2, 2024-01-02T15:04:05Z)\n" +
+		"+++ right: staging\n" +
+		`Value mismatch at /root/a: "1" != "2"`
+	if report.String() != expected {
+		t.Errorf("DiffReport.String() = %q, want %q", report.String(), expected)
+	}
+}
+
+func TestDiffNDocuments(t *testing.T) {
+	docs := []Document{
+		{Map: XMLMap{"/root/a": "1"}, Meta: Metadata{Source: "a"}},
+		{Map: XMLMap{"/root/a": "1"}, Meta: Metadata{Source: "b"}},
+		{Map: XMLMap{"/root/a": "2"}, Meta: Metadata{Source: "c"}},
+	}
+
+	result := DiffNDocuments(docs)
+	if len(result.Meta) != 3 || result.Meta[2].Source != "c" {
+		t.Errorf("DiffNDocuments() meta = %v", result.Meta)
+	}
+	if result.Counts[0][2] != 1 || result.Counts[0][1] != 0 {
+		t.Errorf("DiffNDocuments() counts = %v", result.Counts)
+	}
+}
@@ -0,0 +1,110 @@
+package xmlsurf
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// ErrEmptyDocument is returned when the input has no root element or the
+// document holds no values
+var ErrEmptyDocument = errors.New("empty document")
+
+// ErrMultipleRoots is wrapped by the *SyntaxError returned when the input
+// has more than one root element
+var ErrMultipleRoots = errors.New("multiple root elements")
+
+// ErrDuplicatePath is returned when several values map to the same path,
+// e.g. while parsing with OverwriteError or by NewFromPairs
+var ErrDuplicatePath = errors.New("duplicate path")
+
+// ErrPathNotFound is returned when the map holds no value at a requested path
+var ErrPathNotFound = errors.New("path not found")
+
+// ErrNilMap is returned when modifying a nil XMLMap, e.g. by Merge
+var ErrNilMap = errors.New("nil XMLMap")
+
+// ErrInvalidCharacter is returned with ControlCharError when a value holds a
+// character that XML 1.0 does not allow
+var ErrInvalidCharacter = errors.New("invalid XML character")
+
+// ErrUnknownPath is returned when writing a map with a path that matches none of
+// the patterns given to WithAllowedPaths
+var ErrUnknownPath = errors.New("unknown path")
+
+// ErrConvertedInput is returned by Stream.Checkpoint when the input was decompressed or
+// converted from UTF-16, as offsets in the decoded input cannot be resumed from
+var ErrConvertedInput = errors.New("stream input was converted")
+
+// ErrInvalidXPath is returned by Evaluate for an expression that cannot be parsed or evaluated
+var ErrInvalidXPath = errors.New("invalid XPath expression")
+
+// errInvalidName is wrapped by the *SyntaxError returned for a name that is not a qualified name
+var errInvalidName = errors.New("invalid XML name")
+
+// SyntaxError describes malformed XML input
+type SyntaxError struct {
+	Msg    string // Description of the problem
+	Line   int    // 1-based line at which the problem was detected
+	Offset int64  // Byte offset at which the problem was detected
+	Err    error  // Underlying error, a *xml.SyntaxError, ErrMultipleRoots or an invalid name error
+}
+
+// Error returns a description of the problem with its line. The message for
+// multiple root elements predates line reporting and is kept without it.
+func (e *SyntaxError) Error() string {
+	if errors.Is(e.Err, ErrMultipleRoots) {
+		return "XML syntax error: " + e.Msg
+	}
+	return fmt.Sprintf("XML syntax error on line %d: %s", e.Line, e.Msg)
+}
+
+// Unwrap returns the underlying error
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// decodeError converts syntax errors of the decoder, ErrMultipleRoots and invalid names into a *SyntaxError.
+// Other errors, e.g. from the underlying reader, are returned as they are.
+func decodeError(decoder *xml.Decoder, err error) error {
+	var xmlErr *xml.SyntaxError
+	switch {
+	case errors.As(err, &xmlErr):
+		return &SyntaxError{Msg: xmlErr.Msg, Line: xmlErr.Line, Offset: decoder.InputOffset(), Err: xmlErr}
+	case errors.Is(err, ErrMultipleRoots), errors.Is(err, errInvalidName):
+		line, _ := decoder.InputPos()
+		return &SyntaxError{Msg: err.Error(), Line: line, Offset: decoder.InputOffset(), Err: err}
+	}
+	return err
+}
+
+// PanicError is returned instead of panicking when an operation fails unexpectedly,
+// for example inside a user supplied ValueTransform or ElementOrder function.
+// Any other PanicError indicates a bug in xmlsurf.
+type PanicError struct {
+	Op    string // Operation that failed, e.g. "parse" or "write XML"
+	Value any    // Value passed to panic
+	Stack []byte // Stack trace at the time of the panic
+}
+
+// Error returns a description of the failure
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("xmlsurf: %s: internal error: %v", e.Op, e.Value)
+}
+
+// Unwrap returns the panic value if it is an error
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// recoverPanic converts a panic into a *PanicError stored in err.
+// It must be deferred directly by a function with a named error result.
+func recoverPanic(op string, err *error) {
+	if value := recover(); value != nil {
+		*err = &PanicError{Op: op, Value: value, Stack: debug.Stack()}
+	}
+}
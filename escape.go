@@ -0,0 +1,96 @@
+package xmlsurf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ControlCharPolicy decides how ToXML and WriteXML write characters that XML 1.0
+// does not allow, such as most control characters below U+0020
+type ControlCharPolicy int
+
+const (
+	// ControlCharReplace writes U+FFFD instead of the character; this is the default
+	ControlCharReplace ControlCharPolicy = iota
+	// ControlCharSkip leaves the character out
+	ControlCharSkip
+	// ControlCharError fails writing with an error wrapping ErrInvalidCharacter
+	ControlCharError
+	// ControlCharReference writes a numeric character reference such as &#x1;.
+	// This is only well-formed in XML 1.1; XML 1.0 parsers, including ParseToMap, reject it.
+	ControlCharReference
+)
+
+// isXMLChar reports whether r is allowed in XML 1.0 documents
+func isXMLChar(r rune) bool {
+	return r == 0x09 || r == 0x0A || r == 0x0D ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}
+
+// hasInvalidChars reports whether value holds characters not allowed in XML 1.0 or invalid UTF-8
+func hasInvalidChars(value string) bool {
+	for i := 0; i < len(value); {
+		r, width := utf8.DecodeRuneInString(value[i:])
+		if (r == utf8.RuneError && width == 1) || !isXMLChar(r) {
+			return true
+		}
+		i += width
+	}
+	return false
+}
+
+// escapeValue writes value escaped for use as text or an attribute value, handling
+// invalid characters according to policy. Line breaks are kept literal unless
+// escapeNewlines is set. Invalid UTF-8 bytes have no code point to reference and
+// are replaced by U+FFFD under ControlCharReference.
+func escapeValue(buf *bytes.Buffer, value string, policy ControlCharPolicy, escapeNewlines bool) error {
+	start := 0
+	flush := func(end int) {
+		chunk := value[start:end]
+		for !escapeNewlines {
+			idx := strings.IndexByte(chunk, '\n')
+			if idx == -1 {
+				break
+			}
+			xml.EscapeText(buf, []byte(chunk[:idx]))
+			buf.WriteByte('\n')
+			chunk = chunk[idx+1:]
+		}
+		xml.EscapeText(buf, []byte(chunk))
+	}
+
+	for i := 0; i < len(value); {
+		r, width := utf8.DecodeRuneInString(value[i:])
+		invalidUTF8 := r == utf8.RuneError && width == 1
+		if !invalidUTF8 && isXMLChar(r) {
+			i += width
+			continue
+		}
+
+		flush(i)
+		switch {
+		case policy == ControlCharError:
+			if invalidUTF8 {
+				return fmt.Errorf("%w: invalid UTF-8 byte %#x", ErrInvalidCharacter, value[i])
+			}
+			return fmt.Errorf("%w %U", ErrInvalidCharacter, r)
+		case policy == ControlCharSkip:
+		case policy == ControlCharReference && !invalidUTF8:
+			buf.WriteString("&#x")
+			buf.WriteString(strconv.FormatInt(int64(r), 16))
+			buf.WriteString(";")
+		default:
+			buf.WriteRune(utf8.RuneError)
+		}
+		i += width
+		start = i
+	}
+	flush(len(value))
+	return nil
+}
@@ -0,0 +1,96 @@
+package xmlsurf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWriteXMLEscapingRoundTrip(t *testing.T) {
+	values := []string{
+		`a&b<c>d"e'f`,
+		"line1\nline2",
+		"cr\r\nlf",
+		"tab\there",
+		"]]>",
+		"&amp; already escaped",
+		"ünï € 𝄞",
+		"  padded  ",
+	}
+
+	for _, value := range values {
+		for _, newline := range []string{"\n", "\r\n"} {
+			m := XMLMap{"/root/value": value, "/root/@attr": value}
+			var b strings.Builder
+			if err := m.WriteXML(&b, WithIndent("", "  "), WithNewline(newline)); err != nil {
+				t.Fatalf("WriteXML(%q) error = %v", value, err)
+			}
+			got, err := ParseToMap(strings.NewReader(b.String()), WithTrimValues(false))
+			if err != nil {
+				t.Fatalf("ParseToMap(%s) error = %v", b.String(), err)
+			}
+			if diffs := m.Diffs(got); len(diffs) > 0 {
+				t.Errorf("round trip of %q with newline %q: %v", value, newline, diffs)
+			}
+		}
+	}
+}
+
+func TestWithControlChars(t *testing.T) {
+	m := XMLMap{"/root/value": "a\x01b", "/root/@attr": "c\x1fd"}
+
+	tests := []struct {
+		name    string
+		policy  ControlCharPolicy
+		want    string
+		wantErr bool
+	}{
+		{"replace", ControlCharReplace, "<root attr=\"c\uFFFDd\"><value>a\uFFFDb</value></root>", false},
+		{"skip", ControlCharSkip, `<root attr="cd"><value>ab</value></root>`, false},
+		{"reference", ControlCharReference, `<root attr="c&#x1f;d"><value>a&#x1;b</value></root>`, false},
+		{"error", ControlCharError, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			err := m.WriteXML(&b, WithControlChars(tt.policy))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCharacter) {
+					t.Fatalf("WriteXML() error = %v, want ErrInvalidCharacter", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WriteXML() error = %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("WriteXML() = %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWithControlCharsIndented(t *testing.T) {
+	m := XMLMap{"/root/item/@attr": "x\x02", "/root/item/value": "1"}
+	var b strings.Builder
+	if err := m.WriteXML(&b, WithIndent("", "  "), WithControlChars(ControlCharSkip)); err != nil {
+		t.Fatalf("WriteXML() error = %v", err)
+	}
+	want := "<root>\n  <item attr=\"x\">\n    <value>1</value>\n  </item>\n</root>"
+	if b.String() != want {
+		t.Errorf("WriteXML() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestWithRawValues(t *testing.T) {
+	m := XMLMap{"/root/html": "<b>bold</b> &amp; more", "/root/@attr": "<&>"}
+	var b strings.Builder
+	if err := m.WriteXML(&b, WithRawValues(true)); err != nil {
+		t.Fatalf("WriteXML() error = %v", err)
+	}
+	want := `<root attr="&lt;&amp;&gt;"><html><b>bold</b> &amp; more</html></root>`
+	if b.String() != want {
+		t.Errorf("WriteXML() = %s, want %s", b.String(), want)
+	}
+}
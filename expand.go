@@ -0,0 +1,70 @@
+package xmlsurf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Expand returns a copy of the map with placeholders like ${orderId} in paths and
+// values replaced by the value of the variable of that name, e.g. to splice test data
+// into a request template before ToXML. $${ is written as a literal ${.
+// It fails if a placeholder names an undefined variable, or if two paths expand to the same path.
+func (m XMLMap) Expand(vars map[string]string) (XMLMap, error) {
+	result := make(XMLMap, len(m))
+	undefined := make(map[string]bool)
+
+	for path, value := range m {
+		expandedPath := expandPlaceholders(path, vars, undefined)
+		if _, exists := result[expandedPath]; exists {
+			return nil, fmt.Errorf("expand %s: %w %s", path, ErrDuplicatePath, expandedPath)
+		}
+		result[expandedPath] = expandPlaceholders(value, vars, undefined)
+	}
+
+	if len(undefined) > 0 {
+		names := make([]string, 0, len(undefined))
+		for name := range undefined {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("expand: undefined variables: %s", strings.Join(names, ", "))
+	}
+	return result, nil
+}
+
+// expandPlaceholders replaces the ${name} placeholders of s, recording undefined names
+func expandPlaceholders(s string, vars map[string]string, undefined map[string]bool) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start == -1 {
+			break
+		}
+		if start > 0 && s[start-1] == '$' {
+			// $${ escapes a literal ${
+			b.WriteString(s[:start])
+			b.WriteString("{")
+			s = s[start+2:]
+			continue
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end == -1 {
+			break
+		}
+		name := s[start+2 : start+end]
+		b.WriteString(s[:start])
+		if value, ok := vars[name]; ok {
+			b.WriteString(value)
+		} else {
+			undefined[name] = true
+		}
+		s = s[start+end+1:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
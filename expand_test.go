@@ -0,0 +1,73 @@
+package xmlsurf
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	vars := map[string]string{"orderId": "42", "item": "sku", "n": "2"}
+
+	tests := []struct {
+		name    string
+		m       XMLMap
+		want    XMLMap
+		wantErr string
+	}{
+		{
+			name: "values and paths",
+			m: XMLMap{
+				"/order/@id":              "${orderId}",
+				"/order/items/${item}":    "A-${orderId}-${n}",
+				"/order/lines/line[${n}]": "x",
+				"/order/note":             "no placeholders",
+			},
+			want: XMLMap{
+				"/order/@id":           "42",
+				"/order/items/sku":     "A-42-2",
+				"/order/lines/line[2]": "x",
+				"/order/note":          "no placeholders",
+			},
+		},
+		{
+			name: "escaped and unterminated placeholders",
+			m:    XMLMap{"/a/b": "$${orderId} costs $5 ${orderId"},
+			want: XMLMap{"/a/b": "${orderId} costs $5 ${orderId"},
+		},
+		{
+			name:    "undefined variables",
+			m:       XMLMap{"/a/b": "${missing}", "/a/${other}": "1"},
+			wantErr: "undefined variables: missing, other",
+		},
+		{
+			name:    "paths expanding to the same path",
+			m:       XMLMap{"/a/${item}": "1", "/a/sku": "2"},
+			wantErr: "duplicate path /a/sku",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.m.Expand(vars)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expand() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expand() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	_, err := XMLMap{"/a/${item}": "1", "/a/sku": "2"}.Expand(vars)
+	if !errors.Is(err, ErrDuplicatePath) {
+		t.Errorf("Expand() error = %v, want ErrDuplicatePath", err)
+	}
+}
@@ -0,0 +1,51 @@
+package xmlsurf
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Extract pulls named values out of the map. The template maps names to paths,
+// e.g. {"orderId": "/resp/order/@id"}, and may use the wildcards described in PatternDiffs.
+// It returns an error if a path matches no value or a wildcard path matches several values.
+func (m XMLMap) Extract(template map[string]string) (map[string]string, error) {
+	// Process names in sorted order so the reported error is deterministic
+	names := make([]string, 0, len(template))
+	for name := range template {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make(map[string]string, len(template))
+	for _, name := range names {
+		pattern := template[name]
+		matcher := compilePathPattern(pattern)
+
+		if !matcher.wildcard {
+			value, ok := m[pattern]
+			if !ok {
+				return nil, fmt.Errorf("extract %q: no value at %s", name, pattern)
+			}
+			result[name] = value
+			continue
+		}
+
+		matches := make([]string, 0, 1)
+		for path := range m {
+			if matcher.match(path) {
+				matches = append(matches, path)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return nil, fmt.Errorf("extract %q: no value at %s", name, pattern)
+		case 1:
+			result[name] = m[matches[0]]
+		default:
+			sort.Strings(matches)
+			return nil, fmt.Errorf("extract %q: %d values match %s: %v", name, len(matches), pattern, matches)
+		}
+	}
+
+	return result, nil
+}
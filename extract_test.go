@@ -0,0 +1,65 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestXMLMapExtract(t *testing.T) {
+	response := XMLMap{
+		"/resp/order/@id":           "A-1",
+		"/resp/order/status":        "NEW",
+		"/resp/order/item[1]/sku":   "X",
+		"/resp/order/item[2]/sku":   "Y",
+		"/resp/order/customer/name": "Jane",
+	}
+
+	tests := []struct {
+		name        string
+		template    map[string]string
+		expected    map[string]string
+		expectedErr string
+	}{
+		{
+			name: "exact and wildcard paths",
+			template: map[string]string{
+				"orderId":   "/resp/order/@id",
+				"secondSku": "/resp/order/item[2]/sku",
+				"customer":  "/resp/*/customer/name",
+			},
+			expected: map[string]string{
+				"orderId":   "A-1",
+				"secondSku": "Y",
+				"customer":  "Jane",
+			},
+		},
+		{
+			name:        "missing path",
+			template:    map[string]string{"code": "/resp/order/code"},
+			expectedErr: `extract "code": no value at /resp/order/code`,
+		},
+		{
+			name:        "ambiguous wildcard",
+			template:    map[string]string{"sku": "/resp/order/item[*]/sku"},
+			expectedErr: `extract "sku": 2 values match /resp/order/item[*]/sku: [/resp/order/item[1]/sku /resp/order/item[2]/sku]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := response.Extract(tt.template)
+			if tt.expectedErr != "" {
+				if err == nil || err.Error() != tt.expectedErr {
+					t.Errorf("Extract() error = %v, want %q", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Extract() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
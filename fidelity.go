@@ -0,0 +1,172 @@
+package xmlsurf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// FidelityReport describes the information lost when a document is parsed into
+// an XMLMap and written back with ToXML
+type FidelityReport struct {
+	// Diffs lists differences between the parsed document and the re-parsed output
+	Diffs []Diff
+	// OrderChanged reports whether elements are written in a different order than in the source
+	OrderChanged bool
+	// Declaration reports whether the source had an <?xml?> declaration, which is dropped
+	Declaration bool
+	// Comments counts dropped comments
+	Comments int
+	// ProcessingInstructions counts dropped processing instructions other than the declaration
+	ProcessingInstructions int
+	// Directives counts dropped directives such as <!DOCTYPE>
+	Directives int
+	// TrimmedValues counts text values whose surrounding whitespace was trimmed
+	TrimmedValues int
+	// MixedContent counts elements mixing text with child elements, whose text position is lost
+	MixedContent int
+	// EmptyElements counts dropped elements without text, child elements and attributes,
+	// such as <flag/>; WithEmptyElements keeps them
+	EmptyElements int
+}
+
+// Lossless reports whether the round-trip preserved all information
+func (r *FidelityReport) Lossless() bool {
+	return len(r.Diffs) == 0 && !r.OrderChanged && !r.Declaration && r.Comments == 0 &&
+		r.ProcessingInstructions == 0 && r.Directives == 0 && r.TrimmedValues == 0 && r.MixedContent == 0 &&
+		r.EmptyElements == 0
+}
+
+// RoundTrip parses the document, serializes it with ToXML, parses the output again
+// and returns the differences between both parses. See Fidelity for a full report.
+func RoundTrip(r io.Reader, opts ...Option) ([]Diff, error) {
+	report, err := Fidelity(r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return report.Diffs, nil
+}
+
+// Fidelity performs a round-trip like RoundTrip and reports everything that is lost,
+// including constructs that an XMLMap cannot represent at all
+func Fidelity(r io.Reader, opts ...Option) (*FidelityReport, error) {
+	options := DefaultParseOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FidelityReport{}
+	if err := countLostConstructs(data, options, report); err != nil {
+		return nil, err
+	}
+
+	first := make([]string, 0, 50)
+	parsed, err := parseDocument(bytes.NewReader(data), options, &first)
+	if err != nil {
+		return nil, err
+	}
+
+	var output bytes.Buffer
+	if err := parsed.ToXML(&output, false); err != nil {
+		return nil, err
+	}
+
+	// The output is a whole document, with the synthetic root of a fragment as its root
+	// element, written from what the first parse kept
+	second := make([]string, 0, len(first))
+	reparsed, err := parseDocument(&output, options.innerOptions(), &second)
+	if err != nil {
+		return nil, err
+	}
+
+	report.Diffs = parsed.Diffs(reparsed)
+	report.OrderChanged = !equalStrings(first, second)
+	return report, nil
+}
+
+// countLostConstructs counts the constructs of the document that XMLMap does not keep
+func countLostConstructs(data []byte, options *ParseOptions, report *FidelityReport) error {
+	decoder := newDecoder(bytes.NewReader(data), options)
+
+	// Per open element: whether it has text, child elements and stored attributes
+	type elementState struct {
+		text     bool
+		children bool
+		attrs    bool
+	}
+	stack := make([]elementState, 0, 10)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return decodeError(decoder, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if len(stack) > 0 {
+				stack[len(stack)-1].children = true
+			}
+			state := elementState{}
+			for _, attr := range t.Attr {
+				if attr.Name.Space != "xmlns" && attr.Name.Local != "xmlns" {
+					state.attrs = true
+				}
+			}
+			stack = append(stack, state)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				state := stack[len(stack)-1]
+				if state.text && state.children {
+					report.MixedContent++
+				}
+				if !state.text && !state.children && !state.attrs && !options.EmptyElements {
+					report.EmptyElements++
+				}
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			trimmed := strings.TrimSpace(string(t))
+			if trimmed == "" || len(stack) == 0 {
+				continue
+			}
+			stack[len(stack)-1].text = true
+			if options.TrimValues && trimmed != string(t) {
+				report.TrimmedValues++
+			}
+		case xml.Comment:
+			report.Comments++
+		case xml.ProcInst:
+			if t.Target == "xml" {
+				report.Declaration = true
+			} else {
+				report.ProcessingInstructions++
+			}
+		case xml.Directive:
+			report.Directives++
+		}
+	}
+	return nil
+}
+
+// equalStrings reports whether two string slices are equal
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFidelity(t *testing.T) {
+	tests := []struct {
+		name     string
+		xml      string
+		options  []Option
+		expected FidelityReport
+		lossless bool
+	}{
+		{
+			name:     "lossless document",
+			xml:      `<root><a>1</a><b x="y">2</b></root>`,
+			lossless: true,
+		},
+		{
+			name: "lost constructs",
+			xml: `<?xml version="1.0"?>
+				<!DOCTYPE root>
+				<!-- comment -->
+				<root><?pi data?><z> padded </z><a>text<child>c</child>tail</a></root>`,
+			expected: FidelityReport{
+				OrderChanged:           true,
+				Declaration:            true,
+				Comments:               1,
+				ProcessingInstructions: 1,
+				Directives:             1,
+				TrimmedValues:          1,
+				MixedContent:           1,
+			},
+		},
+		{
+			name:     "dropped empty elements",
+			xml:      `<root><a>1</a><flag/><item id="1"/><note> </note></root>`,
+			expected: FidelityReport{EmptyElements: 2},
+		},
+		{
+			name:     "empty elements kept",
+			xml:      `<root><a>1</a><flag/><item id="1"/><note> </note></root>`,
+			options:  []Option{WithEmptyElements(true)},
+			lossless: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := Fidelity(strings.NewReader(tt.xml), tt.options...)
+			if err != nil {
+				t.Fatalf("Fidelity() error = %v", err)
+			}
+			if report.Lossless() != tt.lossless {
+				t.Errorf("Lossless() = %v, want %v (%+v)", report.Lossless(), tt.lossless, report)
+			}
+			if len(report.Diffs) != 0 {
+				t.Errorf("Fidelity() diffs = %v, want none", report.Diffs)
+			}
+			report.Diffs = nil
+			if !reflect.DeepEqual(*report, tt.expected) {
+				t.Errorf("Fidelity() = %+v, want %+v", *report, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	diffs, err := RoundTrip(strings.NewReader(`<root><item>1</item><item>2</item></root>`))
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("RoundTrip() = %v, want no diffs", diffs)
+	}
+
+	if _, err := RoundTrip(strings.NewReader(`<root>`)); err == nil {
+		t.Errorf("RoundTrip() expected error for malformed input")
+	}
+}
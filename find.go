@@ -0,0 +1,25 @@
+package xmlsurf
+
+import "regexp"
+
+// FindValue returns the paths holding exactly value, in path order
+func (m XMLMap) FindValue(value string) []string {
+	return m.findPaths(func(v string) bool { return v == value })
+}
+
+// FindValueRegexp returns the paths whose value matches re, in path order
+func (m XMLMap) FindValueRegexp(re *regexp.Regexp) []string {
+	return m.findPaths(re.MatchString)
+}
+
+// findPaths returns the paths whose value satisfies match, in path order
+func (m XMLMap) findPaths(match func(string) bool) []string {
+	paths := make([]string, 0)
+	for path, value := range m {
+		if match(value) {
+			paths = append(paths, path)
+		}
+	}
+	sortByPath(paths, func(path string) string { return path })
+	return paths
+}
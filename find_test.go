@@ -0,0 +1,43 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestFindValue(t *testing.T) {
+	m := XMLMap{
+		"/order/@id":             "abc123",
+		"/order/item[10]/ref":    "abc123",
+		"/order/item[2]/ref":     "abc123",
+		"/order/item[2]/name":    "Widget",
+		"/order/customer/ref":    "ABC123",
+		"/order/customer/note":   "",
+		"/order/shipment/parcel": "abc1234",
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{name: "several paths", value: "abc123", want: []string{"/order/@id", "/order/item[2]/ref", "/order/item[10]/ref"}},
+		{name: "empty value", value: "", want: []string{"/order/customer/note"}},
+		{name: "no match", value: "xyz", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.FindValue(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindValue(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	got := m.FindValueRegexp(regexp.MustCompile(`(?i)^abc123`))
+	want := []string{"/order/@id", "/order/customer/ref", "/order/item[2]/ref", "/order/item[10]/ref", "/order/shipment/parcel"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindValueRegexp() = %v, want %v", got, want)
+	}
+}
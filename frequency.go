@@ -0,0 +1,75 @@
+package xmlsurf
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// PathFrequency reports how often a path differs across a corpus of document pairs
+type PathFrequency struct {
+	Path  string  `json:"path"`  // Path template with every index replaced by [*]
+	Count int     `json:"count"` // Number of pairs in which the path differs
+	Ratio float64 `json:"ratio"` // Count divided by the number of pairs
+}
+
+// ChangeFrequency compares each pair of maps and counts, per path template, the
+// number of pairs with at least one difference at that template. Indices are
+// replaced by [*] so repeated elements are counted together.
+// The result is sorted by descending count, then by path.
+func ChangeFrequency(pairs [][2]XMLMap, opts ...CompareOption) []PathFrequency {
+	counts := make(map[string]int)
+	for _, pair := range pairs {
+		seen := make(map[string]bool)
+		for _, diff := range pair[0].Diffs(pair[1], opts...) {
+			template, _ := pathTemplate(diff.Path)
+			if !seen[template] {
+				seen[template] = true
+				counts[template]++
+			}
+		}
+	}
+
+	result := make([]PathFrequency, 0, len(counts))
+	for path, count := range counts {
+		result = append(result, PathFrequency{
+			Path:  path,
+			Count: count,
+			Ratio: float64(count) / float64(len(pairs)),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+// WriteFrequencyCSV writes path frequencies as CSV with a path,count,ratio header
+func WriteFrequencyCSV(w io.Writer, frequencies []PathFrequency) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"path", "count", "ratio"}); err != nil {
+		return err
+	}
+	for _, f := range frequencies {
+		record := []string{
+			f.Path,
+			strconv.Itoa(f.Count),
+			strconv.FormatFloat(f.Ratio, 'f', 4, 64),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteFrequencyJSON writes path frequencies as a JSON array
+func WriteFrequencyJSON(w io.Writer, frequencies []PathFrequency) error {
+	return json.NewEncoder(w).Encode(frequencies)
+}
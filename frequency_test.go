@@ -0,0 +1,56 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChangeFrequency(t *testing.T) {
+	pairs := [][2]XMLMap{
+		{
+			{"/root/id": "1", "/root/item[1]/price": "1", "/root/item[2]/price": "2"},
+			{"/root/id": "2", "/root/item[1]/price": "3", "/root/item[2]/price": "4"},
+		},
+		{
+			{"/root/id": "1", "/root/status": "OK"},
+			{"/root/id": "2", "/root/status": "OK"},
+		},
+		{
+			{"/root/id": "1", "/root/status": "OK"},
+			{"/root/id": "1", "/root/status": "FAIL"},
+		},
+		{
+			{"/root/id": "1"},
+			{"/root/id": "1"},
+		},
+	}
+
+	frequencies := ChangeFrequency(pairs)
+	expected := []PathFrequency{
+		{Path: "/root/id", Count: 2, Ratio: 0.5},
+		{Path: "/root/item[*]/price", Count: 1, Ratio: 0.25},
+		{Path: "/root/status", Count: 1, Ratio: 0.25},
+	}
+	if !reflect.DeepEqual(frequencies, expected) {
+		t.Fatalf("ChangeFrequency() = %v, want %v", frequencies, expected)
+	}
+
+	var csvOutput strings.Builder
+	if err := WriteFrequencyCSV(&csvOutput, frequencies); err != nil {
+		t.Fatalf("WriteFrequencyCSV() error = %v", err)
+	}
+	expectedCSV := "path,count,ratio\n/root/id,2,0.5000\n/root/item[*]/price,1,0.2500\n/root/status,1,0.2500\n"
+	if csvOutput.String() != expectedCSV {
+		t.Errorf("WriteFrequencyCSV() = %q, want %q", csvOutput.String(), expectedCSV)
+	}
+
+	var jsonOutput strings.Builder
+	if err := WriteFrequencyJSON(&jsonOutput, frequencies[:1]); err != nil {
+		t.Fatalf("WriteFrequencyJSON() error = %v", err)
+	}
+	expectedJSON := `[{"path":"/root/id","count":2,"ratio":0.5}]` + "\n"
+	if jsonOutput.String() != expectedJSON {
+		t.Errorf("WriteFrequencyJSON() = %q, want %q", jsonOutput.String(), expectedJSON)
+	}
+}
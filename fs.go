@@ -0,0 +1,41 @@
+package xmlsurf
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// ParseFile parses the named XML file from the file system
+func ParseFile(fsys fs.FS, name string, opts ...Option) (XMLMap, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	result, err := ParseToMap(file, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return result, nil
+}
+
+// ParseGlob parses all XML files matching the pattern (see fs.Glob) and
+// returns a map of file names to their parsed XMLMaps.
+// Parsing stops at the first file that fails.
+func ParseGlob(fsys fs.FS, pattern string, opts ...Option) (map[string]XMLMap, error) {
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]XMLMap, len(names))
+	for _, name := range names {
+		result, err := ParseFile(fsys, name, opts...)
+		if err != nil {
+			return nil, err
+		}
+		results[name] = result
+	}
+	return results, nil
+}
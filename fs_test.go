@@ -0,0 +1,51 @@
+package xmlsurf
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"testdata/a.xml": {Data: []byte(`<root><item>a</item></root>`)},
+	}
+
+	result, err := ParseFile(fsys, "testdata/a.xml")
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	if !result.Equal(XMLMap{"/root/item": "a"}) {
+		t.Errorf("ParseFile() result = %v", result)
+	}
+
+	_, err = ParseFile(fsys, "testdata/missing.xml")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ParseFile() error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestParseGlob(t *testing.T) {
+	fsys := fstest.MapFS{
+		"testdata/a.xml":   {Data: []byte(`<root><item>a</item></root>`)},
+		"testdata/b.xml":   {Data: []byte(`<root><item>b</item></root>`)},
+		"testdata/c.txt":   {Data: []byte(`not xml`)},
+		"testdata/bad.xml": {Data: []byte(`<root>`)},
+	}
+
+	results, err := ParseGlob(fsys, "testdata/[ab].xml")
+	if err != nil {
+		t.Fatalf("ParseGlob() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("ParseGlob() returned %d maps, want 2", len(results))
+	}
+	if !results["testdata/b.xml"].Equal(XMLMap{"/root/item": "b"}) {
+		t.Errorf("ParseGlob() result for b.xml = %v", results["testdata/b.xml"])
+	}
+
+	if _, err := ParseGlob(fsys, "testdata/*.xml"); err == nil {
+		t.Errorf("ParseGlob() expected error for malformed file")
+	}
+}
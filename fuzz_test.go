@@ -0,0 +1,174 @@
+package xmlsurf
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Not panicking is part of the API contract: malformed documents and arbitrary
+// maps must produce errors or diffs, never panics or a *PanicError.
+
+// checkNoPanicError fails the test if err reports a recovered internal failure
+func checkNoPanicError(t *testing.T, op string, err error) {
+	t.Helper()
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		t.Fatalf("%s recovered from a panic: %v\n%s", op, panicErr.Value, panicErr.Stack)
+	}
+}
+
+// exerciseMap calls the map operations on m and fails on any recovered panic
+func exerciseMap(t *testing.T, m, other XMLMap) {
+	var buf bytes.Buffer
+	checkNoPanicError(t, "ToXML", m.ToXML(&buf, true))
+	checkNoPanicError(t, "WriteXML", m.WriteXML(&buf, WithSelfClosing(true), WithNewline("\r\n"), WithDeclaration("UTF-8")))
+	for path := range m {
+		checkNoPanicError(t, "ToCSV", m.ToCSV(&buf, path, []string{"@id", "."}))
+		_, err := m.Extract(map[string]string{"value": path})
+		checkNoPanicError(t, "Extract", err)
+		_, err = m.Evaluate(path)
+		checkNoPanicError(t, "Evaluate", err)
+	}
+	_, err := m.WrapNested()
+	checkNoPanicError(t, "WrapNested", err)
+	_, err = m.NestedTree()
+	checkNoPanicError(t, "NestedTree", err)
+	m.Validate()
+
+	m.Diffs(other, WithNestedDocuments(true))
+	m.DiffsIgnoreOrder(other)
+	m.DiffsIgnoreOrder(other, WithDiffSemantics(DiffSemanticsV1))
+	m.DiffsLeaves(other)
+	m.ContainsDiffs(other)
+	m.PatternDiffs(other)
+	m.UnwrapNested()
+	m.Clone().Merge(other, MergeAppend)
+	GroupDiffs(m.Diffs(other))
+	DiffN([]XMLMap{m, other})
+	SynthesizePattern([]XMLMap{m, other})
+	ChangeFrequency([][2]XMLMap{{m, other}})
+}
+
+func FuzzParseToMap(f *testing.F) {
+	f.Add(`<root><a x="1">v</a><a>w</a><b><c/></b></root>`)
+	f.Add(`<s:Envelope xmlns:s="urn:s"><s:Body><x>&lt;y&gt;1&lt;/y&gt;</x></s:Body></s:Envelope>`)
+	f.Add(`<root><item>1</item><item><item>2</item></item></root>`)
+	f.Add(`<a><b></a>`)
+	f.Add(``)
+
+	f.Fuzz(func(t *testing.T, data string) {
+		m, err := ParseToMap(strings.NewReader(data), WithUnwrapNested(true), WithNamespaces(true), WithEmptyElements(true))
+		checkNoPanicError(t, "ParseToMap", err)
+		if err != nil {
+			return
+		}
+		// Parsed maps always hold well-formed paths
+		if err := m.Validate(); err != nil {
+			t.Fatalf("Validate() of parsed map error = %v", err)
+		}
+		other, err := ParseToMap(strings.NewReader(data))
+		checkNoPanicError(t, "ParseToMap", err)
+		exerciseMap(t, m, other)
+	})
+}
+
+// checkContains fails the test if got lacks a path of want or holds another value for it
+func checkContains(t *testing.T, op string, want, got XMLMap) {
+	t.Helper()
+	for path, value := range want {
+		if gotValue, ok := got[path]; !ok {
+			t.Fatalf("%s lost path %q\nwant %v\ngot  %v", op, path, want, got)
+		} else if gotValue != value {
+			t.Fatalf("%s changed %q from %q to %q", op, path, value, gotValue)
+		}
+	}
+}
+
+// FuzzRoundTrip checks that writing a parsed map and parsing the result keeps every
+// path and value, and that index styles convert back to brackets unchanged
+func FuzzRoundTrip(f *testing.F) {
+	f.Add(`<root><a x="1">v</a><a>w</a><b><c/></b></root>`)
+	f.Add(`<root><item id="1"><name>A</name></item><item id="2"><name>B</name></item></root>`)
+	f.Add(`<root><a>&lt;&amp;&gt;"'</a><b xml:lang="en"> t </b></root>`)
+	f.Add(`<p:root xmlns:p="urn:p" xmlns="urn:d"><p:a p:x="1">v</p:a><b/></p:root>`)
+
+	f.Fuzz(func(t *testing.T, data string) {
+		// Without WithEmptyElements, dropping an empty sibling renumbers the others
+		for _, opts := range [][]Option{
+			{WithEmptyElements(true)},
+			{WithEmptyElements(true), WithNamespaces(true)},
+		} {
+			m, err := ParseToMap(strings.NewReader(data), opts...)
+			checkNoPanicError(t, "ParseToMap", err)
+			if err != nil {
+				return
+			}
+
+			// Maps hold prefixes but no declarations, so any URI keeps the prefixes
+			namespaces := make(map[string]string)
+			for path := range m {
+				for _, segment := range strings.Split(path, "/") {
+					if prefix := namePrefix(strings.TrimPrefix(segment, "@")); prefix != "" {
+						namespaces[prefix] = "urn:" + prefix
+					}
+				}
+			}
+			var buf bytes.Buffer
+			if err := m.WriteXML(&buf, WithNamespaceDeclarations(namespaces)); err != nil {
+				t.Fatalf("WriteXML() error = %v", err)
+			}
+			written, err := ParseToMap(&buf, opts...)
+			if err != nil {
+				t.Fatalf("ParseToMap() of written map error = %v\n%s", err, buf.String())
+			}
+			checkContains(t, "WriteXML round trip", m, written)
+		}
+
+		for _, style := range []IndexStyle{IndexDot, IndexHash} {
+			styled, err := ParseToMap(strings.NewReader(data), WithIndexStyle(style))
+			checkNoPanicError(t, "ParseToMap", err)
+			if err != nil {
+				return
+			}
+			bracketed, _ := ParseToMap(strings.NewReader(data))
+			checkContains(t, "BracketIndices", bracketed, styled.BracketIndices(style))
+		}
+	})
+}
+
+func FuzzXMLMapOperations(f *testing.F) {
+	f.Add("/root/a[1]", "v", "/root/a[2]/@x", "w")
+	f.Add("/root/a", "<x>1</x>", "/root/a!/x", "1")
+	f.Add("/", "", "root", "[")
+	f.Add("/root/a[0]", "", "/root/a[x]/@", "]")
+	f.Add("/@", "0", "//a[last()]/@*", "0")
+
+	f.Fuzz(func(t *testing.T, path1, value1, path2, value2 string) {
+		m := XMLMap{path1: value1, path2: value2}
+		exerciseMap(t, m, XMLMap{path2: value1})
+		exerciseMap(t, XMLMap{path1: value2}, m)
+	})
+}
+
+func TestPanicErrorFromCallback(t *testing.T) {
+	_, err := ParseToMap(strings.NewReader(`<root>x</root>`), WithValueTransform(func(string) string {
+		panic("transform failed")
+	}))
+
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("ParseToMap() error = %v, want *PanicError", err)
+	}
+	if panicErr.Op != "parse" || panicErr.Value != "transform failed" {
+		t.Errorf("PanicError = %+v", panicErr)
+	}
+
+	err = XMLMap{"/root/a": "1", "/root/b": "2"}.WriteXML(&bytes.Buffer{}, WithElementOrder(func(a, b string) bool {
+		panic(errors.New("order failed"))
+	}))
+	if !errors.As(err, &panicErr) || panicErr.Op != "write XML" || panicErr.Unwrap() == nil {
+		t.Errorf("WriteXML() error = %v, want *PanicError wrapping the panic error", err)
+	}
+}
@@ -0,0 +1,44 @@
+package xmlsurf
+
+import (
+	"strings"
+)
+
+// GetAll returns the values of all elements at the index-free path in index order,
+// e.g. GetAll("/root/items/item") returns the values of item[1], item[2], ...
+// A single element without index is returned as the only value. Elements that
+// only contain children or attributes contribute an empty value.
+func (m XMLMap) GetAll(path string) []string {
+	records := m.recordPaths(path)
+	values := make([]string, len(records))
+	for i, record := range records {
+		values[i] = m[record]
+	}
+	return values
+}
+
+// GetAllSubtrees returns the subtree of every element at the index-free path in
+// index order. Each subtree is a standalone map rooted at the element, so
+// /root/items/item[2]/name becomes /item/name in the second subtree. A path that
+// is not absolute, e.g. "item", selects no elements.
+func (m XMLMap) GetAllSubtrees(path string) []XMLMap {
+	if !strings.HasPrefix(path, "/") {
+		return []XMLMap{}
+	}
+	records := m.recordPaths(path)
+	root := path[strings.LastIndex(path, "/"):]
+
+	subtrees := make([]XMLMap, len(records))
+	positions := make(map[string]int, len(records))
+	for i, record := range records {
+		subtrees[i] = make(XMLMap)
+		positions[record] = i
+	}
+
+	for p, value := range m {
+		if record, ok := recordOf(p, path); ok {
+			subtrees[positions[record]][root+p[len(record):]] = value
+		}
+	}
+	return subtrees
+}
@@ -0,0 +1,99 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestXMLMapGetAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        XMLMap
+		path     string
+		expected []string
+	}{
+		{
+			name: "indexed siblings in index order",
+			m: XMLMap{
+				"/root/items/item[10]":  "j",
+				"/root/items/item[2]":   "b",
+				"/root/items/item[1]":   "a",
+				"/root/items/itemCount": "3",
+			},
+			path:     "/root/items/item",
+			expected: []string{"a", "b", "j"},
+		},
+		{
+			name: "single element",
+			m: XMLMap{
+				"/root/item": "only",
+			},
+			path:     "/root/item",
+			expected: []string{"only"},
+		},
+		{
+			name: "elements without own value",
+			m: XMLMap{
+				"/root/item[1]/name": "a",
+				"/root/item[2]":      "b",
+			},
+			path:     "/root/item",
+			expected: []string{"", "b"},
+		},
+		{
+			name:     "no elements",
+			m:        XMLMap{"/root/other": "x"},
+			path:     "/root/item",
+			expected: []string{},
+		},
+		{
+			name:     "relative path",
+			m:        XMLMap{"/root/item": "x"},
+			path:     "item",
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.m.GetAll(tt.path); !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("GetAll() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestXMLMapGetAllSubtrees(t *testing.T) {
+	m := XMLMap{
+		"/root/items/item[1]/@id":  "1",
+		"/root/items/item[1]/name": "pen",
+		"/root/items/item[2]":      "loose",
+		"/root/items/item[2]/@id":  "2",
+		"/root/items/itemCount":    "2",
+		"/root/items/item[2]!/x/y": "nested",
+	}
+
+	expected := []XMLMap{
+		{
+			"/item/@id":  "1",
+			"/item/name": "pen",
+		},
+		{
+			"/item":      "loose",
+			"/item/@id":  "2",
+			"/item!/x/y": "nested",
+		},
+	}
+
+	result := m.GetAllSubtrees("/root/items/item")
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("GetAllSubtrees() = %v, want %v", result, expected)
+	}
+
+	// Relative paths select nothing
+	for _, path := range []string{"item", ""} {
+		if result := m.GetAllSubtrees(path); len(result) != 0 {
+			t.Errorf("GetAllSubtrees(%q) = %v, want no subtrees", path, result)
+		}
+	}
+}
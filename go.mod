@@ -1,6 +1,3 @@
 module github.com/bmcszk/xmlsurf
 
 go 1.22
-
-require (
-)
@@ -0,0 +1,189 @@
+// Package godogsteps provides godog step definitions for asserting XML responses
+// in Gherkin scenarios.
+package godogsteps
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/bmcszk/xmlsurf"
+	"github.com/cucumber/godog"
+)
+
+func init() {
+	xmlsurf.RegisterModule("godogsteps")
+}
+
+// ErrNoResponse is returned by steps that run before a response was set
+var ErrNoResponse = errors.New("no response XML")
+
+// Steps holds the response XML of a scenario
+type Steps struct {
+	options  []xmlsurf.Option
+	response xmlsurf.XMLMap
+}
+
+// Register adds the XML steps to the scenario and returns the Steps holding its
+// response. Call it from the ScenarioInitializer, so each scenario starts without
+// a response; your own steps set it with SetResponse. The steps are:
+//
+//	the response XML is:                                   (doc string)
+//	the response XML at "<path>" should equal "<value>"
+//	the response XML at "<path>" should contain "<text>"
+//	the response XML at "<path>" should exist
+//	the response XML at "<path>" should not exist
+//	the response XML should have <n> "<path>" elements
+//	the response XML should contain:                       (table of path and value)
+//	the response XML should match:                         (doc string)
+//
+// The path of a repeated element may omit its index to select the first one.
+func Register(sc *godog.ScenarioContext, opts ...xmlsurf.Option) *Steps {
+	s := &Steps{options: opts}
+	sc.Step(`^the response XML is:$`, s.responseIs)
+	sc.Step(`^the response XML at "([^"]*)" should equal "([^"]*)"$`, s.shouldEqual)
+	sc.Step(`^the response XML at "([^"]*)" should contain "([^"]*)"$`, s.shouldContain)
+	sc.Step(`^the response XML at "([^"]*)" should exist$`, s.shouldExist)
+	sc.Step(`^the response XML at "([^"]*)" should not exist$`, s.shouldNotExist)
+	sc.Step(`^the response XML should have (\d+) "([^"]*)" elements?$`, s.shouldHaveCount)
+	sc.Step(`^the response XML should contain:$`, s.shouldContainTable)
+	sc.Step(`^the response XML should match:$`, s.shouldMatch)
+	return s
+}
+
+// SetResponse parses r as the response XML of the scenario
+func (s *Steps) SetResponse(r io.Reader) error {
+	m, err := xmlsurf.ParseToMap(r, s.options...)
+	if err != nil {
+		return err
+	}
+	s.response = m
+	return nil
+}
+
+// SetResponseMap sets an already parsed map as the response XML of the scenario
+func (s *Steps) SetResponseMap(m xmlsurf.XMLMap) {
+	s.response = m
+}
+
+// Response returns the response XML of the scenario, or nil if none was set
+func (s *Steps) Response() xmlsurf.XMLMap {
+	return s.response
+}
+
+func (s *Steps) responseIs(doc *godog.DocString) error {
+	return s.SetResponse(strings.NewReader(doc.Content))
+}
+
+// values returns the values at path: the value of the path itself, or else the
+// values of all elements at the index-free path
+func (s *Steps) values(path string) ([]string, error) {
+	if s.response == nil {
+		return nil, ErrNoResponse
+	}
+	if value, ok := s.response[path]; ok {
+		return []string{value}, nil
+	}
+	return s.response.GetAll(path), nil
+}
+
+// value returns the value at path, or the first of the elements at the index-free path
+func (s *Steps) value(path string) (string, error) {
+	values, err := s.values(path)
+	if err != nil {
+		return "", err
+	}
+	if len(values) == 0 {
+		return "", fmt.Errorf("%w: %s", xmlsurf.ErrPathNotFound, path)
+	}
+	return values[0], nil
+}
+
+func (s *Steps) shouldEqual(path, want string) error {
+	got, err := s.value(path)
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("value at %s is %q, want %q", path, got, want)
+	}
+	return nil
+}
+
+func (s *Steps) shouldContain(path, want string) error {
+	got, err := s.value(path)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(got, want) {
+		return fmt.Errorf("value at %s is %q, want it to contain %q", path, got, want)
+	}
+	return nil
+}
+
+func (s *Steps) shouldExist(path string) error {
+	_, err := s.value(path)
+	return err
+}
+
+func (s *Steps) shouldNotExist(path string) error {
+	values, err := s.values(path)
+	if err != nil {
+		return err
+	}
+	if len(values) != 0 {
+		return fmt.Errorf("path %s exists with value %q", path, values[0])
+	}
+	return nil
+}
+
+func (s *Steps) shouldHaveCount(count, path string) error {
+	want, err := strconv.Atoi(count)
+	if err != nil {
+		return err
+	}
+	if s.response == nil {
+		return ErrNoResponse
+	}
+	if got := len(s.response.GetAll(path)); got != want {
+		return fmt.Errorf("found %d elements at %s, want %d", got, path, want)
+	}
+	return nil
+}
+
+func (s *Steps) shouldContainTable(table *godog.Table) error {
+	subset := make(xmlsurf.XMLMap, len(table.Rows))
+	for _, row := range table.Rows {
+		if len(row.Cells) != 2 {
+			return fmt.Errorf("table rows must have a path and a value, got %d cells", len(row.Cells))
+		}
+		subset[row.Cells[0].Value] = row.Cells[1].Value
+	}
+	return s.contains(subset)
+}
+
+func (s *Steps) shouldMatch(doc *godog.DocString) error {
+	expected, err := xmlsurf.ParseToMap(strings.NewReader(doc.Content), s.options...)
+	if err != nil {
+		return err
+	}
+	return s.contains(expected)
+}
+
+// contains returns an error listing the diffs if the response does not contain subset
+func (s *Steps) contains(subset xmlsurf.XMLMap) error {
+	if s.response == nil {
+		return ErrNoResponse
+	}
+	diffs := s.response.ContainsDiffs(subset)
+	if len(diffs) == 0 {
+		return nil
+	}
+	lines := make([]string, len(diffs))
+	for i, diff := range diffs {
+		lines[i] = diff.String()
+	}
+	return fmt.Errorf("response XML differs:\n%s", strings.Join(lines, "\n"))
+}
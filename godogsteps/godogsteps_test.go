@@ -0,0 +1,72 @@
+package godogsteps
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/bmcszk/xmlsurf"
+	"github.com/cucumber/godog"
+)
+
+func TestSteps(t *testing.T) {
+	suite := godog.TestSuite{
+		Name: "godogsteps",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			Register(sc)
+		},
+		Options: &godog.Options{
+			Format:   "progress",
+			Output:   io.Discard,
+			Paths:    []string{"testdata"},
+			Strict:   true,
+			TestingT: t,
+		},
+	}
+	if status := suite.Run(); status != 0 {
+		t.Errorf("godog suite status = %d, want 0", status)
+	}
+}
+
+func TestStepFailures(t *testing.T) {
+	s := &Steps{}
+	if err := s.shouldEqual("/order/status", "SHIPPED"); !errors.Is(err, ErrNoResponse) {
+		t.Errorf("step without a response error = %v, want ErrNoResponse", err)
+	}
+	if err := s.SetResponse(strings.NewReader(`<order><status>NEW</status><item><sku>A-1</sku></item><item><sku>B-2</sku></item></order>`)); err != nil {
+		t.Fatalf("SetResponse() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		step    func() error
+		wantErr string
+	}{
+		{name: "value mismatch", step: func() error { return s.shouldEqual("/order/status", "SHIPPED") }, wantErr: `"NEW", want "SHIPPED"`},
+		{name: "missing path", step: func() error { return s.shouldEqual("/order/id", "1") }, wantErr: "/order/id"},
+		{name: "text not contained", step: func() error { return s.shouldContain("/order/status", "SHIP") }, wantErr: `want it to contain "SHIP"`},
+		{name: "unexpected path", step: func() error { return s.shouldNotExist("/order/status") }, wantErr: "exists"},
+		{name: "count mismatch", step: func() error { return s.shouldHaveCount("3", "/order/item") }, wantErr: "found 2 elements"},
+		{
+			name: "subset mismatch",
+			step: func() error {
+				return s.shouldMatch(&godog.DocString{Content: `<order><status>SHIPPED</status></order>`})
+			},
+			wantErr: "/order/status",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.step()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("step error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := s.shouldExist("/order/id"); !errors.Is(err, xmlsurf.ErrPathNotFound) {
+		t.Errorf("shouldExist() error = %v, want ErrPathNotFound", err)
+	}
+}
@@ -0,0 +1,26 @@
+Feature: Order response
+
+  Scenario: Shipped order
+    Given the response XML is:
+      """
+      <order id="42">
+        <status>SHIPPED</status>
+        <note>Leave at the front door</note>
+        <item><sku>A-1</sku></item>
+        <item><sku>B-2</sku></item>
+      </order>
+      """
+    Then the response XML at "/order/status" should equal "SHIPPED"
+    And the response XML at "/order/@id" should equal "42"
+    And the response XML at "/order/item[1]/sku" should equal "A-1"
+    And the response XML at "/order/note" should contain "front door"
+    And the response XML at "/order/item" should exist
+    And the response XML at "/order/cancelled" should not exist
+    And the response XML should have 2 "/order/item" elements
+    And the response XML should contain:
+      | /order/status      | SHIPPED |
+      | /order/item[2]/sku | B-2     |
+    And the response XML should match:
+      """
+      <order><status>SHIPPED</status></order>
+      """
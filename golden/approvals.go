@@ -0,0 +1,76 @@
+package golden
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bmcszk/xmlsurf"
+)
+
+// VerifyXML parses r and compares it, ignoring the order of repeated elements, with
+// the approved snapshot testdata/<test name>.approved.xml. If they differ or nothing
+// was approved yet, it writes the canonical serialization of r next to it as
+// <test name>.received.xml and reports an error; review the received file and rename
+// it to approve it. A received file is removed once the snapshot matches.
+// When updating, it writes the approved file directly. It reports whether r matches.
+func VerifyXML(t testing.TB, r io.Reader, opts ...Option) bool {
+	t.Helper()
+	name := snapshotName(t.Name())
+	approved := Golden(t, name+".approved.xml", opts...)
+	received := Golden(t, name+".received.xml", opts...)
+
+	actual, err := xmlsurf.ParseToMap(r, approved.parseOptions...)
+	if err != nil {
+		t.Errorf("parsing received XML: %v", err)
+		return false
+	}
+	if update() {
+		approved.write(actual)
+		return true
+	}
+
+	expected, err := xmlsurf.ParseFile(os.DirFS(approved.dir), filepath.ToSlash(approved.name), approved.parseOptions...)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("approved file %s: %v", approved.Path(), err)
+		return false
+	}
+	if err == nil {
+		diffs := actual.DiffsIgnoreOrder(expected, xmlsurf.WithIgnorePaths(approved.ignorePaths...))
+		if len(diffs) == 0 {
+			if err := os.Remove(received.Path()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("removing received file: %v", err)
+			}
+			return true
+		}
+		lines := make([]string, 0, len(diffs))
+		for _, group := range xmlsurf.GroupDiffs(diffs) {
+			lines = append(lines, "  "+group.String())
+		}
+		received.write(actual)
+		t.Errorf("received XML differs from %s:\n%s\nreview %s and rename it to %s to approve it",
+			approved.Path(), strings.Join(lines, "\n"), received.Path(), approved.Path())
+		return false
+	}
+
+	received.write(actual)
+	t.Errorf("no approved XML at %s; review %s and rename it to %s to approve it",
+		approved.Path(), received.Path(), approved.Path())
+	return false
+}
+
+// snapshotName turns a test name into a file name, replacing the separators of
+// subtests and characters file systems reject with underscores
+func snapshotName(testName string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', ' ':
+			return '_'
+		}
+		return r
+	}, testName)
+}
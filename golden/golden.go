@@ -0,0 +1,146 @@
+// Package golden compares xmlsurf maps in tests with golden XML files in testdata
+// and rewrites the files when the tests run with XMLSURF_UPDATE_GOLDEN=1 set.
+package golden
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bmcszk/xmlsurf"
+)
+
+func init() {
+	xmlsurf.RegisterModule("golden")
+}
+
+// UpdateEnv is the environment variable that rewrites the golden files with the actual
+// maps when set to a true value, e.g. XMLSURF_UPDATE_GOLDEN=1 go test ./...
+// An environment variable rather than a flag leaves the flags of test binaries alone.
+const UpdateEnv = "XMLSURF_UPDATE_GOLDEN"
+
+// update reports whether the golden files are rewritten
+func update() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(UpdateEnv))
+	return enabled
+}
+
+// File is a golden file of a test
+type File struct {
+	t            testing.TB
+	dir          string
+	name         string
+	ignorePaths  []string
+	parseOptions []xmlsurf.Option
+	writeOptions []xmlsurf.WriteOption
+}
+
+// Option configures a golden File
+type Option func(*File)
+
+// WithDir returns an Option that looks for the file in dir instead of testdata
+func WithDir(dir string) Option {
+	return func(f *File) {
+		f.dir = dir
+	}
+}
+
+// WithIgnorePaths returns an Option that excludes the paths and everything below them
+// from the comparison, e.g. timestamps or generated IDs
+func WithIgnorePaths(paths ...string) Option {
+	return func(f *File) {
+		f.ignorePaths = append(f.ignorePaths, paths...)
+	}
+}
+
+// WithParseOptions returns an Option that parses the golden file with opts
+func WithParseOptions(opts ...xmlsurf.Option) Option {
+	return func(f *File) {
+		f.parseOptions = append(f.parseOptions, opts...)
+	}
+}
+
+// WithWriteOptions returns an Option that writes the golden file with opts when updating.
+// Files are indented with two spaces unless opts set another indentation.
+func WithWriteOptions(opts ...xmlsurf.WriteOption) Option {
+	return func(f *File) {
+		f.writeOptions = append(f.writeOptions, opts...)
+	}
+}
+
+// Golden returns the golden file testdata/name.xml of the test. The extension is
+// added unless name already has one.
+func Golden(t testing.TB, name string, opts ...Option) *File {
+	t.Helper()
+	if filepath.Ext(name) == "" {
+		name += ".xml"
+	}
+	f := &File{t: t, dir: "testdata", name: name}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Path returns the path of the golden file
+func (f *File) Path() string {
+	return filepath.Join(f.dir, f.name)
+}
+
+// Load parses the golden file, failing the test if it cannot be read
+func (f *File) Load() xmlsurf.XMLMap {
+	f.t.Helper()
+	expected, err := xmlsurf.ParseFile(os.DirFS(f.dir), filepath.ToSlash(f.name), f.parseOptions...)
+	if errors.Is(err, fs.ErrNotExist) {
+		f.t.Fatalf("golden file %s does not exist; run the test with "+UpdateEnv+"=1 to create it", f.Path())
+	}
+	if err != nil {
+		f.t.Fatalf("golden file %s: %v", f.Path(), err)
+	}
+	return expected
+}
+
+// AssertEqual reports an error listing the differences if actual differs from the
+// golden file outside the ignored paths, and reports whether both are equal.
+// When updating, it writes actual to the golden file instead.
+func (f *File) AssertEqual(actual xmlsurf.XMLMap) bool {
+	f.t.Helper()
+	if update() {
+		f.write(actual)
+		return true
+	}
+
+	diffs := actual.Diffs(f.Load(), xmlsurf.WithIgnorePaths(f.ignorePaths...))
+	if len(diffs) == 0 {
+		return true
+	}
+	lines := make([]string, 0, len(diffs))
+	for _, group := range xmlsurf.GroupDiffs(diffs) {
+		lines = append(lines, "  "+group.String())
+	}
+	f.t.Errorf("map differs from golden file %s (run with "+UpdateEnv+"=1 to rewrite it):\n%s", f.Path(), strings.Join(lines, "\n"))
+	return false
+}
+
+// write replaces the golden file with actual
+func (f *File) write(actual xmlsurf.XMLMap) {
+	f.t.Helper()
+	var buf bytes.Buffer
+	opts := append([]xmlsurf.WriteOption{xmlsurf.WithIndent("", "  ")}, f.writeOptions...)
+	if err := actual.WriteXML(&buf, opts...); err != nil {
+		f.t.Fatalf("writing golden file %s: %v", f.Path(), err)
+	}
+	buf.WriteString("\n")
+
+	if err := os.MkdirAll(filepath.Dir(f.Path()), 0o755); err != nil {
+		f.t.Fatalf("writing golden file %s: %v", f.Path(), err)
+	}
+	if err := os.WriteFile(f.Path(), buf.Bytes(), 0o644); err != nil {
+		f.t.Fatalf("writing golden file %s: %v", f.Path(), err)
+	}
+}
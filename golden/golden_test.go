@@ -0,0 +1,206 @@
+package golden
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bmcszk/xmlsurf"
+)
+
+// Test packages importing golden may define a flag of the same name as before
+var _ = flag.Bool("update", false, "update the test's own files")
+
+// recorder is a testing.TB that records failures instead of reporting them
+type recorder struct {
+	testing.TB
+	errors []string
+	fatal  bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+	r.fatal = true
+	runtime.Goexit()
+}
+
+// run calls fn with a recorder in its own goroutine, so Fatalf can stop it
+func run(t *testing.T, fn func(tb testing.TB)) *recorder {
+	r := &recorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(r)
+	}()
+	<-done
+	return r
+}
+
+func setUpdate(t *testing.T, value bool) {
+	t.Setenv(UpdateEnv, strconv.FormatBool(value))
+}
+
+func TestGolden(t *testing.T) {
+	dir := t.TempDir()
+	actual := xmlsurf.XMLMap{
+		"/order/@id":           "42",
+		"/order/created":       "2024-01-01T10:00:00Z",
+		"/order/item[1]/price": "10",
+		"/order/item[2]/price": "20",
+	}
+
+	setUpdate(t, true)
+	r := run(t, func(tb testing.TB) { Golden(tb, "order", WithDir(dir)).AssertEqual(actual) })
+	if len(r.errors) != 0 {
+		t.Fatalf("AssertEqual() when updating reported %v", r.errors)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "order.xml"))
+	if err != nil {
+		t.Fatalf("golden file not written: %v", err)
+	}
+	if !strings.Contains(string(data), "\n  <created>") || !strings.HasSuffix(string(data), ">\n") {
+		t.Errorf("golden file = %q, want indented XML ending with a newline", data)
+	}
+
+	setUpdate(t, false)
+	tests := []struct {
+		name       string
+		actual     xmlsurf.XMLMap
+		opts       []Option
+		wantErrors []string
+	}{
+		{name: "equal", actual: actual},
+		{
+			name: "ignored path",
+			actual: xmlsurf.XMLMap{
+				"/order/@id": "42", "/order/created": "2024-06-30T12:00:00Z",
+				"/order/item[1]/price": "10", "/order/item[2]/price": "20",
+			},
+			opts: []Option{WithIgnorePaths("/order/created")},
+		},
+		{
+			name: "differences",
+			actual: xmlsurf.XMLMap{
+				"/order/@id": "42", "/order/created": "2024-01-01T10:00:00Z",
+				"/order/item[1]/price": "11", "/order/item[2]/price": "21", "/order/note": "rush",
+			},
+			wantErrors: []string{"order.xml", UpdateEnv, "/order/item[*]/price for indices 1,2", "/order/note"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var equal bool
+			r := run(t, func(tb testing.TB) {
+				equal = Golden(tb, "order", append(tt.opts, WithDir(dir))...).AssertEqual(tt.actual)
+			})
+			if equal != (len(tt.wantErrors) == 0) {
+				t.Errorf("AssertEqual() = %v, errors %v", equal, r.errors)
+			}
+			got := strings.Join(r.errors, "\n")
+			for _, want := range tt.wantErrors {
+				if !strings.Contains(got, want) {
+					t.Errorf("reported %q, want it to mention %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGoldenMissingFile(t *testing.T) {
+	setUpdate(t, false)
+	r := run(t, func(tb testing.TB) {
+		Golden(tb, "missing.xml", WithDir(t.TempDir())).AssertEqual(xmlsurf.XMLMap{"/a": "1"})
+	})
+	if !r.fatal || len(r.errors) != 1 || !strings.Contains(r.errors[0], UpdateEnv) {
+		t.Errorf("AssertEqual() of a missing file reported %v, fatal %v", r.errors, r.fatal)
+	}
+}
+
+func TestGoldenPath(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{name: "order", want: filepath.Join("testdata", "order.xml")},
+		{name: "order.golden", want: filepath.Join("testdata", "order.golden")},
+		{name: "order", opts: []Option{WithDir("fixtures")}, want: filepath.Join("fixtures", "order.xml")},
+	}
+
+	for _, tt := range tests {
+		if got := Golden(t, tt.name, tt.opts...).Path(); got != tt.want {
+			t.Errorf("Golden(%q).Path() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyXML(t *testing.T) {
+	setUpdate(t, false)
+	dir := t.TempDir()
+	approved := filepath.Join(dir, "TestVerifyXML.approved.xml")
+	received := filepath.Join(dir, "TestVerifyXML.received.xml")
+	verify := func(input string) *recorder {
+		return run(t, func(tb testing.TB) {
+			VerifyXML(tb, strings.NewReader(input), WithDir(dir), WithIgnorePaths("/order/created"))
+		})
+	}
+
+	// Nothing approved yet
+	r := verify(`<order><created>monday</created><item>a</item><item>b</item></order>`)
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "rename it") {
+		t.Fatalf("VerifyXML() without an approved file reported %v", r.errors)
+	}
+	if err := os.Rename(received, approved); err != nil {
+		t.Fatalf("approving received file: %v", err)
+	}
+
+	// Reordered items and an ignored path still match
+	if r := verify(`<order><created>tuesday</created><item>b</item><item>a</item></order>`); len(r.errors) != 0 {
+		t.Errorf("VerifyXML() of a matching document reported %v", r.errors)
+	}
+
+	// A changed value writes the received file
+	r = verify(`<order><created>monday</created><item>a</item><item>c</item></order>`)
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "/order/item") {
+		t.Errorf("VerifyXML() of a changed document reported %v", r.errors)
+	}
+	if _, err := os.Stat(received); err != nil {
+		t.Errorf("received file not written: %v", err)
+	}
+
+	// A match removes the stale received file
+	if r := verify(`<order><item>a</item><item>b</item><created>monday</created></order>`); len(r.errors) != 0 {
+		t.Errorf("VerifyXML() of a matching document reported %v", r.errors)
+	}
+	if _, err := os.Stat(received); !os.IsNotExist(err) {
+		t.Errorf("received file left after a match: %v", err)
+	}
+
+	// Updating approves directly
+	setUpdate(t, true)
+	if r := verify(`<order><item>c</item></order>`); len(r.errors) != 0 {
+		t.Errorf("VerifyXML() when updating reported %v", r.errors)
+	}
+	m, err := xmlsurf.ParseFile(os.DirFS(dir), "TestVerifyXML.approved.xml")
+	if err != nil || !m.Equal(xmlsurf.XMLMap{"/order/item": "c"}) {
+		t.Errorf("approved file = %v, %v, want the updated document", m, err)
+	}
+}
+
+func TestSnapshotName(t *testing.T) {
+	if got := snapshotName("TestOrder/shipped order:v2"); got != "TestOrder_shipped_order_v2" {
+		t.Errorf("snapshotName() = %q", got)
+	}
+}
@@ -0,0 +1,26 @@
+package xmlsurf
+
+// Has reports whether the map holds a value at path. Elements that only contain
+// child elements have no value of their own; use HasPrefix for them.
+func (m XMLMap) Has(path string) bool {
+	_, ok := m[path]
+	return ok
+}
+
+// HasPrefix reports whether the map holds the element at prefix or any path below it.
+// The prefix may omit the index of its last element, so "/order/item" matches
+// /order/item[3]/sku but not /order/items.
+func (m XMLMap) HasPrefix(prefix string) bool {
+	for path := range m {
+		if _, ok := recordOf(path, prefix); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of elements at the index-free basePath, e.g. 3 for
+// /order/item[1] to /order/item[3]. A single element without index counts as one.
+func (m XMLMap) Count(basePath string) int {
+	return len(m.recordPaths(basePath))
+}
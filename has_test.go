@@ -0,0 +1,46 @@
+package xmlsurf
+
+import "testing"
+
+func TestHasAndCount(t *testing.T) {
+	m := XMLMap{
+		"/order/@id":          "42",
+		"/order/status":       "",
+		"/order/item[1]/sku":  "A",
+		"/order/item[2]/sku":  "B",
+		"/order/item[3]/@qty": "1",
+		"/order/items/total":  "3",
+		"/order/note!/memo":   "fragile",
+	}
+
+	tests := []struct {
+		path      string
+		has       bool
+		hasPrefix bool
+		count     int
+	}{
+		{path: "/order/@id", has: true, hasPrefix: true, count: 1},
+		{path: "/order/status", has: true, hasPrefix: true, count: 1},
+		{path: "/order", hasPrefix: true, count: 1},
+		{path: "/order/item", hasPrefix: true, count: 3},
+		{path: "/order/item[2]", hasPrefix: true, count: 1},
+		{path: "/order/items", hasPrefix: true, count: 1},
+		{path: "/order/note", hasPrefix: true, count: 1},
+		{path: "/order/ite"},
+		{path: "/order/missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := m.Has(tt.path); got != tt.has {
+				t.Errorf("Has() = %v, want %v", got, tt.has)
+			}
+			if got := m.HasPrefix(tt.path); got != tt.hasPrefix {
+				t.Errorf("HasPrefix() = %v, want %v", got, tt.hasPrefix)
+			}
+			if got := m.Count(tt.path); got != tt.count {
+				t.Errorf("Count() = %d, want %d", got, tt.count)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package xmlsurf
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"sort"
+)
+
+// Hash returns a stable SHA-256 digest of the paths and values of the map as a hex
+// string. Maps with equal digests are Equal with the same options, so digests can
+// dedupe documents or skip comparing unchanged ones. WithIgnorePaths, WithNestedDocuments
+// and WithDiffSemantics apply; options relating two maps, such as WithMatchKey,
+// WithListAlignment and WithStructuralOrder, are ignored.
+func (m XMLMap) Hash(opts ...CompareOption) string {
+	m = m.prepareHash(newCompareOptions(opts))
+	paths := make([]string, 0, len(m))
+	for path := range m {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	digest := sha256.New()
+	for _, path := range paths {
+		writeField(digest, path)
+		writeField(digest, m[path])
+	}
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+// HashIgnoreOrder returns a digest like Hash that ignores the order of repeated
+// elements, so maps with equal digests are EqualIgnoreOrder. Like DiffsIgnoreOrder,
+// it pools the values of each path without indices; with DiffSemanticsV1 only the
+// distinct values count.
+func (m XMLMap) HashIgnoreOrder(opts ...CompareOption) string {
+	options := newCompareOptions(opts)
+	groups := groupByBasePath(m.prepareHash(options))
+	basePaths := make([]string, 0, len(groups))
+	for basePath := range groups {
+		basePaths = append(basePaths, basePath)
+	}
+	sort.Strings(basePaths)
+
+	digest := sha256.New()
+	var values []string
+	for _, basePath := range basePaths {
+		entries := groups[basePath]
+		sortByValue(entries)
+		values = values[:0]
+		for i, entry := range entries {
+			if options.Semantics < DiffSemanticsV2 && i > 0 && entry.value == entries[i-1].value {
+				continue
+			}
+			values = append(values, entry.value)
+		}
+
+		// The number of values separates the groups
+		writeField(digest, basePath)
+		writeUvarint(digest, uint64(len(values)))
+		for _, value := range values {
+			writeField(digest, value)
+		}
+	}
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+// prepareHash applies the comparison options that rewrite a single map
+func (m XMLMap) prepareHash(options *CompareOptions) XMLMap {
+	if options.NestedDocuments {
+		m = m.UnwrapNested()
+	}
+	if len(options.IgnorePaths) > 0 {
+		m = m.withoutIgnored(options)
+	}
+	return m
+}
+
+// writeField writes a length-prefixed string, so adjacent fields cannot run into each other
+func writeField(digest hash.Hash, s string) {
+	writeUvarint(digest, uint64(len(s)))
+	digest.Write([]byte(s))
+}
+
+// writeUvarint writes n as a varint
+func writeUvarint(digest hash.Hash, n uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	digest.Write(buf[:binary.PutUvarint(buf[:], n)])
+}
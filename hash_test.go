@@ -0,0 +1,92 @@
+package xmlsurf
+
+import "testing"
+
+func TestHash(t *testing.T) {
+	base := XMLMap{
+		"/order/@id":          "42",
+		"/order/created":      "2024-01-01",
+		"/order/item[1]/name": "apple",
+		"/order/item[2]/name": "pear",
+	}
+
+	tests := []struct {
+		name             string
+		other            XMLMap
+		opts             []CompareOption
+		wantEqual        bool
+		wantEqualNoOrder bool
+	}{
+		{name: "same map", other: base.Clone(), wantEqual: true, wantEqualNoOrder: true},
+		{
+			name: "reordered items",
+			other: XMLMap{
+				"/order/@id": "42", "/order/created": "2024-01-01",
+				"/order/item[1]/name": "pear", "/order/item[2]/name": "apple",
+			},
+			wantEqualNoOrder: true,
+		},
+		{
+			name: "ignored path",
+			other: XMLMap{
+				"/order/@id": "42", "/order/created": "2024-06-30",
+				"/order/item[1]/name": "apple", "/order/item[2]/name": "pear",
+			},
+			opts:             []CompareOption{WithIgnorePaths("/order/created")},
+			wantEqual:        true,
+			wantEqualNoOrder: true,
+		},
+		{
+			name: "changed value",
+			other: XMLMap{
+				"/order/@id": "43", "/order/created": "2024-01-01",
+				"/order/item[1]/name": "apple", "/order/item[2]/name": "pear",
+			},
+		},
+		{
+			name: "value moved to another path",
+			other: XMLMap{
+				"/order/@id": "42", "/order/created": "2024-01-01",
+				"/order/item[1]/name": "apple", "/order/item[2]/note": "pear",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := base.Hash(tt.opts...) == tt.other.Hash(tt.opts...); got != tt.wantEqual {
+				t.Errorf("Hash() equal = %v, want %v", got, tt.wantEqual)
+			}
+			if base.Equal(tt.other, tt.opts...) != tt.wantEqual {
+				t.Error("Equal() disagrees with Hash()")
+			}
+			if got := base.HashIgnoreOrder(tt.opts...) == tt.other.HashIgnoreOrder(tt.opts...); got != tt.wantEqualNoOrder {
+				t.Errorf("HashIgnoreOrder() equal = %v, want %v", got, tt.wantEqualNoOrder)
+			}
+			if base.EqualIgnoreOrder(tt.other, tt.opts...) != tt.wantEqualNoOrder {
+				t.Error("EqualIgnoreOrder() disagrees with HashIgnoreOrder()")
+			}
+		})
+	}
+}
+
+func TestHashIgnoreOrderSemantics(t *testing.T) {
+	twice := XMLMap{"/list/item[1]": "a", "/list/item[2]": "a", "/list/item[3]": "b"}
+	once := XMLMap{"/list/item[1]": "a", "/list/item[2]": "b"}
+
+	if twice.HashIgnoreOrder() != once.HashIgnoreOrder() {
+		t.Error("HashIgnoreOrder() with default DiffSemanticsV1 counts occurrences")
+	}
+	v2 := WithDiffSemantics(DiffSemanticsV2)
+	if twice.HashIgnoreOrder(v2) == once.HashIgnoreOrder(v2) {
+		t.Error("HashIgnoreOrder() with DiffSemanticsV2 ignores the number of occurrences")
+	}
+
+	// Fields are delimited, so concatenations of paths and values differ
+	if (XMLMap{"/a": "bc"}).Hash() == (XMLMap{"/ab": "c"}).Hash() {
+		t.Error("Hash() of different maps collides")
+	}
+	if got := (XMLMap{"/a": "1"}).Hash(); len(got) != 64 {
+		t.Errorf("Hash() = %q, want a hex SHA-256 digest", got)
+	}
+}
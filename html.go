@@ -0,0 +1,77 @@
+package xmlsurf
+
+import (
+	"html/template"
+	"io"
+)
+
+// diffHTMLTemplate is a standalone page with inline styles, so it can be attached to tickets
+var diffHTMLTemplate = template.Must(template.New("diff").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Left}} vs {{.Right}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+td { font-family: monospace; white-space: pre-wrap; word-break: break-all; }
+th { background: #eee; }
+tr.extra td.left, tr.value td.left { background: #fdd; }
+tr.missing td.right, tr.value td.right { background: #dfd; }
+td.absent { background: #f4f4f4; color: #999; }
+</style>
+</head>
+<body>
+<h1>{{.Left}} vs {{.Right}}</h1>
+<p>{{len .Rows}} differences: {{.Missing}} missing, {{.Extra}} extra, {{.Changed}} changed</p>
+{{- if .Rows}}
+<table>
+<thead><tr><th>Path</th><th>{{.Left}}</th><th>{{.Right}}</th></tr></thead>
+<tbody>
+{{- range .Rows}}
+<tr class="{{.Class}}"><td>{{.Path}}</td>
+{{- if eq .Class "missing"}}<td class="left absent">(absent)</td>{{else}}<td class="left">{{.LeftValue}}</td>{{end}}
+{{- if eq .Class "extra"}}<td class="right absent">(absent)</td>{{else}}<td class="right">{{.RightValue}}</td>{{end}}</tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+// diffHTMLRow is a row of the HTML diff report
+type diffHTMLRow struct {
+	Diff
+	Class string
+}
+
+// RenderDiffHTML writes a standalone HTML page showing the diffs side by side,
+// with the left and right documents named in the column headers. Rows are ordered by path.
+func RenderDiffHTML(diffs []Diff, leftName, rightName string, w io.Writer) error {
+	page := struct {
+		Left, Right             string
+		Rows                    []diffHTMLRow
+		Missing, Extra, Changed int
+	}{Left: leftName, Right: rightName}
+
+	for _, diff := range diffs {
+		row := diffHTMLRow{Diff: diff}
+		switch diff.Type {
+		case DiffMissing:
+			row.Class = "missing"
+			page.Missing++
+		case DiffExtra:
+			row.Class = "extra"
+			page.Extra++
+		default:
+			row.Class = "value"
+			page.Changed++
+		}
+		page.Rows = append(page.Rows, row)
+	}
+	sortByPath(page.Rows, func(row diffHTMLRow) string { return row.Path })
+
+	return diffHTMLTemplate.Execute(w, page)
+}
@@ -0,0 +1,51 @@
+package xmlsurf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderDiffHTML(t *testing.T) {
+	diffs := []Diff{
+		{Path: "/root/b", LeftValue: "<2>", RightValue: "3", Type: DiffValue},
+		{Path: "/root/a", RightValue: "new", Type: DiffMissing},
+		{Path: "/root/c/@id", LeftValue: "old", Type: DiffExtra},
+	}
+
+	var b strings.Builder
+	if err := RenderDiffHTML(diffs, "expected.xml", "actual <prod>", &b); err != nil {
+		t.Fatalf("RenderDiffHTML() error = %v", err)
+	}
+	page := b.String()
+
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"<th>expected.xml</th><th>actual &lt;prod&gt;</th>",
+		"3 differences: 1 missing, 1 extra, 1 changed",
+		`<tr class="missing"><td>/root/a</td><td class="left absent">(absent)</td><td class="right">new</td></tr>`,
+		`<tr class="value"><td>/root/b</td><td class="left">&lt;2&gt;</td><td class="right">3</td></tr>`,
+		`<tr class="extra"><td>/root/c/@id</td><td class="left">old</td><td class="right absent">(absent)</td></tr>`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page does not contain %q:\n%s", want, page)
+		}
+	}
+
+	// Rows are ordered by path
+	if strings.Index(page, "/root/a") > strings.Index(page, "/root/b") {
+		t.Error("rows are not ordered by path")
+	}
+}
+
+func TestRenderDiffHTMLNoDiffs(t *testing.T) {
+	var b strings.Builder
+	if err := RenderDiffHTML(nil, "a", "b", &b); err != nil {
+		t.Fatalf("RenderDiffHTML() error = %v", err)
+	}
+	if strings.Contains(b.String(), "<table>") {
+		t.Error("page without diffs contains a table")
+	}
+	if !strings.Contains(b.String(), "0 differences") {
+		t.Errorf("page = %s", b.String())
+	}
+}
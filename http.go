@@ -0,0 +1,72 @@
+package xmlsurf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ContentTypeXML is the Content-Type set on requests created by NewRequest
+const ContentTypeXML = "application/xml; charset=utf-8"
+
+// DecodeResponse parses the body of an HTTP response into an XMLMap and closes it.
+// Bodies with a gzip Content-Encoding are decompressed.
+func DecodeResponse(resp *http.Response, opts ...Option) (XMLMap, error) {
+	defer resp.Body.Close()
+	return decodeHTTPBody(resp.Body, resp.Header, opts)
+}
+
+// DecodeRequest parses the body of an HTTP request into an XMLMap, for use in handlers.
+// Bodies with a gzip Content-Encoding are decompressed.
+func DecodeRequest(req *http.Request, opts ...Option) (XMLMap, error) {
+	return decodeHTTPBody(req.Body, req.Header, opts)
+}
+
+// NewRequest creates an HTTP request with the XMLMap serialized as its body.
+// The Content-Type is set to ContentTypeXML and can be overridden on the returned request.
+// If compress is true the body is gzip-compressed and Content-Encoding is set.
+func NewRequest(ctx context.Context, method, url string, m XMLMap, compress bool) (*http.Request, error) {
+	var body bytes.Buffer
+	if compress {
+		gz := gzip.NewWriter(&body)
+		if err := m.ToXML(gz, false); err != nil {
+			return nil, err
+		}
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+	} else if err := m.ToXML(&body, false); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", ContentTypeXML)
+	if compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	return req, nil
+}
+
+// decodeHTTPBody parses an HTTP body, decompressing it according to the headers
+func decodeHTTPBody(body io.Reader, header http.Header, opts []Option) (XMLMap, error) {
+	switch encoding := strings.ToLower(header.Get("Content-Encoding")); encoding {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		body = gz
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding %q", encoding)
+	}
+	return ParseToMap(body, opts...)
+}
@@ -0,0 +1,57 @@
+package xmlsurf
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		compress bool
+	}{
+		{name: "plain body", compress: false},
+		{name: "gzip body", compress: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Content-Type"); got != ContentTypeXML {
+					t.Errorf("Content-Type = %q, want %q", got, ContentTypeXML)
+				}
+				m, err := DecodeRequest(r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				m["/order/status"] = "accepted"
+				w.Header().Set("Content-Type", ContentTypeXML)
+				_ = m.ToXML(w, false)
+			})
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			request := XMLMap{"/order/@id": "7", "/order/item": "a"}
+			req, err := NewRequest(context.Background(), http.MethodPost, server.URL, request, tt.compress)
+			if err != nil {
+				t.Fatalf("NewRequest() error = %v", err)
+			}
+			resp, err := server.Client().Do(req)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+
+			result, err := DecodeResponse(resp)
+			if err != nil {
+				t.Fatalf("DecodeResponse() error = %v", err)
+			}
+			expected := XMLMap{"/order/@id": "7", "/order/item": "a", "/order/status": "accepted"}
+			if !result.Equal(expected) {
+				t.Errorf("DecodeResponse() = %v, want %v", result, expected)
+			}
+		})
+	}
+}
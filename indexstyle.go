@@ -0,0 +1,153 @@
+package xmlsurf
+
+import (
+	"strconv"
+	"strings"
+)
+
+// IndexStyle selects how indices of repeated elements are written in paths
+type IndexStyle int
+
+const (
+	// IndexBrackets writes indices in brackets, e.g. /root/items/item[1]; this is the default
+	IndexBrackets IndexStyle = iota
+	// IndexDot writes indices after a dot, e.g. /root/items/item.1
+	IndexDot
+	// IndexHash writes indices after a hash, e.g. /root/items/item#1
+	IndexHash
+)
+
+// appendIndex writes an index in the style to the builder
+func (s IndexStyle) appendIndex(b *strings.Builder, index int) {
+	switch s {
+	case IndexDot:
+		b.WriteByte('.')
+		b.WriteString(strconv.Itoa(index))
+	case IndexHash:
+		b.WriteByte('#')
+		b.WriteString(strconv.Itoa(index))
+	default:
+		b.WriteByte('[')
+		b.WriteString(strconv.Itoa(index))
+		b.WriteByte(']')
+	}
+}
+
+// separator returns the character introducing an index, or 0 for brackets
+func (s IndexStyle) separator() byte {
+	switch s {
+	case IndexDot:
+		return '.'
+	case IndexHash:
+		return '#'
+	default:
+		return 0
+	}
+}
+
+// FormatIndices returns a copy of the map with the indices of its paths written in
+// the style, for tools that cannot handle "[" and "]" in keys, such as metric labels
+// or environment variable names. The other methods of XMLMap expect bracket indices;
+// BracketIndices converts back.
+func (m XMLMap) FormatIndices(style IndexStyle) XMLMap {
+	result := make(XMLMap, len(m))
+	var b strings.Builder
+	for path, value := range m {
+		result[formatIndices(path, style, &b)] = value
+	}
+	return result
+}
+
+// BracketIndices returns a copy of the map with indices written in the style
+// converted back to brackets. With IndexDot, every segment ending in a dot and
+// digits is taken as indexed; "#" cannot occur in XML names, so IndexHash is unambiguous.
+func (m XMLMap) BracketIndices(style IndexStyle) XMLMap {
+	result := make(XMLMap, len(m))
+	var b strings.Builder
+	for path, value := range m {
+		result[bracketIndices(path, style, &b)] = value
+	}
+	return result
+}
+
+// RebaseIndices returns a copy of the map with its bracket indices shifted from
+// numbering repeated elements from the base from to the base to, e.g.
+// RebaseIndices(0, 1) converts a map parsed with WithIndexBase(0) back to the
+// 1-based indices the other methods of XMLMap expect.
+func (m XMLMap) RebaseIndices(from, to int) XMLMap {
+	result := make(XMLMap, len(m))
+	var b strings.Builder
+	for path, value := range m {
+		result[rebaseIndices(path, to-from, &b)] = value
+	}
+	return result
+}
+
+// index returns the index written for the 1-based position of a repeated element
+func (o *ParseOptions) index(position int) int {
+	return position - 1 + o.IndexBase
+}
+
+// rebaseIndices adds delta to the bracket indices of a path
+func rebaseIndices(path string, delta int, b *strings.Builder) string {
+	if delta == 0 || !strings.Contains(path, "[") {
+		return path
+	}
+	return rewriteSegments(path, b, func(b *strings.Builder, segment pathSegment) {
+		if !segment.indexed {
+			b.WriteString(segment.text)
+			return
+		}
+		b.WriteString(segment.name)
+		b.WriteByte('[')
+		b.WriteString(strconv.Itoa(segment.index + delta))
+		b.WriteByte(']')
+	})
+}
+
+// formatIndices rewrites the bracket indices of a path in the style
+func formatIndices(path string, style IndexStyle, b *strings.Builder) string {
+	if style == IndexBrackets || !strings.Contains(path, "[") {
+		return path
+	}
+	return rewriteSegments(path, b, func(b *strings.Builder, segment pathSegment) {
+		if !segment.indexed {
+			b.WriteString(segment.text)
+			return
+		}
+		b.WriteString(segment.name)
+		style.appendIndex(b, segment.index)
+	})
+}
+
+// bracketIndices rewrites the indices of a path written in the style as brackets
+func bracketIndices(path string, style IndexStyle, b *strings.Builder) string {
+	separator := style.separator()
+	if separator == 0 || strings.IndexByte(path, separator) == -1 {
+		return path
+	}
+	return rewriteSegments(path, b, func(b *strings.Builder, segment pathSegment) {
+		at := strings.LastIndexByte(segment.text, separator)
+		if at <= 0 || !isDigits(segment.text[at+1:]) {
+			b.WriteString(segment.text)
+			return
+		}
+		b.WriteString(segment.text[:at])
+		b.WriteByte('[')
+		b.WriteString(segment.text[at+1:])
+		b.WriteByte(']')
+	})
+}
+
+// isDigits reports whether s is a non-empty string of ASCII digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
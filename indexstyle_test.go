@@ -0,0 +1,153 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatIndices(t *testing.T) {
+	m := XMLMap{
+		"/root/items/item[1]/@id":   "a",
+		"/root/items/item[2]/name":  "b",
+		"/root/doc[2]!/x/y[3]":      "c",
+		"/root/single":              "d",
+		"/root/note/@id[2]":         "e",
+		"/root/v1.2/item[10]/value": "f",
+	}
+
+	tests := []struct {
+		style IndexStyle
+		want  XMLMap
+	}{
+		{IndexBrackets, m},
+		{IndexDot, XMLMap{
+			"/root/items/item.1/@id":   "a",
+			"/root/items/item.2/name":  "b",
+			"/root/doc.2!/x/y.3":       "c",
+			"/root/single":             "d",
+			"/root/note/@id.2":         "e",
+			"/root/v1.2/item.10/value": "f",
+		}},
+		{IndexHash, XMLMap{
+			"/root/items/item#1/@id":   "a",
+			"/root/items/item#2/name":  "b",
+			"/root/doc#2!/x/y#3":       "c",
+			"/root/single":             "d",
+			"/root/note/@id#2":         "e",
+			"/root/v1.2/item#10/value": "f",
+		}},
+	}
+
+	for _, tt := range tests {
+		got := m.FormatIndices(tt.style)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FormatIndices(%d) = %v, want %v", tt.style, got, tt.want)
+		}
+		back := got.BracketIndices(tt.style)
+		if tt.style == IndexDot {
+			// "v1.2" is read as an indexed element
+			delete(back, "/root/v1[2]/item[10]/value")
+			back["/root/v1.2/item[10]/value"] = "f"
+		}
+		if !reflect.DeepEqual(back, m) {
+			t.Errorf("BracketIndices(%d) = %v, want %v", tt.style, back, m)
+		}
+	}
+}
+
+func TestWithIndexStyle(t *testing.T) {
+	xml := `<root><item id="1">a</item><item id="2">b</item><note>x<!---->y</note></root>`
+
+	got, err := ParseToMap(strings.NewReader(xml), WithIndexStyle(IndexHash))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+	want := XMLMap{
+		"/root/item#1":     "a",
+		"/root/item#1/@id": "1",
+		"/root/item#2":     "b",
+		"/root/item#2/@id": "2",
+		"/root/note":       "y",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseToMap() = %v, want %v", got, want)
+	}
+
+	stream := NewStream(strings.NewReader(xml), WithIndexStyle(IndexDot))
+	record, err := stream.Next()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if record.Path != "/root/item.1" {
+		t.Errorf("Record.Path = %q, want %q", record.Path, "/root/item.1")
+	}
+}
+
+func TestWithIndexBase(t *testing.T) {
+	xml := `<root><item id="1">a</item><item id="2">b</item><note>x<!---->y</note><single>s</single></root>`
+
+	got, err := ParseToMap(strings.NewReader(xml), WithIndexBase(0))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+	want := XMLMap{
+		"/root/item[0]":     "a",
+		"/root/item[0]/@id": "1",
+		"/root/item[1]":     "b",
+		"/root/item[1]/@id": "2",
+		"/root/note":        "y",
+		"/root/single":      "s",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseToMap() = %v, want %v", got, want)
+	}
+
+	oneBased, err := ParseToMap(strings.NewReader(xml))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+	if back := got.RebaseIndices(0, 1); !reflect.DeepEqual(back, oneBased) {
+		t.Errorf("RebaseIndices(0, 1) = %v, want %v", back, oneBased)
+	}
+
+	styled, err := ParseToMap(strings.NewReader(xml), WithIndexBase(0), WithIndexStyle(IndexDot))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+	if styled["/root/item.0"] != "a" {
+		t.Errorf("ParseToMap() with IndexDot = %v, want /root/item.0", styled)
+	}
+
+	stream := NewStream(strings.NewReader(xml), WithIndexBase(0))
+	record, err := stream.Next()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if record.Path != "/root/item[0]" {
+		t.Errorf("Record.Path = %q, want %q", record.Path, "/root/item[0]")
+	}
+}
+
+func TestRebaseIndices(t *testing.T) {
+	m := XMLMap{
+		"/root/item[1]/sub[2]/@id": "a",
+		"/root/item[3]":            "b",
+		"/root/plain":              "c",
+		"/root/doc[2]!/inner/x[1]": "d",
+		"/root/odd[x]":             "e",
+	}
+	want := XMLMap{
+		"/root/item[0]/sub[1]/@id": "a",
+		"/root/item[2]":            "b",
+		"/root/plain":              "c",
+		"/root/doc[1]!/inner/x[0]": "d",
+		"/root/odd[x]":             "e",
+	}
+	if got := m.RebaseIndices(1, 0); !reflect.DeepEqual(got, want) {
+		t.Errorf("RebaseIndices(1, 0) = %v, want %v", got, want)
+	}
+	if got := m.RebaseIndices(1, 1); !reflect.DeepEqual(got, m) {
+		t.Errorf("RebaseIndices(1, 1) = %v, want %v", got, m)
+	}
+}
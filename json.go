@@ -0,0 +1,208 @@
+package xmlsurf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FindXMLInJSON returns JSON Pointers (RFC 6901) to all string values in the
+// JSON document that look like XML, i.e. start with "<" after trimming whitespace.
+// The pointers are sorted for consistent output.
+func FindXMLInJSON(data []byte) ([]string, error) {
+	doc, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	pointers := make([]string, 0)
+	findXMLStrings(doc, "", &pointers)
+	sort.Strings(pointers)
+	return pointers, nil
+}
+
+// ParseJSONField parses the XML string stored at the JSON Pointer in the JSON document
+func ParseJSONField(data []byte, pointer string, opts ...Option) (XMLMap, error) {
+	doc, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := resolveJSONPointer(doc, pointer)
+	if err != nil {
+		return nil, err
+	}
+	str, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("JSON value at %q is not a string", pointer)
+	}
+
+	return ParseToMap(strings.NewReader(str), opts...)
+}
+
+// EmbedJSONField serializes the XMLMap and stores it as a string at the JSON Pointer,
+// returning the updated JSON document. The field must already exist.
+// Object keys in the returned document are sorted.
+func EmbedJSONField(data []byte, pointer string, m XMLMap) ([]byte, error) {
+	doc, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf strings.Builder
+	if err := m.ToXML(&buf, false); err != nil {
+		return nil, err
+	}
+
+	doc, err = replaceJSONPointer(doc, pointer, buf.String())
+	if err != nil {
+		return nil, err
+	}
+	return encodeJSON(doc)
+}
+
+// encodeJSON encodes a JSON document without escaping the XML markup characters
+func encodeJSON(doc interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// DiffJSONField parses the XML embedded at the JSON Pointer in both JSON documents
+// and returns the differences between them
+func DiffJSONField(left, right []byte, pointer string, opts ...Option) ([]Diff, error) {
+	leftMap, err := ParseJSONField(left, pointer, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("left: %w", err)
+	}
+	rightMap, err := ParseJSONField(right, pointer, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("right: %w", err)
+	}
+	return leftMap.Diffs(rightMap), nil
+}
+
+// decodeJSON decodes a JSON document preserving number formatting
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// findXMLStrings collects pointers to XML-looking strings below the value
+func findXMLStrings(value interface{}, pointer string, pointers *[]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			findXMLStrings(child, pointer+"/"+escapeJSONPointer(key), pointers)
+		}
+	case []interface{}:
+		for i, child := range v {
+			findXMLStrings(child, pointer+"/"+strconv.Itoa(i), pointers)
+		}
+	case string:
+		if strings.HasPrefix(strings.TrimSpace(v), "<") {
+			*pointers = append(*pointers, pointer)
+		}
+	}
+}
+
+// splitJSONPointer splits a JSON Pointer into unescaped reference tokens
+func splitJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// escapeJSONPointer escapes a reference token for use in a JSON Pointer
+func escapeJSONPointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// resolveJSONPointer returns the value referenced by the JSON Pointer
+func resolveJSONPointer(doc interface{}, pointer string) (interface{}, error) {
+	tokens, err := splitJSONPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	current := doc
+	for _, token := range tokens {
+		current, err = jsonChild(current, token, pointer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return current, nil
+}
+
+// replaceJSONPointer replaces the value referenced by the JSON Pointer
+func replaceJSONPointer(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := splitJSONPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parent := doc
+	for _, token := range tokens[:len(tokens)-1] {
+		parent, err = jsonChild(parent, token, pointer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	last := tokens[len(tokens)-1]
+	if _, err := jsonChild(parent, last, pointer); err != nil {
+		return nil, err
+	}
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[last] = value
+	case []interface{}:
+		i, _ := strconv.Atoi(last)
+		p[i] = value
+	}
+	return doc, nil
+}
+
+// jsonChild returns the child of an object or array for a reference token
+func jsonChild(value interface{}, token, pointer string) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		child, ok := v[token]
+		if !ok {
+			return nil, fmt.Errorf("JSON pointer %q not found", pointer)
+		}
+		return child, nil
+	case []interface{}:
+		i, err := strconv.Atoi(token)
+		if err != nil || i < 0 || i >= len(v) {
+			return nil, fmt.Errorf("JSON pointer %q not found", pointer)
+		}
+		return v[i], nil
+	default:
+		return nil, fmt.Errorf("JSON pointer %q not found", pointer)
+	}
+}
@@ -0,0 +1,66 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindXMLInJSON(t *testing.T) {
+	data := []byte(`{"id": 1, "payload": {"body": " <root>a</root>"}, "items": ["x", "<item/>"], "a/b": "<c/>"}`)
+
+	pointers, err := FindXMLInJSON(data)
+	if err != nil {
+		t.Fatalf("FindXMLInJSON() error = %v", err)
+	}
+	expected := []string{"/a~1b", "/items/1", "/payload/body"}
+	if !reflect.DeepEqual(pointers, expected) {
+		t.Errorf("FindXMLInJSON() = %v, want %v", pointers, expected)
+	}
+}
+
+func TestParseJSONField(t *testing.T) {
+	data := []byte(`{"payload": {"body": "<root><item id=\"1\">a</item></root>"}, "count": 2}`)
+
+	result, err := ParseJSONField(data, "/payload/body")
+	if err != nil {
+		t.Fatalf("ParseJSONField() error = %v", err)
+	}
+	expected := XMLMap{"/root/item": "a", "/root/item/@id": "1"}
+	if !result.Equal(expected) {
+		t.Errorf("ParseJSONField() = %v, want %v", result, expected)
+	}
+
+	if _, err := ParseJSONField(data, "/payload/missing"); err == nil {
+		t.Errorf("ParseJSONField() expected error for missing field")
+	}
+	if _, err := ParseJSONField(data, "/count"); err == nil {
+		t.Errorf("ParseJSONField() expected error for non-string field")
+	}
+}
+
+func TestEmbedJSONField(t *testing.T) {
+	data := []byte(`{"payload": ["<root>a</root>"], "count": 2}`)
+
+	result, err := EmbedJSONField(data, "/payload/0", XMLMap{"/root": "b"})
+	if err != nil {
+		t.Fatalf("EmbedJSONField() error = %v", err)
+	}
+	expected := `{"count":2,"payload":["<root>b</root>"]}`
+	if string(result) != expected {
+		t.Errorf("EmbedJSONField() = %s, want %s", result, expected)
+	}
+}
+
+func TestDiffJSONField(t *testing.T) {
+	left := []byte(`{"body": "<root><a>1</a></root>", "ts": 1}`)
+	right := []byte(`{"body": "<root><a>2</a></root>", "ts": 2}`)
+
+	diffs, err := DiffJSONField(left, right, "/body")
+	if err != nil {
+		t.Fatalf("DiffJSONField() error = %v", err)
+	}
+	expected := []Diff{{Path: "/root/a", LeftValue: "1", RightValue: "2", Type: DiffValue}}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("DiffJSONField() = %v, want %v", diffs, expected)
+	}
+}
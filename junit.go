@@ -0,0 +1,88 @@
+package xmlsurf
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite is a test suite of a JUnit XML report
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is a test case of a JUnit XML report
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+// junitFailure describes a failed test case
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitFailureTypes names the diff types in failure elements
+var junitFailureTypes = map[DiffType]string{
+	DiffMissing: "missing",
+	DiffExtra:   "extra",
+	DiffValue:   "value",
+}
+
+// WriteJUnit writes the diffs as a JUnit XML report with one test suite named suite
+// and one failed test case per diff, named by its path, so CI systems can show
+// contract violations natively. Without diffs the suite holds a single passed test
+// case named after the suite.
+func WriteJUnit(w io.Writer, suite string, diffs []Diff) error {
+	sorted := make([]Diff, len(diffs))
+	copy(sorted, diffs)
+	sortByPath(sorted, func(diff Diff) string { return diff.Path })
+
+	testSuite := junitTestSuite{Name: suite}
+	for _, diff := range sorted {
+		testSuite.TestCases = append(testSuite.TestCases, junitTestCase{
+			Name:      diff.Path,
+			ClassName: suite,
+			Failure: &junitFailure{
+				Message: diff.String(),
+				Type:    junitFailureTypes[diff.Type],
+				Text:    "left:  " + diff.LeftValue + "\nright: " + diff.RightValue,
+			},
+		})
+	}
+	testSuite.Failures = len(testSuite.TestCases)
+	if len(testSuite.TestCases) == 0 {
+		testSuite.TestCases = []junitTestCase{{Name: suite, ClassName: suite}}
+	}
+	testSuite.Tests = len(testSuite.TestCases)
+
+	report := junitTestSuites{
+		Tests:    testSuite.Tests,
+		Failures: testSuite.Failures,
+		Suites:   []junitTestSuite{testSuite},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,54 @@
+package xmlsurf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	diffs := []Diff{
+		{Path: "/root/b", LeftValue: "2", RightValue: "3", Type: DiffValue},
+		{Path: "/root/a", RightValue: "new", Type: DiffMissing},
+	}
+
+	var b strings.Builder
+	if err := WriteJUnit(&b, "orders contract", diffs); err != nil {
+		t.Fatalf("WriteJUnit() error = %v", err)
+	}
+
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="2" failures="2">
+  <testsuite name="orders contract" tests="2" failures="2">
+    <testcase name="/root/a" classname="orders contract">
+      <failure message="Missing path: /root/a (right value: &#34;new&#34;)" type="missing">left:  &#xA;right: new</failure>
+    </testcase>
+    <testcase name="/root/b" classname="orders contract">
+      <failure message="Value mismatch at /root/b: &#34;2&#34; != &#34;3&#34;" type="value">left:  2&#xA;right: 3</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	if b.String() != want {
+		t.Errorf("WriteJUnit() =\n%s\nwant\n%s", b.String(), want)
+	}
+
+	// The report is well-formed and parses with this package
+	m, err := ParseToMap(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+	if got := m["/testsuites/testsuite/testcase[2]/failure/@type"]; got != "value" {
+		t.Errorf("second failure type = %q, want value", got)
+	}
+}
+
+func TestWriteJUnitNoDiffs(t *testing.T) {
+	var b strings.Builder
+	if err := WriteJUnit(&b, "orders contract", nil); err != nil {
+		t.Fatalf("WriteJUnit() error = %v", err)
+	}
+	if !strings.Contains(b.String(), `<testsuite name="orders contract" tests="1" failures="0">`) ||
+		!strings.Contains(b.String(), `<testcase name="orders contract" classname="orders contract"></testcase>`) {
+		t.Errorf("WriteJUnit() =\n%s", b.String())
+	}
+}
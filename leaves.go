@@ -0,0 +1,89 @@
+package xmlsurf
+
+import (
+	"sort"
+	"strings"
+)
+
+// EqualLeaves returns true if both maps hold the same leaf values, ignoring structure.
+// See DiffsLeaves for details.
+func (m XMLMap) EqualLeaves(other XMLMap, opts ...CompareOption) bool {
+	return len(m.DiffsLeaves(other, opts...)) == 0
+}
+
+// DiffsLeaves compares the multisets of (leaf local name, value) pairs of both maps,
+// ignoring where in the structure the leaves are located. Namespace prefixes and
+// indices are dropped from leaf names, attributes keep their "@".
+// Surplus pairs are reported at their original paths as DiffExtra (only in m)
+// or DiffMissing (only in other).
+func (m XMLMap) DiffsLeaves(other XMLMap, opts ...CompareOption) []Diff {
+	left, right := prepareCompare(m, other, newCompareOptions(opts))
+
+	leftPairs := groupByLeaf(left)
+	rightPairs := groupByLeaf(right)
+
+	diffs := make([]Diff, 0)
+	for pair, leftPaths := range leftPairs {
+		rightPaths := rightPairs[pair]
+		for _, path := range leftPaths[min(len(rightPaths), len(leftPaths)):] {
+			diffs = append(diffs, Diff{
+				Path:      path,
+				LeftValue: pair.value,
+				Type:      DiffExtra,
+			})
+		}
+	}
+	for pair, rightPaths := range rightPairs {
+		leftPaths := leftPairs[pair]
+		for _, path := range rightPaths[min(len(leftPaths), len(rightPaths)):] {
+			diffs = append(diffs, Diff{
+				Path:       path,
+				RightValue: pair.value,
+				Type:       DiffMissing,
+			})
+		}
+	}
+
+	// Sort diffs by path for consistent output
+	if len(diffs) > 0 {
+		sort.Slice(diffs, func(i, j int) bool {
+			return diffs[i].Path < diffs[j].Path
+		})
+	}
+
+	return diffs
+}
+
+// leafPair identifies a leaf by its local name and value
+type leafPair struct {
+	name  string
+	value string
+}
+
+// groupByLeaf groups the sorted paths of a map by their leaf pair
+func groupByLeaf(m XMLMap) map[leafPair][]string {
+	pairs := make(map[leafPair][]string, len(m))
+	for path, value := range m {
+		pair := leafPair{name: leafName(path), value: value}
+		pairs[pair] = append(pairs[pair], path)
+	}
+	for _, paths := range pairs {
+		sort.Strings(paths)
+	}
+	return pairs
+}
+
+// leafName returns the local name of the last path segment
+func leafName(path string) string {
+	segment := lastPathSegment(path).base
+
+	attr := strings.HasPrefix(segment, "@")
+	segment = strings.TrimPrefix(segment, "@")
+	if idx := strings.Index(segment, ":"); idx != -1 {
+		segment = segment[idx+1:]
+	}
+	if attr {
+		return "@" + segment
+	}
+	return segment
+}
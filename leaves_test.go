@@ -0,0 +1,70 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestXMLMapDiffsLeaves(t *testing.T) {
+	tests := []struct {
+		name     string
+		map1     XMLMap
+		map2     XMLMap
+		expected []Diff
+	}{
+		{
+			name: "restructured document with same leaves",
+			map1: XMLMap{
+				"/order/customer/name": "Jane",
+				"/order/items/item[1]": "a",
+				"/order/items/item[2]": "b",
+				"/order/@id":           "7",
+			},
+			map2: XMLMap{
+				"/ns:Envelope/ns:Body/ns:name":    "Jane",
+				"/ns:Envelope/ns:Body/ns:item[1]": "b",
+				"/ns:Envelope/ns:Body/ns:item[2]": "a",
+				"/ns:Envelope/@ns:id":             "7",
+			},
+			expected: []Diff{},
+		},
+		{
+			name: "same value under different leaf name",
+			map1: XMLMap{
+				"/a/name": "x",
+			},
+			map2: XMLMap{
+				"/b/title": "x",
+			},
+			expected: []Diff{
+				{Path: "/a/name", LeftValue: "x", Type: DiffExtra},
+				{Path: "/b/title", RightValue: "x", Type: DiffMissing},
+			},
+		},
+		{
+			name: "duplicate leaves are counted",
+			map1: XMLMap{
+				"/a/item[1]": "x",
+				"/a/item[2]": "x",
+			},
+			map2: XMLMap{
+				"/b/item": "x",
+			},
+			expected: []Diff{
+				{Path: "/a/item[2]", LeftValue: "x", Type: DiffExtra},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs := tt.map1.DiffsLeaves(tt.map2)
+			if !reflect.DeepEqual(diffs, tt.expected) {
+				t.Errorf("DiffsLeaves() = %v, want %v", diffs, tt.expected)
+			}
+			if equal := tt.map1.EqualLeaves(tt.map2); equal != (len(tt.expected) == 0) {
+				t.Errorf("EqualLeaves() = %v, want %v", equal, len(tt.expected) == 0)
+			}
+		})
+	}
+}
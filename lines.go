@@ -0,0 +1,124 @@
+package xmlsurf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Escaping used by WriteLines and FromLines. Backslashes and line breaks are
+// escaped in paths and values; "=" is also escaped in paths, so the first
+// unescaped "=" separates the path from the value.
+var (
+	lineValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	linePathEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "=", `\=`)
+)
+
+// WriteLines writes the map as one "path=value" line per path, e.g. /root/items/item[2]/@id=7.
+// Lines are ordered by path depth and then by name and index, so the output is stable
+// and can be grepped and diffed line by line. FromLines reads it back.
+func (m XMLMap) WriteLines(w io.Writer) error {
+	paths := make([]string, 0, len(m))
+	for path := range m {
+		paths = append(paths, path)
+	}
+	sortByPath(paths, func(path string) string { return path })
+
+	bw := bufio.NewWriter(w)
+	for _, path := range paths {
+		linePathEscaper.WriteString(bw, path)
+		bw.WriteByte('=')
+		lineValueEscaper.WriteString(bw, m[path])
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// FromLines reads "path=value" lines as written by WriteLines.
+// Empty lines and lines starting with # are ignored.
+func FromLines(r io.Reader) (XMLMap, error) {
+	result := make(XMLMap)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		path, value, err := splitLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		if _, exists := result[path]; exists {
+			return nil, fmt.Errorf("line %d: duplicate path %s", lineNumber, path)
+		}
+		result[path] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// splitLine splits an escaped "path=value" line into the unescaped path and value
+func splitLine(line string) (string, string, error) {
+	var path strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; c {
+		case '=':
+			value, err := unescapeLine(line[i+1:])
+			return path.String(), value, err
+		case '\\':
+			if i+1 == len(line) {
+				return "", "", fmt.Errorf("unterminated escape in %q", line)
+			}
+			i++
+			if line[i] == '=' {
+				path.WriteByte('=')
+				continue
+			}
+			unescaped, err := unescapeLine(line[i-1 : i+1])
+			if err != nil {
+				return "", "", err
+			}
+			path.WriteString(unescaped)
+		default:
+			path.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("missing = in %q", line)
+}
+
+// unescapeLine reverses the escaping of lineValueEscaper
+func unescapeLine(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		if i == len(s) {
+			return "", fmt.Errorf("unterminated escape in %q", s)
+		}
+		switch s[i] {
+		case '\\':
+			b.WriteByte('\\')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		default:
+			return "", fmt.Errorf("unknown escape \\%c in %q", s[i], s)
+		}
+	}
+	return b.String(), nil
+}
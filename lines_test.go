@@ -0,0 +1,85 @@
+package xmlsurf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestXMLMapWriteLines(t *testing.T) {
+	m := XMLMap{
+		"/root/b":          "multi\nline\ttext",
+		"/root/a/@id":      "1",
+		"/root/a":          `C:\path=x`,
+		"/root/items/i[2]": "",
+		"/root/items/i[1]": " padded ",
+	}
+
+	var buf bytes.Buffer
+	if err := m.WriteLines(&buf); err != nil {
+		t.Fatalf("WriteLines() error = %v", err)
+	}
+
+	expected := `/root/a=C:\\path=x
+/root/b=multi\nline\ttext
+/root/a/@id=1
+/root/items/i[1]= padded 
+/root/items/i[2]=
+`
+	if buf.String() != expected {
+		t.Errorf("WriteLines() =\n%s\nwant\n%s", buf.String(), expected)
+	}
+
+	parsed, err := FromLines(&buf)
+	if err != nil {
+		t.Fatalf("FromLines() error = %v", err)
+	}
+	if !parsed.Equal(m) {
+		t.Errorf("FromLines(WriteLines()) = %v, want %v", parsed, m)
+	}
+}
+
+func TestFromLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected XMLMap
+		wantErr  bool
+	}{
+		{
+			name:  "comments, blank lines and CRLF",
+			input: "# fixture\r\n\r\n/root/a=1\r\n/root/b\\=c=2\n",
+			expected: XMLMap{
+				"/root/a":   "1",
+				"/root/b=c": "2",
+			},
+		},
+		{
+			name:    "missing separator",
+			input:   "/root/a\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown escape",
+			input:   `/root/a=\x`,
+			wantErr: true,
+		},
+		{
+			name:    "duplicate path",
+			input:   "/root/a=1\n/root/a=2\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := FromLines(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FromLines() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !result.Equal(tt.expected) {
+				t.Errorf("FromLines() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package xmlsurf
+
+import (
+	"bytes"
+	"encoding/xml"
+)
+
+// FromValue marshals v with encoding/xml and parses the result like ParseToMap, so typed
+// request and response structs can be compared with other maps without golden XML.
+// Options apply to the parse; as with ParseToMap, fields marshaled as empty elements
+// only get a path with WithEmptyElements.
+func FromValue(v any, opts ...Option) (XMLMap, error) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return ParseToMap(bytes.NewReader(data), opts...)
+}
@@ -0,0 +1,86 @@
+package xmlsurf
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testOrder struct {
+	XMLName struct{}   `xml:"order"`
+	ID      int        `xml:"id,attr"`
+	Note    string     `xml:"note,omitempty"`
+	Comment string     `xml:"comment"`
+	Items   []testItem `xml:"items>item"`
+}
+
+type testItem struct {
+	SKU string  `xml:"sku,attr"`
+	Qty int     `xml:"qty"`
+	Net float64 `xml:"net"`
+}
+
+func TestFromValue(t *testing.T) {
+	order := testOrder{ID: 7, Items: []testItem{{SKU: "a", Qty: 2, Net: 1.5}, {SKU: "b", Qty: 1, Net: 10}}}
+
+	tests := []struct {
+		name string
+		v    any
+		opts []Option
+		want XMLMap
+	}{
+		{
+			name: "struct",
+			v:    order,
+			want: XMLMap{
+				"/order/@id":                "7",
+				"/order/items/item[1]/@sku": "a",
+				"/order/items/item[1]/qty":  "2",
+				"/order/items/item[1]/net":  "1.5",
+				"/order/items/item[2]/@sku": "b",
+				"/order/items/item[2]/qty":  "1",
+				"/order/items/item[2]/net":  "10",
+			},
+		},
+		{
+			name: "pointer with empty elements",
+			v:    &testOrder{ID: 1},
+			opts: []Option{WithEmptyElements(true)},
+			want: XMLMap{"/order/@id": "1", "/order/comment": "", "/order/items": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromValue(tt.v, tt.opts...)
+			if err != nil {
+				t.Fatalf("FromValue() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FromValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// The map compares with a parsed document
+	parsed, err := ParseToMap(strings.NewReader(`<order id="7"><items><item sku="a"><qty>2</qty><net>1.5</net></item>` +
+		`<item sku="b"><qty>3</qty><net>10</net></item></items></order>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromValue, _ := FromValue(order)
+	want := []Diff{{Path: "/order/items/item[2]/qty", LeftValue: "1", RightValue: "3", Type: DiffValue}}
+	if diffs := fromValue.Diffs(parsed); !reflect.DeepEqual(diffs, want) {
+		t.Errorf("Diffs() = %v, want %v", diffs, want)
+	}
+}
+
+func TestFromValueErrors(t *testing.T) {
+	if _, err := FromValue(make(chan int)); err == nil {
+		t.Error("FromValue() of a channel error = nil")
+	}
+	if _, err := FromValue(nil); !errors.Is(err, ErrEmptyDocument) {
+		t.Errorf("FromValue(nil) error = %v, want ErrEmptyDocument", err)
+	}
+}
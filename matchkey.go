@@ -0,0 +1,150 @@
+package xmlsurf
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MatchKey pairs the instances of a repeated element by the value at a key path
+type MatchKey struct {
+	// Path is the path of the repeated element without indices, e.g. /order/items/item
+	Path string
+	// Key is the path of the key value relative to the element, e.g. "@id" or "sku"; "." is the element's own value
+	Key string
+}
+
+// keyedInstance is an instance of a repeated element
+type keyedInstance struct {
+	path  string // Path of the instance, e.g. /order/items/item[2]
+	index int    // 1-based index, 1 for an element without index
+	key   string
+}
+
+// matchByKeys renumbers the instances of repeated elements in right so that each
+// instance gets the index of the instance in left with the same key value.
+// Keys of outer elements are applied first, so the parents of inner elements line up.
+func matchByKeys(left, right XMLMap, keys []MatchKey) XMLMap {
+	sorted := make([]MatchKey, len(keys))
+	copy(sorted, keys)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return strings.Count(sorted[i].Path, "/") < strings.Count(sorted[j].Path, "/")
+	})
+
+	for _, key := range sorted {
+		right = right.matchByKey(left, key)
+	}
+	return right
+}
+
+// matchByKey renumbers the instances of one repeated element of m to pair them
+// with the instances of left. Instances without a partner are numbered after
+// the instances of left; instances with the same key are paired in document order.
+func (m XMLMap) matchByKey(left XMLMap, key MatchKey) XMLMap {
+	leftGroups, _ := left.keyedInstances(key)
+	rightGroups, instanceOf := m.keyedInstances(key)
+	if len(rightGroups) == 0 {
+		return m
+	}
+
+	renamed := make(map[string]string)
+	for parent, instances := range rightGroups {
+		partners := make(map[string][]keyedInstance)
+		next := 1
+		for _, instance := range leftGroups[parent] {
+			partners[instance.key] = append(partners[instance.key], instance)
+			next = max(next, instance.index+1)
+		}
+
+		for _, instance := range instances {
+			if queue := partners[instance.key]; len(queue) > 0 {
+				renamed[instance.path] = queue[0].path
+				partners[instance.key] = queue[1:]
+				continue
+			}
+			renamed[instance.path] = parent + "/" + lastSegmentName(key.Path) + "[" + strconv.Itoa(next) + "]"
+			next++
+		}
+	}
+
+	result := make(XMLMap, len(m))
+	for path, value := range m {
+		if instance, ok := instanceOf[path]; ok {
+			path = renamed[instance] + path[len(instance):]
+		}
+		result[path] = value
+	}
+	return result
+}
+
+// keyedInstances finds the instances of the repeated element of key, grouped by
+// parent path and ordered by index, and the instance path of every path below one
+func (m XMLMap) keyedInstances(key MatchKey) (map[string][]keyedInstance, map[string]string) {
+	depth := strings.Count(key.Path, "/")
+	instanceOf := make(map[string]string)
+	seen := make(map[string]bool)
+	groups := make(map[string][]keyedInstance)
+
+	for path := range m {
+		instance, ok := instancePath(path, key.Path, depth)
+		if !ok {
+			continue
+		}
+		instanceOf[path] = instance
+		if seen[instance] {
+			continue
+		}
+		seen[instance] = true
+
+		parent := instance[:strings.LastIndex(instance, "/")]
+		_, index := splitIndex(instance[len(parent)+1:])
+		keyPath := instance
+		if key.Key != "." && key.Key != "" {
+			keyPath += "/" + key.Key
+		}
+		groups[parent] = append(groups[parent], keyedInstance{path: instance, index: index, key: m[keyPath]})
+	}
+
+	for _, instances := range groups {
+		sort.Slice(instances, func(i, j int) bool {
+			return instances[i].index < instances[j].index
+		})
+	}
+	return groups, instanceOf
+}
+
+// instancePath returns the prefix of path that is an instance of the element at
+// elementPath, which has the given depth, e.g. /a/b[2] for /a[1]/b[2]/c and /a/b
+func instancePath(path, elementPath string, depth int) (string, bool) {
+	end := 0
+	for i := 0; i < depth; i++ {
+		next := strings.IndexByte(path[end+1:], '/')
+		if next == -1 {
+			end = len(path)
+			if i < depth-1 {
+				return "", false
+			}
+			break
+		}
+		end += 1 + next
+	}
+	instance := path[:end]
+	if nested := strings.Index(instance, NestedSeparator); nested != -1 {
+		return "", false
+	}
+	if isAttributePath(instance) {
+		return "", false
+	}
+
+	pathBuilder := getPathBuilder()
+	defer putPathBuilder(pathBuilder)
+	if extractBasePath(instance, pathBuilder) != elementPath {
+		return "", false
+	}
+	return instance, true
+}
+
+// lastSegmentName returns the name of the last element of a path without index
+func lastSegmentName(path string) string {
+	return lastPathSegment(path).name
+}
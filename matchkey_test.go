@@ -0,0 +1,87 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithMatchKey(t *testing.T) {
+	left := XMLMap{
+		"/order/items/item[1]/@id": "a",
+		"/order/items/item[1]/qty": "1",
+		"/order/items/item[2]/@id": "b",
+		"/order/items/item[2]/qty": "2",
+		"/order/items/item[3]/@id": "c",
+		"/order/items/item[3]/qty": "3",
+	}
+	// Same items in another order, b changed, c removed, d added
+	right := XMLMap{
+		"/order/items/item[1]/@id": "d",
+		"/order/items/item[1]/qty": "4",
+		"/order/items/item[2]/@id": "b",
+		"/order/items/item[2]/qty": "5",
+		"/order/items/item[3]/@id": "a",
+		"/order/items/item[3]/qty": "1",
+	}
+
+	want := []Diff{
+		{Path: "/order/items/item[2]/qty", LeftValue: "2", RightValue: "5", Type: DiffValue},
+		{Path: "/order/items/item[3]/@id", LeftValue: "c", Type: DiffExtra},
+		{Path: "/order/items/item[3]/qty", LeftValue: "3", Type: DiffExtra},
+		{Path: "/order/items/item[4]/@id", RightValue: "d", Type: DiffMissing},
+		{Path: "/order/items/item[4]/qty", RightValue: "4", Type: DiffMissing},
+	}
+	if got := left.Diffs(right, WithMatchKey("/order/items/item", "@id")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diffs() = %v, want %v", got, want)
+	}
+
+	// The right map is not modified
+	if right["/order/items/item[1]/@id"] != "d" {
+		t.Error("Diffs() modified the right map")
+	}
+}
+
+func TestWithMatchKeyNested(t *testing.T) {
+	left := XMLMap{
+		"/orders/order[1]/id":          "o1",
+		"/orders/order[1]/line[1]/sku": "x",
+		"/orders/order[1]/line[1]/qty": "1",
+		"/orders/order[1]/line[2]/sku": "y",
+		"/orders/order[1]/line[2]/qty": "2",
+		"/orders/order[2]/id":          "o2",
+		"/orders/order[2]/line/sku":    "z",
+		"/orders/order[2]/line/qty":    "9",
+	}
+	right := XMLMap{
+		"/orders/order[1]/id":          "o2",
+		"/orders/order[1]/line/sku":    "z",
+		"/orders/order[1]/line/qty":    "9",
+		"/orders/order[2]/id":          "o1",
+		"/orders/order[2]/line[1]/sku": "y",
+		"/orders/order[2]/line[1]/qty": "3",
+		"/orders/order[2]/line[2]/sku": "x",
+		"/orders/order[2]/line[2]/qty": "1",
+	}
+
+	got := left.Diffs(right, WithMatchKey("/orders/order/line", "sku"), WithMatchKey("/orders/order", "id"))
+	want := []Diff{
+		{Path: "/orders/order[1]/line[2]/qty", LeftValue: "2", RightValue: "3", Type: DiffValue},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diffs() = %v, want %v", got, want)
+	}
+}
+
+func TestWithMatchKeyDuplicatesAndOwnValue(t *testing.T) {
+	left := XMLMap{"/list/v[1]": "a", "/list/v[2]": "a", "/list/v[3]": "b"}
+	right := XMLMap{"/list/v[1]": "b", "/list/v[2]": "a", "/list/v[3]": "c"}
+
+	got := left.Diffs(right, WithMatchKey("/list/v", "."))
+	want := []Diff{
+		{Path: "/list/v[2]", LeftValue: "a", Type: DiffExtra},
+		{Path: "/list/v[4]", RightValue: "c", Type: DiffMissing},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diffs() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,169 @@
+package xmlsurf
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MergeStrategy controls how Merge resolves paths present in both maps
+type MergeStrategy int
+
+const (
+	// MergeOverwrite replaces existing values with values from the other map
+	MergeOverwrite MergeStrategy = iota
+	// MergeKeepExisting keeps existing values and only adds missing paths
+	MergeKeepExisting
+	// MergeAppend appends the children of the other map's root as new indexed
+	// siblings after the existing elements with the same name
+	MergeAppend
+)
+
+// Clone returns a copy of the XMLMap
+func (m XMLMap) Clone() XMLMap {
+	if m == nil {
+		return nil
+	}
+	clone := make(XMLMap, len(m))
+	for path, value := range m {
+		clone[path] = value
+	}
+	return clone
+}
+
+// Merge merges the other XMLMap into m using the given strategy.
+// With MergeAppend, every child element of the root in other is added as a new
+// sibling below the root of m: /root/item in other becomes /root/item[n+1] when m
+// already holds n item elements, and existing unindexed elements are renumbered to [1].
+// Paths that do not belong to a child element of the root (the root value and
+// root attributes) are merged as with MergeOverwrite.
+// Merging into a nil map fails with ErrNilMap, as the map cannot be allocated in place.
+func (m XMLMap) Merge(other XMLMap, strategy MergeStrategy) error {
+	if m == nil {
+		if len(other) == 0 {
+			return nil
+		}
+		return ErrNilMap
+	}
+	switch strategy {
+	case MergeKeepExisting:
+		for path, value := range other {
+			if _, exists := m[path]; !exists {
+				m[path] = value
+			}
+		}
+	case MergeAppend:
+		m.mergeAppend(other)
+	default:
+		for path, value := range other {
+			m[path] = value
+		}
+	}
+	return nil
+}
+
+// appendedPath is a path of the other map in MergeAppend, relative to its root child element
+type appendedPath struct {
+	rest  string
+	value string
+}
+
+// mergeAppend implements the MergeAppend strategy
+func (m XMLMap) mergeAppend(other XMLMap) {
+	// Elements are appended below the root of m, whatever the root of other is named
+	root := m.rootPath()
+	if root == "" {
+		root = other.rootPath()
+	}
+
+	// Group the paths of other by root child name and by index
+	groups := make(map[string]map[int][]appendedPath)
+	for path, value := range other {
+		parts := strings.SplitN(path, "/", 4)
+		if len(parts) < 2 {
+			m[path] = value
+			continue
+		}
+		if len(parts) < 3 || strings.HasPrefix(parts[2], "@") {
+			m[root+path[len(parts[1])+1:]] = value
+			continue
+		}
+		name, index := splitIndex(parts[2])
+		key := root + "/" + name
+		if groups[key] == nil {
+			groups[key] = make(map[int][]appendedPath)
+		}
+		rest := path[len(parts[1])+len(parts[2])+2:]
+		groups[key][index] = append(groups[key][index], appendedPath{rest: rest, value: value})
+	}
+
+	pathBuilder := getPathBuilder()
+	defer putPathBuilder(pathBuilder)
+
+	for base, instances := range groups {
+		existing := m.countSiblings(base)
+		if existing == 1 {
+			m.renameSibling(base, base+"[1]")
+		}
+
+		indices := make([]int, 0, len(instances))
+		for index := range instances {
+			indices = append(indices, index)
+		}
+		sort.Ints(indices)
+
+		indexed := existing+len(indices) > 1
+		for rank, index := range indices {
+			next := existing + rank + 1
+			for _, path := range instances[index] {
+				// Replace the original root child segment with the new index
+				pathBuilder.Reset()
+				pathBuilder.WriteString(base)
+				if indexed {
+					pathBuilder.WriteString("[")
+					pathBuilder.WriteString(strconv.Itoa(next))
+					pathBuilder.WriteString("]")
+				}
+				pathBuilder.WriteString(path.rest)
+				m[pathBuilder.String()] = path.value
+			}
+		}
+	}
+}
+
+// countSiblings returns the number of elements in m matching the index-free base path
+func (m XMLMap) countSiblings(base string) int {
+	count := 0
+	for path := range m {
+		if !strings.HasPrefix(path, base) {
+			continue
+		}
+		rest := path[len(base):]
+		switch {
+		case rest == "" || rest[0] == '/':
+			if count < 1 {
+				count = 1
+			}
+		case rest[0] == '[':
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				continue
+			}
+			if index, err := strconv.Atoi(rest[1:end]); err == nil && index > count {
+				count = index
+			}
+		}
+	}
+	return count
+}
+
+// renameSibling moves the element at from, including its subtree, to to
+func (m XMLMap) renameSibling(from, to string) {
+	prefix := from + "/"
+	for path, value := range m {
+		if path == from || strings.HasPrefix(path, prefix) {
+			delete(m, path)
+			m[to+path[len(from):]] = value
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package xmlsurf
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestXMLMapClone(t *testing.T) {
+	original := XMLMap{
+		"/root/item":     "value",
+		"/root/item/@id": "1",
+	}
+
+	clone := original.Clone()
+	if !clone.Equal(original) {
+		t.Errorf("Clone() = %v, want %v", clone, original)
+	}
+
+	clone["/root/item"] = "changed"
+	if original["/root/item"] != "value" {
+		t.Errorf("Clone() shares storage with the original map")
+	}
+
+	var empty XMLMap
+	if empty.Clone() != nil {
+		t.Errorf("Clone() of nil map should be nil")
+	}
+}
+
+func TestXMLMapMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     XMLMap
+		other    XMLMap
+		strategy MergeStrategy
+		expected XMLMap
+	}{
+		{
+			name: "overwrite replaces existing values",
+			base: XMLMap{
+				"/root/a": "1",
+				"/root/b": "2",
+			},
+			other: XMLMap{
+				"/root/b": "3",
+				"/root/c": "4",
+			},
+			strategy: MergeOverwrite,
+			expected: XMLMap{
+				"/root/a": "1",
+				"/root/b": "3",
+				"/root/c": "4",
+			},
+		},
+		{
+			name: "keep existing only adds missing paths",
+			base: XMLMap{
+				"/root/a": "1",
+				"/root/b": "2",
+			},
+			other: XMLMap{
+				"/root/b": "3",
+				"/root/c": "4",
+			},
+			strategy: MergeKeepExisting,
+			expected: XMLMap{
+				"/root/a": "1",
+				"/root/b": "2",
+				"/root/c": "4",
+			},
+		},
+		{
+			name: "append single element to single element",
+			base: XMLMap{
+				"/root/item/name":  "first",
+				"/root/item/@id":   "1",
+				"/root/other":      "x",
+				"/root/@version":   "1",
+				"/root/items/item": "untouched",
+			},
+			other: XMLMap{
+				"/root/item/name": "second",
+				"/root/item/@id":  "2",
+				"/root/@version":  "2",
+			},
+			strategy: MergeAppend,
+			expected: XMLMap{
+				"/root/item[1]/name": "first",
+				"/root/item[1]/@id":  "1",
+				"/root/item[2]/name": "second",
+				"/root/item[2]/@id":  "2",
+				"/root/other":        "x",
+				"/root/@version":     "2",
+				"/root/items/item":   "untouched",
+			},
+		},
+		{
+			name: "append repeated elements after existing ones",
+			base: XMLMap{
+				"/root/item[1]": "a",
+				"/root/item[2]": "b",
+			},
+			other: XMLMap{
+				"/root/item[1]": "c",
+				"/root/item[2]": "d",
+			},
+			strategy: MergeAppend,
+			expected: XMLMap{
+				"/root/item[1]": "a",
+				"/root/item[2]": "b",
+				"/root/item[3]": "c",
+				"/root/item[4]": "d",
+			},
+		},
+		{
+			name: "append new elements keeps their indices",
+			base: XMLMap{
+				"/root/a": "1",
+			},
+			other: XMLMap{
+				"/root/item[1]": "c",
+				"/root/item[2]": "d",
+				"/root/single":  "e",
+			},
+			strategy: MergeAppend,
+			expected: XMLMap{
+				"/root/a":       "1",
+				"/root/item[1]": "c",
+				"/root/item[2]": "d",
+				"/root/single":  "e",
+			},
+		},
+		{
+			name: "append below the root of the base map",
+			base: XMLMap{
+				"/root/item": "a",
+			},
+			other: XMLMap{
+				"/batch/item[1]":      "b",
+				"/batch/item[2]/name": "c",
+				"/batch/@version":     "2",
+			},
+			strategy: MergeAppend,
+			expected: XMLMap{
+				"/root/item[1]":      "a",
+				"/root/item[2]":      "b",
+				"/root/item[3]/name": "c",
+				"/root/@version":     "2",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.base.Clone()
+			if err := result.Merge(tt.other, tt.strategy); err != nil {
+				t.Fatalf("Merge() error = %v", err)
+			}
+			if !result.Equal(tt.expected) {
+				t.Errorf("Merge() result = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestXMLMapMergeNil(t *testing.T) {
+	var m XMLMap
+	if err := m.Merge(XMLMap{"/root/a": "1"}, MergeOverwrite); !errors.Is(err, ErrNilMap) {
+		t.Errorf("Merge() into nil map error = %v, want ErrNilMap", err)
+	}
+	if err := m.Merge(nil, MergeAppend); err != nil {
+		t.Errorf("Merge() of nothing into nil map error = %v, want nil", err)
+	}
+}
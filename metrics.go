@@ -0,0 +1,67 @@
+package xmlsurf
+
+import (
+	"errors"
+	"time"
+)
+
+// ParseMetrics receives statistics about every parsed document, e.g. to export them
+// as Prometheus metrics. ObserveParse is called on the parsing goroutine once parsing
+// has finished, so implementations used by concurrent parsers must be safe for concurrent use.
+type ParseMetrics interface {
+	ObserveParse(stats ParseStats)
+}
+
+// ParseMetricsFunc adapts a function to the ParseMetrics interface
+type ParseMetricsFunc func(stats ParseStats)
+
+// ObserveParse calls f(stats)
+func (f ParseMetricsFunc) ObserveParse(stats ParseStats) {
+	f(stats)
+}
+
+// ParseStats describes one parsed document
+type ParseStats struct {
+	Bytes    int64         // Bytes read from the input
+	Elements int           // Elements read
+	Paths    int           // Paths of the resulting map, 0 on error
+	Duration time.Duration // Time spent parsing
+	Err      error         // Error returned by the parse, nil on success
+	// ErrorKind classifies Err for use as a metric label, see the ErrorKind constants
+	ErrorKind ErrorKind
+}
+
+// ErrorKind classifies parse errors
+type ErrorKind string
+
+const (
+	ErrorKindNone          ErrorKind = ""               // No error
+	ErrorKindSyntax        ErrorKind = "syntax"         // Malformed XML, a *SyntaxError other than ErrMultipleRoots
+	ErrorKindMultipleRoots ErrorKind = "multiple_roots" // ErrMultipleRoots
+	ErrorKindEmpty         ErrorKind = "empty"          // ErrEmptyDocument
+	ErrorKindDuplicatePath ErrorKind = "duplicate_path" // ErrDuplicatePath
+	ErrorKindPanic         ErrorKind = "panic"          // A *PanicError, e.g. from a ValueTransform
+	ErrorKindRead          ErrorKind = "read"           // Any other error, typically from the reader
+)
+
+// classifyError returns the ErrorKind of err
+func classifyError(err error) ErrorKind {
+	var syntaxErr *SyntaxError
+	var panicErr *PanicError
+	switch {
+	case err == nil:
+		return ErrorKindNone
+	case errors.Is(err, ErrMultipleRoots):
+		return ErrorKindMultipleRoots
+	case errors.As(err, &syntaxErr):
+		return ErrorKindSyntax
+	case errors.Is(err, ErrEmptyDocument):
+		return ErrorKindEmpty
+	case errors.Is(err, ErrDuplicatePath):
+		return ErrorKindDuplicatePath
+	case errors.As(err, &panicErr):
+		return ErrorKindPanic
+	default:
+		return ErrorKindRead
+	}
+}
@@ -0,0 +1,87 @@
+package xmlsurf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestWithMetrics(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     []Option
+		kind     ErrorKind
+		elements int
+		paths    int
+	}{
+		{name: "success", input: `<root a="1"><b>x</b><c>y</c></root>`, elements: 3, paths: 3},
+		{name: "syntax error", input: `<root><b></root>`, kind: ErrorKindSyntax},
+		{name: "multiple roots", input: `<a>1</a><b>2</b>`, kind: ErrorKindMultipleRoots},
+		{name: "empty", input: ``, kind: ErrorKindEmpty},
+		{
+			name:  "duplicate path",
+			input: `<root>a<!---->b</root>`,
+			opts:  []Option{WithOverwritePolicy(OverwriteError)},
+			kind:  ErrorKindDuplicatePath,
+		},
+		{
+			name:  "panic",
+			input: `<root>a</root>`,
+			opts:  []Option{WithValueTransform(func(string) string { panic("boom") })},
+			kind:  ErrorKindPanic,
+		},
+		{
+			name:     "embedded documents are not reported separately",
+			input:    `<root><doc>&lt;x&gt;1&lt;/x&gt;</doc></root>`,
+			opts:     []Option{WithUnwrapNested(true)},
+			elements: 2,
+			paths:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var observed []ParseStats
+			metrics := ParseMetricsFunc(func(stats ParseStats) {
+				observed = append(observed, stats)
+			})
+
+			_, err := ParseToMap(strings.NewReader(tt.input), append(tt.opts, WithMetrics(metrics))...)
+			if len(observed) != 1 {
+				t.Fatalf("observed %d parses, want 1", len(observed))
+			}
+			stats := observed[0]
+			if stats.Err != err {
+				t.Errorf("Err = %v, want %v", stats.Err, err)
+			}
+			if stats.ErrorKind != tt.kind {
+				t.Errorf("ErrorKind = %q, want %q", stats.ErrorKind, tt.kind)
+			}
+			if stats.Bytes != int64(len(tt.input)) && tt.kind == ErrorKindNone {
+				t.Errorf("Bytes = %d, want %d", stats.Bytes, len(tt.input))
+			}
+			if tt.kind == ErrorKindNone && (stats.Elements != tt.elements || stats.Paths != tt.paths) {
+				t.Errorf("Elements, Paths = %d, %d, want %d, %d", stats.Elements, stats.Paths, tt.elements, tt.paths)
+			}
+			if stats.Duration < 0 {
+				t.Errorf("Duration = %v, want >= 0", stats.Duration)
+			}
+		})
+	}
+}
+
+func TestWithMetricsReadError(t *testing.T) {
+	var kind ErrorKind
+	metrics := ParseMetricsFunc(func(stats ParseStats) { kind = stats.ErrorKind })
+
+	readErr := errors.New("connection reset")
+	_, err := NewParser(WithMetrics(metrics)).Parse(iotest.ErrReader(readErr))
+	if !errors.Is(err, readErr) {
+		t.Fatalf("Parse() error = %v, want %v", err, readErr)
+	}
+	if kind != ErrorKindRead {
+		t.Errorf("ErrorKind = %q, want %q", kind, ErrorKindRead)
+	}
+}
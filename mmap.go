@@ -0,0 +1,34 @@
+package xmlsurf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// ParseFileToMap parses the XML file at path like ParseToMap. Where the platform
+// supports it, the file is memory-mapped, so large files are paged in by the kernel
+// instead of being copied through read calls; elsewhere, and for files that cannot be
+// mapped such as pipes, it is read through a buffered reader.
+func ParseFileToMap(path string, opts ...Option) (XMLMap, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var result XMLMap
+	data, unmap := mapFile(file)
+	if data != nil {
+		// The parsed values are copies, so the mapping can be released right after parsing
+		defer unmap()
+		result, err = ParseToMap(bytes.NewReader(data), opts...)
+	} else {
+		result, err = ParseToMap(bufio.NewReader(file), opts...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,10 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package xmlsurf
+
+import "os"
+
+// mapFile is not supported on this platform; the caller reads the file instead
+func mapFile(*os.File) ([]byte, func()) {
+	return nil, nil
+}
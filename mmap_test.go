@@ -0,0 +1,60 @@
+package xmlsurf
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFileToMap(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	large := "<items>" + strings.Repeat("<item>value</item>", 10000) + "</items>"
+	m, err := ParseFileToMap(write("large.xml", large))
+	if err != nil {
+		t.Fatalf("ParseFileToMap() error = %v", err)
+	}
+	if len(m) != 10000 || m["/items/item[10000]"] != "value" {
+		t.Errorf("ParseFileToMap() returned %d paths, want 10000", len(m))
+	}
+
+	m, err = ParseFileToMap(write("ns.xml", `<a:root xmlns:a="urn:a"><a:b> x </a:b></a:root>`), WithNamespaces(false))
+	if err != nil {
+		t.Fatalf("ParseFileToMap() error = %v", err)
+	}
+	if want := (XMLMap{"/root/b": "x"}); !reflect.DeepEqual(m, want) {
+		t.Errorf("ParseFileToMap() = %v, want %v", m, want)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr error
+	}{
+		{name: "missing file", path: filepath.Join(dir, "missing.xml"), wantErr: fs.ErrNotExist},
+		{name: "empty file", path: write("empty.xml", ""), wantErr: ErrEmptyDocument},
+		{name: "syntax error", path: write("broken.xml", "<a><b></a>")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFileToMap(tt.path)
+			if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Errorf("ParseFileToMap() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && !os.IsNotExist(err) && !strings.Contains(err.Error(), tt.path) {
+				t.Errorf("error %q does not name the file", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package xmlsurf
+
+import (
+	"math"
+	"os"
+	"syscall"
+)
+
+// mapFile maps a regular file into memory read-only. It returns nil if the file
+// cannot be mapped, so the caller falls back to reading it.
+func mapFile(file *os.File) ([]byte, func()) {
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() || info.Size() == 0 || info.Size() > math.MaxInt {
+		return nil, nil
+	}
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil
+	}
+	return data, func() { syscall.Munmap(data) }
+}
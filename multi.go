@@ -0,0 +1,159 @@
+package xmlsurf
+
+import (
+	"regexp"
+	"sort"
+)
+
+// MultiDiff summarizes the differences between more than two XMLMaps
+type MultiDiff struct {
+	// Counts holds the number of differences between map i and map j
+	Counts [][]int
+	// Diffs holds the differences between map i (left) and map j (right), nil on the diagonal
+	Diffs [][][]Diff
+	// Consensus holds the paths and values agreed on by a strict majority of the maps
+	Consensus XMLMap
+	// Meta holds the metadata of each compared document, if compared with DiffNDocuments
+	Meta []Metadata
+}
+
+// DiffN compares every pair of maps and computes their consensus document.
+// Options are applied to every pairwise comparison as in Diffs.
+func DiffN(maps []XMLMap, opts ...CompareOption) MultiDiff {
+	n := len(maps)
+	result := MultiDiff{
+		Counts:    make([][]int, n),
+		Diffs:     make([][][]Diff, n),
+		Consensus: Consensus(maps),
+	}
+	for i := range maps {
+		result.Counts[i] = make([]int, n)
+		result.Diffs[i] = make([][]Diff, n)
+	}
+
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			diffs := maps[i].Diffs(maps[j], opts...)
+			result.Diffs[i][j] = diffs
+			result.Diffs[j][i] = invertDiffs(diffs)
+			result.Counts[i][j] = len(diffs)
+			result.Counts[j][i] = len(diffs)
+		}
+	}
+
+	return result
+}
+
+// Consensus returns the paths and values shared by a strict majority of the maps
+func Consensus(maps []XMLMap) XMLMap {
+	counts := make(map[string]map[string]int)
+	for _, m := range maps {
+		for path, value := range m {
+			if counts[path] == nil {
+				counts[path] = make(map[string]int, 1)
+			}
+			counts[path][value]++
+		}
+	}
+
+	result := make(XMLMap)
+	for path, values := range counts {
+		for value, count := range values {
+			if count*2 > len(maps) {
+				result[path] = value
+				break
+			}
+		}
+	}
+	return result
+}
+
+// invertDiffs returns the diffs as seen from the other side of the comparison
+func invertDiffs(diffs []Diff) []Diff {
+	inverted := make([]Diff, len(diffs))
+	for i, diff := range diffs {
+		inverted[i] = Diff{
+			Path:       diff.Path,
+			LeftValue:  diff.RightValue,
+			RightValue: diff.LeftValue,
+			Type:       diff.Type,
+		}
+		switch diff.Type {
+		case DiffMissing:
+			inverted[i].Type = DiffExtra
+		case DiffExtra:
+			inverted[i].Type = DiffMissing
+		}
+	}
+	sort.Slice(inverted, func(i, j int) bool {
+		return inverted[i].Path < inverted[j].Path
+	})
+	return inverted
+}
+
+// Regular expressions used for volatile values in synthesized patterns
+var volatileValueShapes = []*regexp.Regexp{
+	regexp.MustCompile(`^-?[0-9]+$`),
+	regexp.MustCompile(`^-?[0-9]+\.[0-9]+$`),
+	regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}$`),
+	regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?(Z|[+-][0-9]{2}:[0-9]{2})?$`),
+	regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`),
+}
+
+// SynthesizePattern builds a pattern map (see PatternDiffs) from samples of similar documents.
+// Paths present in every sample are included: stable values literally (escaped with
+// MatchLiteral if they look like a matcher), volatile values as a matcher. A volatile value becomes a MatchRegexpPrefix matcher when all samples
+// share a shape (integer, decimal, date, date-time or UUID), MatchNonEmpty when no
+// sample is empty and MatchAny otherwise. Paths missing from some samples are left out.
+func SynthesizePattern(samples []XMLMap) XMLMap {
+	result := make(XMLMap)
+	if len(samples) == 0 {
+		return result
+	}
+
+	for path, first := range samples[0] {
+		values := make([]string, 0, len(samples))
+		stable := true
+		for _, sample := range samples {
+			value, ok := sample[path]
+			if !ok {
+				values = nil
+				break
+			}
+			values = append(values, value)
+			stable = stable && value == first
+		}
+		if values == nil {
+			continue
+		}
+		if stable {
+			result[path] = MatchLiteral(first)
+			continue
+		}
+		result[path] = volatileMatcher(values)
+	}
+	return result
+}
+
+// volatileMatcher returns the most specific pattern value matching all values
+func volatileMatcher(values []string) string {
+	for _, shape := range volatileValueShapes {
+		matchesAll := true
+		for _, value := range values {
+			if !shape.MatchString(value) {
+				matchesAll = false
+				break
+			}
+		}
+		if matchesAll {
+			return MatchRegexpPrefix + shape.String()
+		}
+	}
+
+	for _, value := range values {
+		if value == "" {
+			return MatchAny
+		}
+	}
+	return MatchNonEmpty
+}
@@ -0,0 +1,86 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffN(t *testing.T) {
+	maps := []XMLMap{
+		{"/root/a": "1", "/root/b": "x", "/root/c": "only"},
+		{"/root/a": "1", "/root/b": "y"},
+		{"/root/a": "1", "/root/b": "x"},
+	}
+
+	result := DiffN(maps)
+
+	expectedCounts := [][]int{
+		{0, 2, 1},
+		{2, 0, 1},
+		{1, 1, 0},
+	}
+	if !reflect.DeepEqual(result.Counts, expectedCounts) {
+		t.Errorf("DiffN() counts = %v, want %v", result.Counts, expectedCounts)
+	}
+
+	expectedDiffs := []Diff{
+		{Path: "/root/b", LeftValue: "y", RightValue: "x", Type: DiffValue},
+		{Path: "/root/c", RightValue: "only", Type: DiffMissing},
+	}
+	if !reflect.DeepEqual(result.Diffs[1][0], expectedDiffs) {
+		t.Errorf("DiffN() diffs[1][0] = %v, want %v", result.Diffs[1][0], expectedDiffs)
+	}
+	if result.Diffs[1][1] != nil {
+		t.Errorf("DiffN() diagonal should be nil")
+	}
+
+	expectedConsensus := XMLMap{"/root/a": "1", "/root/b": "x"}
+	if !result.Consensus.Equal(expectedConsensus) {
+		t.Errorf("DiffN() consensus = %v, want %v", result.Consensus, expectedConsensus)
+	}
+}
+
+func TestSynthesizePattern(t *testing.T) {
+	samples := []XMLMap{
+		{
+			"/resp/status":   "OK",
+			"/resp/id":       "17",
+			"/resp/ts":       "2024-01-01T10:00:00Z",
+			"/resp/token":    "abc",
+			"/resp/note":     "",
+			"/resp/optional": "x",
+			"/resp/mask":     "*",
+			"/resp/expr":     "re:[a-z]",
+		},
+		{
+			"/resp/status": "OK",
+			"/resp/id":     "942",
+			"/resp/ts":     "2024-03-05T08:30:00.123+01:00",
+			"/resp/token":  "xyz",
+			"/resp/note":   "hello",
+			"/resp/mask":   "*",
+			"/resp/expr":   "re:[a-z]",
+		},
+	}
+
+	pattern := SynthesizePattern(samples)
+
+	expected := XMLMap{
+		"/resp/status": "OK",
+		"/resp/id":     "re:^-?[0-9]+$",
+		"/resp/ts":     `re:^[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?(Z|[+-][0-9]{2}:[0-9]{2})?$`,
+		"/resp/token":  MatchNonEmpty,
+		"/resp/note":   MatchAny,
+		"/resp/mask":   `\*`,
+		"/resp/expr":   `\re:[a-z]`,
+	}
+	if !pattern.Equal(expected) {
+		t.Errorf("SynthesizePattern() = %v, want %v", pattern, expected)
+	}
+
+	for i, sample := range samples {
+		if !sample.MatchesPattern(pattern) {
+			t.Errorf("sample %d does not match the synthesized pattern: %v", i, sample.PatternDiffs(pattern))
+		}
+	}
+}
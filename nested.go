@@ -0,0 +1,134 @@
+package xmlsurf
+
+import (
+	"strings"
+)
+
+// NestedSeparator separates the path of a value holding an embedded XML document
+// from the paths inside that document, e.g. /response/payload!/order/id
+const NestedSeparator = "!"
+
+// UnwrapNested returns a copy of the map in which element values that are
+// themselves XML documents (typically escaped as &lt;order&gt;... in the source)
+// are replaced by their parsed paths joined with NestedSeparator.
+// Embedded documents are parsed with the given options and unwrapped recursively.
+// Values that look like XML but fail to parse are kept as they are.
+func (m XMLMap) UnwrapNested(opts ...Option) XMLMap {
+	options := DefaultParseOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+	return m.unwrapNested(options)
+}
+
+// unwrapNested implements UnwrapNested with evaluated options
+func (m XMLMap) unwrapNested(options *ParseOptions) XMLMap {
+	// Embedded documents are observed, selected and post-processed as part of the outer document
+	options = options.innerOptions()
+	result := make(XMLMap, len(m))
+	for path, value := range m {
+		if !isAttributePath(path) && looksLikeXML(value) {
+			nested, err := parseWithOptions(strings.NewReader(value), options)
+			if err == nil {
+				for nestedPath, nestedValue := range nested.unwrapNested(options) {
+					result[path+NestedSeparator+nestedPath] = nestedValue
+				}
+				continue
+			}
+		}
+		result[path] = value
+	}
+	return result
+}
+
+// WrapNested returns a copy of the map in which paths containing NestedSeparator
+// are serialized back into XML string values of their outer element.
+// The serialized documents are escaped when the map is written with ToXML.
+func (m XMLMap) WrapNested() (_ XMLMap, err error) {
+	defer recoverPanic("wrap nested documents", &err)
+
+	result := make(XMLMap, len(m))
+	nested := make(map[string]XMLMap)
+	for path, value := range m {
+		idx := strings.Index(path, NestedSeparator)
+		if idx == -1 {
+			result[path] = value
+			continue
+		}
+		outer := path[:idx]
+		if nested[outer] == nil {
+			nested[outer] = make(XMLMap)
+		}
+		nested[outer][path[idx+len(NestedSeparator):]] = value
+	}
+
+	for outer, inner := range nested {
+		var builder strings.Builder
+		// ToXML wraps deeper nested documents itself
+		if err := inner.ToXML(&builder, false); err != nil {
+			return nil, err
+		}
+		result[outer] = builder.String()
+	}
+	return result, nil
+}
+
+// hasNestedPaths reports whether any path refers into an embedded document
+func (m XMLMap) hasNestedPaths() bool {
+	for path := range m {
+		if strings.Contains(path, NestedSeparator) {
+			return true
+		}
+	}
+	return false
+}
+
+// looksLikeXML reports whether a value may hold an XML document
+func looksLikeXML(value string) bool {
+	value = strings.TrimSpace(strings.TrimPrefix(value, "\ufeff"))
+	return strings.HasPrefix(value, "<") && strings.HasSuffix(value, ">")
+}
+
+// isAttributePath reports whether the path refers to an attribute
+func isAttributePath(path string) bool {
+	idx := strings.LastIndex(path, "/")
+	return idx != -1 && strings.HasPrefix(path[idx+1:], "@")
+}
+
+// SplitNestedPath splits a path into the outer path and the paths inside each
+// embedded document, e.g. /a/b!/c/d!/e becomes ["/a/b", "/c/d", "/e"]
+func SplitNestedPath(path string) []string {
+	return strings.Split(path, NestedSeparator)
+}
+
+// JoinNestedPath joins an outer path and paths inside embedded documents
+func JoinNestedPath(parts ...string) string {
+	return strings.Join(parts, NestedSeparator)
+}
+
+// Nested returns the document embedded at the path, with paths relative to it.
+// The document is taken from nested paths below the path if the map was unwrapped,
+// otherwise the value at the path is parsed with the given options.
+// It returns nil if the path holds no embedded document.
+func (m XMLMap) Nested(path string, opts ...Option) XMLMap {
+	prefix := path + NestedSeparator
+	result := make(XMLMap)
+	for p, value := range m {
+		if strings.HasPrefix(p, prefix) {
+			result[p[len(prefix):]] = value
+		}
+	}
+	if len(result) > 0 {
+		return result
+	}
+
+	value, ok := m[path]
+	if !ok || !looksLikeXML(value) {
+		return nil
+	}
+	nested, err := ParseToMap(strings.NewReader(value), opts...)
+	if err != nil {
+		return nil
+	}
+	return nested
+}
@@ -0,0 +1,136 @@
+package xmlsurf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseWithUnwrapNested(t *testing.T) {
+	xml := `<response>
+		<status>OK</status>
+		<payload>&lt;order id="7"&gt;&lt;item&gt;a&lt;/item&gt;&lt;note&gt;&amp;lt;x&amp;gt;1&amp;lt;/x&amp;gt;&lt;/note&gt;&lt;/order&gt;</payload>
+		<text>&lt;not xml</text>
+	</response>`
+
+	result, err := ParseToMap(strings.NewReader(xml), WithUnwrapNested(true))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+
+	expected := XMLMap{
+		"/response/status":                 "OK",
+		"/response/payload!/order/@id":     "7",
+		"/response/payload!/order/item":    "a",
+		"/response/payload!/order/note!/x": "1",
+		"/response/text":                   "<not xml",
+	}
+	if !result.Equal(expected) {
+		t.Errorf("ParseToMap() = %v, want %v", result, expected)
+	}
+}
+
+func TestXMLMapWrapNested(t *testing.T) {
+	input := XMLMap{
+		"/response/status":              "OK",
+		"/response/payload!/order/item": "a",
+		"/response/payload!/order/@id":  "7",
+	}
+
+	wrapped, err := input.WrapNested()
+	if err != nil {
+		t.Fatalf("WrapNested() error = %v", err)
+	}
+	expected := XMLMap{
+		"/response/status":  "OK",
+		"/response/payload": `<order id="7"><item>a</item></order>`,
+	}
+	if !wrapped.Equal(expected) {
+		t.Errorf("WrapNested() = %v, want %v", wrapped, expected)
+	}
+
+	var builder strings.Builder
+	if err := input.ToXML(&builder, false); err != nil {
+		t.Fatalf("ToXML() error = %v", err)
+	}
+	expectedXML := `<response><payload>&lt;order id=&#34;7&#34;&gt;&lt;item&gt;a&lt;/item&gt;&lt;/order&gt;</payload><status>OK</status></response>`
+	if builder.String() != expectedXML {
+		t.Errorf("ToXML() = %v, want %v", builder.String(), expectedXML)
+	}
+
+	reparsed, err := ParseToMap(strings.NewReader(builder.String()), WithUnwrapNested(true))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+	if !reparsed.Equal(input) {
+		t.Errorf("round-trip = %v, want %v", reparsed, input)
+	}
+}
+
+func TestNestedPaths(t *testing.T) {
+	parts := SplitNestedPath("/a/b!/c/d!/e")
+	if len(parts) != 3 || parts[0] != "/a/b" || parts[1] != "/c/d" || parts[2] != "/e" {
+		t.Errorf("SplitNestedPath() = %v", parts)
+	}
+	if path := JoinNestedPath(parts...); path != "/a/b!/c/d!/e" {
+		t.Errorf("JoinNestedPath() = %v", path)
+	}
+
+	unwrapped := XMLMap{
+		"/root/payload!/order/id": "1",
+		"/root/other":             "x",
+	}
+	if nested := unwrapped.Nested("/root/payload"); !nested.Equal(XMLMap{"/order/id": "1"}) {
+		t.Errorf("Nested() on unwrapped map = %v", nested)
+	}
+
+	wrapped := XMLMap{
+		"/root/payload": "<order><id>1</id></order>",
+		"/root/other":   "x",
+	}
+	if nested := wrapped.Nested("/root/payload"); !nested.Equal(XMLMap{"/order/id": "1"}) {
+		t.Errorf("Nested() on wrapped map = %v", nested)
+	}
+	if nested := wrapped.Nested("/root/other"); nested != nil {
+		t.Errorf("Nested() on plain value = %v, want nil", nested)
+	}
+}
+
+func TestDiffsWithNestedDocuments(t *testing.T) {
+	left := XMLMap{
+		"/root/payload": "<order><id>1</id><ts>1</ts></order>",
+	}
+	right := XMLMap{
+		"/root/payload!/order/id": "2",
+		"/root/payload!/order/ts": "2",
+	}
+
+	diffs := left.Diffs(right, WithNestedDocuments(true), WithIgnorePaths("/root/payload!/order/ts"))
+	if len(diffs) != 1 || diffs[0].Path != "/root/payload!/order/id" || diffs[0].Type != DiffValue {
+		t.Errorf("Diffs() = %v", diffs)
+	}
+
+	if !left.Equal(right, WithIgnorePaths("/root/payload")) {
+		t.Errorf("Equal() with ignored embedded document should be true")
+	}
+}
+
+func TestUnwrapNestedOptions(t *testing.T) {
+	// The embedded document declares an xsi:type at a path the outer document also has
+	xml := `<r><x>7</x><p>&lt;r xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" ` +
+		`xmlns:xs="http://www.w3.org/2001/XMLSchema"&gt;&lt;x xsi:type="xs:string"&gt;1&lt;/x&gt;&lt;/r&gt;</p></r>`
+
+	var elements []int
+	progress := WithProgress(func(_ int64, n int) { elements = append(elements, n) })
+	typed, err := ParseToTypedMap(strings.NewReader(xml), WithUnwrapNested(true), progress)
+	if err != nil {
+		t.Fatalf("ParseToTypedMap() error = %v", err)
+	}
+
+	// Progress is reported for the outer document only
+	if len(elements) != 1 || elements[0] != 3 {
+		t.Errorf("progress elements = %v, want [3]", elements)
+	}
+	if got := typed["/r/x"]; got != int64(7) {
+		t.Errorf("/r/x = %#v, want int64(7)", got)
+	}
+}
@@ -0,0 +1,225 @@
+package xmlsurf
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NestedTextKey is the key holding the value of an element that also has attributes
+// or children in the nested form of ToNested; attributes are held under their name
+// prefixed with "@", as in paths
+const NestedTextKey = "#text"
+
+// NestedElement is an element in the nested form of ToNested with its keys in the
+// order ToXML writes them, for encoders that keep the order of keys, such as YAML.
+// An element with only a value has no entries and is written as its value.
+type NestedElement struct {
+	Name    string
+	Value   string
+	Entries []NestedEntry
+}
+
+// NestedEntry is a key of a NestedElement: an attribute under "@name" or the value
+// of the element under NestedTextKey with Value set, or the instances of a child
+// element under its name, which form a list if Repeated
+type NestedEntry struct {
+	Key      string
+	Value    string
+	Elements []*NestedElement
+	Repeated bool
+}
+
+// ToNested converts the XMLMap into generic nested maps, for libraries consuming
+// map[string]interface{} such as template engines and JSON encoders. The result has
+// the root element name as its only key. An element with only a value becomes a string;
+// an element with attributes or children becomes a map[string]interface{} with the
+// attributes under "@name" keys, its value under NestedTextKey and its children under
+// their names. Repeated elements become a []interface{} in index order.
+func (m XMLMap) ToNested() (map[string]interface{}, error) {
+	root, err := m.NestedTree()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{root.Name: root.generic()}, nil
+}
+
+// generic converts the element into its generic representation
+func (e *NestedElement) generic() interface{} {
+	if len(e.Entries) == 0 {
+		return e.Value
+	}
+
+	element := make(map[string]interface{}, len(e.Entries))
+	for _, entry := range e.Entries {
+		switch {
+		case entry.Elements == nil:
+			element[entry.Key] = entry.Value
+		case entry.Repeated:
+			list := make([]interface{}, len(entry.Elements))
+			for i, child := range entry.Elements {
+				list[i] = child.generic()
+			}
+			element[entry.Key] = list
+		default:
+			element[entry.Key] = entry.Elements[0].generic()
+		}
+	}
+	return element
+}
+
+// NestedTree returns the root element of the nested form of ToNested, keeping the
+// order of keys. Embedded documents are serialized into the values of their outer elements.
+func (m XMLMap) NestedTree() (_ *NestedElement, err error) {
+	defer recoverPanic("convert to nested maps", &err)
+
+	if len(m) == 0 {
+		return nil, errors.New("empty XMLMap")
+	}
+
+	if m.hasNestedPaths() {
+		wrapped, err := m.WrapNested()
+		if err != nil {
+			return nil, err
+		}
+		m = wrapped
+	}
+
+	rootPath := m.rootPath()
+	if rootPath == "" {
+		return nil, errors.New("no root element found")
+	}
+
+	root, _, err := buildXMLTree(m, rootPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	return nestedElement(root, DefaultWriteOptions().elementOrder()), nil
+}
+
+// nestedElement returns the nested form of an element node: its attributes under
+// "@name" keys, its value under NestedTextKey and its children under their names,
+// with repeated children grouped at the position of the first one. Children are in
+// the order ToXML writes them with compareFn.
+func nestedElement(node *xmlNode, compareFn func(a, b string) bool) *NestedElement {
+	element := &NestedElement{Name: node.name, Value: node.value}
+	if len(node.attributes) == 0 && len(node.children) == 0 {
+		return element
+	}
+
+	element.Entries = make([]NestedEntry, 0, len(node.attributes)+len(node.children)+1)
+	for _, attr := range node.attributes {
+		element.Entries = append(element.Entries, NestedEntry{Key: "@" + attr.attrName, Value: attr.value})
+	}
+	if node.value != "" {
+		element.Entries = append(element.Entries, NestedEntry{Key: NestedTextKey, Value: node.value})
+	}
+
+	sortChildren(node, compareFn)
+	groups := make(map[string]int, len(node.children))
+	for _, child := range node.children {
+		nested := nestedElement(child, compareFn)
+		if !isIndexedPath(child.path) {
+			element.Entries = append(element.Entries, NestedEntry{Key: child.name, Elements: []*NestedElement{nested}})
+			continue
+		}
+		if i, ok := groups[child.name]; ok {
+			element.Entries[i].Elements = append(element.Entries[i].Elements, nested)
+			continue
+		}
+		groups[child.name] = len(element.Entries)
+		element.Entries = append(element.Entries, NestedEntry{Key: child.name, Elements: []*NestedElement{nested}, Repeated: true})
+	}
+	return element
+}
+
+// isIndexedPath reports whether the last segment of an element path carries an index
+func isIndexedPath(path string) bool {
+	return strings.HasSuffix(path[strings.LastIndex(path, "/")+1:], "]")
+}
+
+// FromNested converts generic nested maps in the format of ToNested into an XMLMap.
+// Keys starting with "@" are attributes and NestedTextKey is the value of the element.
+// Besides strings, scalar values such as numbers and booleans decoded from JSON are
+// accepted and formatted; nil is an empty value. A list with a single item produces
+// the same path as a plain element, as ParseToMap does.
+func FromNested(nested map[string]interface{}) (XMLMap, error) {
+	if len(nested) != 1 {
+		return nil, fmt.Errorf("nested map must have a single root element, got %d keys", len(nested))
+	}
+
+	result := make(XMLMap)
+	for name, value := range nested {
+		if err := fromNestedElement(result, "/"+name, value); err != nil {
+			return nil, err
+		}
+	}
+	if len(result) == 0 {
+		return nil, errors.New("nested map contains no values")
+	}
+	return result, nil
+}
+
+// fromNestedElement stores the element at path described by value and its subtree
+func fromNestedElement(result XMLMap, path string, value interface{}) error {
+	element, ok := value.(map[string]interface{})
+	if !ok {
+		text, err := nestedScalar(path, value)
+		if err != nil {
+			return err
+		}
+		result[path] = text
+		return nil
+	}
+
+	for key, child := range element {
+		switch {
+		case key == NestedTextKey || strings.HasPrefix(key, "@"):
+			childPath := path
+			if key != NestedTextKey {
+				childPath = path + "/" + key
+			}
+			text, err := nestedScalar(childPath, child)
+			if err != nil {
+				return err
+			}
+			result[childPath] = text
+		default:
+			list, ok := child.([]interface{})
+			if !ok {
+				if err := fromNestedElement(result, path+"/"+key, child); err != nil {
+					return err
+				}
+				continue
+			}
+			for i, item := range list {
+				childPath := path + "/" + key
+				if len(list) > 1 {
+					childPath += "[" + strconv.Itoa(i+1) + "]"
+				}
+				if err := fromNestedElement(result, childPath, item); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// nestedScalar formats a scalar value of a nested map
+func nestedScalar(path string, value interface{}) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case float64:
+		// Numbers decoded from JSON
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	case bool, int, int64, int32, uint, uint64, uint32, float32:
+		return fmt.Sprint(value), nil
+	default:
+		return "", fmt.Errorf("unexpected %T at %s", value, path)
+	}
+}
@@ -0,0 +1,124 @@
+package xmlsurf
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToNested(t *testing.T) {
+	m := XMLMap{
+		"/order/@id":             "42",
+		"/order/customer":        "Ann",
+		"/order/items/item[1]":   "pen",
+		"/order/items/item[2]":   "ink",
+		"/order/note":            "fragile",
+		"/order/note/@lang":      "en",
+		"/order/ship/address/to": "Oslo",
+	}
+
+	got, err := m.ToNested()
+	if err != nil {
+		t.Fatalf("ToNested() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"order": map[string]interface{}{
+			"@id":      "42",
+			"customer": "Ann",
+			"items": map[string]interface{}{
+				"item": []interface{}{"pen", "ink"},
+			},
+			"note": map[string]interface{}{
+				"@lang": "en",
+				"#text": "fragile",
+			},
+			"ship": map[string]interface{}{
+				"address": map[string]interface{}{"to": "Oslo"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ToNested() = %v, want %v", got, want)
+	}
+
+	back, err := FromNested(got)
+	if err != nil {
+		t.Fatalf("FromNested() error = %v", err)
+	}
+	if !reflect.DeepEqual(back, m) {
+		t.Errorf("FromNested(ToNested()) = %v, want %v", back, m)
+	}
+
+	if _, err := (XMLMap{}).ToNested(); err == nil {
+		t.Error("ToNested() of an empty map expected an error")
+	}
+}
+
+func TestNestedTree(t *testing.T) {
+	m := XMLMap{
+		"/a/@id":  "1",
+		"/a/z":    "last",
+		"/a/b[1]": "x",
+		"/a/b[2]": "y",
+		"/a/c":    "c",
+	}
+
+	got, err := m.NestedTree()
+	if err != nil {
+		t.Fatalf("NestedTree() error = %v", err)
+	}
+	want := &NestedElement{Name: "a", Entries: []NestedEntry{
+		{Key: "@id", Value: "1"},
+		{Key: "b", Elements: []*NestedElement{{Name: "b", Value: "x"}, {Name: "b", Value: "y"}}, Repeated: true},
+		{Key: "c", Elements: []*NestedElement{{Name: "c", Value: "c"}}},
+		{Key: "z", Elements: []*NestedElement{{Name: "z", Value: "last"}}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NestedTree() = %+v, want %+v", got, want)
+	}
+}
+
+func TestFromNestedJSON(t *testing.T) {
+	data := `{"root": {"@n": 1, "count": 3, "ok": true, "none": null, "list": {"v": ["a"]}}}`
+
+	var nested map[string]interface{}
+	if err := json.NewDecoder(strings.NewReader(data)).Decode(&nested); err != nil {
+		t.Fatal(err)
+	}
+	got, err := FromNested(nested)
+	if err != nil {
+		t.Fatalf("FromNested() error = %v", err)
+	}
+	want := XMLMap{
+		"/root/@n":     "1",
+		"/root/count":  "3",
+		"/root/ok":     "true",
+		"/root/none":   "",
+		"/root/list/v": "a",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FromNested() = %v, want %v", got, want)
+	}
+}
+
+func TestFromNestedErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		nested map[string]interface{}
+	}{
+		{"no root", map[string]interface{}{}},
+		{"several roots", map[string]interface{}{"a": "1", "b": "2"}},
+		{"attribute not a scalar", map[string]interface{}{"a": map[string]interface{}{"@id": []interface{}{"x"}}}},
+		{"unsupported value", map[string]interface{}{"a": struct{}{}}},
+		{"no values", map[string]interface{}{"a": map[string]interface{}{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := FromNested(tt.nested); err == nil {
+				t.Error("FromNested() expected an error")
+			}
+		})
+	}
+}
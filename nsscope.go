@@ -0,0 +1,91 @@
+package xmlsurf
+
+import "encoding/xml"
+
+// xmlNamespaceURI is the namespace bound to the reserved "xml" prefix
+const xmlNamespaceURI = "http://www.w3.org/XML/1998/namespace"
+
+// namespaceScopes holds the namespace declarations in scope while parsing. The
+// declarations of an element are pushed with its start tag and popped with its end
+// tag, so a prefix rebound in a nested element resolves to the outer URI again after it.
+type namespaceScopes struct {
+	bindings []namespaceBinding // Declarations in scope, innermost last
+	marks    []int              // Number of bindings before each open element
+}
+
+// namespaceBinding is a namespace declaration; an empty prefix declares the default namespace
+type namespaceBinding struct {
+	prefix string
+	uri    string
+}
+
+// push opens the scope of an element, declaring the namespaces among its attributes
+func (s *namespaceScopes) push(attrs []xml.Attr) {
+	s.marks = append(s.marks, len(s.bindings))
+	for _, attr := range attrs {
+		switch {
+		case attr.Name.Space == "xmlns":
+			s.declare(attr.Name.Local, attr.Value)
+		case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			s.declare("", attr.Value)
+		}
+	}
+}
+
+// pop closes the scope of the innermost open element
+func (s *namespaceScopes) pop() {
+	if len(s.marks) == 0 {
+		return
+	}
+	s.bindings = s.bindings[:s.marks[len(s.marks)-1]]
+	s.marks = s.marks[:len(s.marks)-1]
+}
+
+// declare binds prefix to uri in the current scope
+func (s *namespaceScopes) declare(prefix, uri string) {
+	s.bindings = append(s.bindings, namespaceBinding{prefix: prefix, uri: uri})
+}
+
+// uri returns the URI prefix is bound to
+func (s *namespaceScopes) uri(prefix string) (string, bool) {
+	for i := len(s.bindings) - 1; i >= 0; i-- {
+		if s.bindings[i].prefix == prefix {
+			return s.bindings[i].uri, true
+		}
+	}
+	return "", false
+}
+
+// prefix returns the innermost prefix bound to uri that is not shadowed by a later
+// declaration of the same prefix. The default namespace is only considered when
+// allowDefault is set, as attributes cannot be in it.
+func (s *namespaceScopes) prefix(uri string, allowDefault bool) (string, bool) {
+	for i := len(s.bindings) - 1; i >= 0; i-- {
+		binding := s.bindings[i]
+		if binding.uri != uri || (binding.prefix == "" && !allowDefault) {
+			continue
+		}
+		if current, _ := s.uri(binding.prefix); current == uri {
+			return binding.prefix, true
+		}
+	}
+	if uri == xmlNamespaceURI {
+		return "xml", true
+	}
+	return "", false
+}
+
+// inScope returns the declarations in scope as prefixes mapped to URIs
+func (s *namespaceScopes) inScope() map[string]string {
+	namespaces := make(map[string]string, len(s.bindings))
+	for _, binding := range s.bindings {
+		namespaces[binding.prefix] = binding.uri
+	}
+	return namespaces
+}
+
+// reset removes all declarations
+func (s *namespaceScopes) reset() {
+	s.bindings = s.bindings[:0]
+	s.marks = s.marks[:0]
+}
@@ -0,0 +1,95 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNamespaceScopes(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  []Option
+		want  XMLMap
+	}{
+		{
+			name: "prefix rebound in a nested element",
+			input: `<a:root xmlns:a="urn:one">` +
+				`<a:inner xmlns:a="urn:two"><a:x>1</a:x></a:inner>` +
+				`<a:after>2</a:after>` +
+				`</a:root>`,
+			want: XMLMap{
+				"/a:root/a:inner/a:x": "1",
+				"/a:root/a:after":     "2",
+			},
+		},
+		{
+			name: "outer URI under another prefix while shadowed",
+			input: `<a:root xmlns:a="urn:one" xmlns:b="urn:one">` +
+				`<inner xmlns:a="urn:two"><b:x>1</b:x><a:y>2</a:y></inner>` +
+				`</a:root>`,
+			// Both prefixes are bound to urn:one on the root; the later declaration wins
+			want: XMLMap{
+				"/b:root/inner/b:x": "1",
+				"/b:root/inner/a:y": "2",
+			},
+		},
+		{
+			name: "declaration leaves scope with its element",
+			input: `<root xmlns:a="urn:one">` +
+				`<x xmlns:b="urn:two"><b:v>1</b:v></x>` +
+				`<y xmlns:c="urn:two"><c:v>2</c:v></y>` +
+				`</root>`,
+			want: XMLMap{
+				"/root/x/b:v": "1",
+				"/root/y/c:v": "2",
+			},
+		},
+		{
+			name: "default namespace redefined",
+			input: `<root xmlns="urn:one" xmlns:p="urn:two">` +
+				`<inner xmlns="urn:two"><v>1</v></inner><w>2</w>` +
+				`</root>`,
+			want: XMLMap{
+				"/root/inner/v": "1",
+				"/root/w":       "2",
+			},
+		},
+		{
+			name:  "innermost prefix wins",
+			input: `<p:root xmlns:p="urn:one"><q:x xmlns:q="urn:one">1</q:x></p:root>`,
+			want:  XMLMap{"/p:root/q:x": "1"},
+		},
+		{
+			name:  "attributes use prefixes in scope",
+			input: `<root xmlns:a="urn:one"><x xmlns:a="urn:two" a:id="1"/><y a:id="2"/></root>`,
+			want: XMLMap{
+				"/root/x/@a:id": "1",
+				"/root/y/@a:id": "2",
+			},
+		},
+		{
+			name:  "attributes are never in the default namespace",
+			input: `<root xmlns="urn:one" xmlns:a="urn:one" a:id="1"/>`,
+			want:  XMLMap{"/a:root/@a:id": "1"},
+		},
+		{
+			name:  "xml prefix",
+			input: `<root xml:lang="en">1</root>`,
+			want:  XMLMap{"/root": "1", "/root/@xml:lang": "en"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseToMap(strings.NewReader(tt.input), tt.opts...)
+			if err != nil {
+				t.Fatalf("ParseToMap() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseToMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package xmlsurf
 
+import "strings"
+
 // Option is a function that configures ParseOptions
 type Option func(*ParseOptions)
 
@@ -7,8 +9,59 @@ type Option func(*ParseOptions)
 type ParseOptions struct {
 	// IncludeNamespaces controls whether namespace prefixes should be included in element and attribute names
 	IncludeNamespaces bool
+	// DefaultNamespacePrefix is the prefix given to elements in a default namespace (xmlns="...")
+	// when namespaces are included; empty leaves them unprefixed
+	DefaultNamespacePrefix string
 	// ValueTransform is a function that transforms each value during parsing
 	ValueTransform func(string) string
+	// TrimValues controls whether leading and trailing whitespace is trimmed from element text
+	TrimValues bool
+	// EmptyElements controls whether elements without text and child elements are stored with an empty value
+	EmptyElements bool
+	// UnwrapNested controls whether values holding embedded XML documents are parsed into nested paths
+	UnwrapNested bool
+	// SizeHint is the expected number of elements, used to pre-allocate parser structures
+	SizeHint int
+	// Progress is called periodically during parsing, see WithProgress
+	Progress func(bytesRead int64, elements int)
+	// OverwritePolicy decides what happens when several values map to the same path
+	OverwritePolicy OverwritePolicy
+	// CollectValues receives the values of every path holding several values, see WithCollectedValues
+	CollectValues func(path string, values []string)
+	// schemaTypes receives the xsi:type of every element that has one, resolved through
+	// the namespace declarations in scope; it is only set by ParseToTypedMap
+	schemaTypes map[string]string
+	// Metrics receives statistics about every parsed document, see WithMetrics
+	Metrics ParseMetrics
+	// TraceHook receives structured events while parsing, see WithTraceHook
+	TraceHook func(event TraceEvent)
+	// IndexStyle selects how indices of repeated elements are written in paths
+	IndexStyle IndexStyle
+	// IndexBase is the index of the first of several repeated elements, 1 by default
+	IndexBase int
+	// AutoDecompress controls whether gzip and zlib compressed input is detected and decompressed
+	AutoDecompress bool
+	// BinaryPaths holds the index-free paths of elements whose base64 content is
+	// written to BinarySink instead of the map, see WithBinaryPaths
+	BinaryPaths map[string]bool
+	// BinarySink returns the writers for the elements selected by BinaryPaths
+	BinarySink BinarySink
+	// SpillLimit is the length in bytes above which element text is written to a
+	// temporary file in SpillDir, see WithSpillover; 0 keeps all values in the map
+	SpillLimit int
+	// SpillDir is the directory of the spilled values, the default temporary directory if empty
+	SpillDir string
+	// FragmentRoot is the path the top-level elements of a fragment are placed under,
+	// see WithAllowFragment; empty requires a single root element
+	FragmentRoot string
+	// RedactPatterns are the paths whose values are replaced with RedactedValue, see WithRedactPatterns
+	RedactPatterns []string
+	// StopAfter holds the index-free paths after which parsing stops once all were read, see WithStopAfter
+	StopAfter map[string]bool
+	// StopAfterElements is the number of complete elements after which parsing stops; 0 reads the whole input
+	StopAfterElements int
+	// SkipSubtrees are the patterns of elements whose subtrees are skipped unparsed, see WithSkipSubtrees
+	SkipSubtrees []string
 }
 
 // WithNamespaces returns an Option that enables namespace prefix inclusion
@@ -18,6 +71,15 @@ func WithNamespaces(include bool) Option {
 	}
 }
 
+// WithDefaultNamespacePrefix returns an Option that gives elements in a default namespace
+// the synthetic prefix, e.g. /ns:root/ns:child for <root xmlns="urn:x"><child/></root>,
+// so they can be told apart from elements in no namespace
+func WithDefaultNamespacePrefix(prefix string) Option {
+	return func(o *ParseOptions) {
+		o.DefaultNamespacePrefix = prefix
+	}
+}
+
 // WithValueTransform returns an Option that sets a function to transform values during parsing
 func WithValueTransform(transform func(string) string) Option {
 	return func(o *ParseOptions) {
@@ -33,10 +95,525 @@ func WithValueTransform(transform func(string) string) Option {
 	}
 }
 
+// WithTrimValues returns an Option that controls whitespace trimming of element text.
+// Trimming is enabled by default. When disabled, element text is stored and passed to
+// ValueTransform as it appears in the document; whitespace-only text is still skipped.
+func WithTrimValues(trim bool) Option {
+	return func(o *ParseOptions) {
+		o.TrimValues = trim
+	}
+}
+
+// WithEmptyElements returns an Option that stores elements without text and child
+// elements, such as <flag/> or <note></note>, with an empty value, so they can be
+// asserted and are recreated by ToXML
+func WithEmptyElements(include bool) Option {
+	return func(o *ParseOptions) {
+		o.EmptyElements = include
+	}
+}
+
+// WithUnwrapNested returns an Option that parses values holding embedded XML documents
+// into nested paths separated by NestedSeparator (see XMLMap.UnwrapNested)
+func WithUnwrapNested(unwrap bool) Option {
+	return func(o *ParseOptions) {
+		o.UnwrapNested = unwrap
+	}
+}
+
+// WithSizeHint returns an Option that pre-allocates parser structures and the result
+// map for about n elements, avoiding growth when parsing large documents
+func WithSizeHint(n int) Option {
+	return func(o *ParseOptions) {
+		o.SizeHint = n
+	}
+}
+
+// WithProgress returns an Option that calls report periodically during parsing with the
+// number of bytes read and elements parsed so far: every 1024 elements, after every MiB
+// of input and once when parsing completes. report is called on the parsing goroutine.
+func WithProgress(report func(bytesRead int64, elements int)) Option {
+	return func(o *ParseOptions) {
+		o.Progress = report
+	}
+}
+
+// WithOverwritePolicy returns an Option that decides what happens when several values
+// of the document map to the same path, e.g. text split by a comment or child element,
+// or attributes in different namespaces when namespaces are not included
+func WithOverwritePolicy(policy OverwritePolicy) Option {
+	return func(o *ParseOptions) {
+		o.OverwritePolicy = policy
+	}
+}
+
+// WithCollectedValues returns an Option that sets the OverwriteCollect policy and passes the
+// values of every path holding several values, in document order, to collect. The map
+// keeps the last value, so collected values never mix with the paths of real elements.
+// The temporary files of values spilled by WithSpillover that the map does not keep
+// are removed once collect returns.
+func WithCollectedValues(collect func(path string, values []string)) Option {
+	return func(o *ParseOptions) {
+		o.OverwritePolicy = OverwriteCollect
+		o.CollectValues = collect
+	}
+}
+
+// WithIndexStyle returns an Option that writes the indices of repeated elements in the
+// style, e.g. /root/items/item.1 with IndexDot. The other methods of XMLMap expect
+// bracket indices, so convert the map with BracketIndices before comparing or writing it.
+func WithIndexStyle(style IndexStyle) Option {
+	return func(o *ParseOptions) {
+		o.IndexStyle = style
+	}
+}
+
+// WithIndexBase returns an Option that numbers repeated elements from base, e.g.
+// /root/items/item[0] with 0, so paths line up with the array indices of JSON or
+// JavaScript tooling. The other methods of XMLMap expect 1-based indices, so convert
+// the map with RebaseIndices(base, 1) before comparing or writing it.
+func WithIndexBase(base int) Option {
+	return func(o *ParseOptions) {
+		o.IndexBase = base
+	}
+}
+
+// WithMetrics returns an Option that reports the size, duration and outcome of every
+// parsed document to metrics. Give it to a Parser or keep the options in a shared slice,
+// so every parse of a service is observed without wrapping the calls.
+func WithMetrics(metrics ParseMetrics) Option {
+	return func(o *ParseOptions) {
+		o.Metrics = metrics
+	}
+}
+
+// WithTraceHook returns an Option that calls hook with a structured event for every
+// start and end tag, namespace declaration and failing token, for debugging documents
+// that parse unexpectedly. SlogTraceHook logs the events with log/slog.
+func WithTraceHook(hook func(event TraceEvent)) Option {
+	return func(o *ParseOptions) {
+		o.TraceHook = hook
+	}
+}
+
+// WithAllowFragment returns an Option that accepts input without a single root element,
+// such as <a>1</a><b>2</b> stored in a database column. The top-level elements are placed
+// under the synthetic root path root, e.g. /fragment/a and /fragment/b for "/fragment";
+// repeated top-level elements are indexed like any siblings. Text outside the elements
+// is ignored. An empty root requires a single root element again.
+func WithAllowFragment(root string) Option {
+	return func(o *ParseOptions) {
+		o.FragmentRoot = ""
+		if root = strings.Trim(root, "/"); root != "" {
+			o.FragmentRoot = "/" + root
+		}
+	}
+}
+
+// WithAutoDecompress returns an Option that detects gzip and zlib compressed input by its
+// magic bytes and decompresses it before parsing, so gzipped feeds need no wrapping reader.
+// Uncompressed input is parsed as before. Offsets and byte counts refer to the decompressed input.
+func WithAutoDecompress(decompress bool) Option {
+	return func(o *ParseOptions) {
+		o.AutoDecompress = decompress
+	}
+}
+
+// WithBinaryPaths returns an Option that decodes the base64 content of the elements at the
+// index-free paths, e.g. /upload/files/file/data, and writes it to the writer sink returns
+// for each of them, instead of storing the text in the map. The map keeps the elements with
+// an empty value. Only the text of one element is buffered at a time, so large documents
+// embedding many files can be parsed without holding all of them. A writer that is an
+// io.Closer is closed after the end tag of its element, or when parsing fails.
+func WithBinaryPaths(sink BinarySink, paths ...string) Option {
+	return func(o *ParseOptions) {
+		o.BinarySink = sink
+		o.BinaryPaths = make(map[string]bool, len(paths))
+		for _, path := range paths {
+			o.BinaryPaths[path] = true
+		}
+	}
+}
+
+// WithSpillover returns an Option that writes element text longer than limit bytes to a
+// temporary file in dir, or in os.TempDir if dir is empty, and stores SpillPrefix followed
+// by the file name in its place. Documents embedding huge payloads can then be parsed for
+// their metadata without holding the payloads in memory. ReadValue reads values back and
+// RemoveSpilled removes the files; the files of a document that fails to parse are removed.
+// Only files written by this process are read and removed, so document text starting
+// with SpillPrefix cannot reach other files.
+// Spilled text is trimmed with WithTrimValues, but not passed to a ValueTransform.
+func WithSpillover(limit int, dir string) Option {
+	return func(o *ParseOptions) {
+		o.SpillLimit = limit
+		o.SpillDir = dir
+	}
+}
+
+// WithRedactPatterns returns an Option that replaces the values at the path patterns, and
+// everything below them, with RedactedValue once the document is parsed, e.g. to log SOAP
+// messages without their security headers. See Redact for the pattern syntax.
+func WithRedactPatterns(patterns ...string) Option {
+	return func(o *ParseOptions) {
+		o.RedactPatterns = append(o.RedactPatterns, patterns...)
+	}
+}
+
+// WithStopAfter returns an Option that stops reading the input once the elements and
+// attributes at all the index-free paths were read, e.g. /Envelope/Header/MessageID to
+// extract a header field without parsing a large body. An element is read at its end tag;
+// the first element at a path satisfies it. The map holds what was read up to there: open
+// ancestors keep their text so far, and an element gets no index if its later siblings were
+// not read. The rest of the input is neither read nor checked for errors.
+func WithStopAfter(paths ...string) Option {
+	return func(o *ParseOptions) {
+		o.StopAfter = nil
+		if len(paths) > 0 {
+			o.StopAfter = make(map[string]bool, len(paths))
+			for _, path := range paths {
+				o.StopAfter[path] = true
+			}
+		}
+	}
+}
+
+// WithStopAfterElements returns an Option that stops reading the input once n elements
+// were read up to their end tag, like WithStopAfter. 0 reads the whole input.
+func WithStopAfterElements(n int) Option {
+	return func(o *ParseOptions) {
+		o.StopAfterElements = n
+	}
+}
+
+// WithSkipSubtrees returns an Option that skips the elements matching the patterns, with
+// everything inside them, without processing their tokens, e.g. to leave out signatures or
+// large payloads. Patterns are index-free paths that may contain the wildcards of
+// PatternDiffs, e.g. /Envelope/Body/*/Payload, or "//" followed by an element name to
+// match it at any depth, e.g. //ds:Signature. Skipped elements do not count as siblings,
+// so the indices of the remaining elements are as if the skipped ones were absent.
+func WithSkipSubtrees(patterns ...string) Option {
+	return func(o *ParseOptions) {
+		o.SkipSubtrees = append(o.SkipSubtrees, patterns...)
+	}
+}
+
 // DefaultParseOptions returns the default parsing options
 func DefaultParseOptions() *ParseOptions {
 	return &ParseOptions{
 		IncludeNamespaces: true,
 		ValueTransform:    nil, // No transformation by default
+		TrimValues:        true,
+		IndexBase:         1,
+	}
+}
+
+// innerOptions returns the options for parsing part of the document parsed with o, such as
+// an embedded document or the records of a Stream: the defaults with the options shaping
+// paths and values copied from o. Options observing the parse, selecting what is read and
+// post-processing the result apply to the whole document only.
+func (o *ParseOptions) innerOptions() *ParseOptions {
+	inner := DefaultParseOptions()
+	inner.IncludeNamespaces = o.IncludeNamespaces
+	inner.DefaultNamespacePrefix = o.DefaultNamespacePrefix
+	inner.ValueTransform = o.ValueTransform
+	inner.TrimValues = o.TrimValues
+	inner.EmptyElements = o.EmptyElements
+	inner.UnwrapNested = o.UnwrapNested
+	inner.OverwritePolicy = o.OverwritePolicy
+	inner.IndexStyle = o.IndexStyle
+	inner.IndexBase = o.IndexBase
+	return inner
+}
+
+// CompareOption is a function that configures CompareOptions
+type CompareOption func(*CompareOptions)
+
+// CompareOptions configures how XMLMaps are compared
+type CompareOptions struct {
+	// IgnorePaths lists paths that are excluded from comparison together with everything below them
+	IgnorePaths []string
+	// NestedDocuments controls whether embedded XML documents are unwrapped on both sides before comparison
+	NestedDocuments bool
+	// Semantics selects the version of the diff behavior
+	Semantics DiffSemantics
+	// MatchKeys pair the instances of repeated elements by key values before comparison
+	MatchKeys []MatchKey
+	// ListAlignment controls whether repeated elements are paired by aligning their lists instead of by index
+	ListAlignment bool
+	// StructuralOrder controls whether ignore-order comparisons pair repeated elements as whole subtrees
+	StructuralOrder bool
+}
+
+// DiffSemantics identifies a version of the comparison behavior.
+// Comparison results only change between versions, so test suites can pin a
+// version while migrating to newer behavior.
+type DiffSemantics int
+
+const (
+	// DiffSemanticsV1 compares repeated values in ignore-order comparisons as sets,
+	// so duplicates are not counted
+	DiffSemanticsV1 DiffSemantics = iota + 1
+	// DiffSemanticsV2 compares repeated values in ignore-order comparisons as multisets,
+	// so each value must occur the same number of times on both sides
+	DiffSemanticsV2
+
+	// DiffSemanticsLatest is the newest version, used with WithDiffSemantics to opt in
+	DiffSemanticsLatest = DiffSemanticsV2
+	// DiffSemanticsDefault is the version used by default. It stays at the original
+	// behavior so existing comparison results never change silently.
+	DiffSemanticsDefault = DiffSemanticsV1
+)
+
+// WithDiffSemantics returns a CompareOption that pins the comparison behavior to a version
+func WithDiffSemantics(version DiffSemantics) CompareOption {
+	return func(o *CompareOptions) {
+		o.Semantics = version
+	}
+}
+
+// WithIgnorePaths returns a CompareOption that excludes the given paths and their subtrees from comparison
+func WithIgnorePaths(paths ...string) CompareOption {
+	return func(o *CompareOptions) {
+		o.IgnorePaths = append(o.IgnorePaths, paths...)
+	}
+}
+
+// WithMatchKey returns a CompareOption that pairs the instances of the repeated element
+// at path (without indices, e.g. /order/items/item) by the value at key (relative to the
+// element, e.g. "@id" or "sku") instead of by position, and then compares each pair field
+// by field. Right instances without a partner are numbered after the left instances and
+// reported as missing. The option can be given for several elements, including nested ones.
+func WithMatchKey(path, key string) CompareOption {
+	return func(o *CompareOptions) {
+		o.MatchKeys = append(o.MatchKeys, MatchKey{Path: path, Key: key})
+	}
+}
+
+// WithListAlignment returns a CompareOption that pairs the instances of repeated elements
+// by aligning both lists on a longest common subsequence of identical subtrees, like a
+// line-based diff, instead of by index. An element inserted in the middle of a list is
+// then reported as missing instead of shifting every later instance into a value
+// mismatch. Unaligned instances between aligned ones are paired in order; inserted
+// instances of the right map are numbered after the instances of the left map.
+// Elements with a WithMatchKey are paired by key.
+func WithListAlignment(align bool) CompareOption {
+	return func(o *CompareOptions) {
+		o.ListAlignment = align
+	}
+}
+
+// WithStructuralOrder returns a CompareOption that makes ignore-order comparisons pair
+// the instances of repeated elements as whole subtrees instead of pooling values per
+// path, so values swapped between fields of different instances are reported.
+// Identical subtrees are paired first, the remaining instances in document order;
+// the pairs are then compared path by path like Diffs.
+func WithStructuralOrder(structural bool) CompareOption {
+	return func(o *CompareOptions) {
+		o.StructuralOrder = structural
+	}
+}
+
+// WithNestedDocuments returns a CompareOption that unwraps embedded XML documents
+// on both sides (see XMLMap.UnwrapNested), so differences inside them are reported
+// with nested paths instead of as a single differing value
+func WithNestedDocuments(unwrap bool) CompareOption {
+	return func(o *CompareOptions) {
+		o.NestedDocuments = unwrap
+	}
+}
+
+// DefaultCompareOptions returns the default comparison options
+func DefaultCompareOptions() *CompareOptions {
+	return &CompareOptions{
+		Semantics: DiffSemanticsDefault,
+	}
+}
+
+// newCompareOptions evaluates comparison options on top of the defaults
+func newCompareOptions(opts []CompareOption) *CompareOptions {
+	options := DefaultCompareOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// isIgnored reports whether the path is excluded by IgnorePaths
+func (o *CompareOptions) isIgnored(path string) bool {
+	for _, ignored := range o.IgnorePaths {
+		if isPathOrBelow(path, ignored) {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteOption is a function that configures WriteOptions
+type WriteOption func(*WriteOptions)
+
+// WriteOptions configures how XML is written
+type WriteOptions struct {
+	// Prefix is written at the beginning of each indented line
+	Prefix string
+	// Indent is written once per nesting level; no indentation is done if Prefix and Indent are empty
+	Indent string
+	// Declaration controls whether an <?xml?> declaration is written
+	Declaration bool
+	// Encoding is the encoding named in the declaration
+	Encoding string
+	// Newline is the line break used after the declaration and between indented lines
+	Newline string
+	// SelfClosing controls whether elements without value and children are written as <name/>
+	SelfClosing bool
+	// ElementOrder orders sibling elements by their full paths; it overrides the other order settings
+	ElementOrder func(a, b string) bool
+	// DocumentOrder lists paths in the order their elements should be written
+	DocumentOrder []string
+	// Sequences declares the order of child element names per parent path
+	Sequences map[string][]string
+	// SpecialElements ranks siblings named like SOAP Header and Body as ToXML always has,
+	// see WithSpecialElementOrder; enabled by default
+	SpecialElements bool
+	// RawValues writes element values verbatim, without escaping
+	RawValues bool
+	// ControlChars decides how characters not allowed in XML 1.0 are written
+	ControlChars ControlCharPolicy
+	// Namespaces maps prefixes to namespace URIs to declare on the root element;
+	// only the prefixes used in the output are declared
+	Namespaces map[string]string
+	// AllowedPaths are the patterns every written path must match, see WithAllowedPaths
+	AllowedPaths []string
+	// UnknownPath is called for each path matching no AllowedPaths pattern, see WithUnknownPathHandler
+	UnknownPath func(path string) error
+}
+
+// WithIndent returns a WriteOption that indents the output like xml.Encoder.Indent
+func WithIndent(prefix, indent string) WriteOption {
+	return func(o *WriteOptions) {
+		o.Prefix = prefix
+		o.Indent = indent
+	}
+}
+
+// WithDeclaration returns a WriteOption that writes an <?xml?> declaration naming the encoding.
+// The output is always UTF-8, so writing fails for any other encoding rather than
+// mislabeling the document; "UTF-8" is matched case-insensitively, as is "UTF8".
+func WithDeclaration(encoding string) WriteOption {
+	return func(o *WriteOptions) {
+		o.Declaration = true
+		o.Encoding = encoding
+	}
+}
+
+// WithNewline returns a WriteOption that sets the line break, e.g. "\r\n"
+func WithNewline(newline string) WriteOption {
+	return func(o *WriteOptions) {
+		o.Newline = newline
+	}
+}
+
+// WithSelfClosing returns a WriteOption that writes elements without value and
+// children as <name/> instead of <name></name>
+func WithSelfClosing(selfClosing bool) WriteOption {
+	return func(o *WriteOptions) {
+		o.SelfClosing = selfClosing
+	}
+}
+
+// WithElementOrder returns a WriteOption that orders sibling elements with less,
+// which receives the full paths of two elements sharing a parent
+func WithElementOrder(less func(a, b string) bool) WriteOption {
+	return func(o *WriteOptions) {
+		o.ElementOrder = less
+	}
+}
+
+// WithSpecialElementOrder returns a WriteOption that controls the ranking of special
+// sibling names kept from the first versions of ToXML: an element whose name contains
+// Header, Username or child is written before one whose name contains Body, Token or
+// another. It is enabled by default; disabled, siblings are ordered by name and index only.
+// Declared sequences and document order take precedence over it.
+func WithSpecialElementOrder(enabled bool) WriteOption {
+	return func(o *WriteOptions) {
+		o.SpecialElements = enabled
+	}
+}
+
+// WithDocumentOrder returns a WriteOption that writes elements in the order of the
+// given paths, typically captured with ParseToMapOrdered. Elements not covered by
+// the paths follow in default order.
+func WithDocumentOrder(paths []string) WriteOption {
+	return func(o *WriteOptions) {
+		o.DocumentOrder = paths
+	}
+}
+
+// WithSequence returns a WriteOption that declares the order of the child elements
+// of a parent path, given as element names without indices. A parent path without
+// indices applies to every instance of a repeated parent. Children not named in the
+// sequence follow in default order.
+func WithSequence(parentPath string, names ...string) WriteOption {
+	return func(o *WriteOptions) {
+		if o.Sequences == nil {
+			o.Sequences = make(map[string][]string)
+		}
+		o.Sequences[parentPath] = names
+	}
+}
+
+// WithRawValues returns a WriteOption that writes element values verbatim, for values
+// holding content that is already escaped or markup that must be kept as is.
+// The caller is responsible for the output being well-formed. Attribute values are always escaped.
+func WithRawValues(raw bool) WriteOption {
+	return func(o *WriteOptions) {
+		o.RawValues = raw
+	}
+}
+
+// WithControlChars returns a WriteOption that decides how characters not allowed in
+// XML 1.0, such as most control characters, are written in values and attribute values
+func WithControlChars(policy ControlCharPolicy) WriteOption {
+	return func(o *WriteOptions) {
+		o.ControlChars = policy
+	}
+}
+
+// WithNamespaceDeclarations returns a WriteOption that declares the namespaces used
+// by the written element and attribute names on the root element, so a subtree whose
+// declarations lived on an ancestor is written as a well-formed document.
+// namespaces maps prefixes to URIs; the empty prefix is the default namespace, declared
+// when an element without a prefix is written. Writing fails if a used prefix is missing.
+func WithNamespaceDeclarations(namespaces map[string]string) WriteOption {
+	return func(o *WriteOptions) {
+		o.Namespaces = namespaces
+	}
+}
+
+// WithAllowedPaths returns a WriteOption that only accepts paths matching one of the
+// patterns, or lying below a path that does, to catch typos in hand-written maps before
+// they turn into silently wrong XML. Patterns may contain the wildcards of PatternDiffs,
+// e.g. /order/item[*]/@*. Writing fails with an error wrapping ErrUnknownPath that
+// names every other path, unless WithUnknownPathHandler decides otherwise.
+func WithAllowedPaths(patterns ...string) WriteOption {
+	return func(o *WriteOptions) {
+		o.AllowedPaths = append(o.AllowedPaths, patterns...)
+	}
+}
+
+// WithUnknownPathHandler returns a WriteOption that calls handler, in path order, for
+// each path rejected by WithAllowedPaths instead of failing. Returning nil writes the
+// path anyway, e.g. after logging a warning; an error stops writing and is returned.
+func WithUnknownPathHandler(handler func(path string) error) WriteOption {
+	return func(o *WriteOptions) {
+		o.UnknownPath = handler
+	}
+}
+
+// DefaultWriteOptions returns the default write options
+func DefaultWriteOptions() *WriteOptions {
+	return &WriteOptions{
+		Encoding:        "UTF-8",
+		Newline:         "\n",
+		SpecialElements: true,
 	}
 }
@@ -0,0 +1,127 @@
+package xmlsurf
+
+import (
+	"strings"
+)
+
+// elementOrder returns the function ordering sibling elements for the write options.
+// With the special element order disabled and no other settings it returns nil: elements
+// are ordered by name and index (see siblingLess), which needs no full path comparisons.
+func (o *WriteOptions) elementOrder() func(a, b string) bool {
+	if o.ElementOrder != nil {
+		return o.ElementOrder
+	}
+	less := comparePaths
+	if o.SpecialElements {
+		less = specialElementOrder
+	} else if len(o.Sequences) == 0 && len(o.DocumentOrder) == 0 {
+		return nil
+	}
+
+	if len(o.Sequences) > 0 {
+		less = sequenceOrder(o.Sequences, less)
+	}
+	if len(o.DocumentOrder) > 0 {
+		less = documentOrder(o.DocumentOrder, less)
+	}
+	return less
+}
+
+// specialRanks are the sibling names ranked by WithSpecialElementOrder, matched as
+// substrings of element names in this order
+var specialRanks = []struct {
+	name string
+	rank int
+}{{"Header", 1}, {"Body", 2}, {"Username", 1}, {"Token", 2}, {"child", 1}, {"another", 2}}
+
+// specialRank returns the rank of the first special name the element name contains, or 0
+func specialRank(name string) int {
+	for _, special := range specialRanks {
+		if strings.Contains(name, special.name) {
+			return special.rank
+		}
+	}
+	return 0
+}
+
+// specialElementOrder orders sibling elements by the rank of their names when both
+// have one, and by name and index otherwise
+func specialElementOrder(a, b string) bool {
+	segmentA, segmentB := lastSegment(a), lastSegment(b)
+	nameA, _ := splitIndex(segmentA)
+	nameB, _ := splitIndex(segmentB)
+	if nameA != nameB {
+		rankA, rankB := specialRank(nameA), specialRank(nameB)
+		if rankA > 0 && rankB > 0 && rankA != rankB {
+			return rankA < rankB
+		}
+	}
+	return compareSegments(segmentA, segmentB)
+}
+
+// documentOrder orders elements by the first position of any path at or below them
+func documentOrder(paths []string, fallback func(a, b string) bool) func(a, b string) bool {
+	ranks := make(map[string]int, len(paths))
+	for i, path := range paths {
+		// Rank the element and all its ancestors
+		for end := len(path); end > 0; end = strings.LastIndex(path[:end], "/") {
+			element := path[:end]
+			if _, ok := ranks[element]; ok {
+				// Ancestors were ranked together with the element
+				break
+			}
+			if !isAttributePath(element) {
+				ranks[element] = i
+			}
+		}
+	}
+
+	return func(a, b string) bool {
+		rankA, okA := ranks[a]
+		rankB, okB := ranks[b]
+		switch {
+		case okA && okB && rankA != rankB:
+			return rankA < rankB
+		case okA != okB:
+			return okA
+		default:
+			return fallback(a, b)
+		}
+	}
+}
+
+// sequenceOrder orders elements by their position in the sequence declared for their parent
+func sequenceOrder(sequences map[string][]string, fallback func(a, b string) bool) func(a, b string) bool {
+	positions := make(map[string]map[string]int, len(sequences))
+	for parent, names := range sequences {
+		positions[parent] = make(map[string]int, len(names))
+		for i, name := range names {
+			positions[parent][name] = i
+		}
+	}
+
+	pathBuilder := new(strings.Builder)
+	return func(a, b string) bool {
+		parent := a[:strings.LastIndex(a, "/")]
+		sequence, ok := positions[parent]
+		if !ok {
+			sequence, ok = positions[extractBasePath(parent, pathBuilder)]
+		}
+		if !ok {
+			return fallback(a, b)
+		}
+
+		nameA, _ := splitIndex(a[strings.LastIndex(a, "/")+1:])
+		nameB, _ := splitIndex(b[strings.LastIndex(b, "/")+1:])
+		posA, okA := sequence[nameA]
+		posB, okB := sequence[nameB]
+		switch {
+		case okA && okB && posA != posB:
+			return posA < posB
+		case okA != okB:
+			return okA
+		default:
+			return fallback(a, b)
+		}
+	}
+}
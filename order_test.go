@@ -0,0 +1,119 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestXMLMapWriteXMLElementOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    XMLMap
+		opts     []WriteOption
+		expected string
+	}{
+		{
+			name: "default order by name and numeric index",
+			input: XMLMap{
+				"/root/item[10]": "j",
+				"/root/item[2]":  "b",
+				"/root/b":        "x",
+				"/root/a":        "y",
+			},
+			expected: "<root><a>y</a><b>x</b><item>b</item><item>j</item></root>",
+		},
+		{
+			name: "default order keeps SOAP header before body",
+			input: XMLMap{
+				"/soap:Envelope/soap:Body/op":     "1",
+				"/soap:Envelope/soap:Header/auth": "2",
+			},
+			expected: "<soap:Envelope><soap:Header><auth>2</auth></soap:Header><soap:Body><op>1</op></soap:Body></soap:Envelope>",
+		},
+		{
+			name: "special element order disabled",
+			input: XMLMap{
+				"/root/child":   "1",
+				"/root/another": "2",
+				"/root/Token":   "3",
+				"/root/User":    "4",
+			},
+			opts:     []WriteOption{WithSpecialElementOrder(false)},
+			expected: "<root><Token>3</Token><User>4</User><another>2</another><child>1</child></root>",
+		},
+		{
+			name: "sequence overrides special element order",
+			input: XMLMap{
+				"/soap:Envelope/soap:Body/op":     "1",
+				"/soap:Envelope/soap:Header/auth": "2",
+			},
+			opts:     []WriteOption{WithSequence("/soap:Envelope", "soap:Body", "soap:Header")},
+			expected: "<soap:Envelope><soap:Body><op>1</op></soap:Body><soap:Header><auth>2</auth></soap:Header></soap:Envelope>",
+		},
+		{
+			name: "custom element order",
+			input: XMLMap{
+				"/root/a": "1",
+				"/root/b": "2",
+				"/root/c": "3",
+			},
+			opts: []WriteOption{WithElementOrder(func(a, b string) bool {
+				return a > b
+			})},
+			expected: "<root><c>3</c><b>2</b><a>1</a></root>",
+		},
+		{
+			name: "declared sequences",
+			input: XMLMap{
+				"/root/child":                 "child value",
+				"/root/another/nested":        "nested value",
+				"/root/list[1]/b":             "1b",
+				"/root/list[1]/a":             "1a",
+				"/root/list[2]/b":             "2b",
+				"/root/list[2]/a":             "2a",
+				"/root/list[2]/not-sequenced": "x",
+			},
+			opts: []WriteOption{
+				WithSequence("/root", "child", "another"),
+				WithSequence("/root/list", "b", "a"),
+			},
+			expected: "<root><child>child value</child><another><nested>nested value</nested></another>" +
+				"<list><b>1b</b><a>1a</a></list><list><b>2b</b><a>2a</a><not-sequenced>x</not-sequenced></list></root>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var builder strings.Builder
+			if err := tt.input.WriteXML(&builder, tt.opts...); err != nil {
+				t.Fatalf("WriteXML() error = %v", err)
+			}
+			if builder.String() != tt.expected {
+				t.Errorf("WriteXML() = %v, want %v", builder.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseToMapOrdered(t *testing.T) {
+	xml := `<root><zeta a="1">z</zeta><items><item>2</item><item>1</item></items><alpha>a</alpha></root>`
+
+	result, order, err := ParseToMapOrdered(strings.NewReader(xml))
+	if err != nil {
+		t.Fatalf("ParseToMapOrdered() error = %v", err)
+	}
+
+	expectedOrder := []string{"/root/zeta", "/root/zeta/@a", "/root/items/item[1]", "/root/items/item[2]", "/root/alpha"}
+	if !reflect.DeepEqual(order, expectedOrder) {
+		t.Errorf("ParseToMapOrdered() order = %v, want %v", order, expectedOrder)
+	}
+
+	var builder strings.Builder
+	if err := result.WriteXML(&builder, WithDocumentOrder(order)); err != nil {
+		t.Fatalf("WriteXML() error = %v", err)
+	}
+	if builder.String() != xml {
+		t.Errorf("WriteXML() with document order = %v, want %v", builder.String(), xml)
+	}
+}
@@ -0,0 +1,86 @@
+package xmlsurf
+
+import (
+	"fmt"
+)
+
+// OverwritePolicy decides what happens when several values of a document map to the same path
+type OverwritePolicy int
+
+const (
+	// OverwriteKeepLast stores the last value; this is the default
+	OverwriteKeepLast OverwritePolicy = iota
+	// OverwriteKeepFirst stores the first value
+	OverwriteKeepFirst
+	// OverwriteError fails parsing with an error wrapping ErrDuplicatePath
+	OverwriteError
+	// OverwriteCollect stores the last value and passes every value to the function
+	// given to WithCollectedValues. The values are kept apart from the map, as an
+	// index appended to the path could not be told apart from repeated elements.
+	OverwriteCollect
+)
+
+// storeOverwritten stores the values mapping to path according to the overwrite policy
+func storeOverwritten(result XMLMap, order *[]string, path string, values []string, options *ParseOptions) error {
+	store := func(path, value string) {
+		result[path] = value
+		if order != nil {
+			*order = append(*order, path)
+		}
+	}
+
+	switch options.OverwritePolicy {
+	case OverwriteKeepFirst:
+		store(path, values[0])
+	case OverwriteError:
+		return fmt.Errorf("%w %s: %d values", ErrDuplicatePath, path, len(values))
+	case OverwriteCollect:
+		store(path, values[len(values)-1])
+		if options.CollectValues != nil {
+			options.CollectValues(path, append([]string(nil), values...))
+		}
+	default:
+		store(path, values[len(values)-1])
+	}
+	return nil
+}
+
+// storeAttrs stores the attributes of the element at path, grouping attributes
+// with the same name according to the overwrite policy
+func storeAttrs(result XMLMap, order *[]string, path string, attrs []parseAttr, options *ParseOptions) error {
+	names := make([]string, 0, len(attrs))
+	values := make(map[string][]string, len(attrs))
+	for _, attr := range attrs {
+		if _, ok := values[attr.name]; !ok {
+			names = append(names, attr.name)
+		}
+		values[attr.name] = append(values[attr.name], attr.value)
+	}
+
+	for _, name := range names {
+		attrPath := path + "/@" + name
+		if len(values[name]) == 1 {
+			result[attrPath] = values[name][0]
+			if order != nil {
+				*order = append(*order, attrPath)
+			}
+			continue
+		}
+		if err := storeOverwritten(result, order, attrPath, values[name], options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasDuplicateAttrs reports whether several attributes share a name
+func hasDuplicateAttrs(attrs []parseAttr) bool {
+	for i := range attrs {
+		for j := i + 1; j < len(attrs); j++ {
+			if attrs[i].name == attrs[j].name {
+				return true
+			}
+		}
+	}
+	return false
+}
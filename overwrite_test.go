@@ -0,0 +1,105 @@
+package xmlsurf
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithOverwritePolicy(t *testing.T) {
+	// Text split by a comment and attributes that only differ in their namespace
+	input := `<root xmlns:a="urn:a" xmlns:b="urn:b">
+	<note>first<!-- split -->second</note>
+	<item a:id="1" b:id="2" kind="x"/>
+</root>`
+
+	tests := []struct {
+		name    string
+		policy  OverwritePolicy
+		want    XMLMap
+		wantErr error
+	}{
+		{
+			name:   "keep last",
+			policy: OverwriteKeepLast,
+			want:   XMLMap{"/root/note": "second", "/root/item/@id": "2", "/root/item/@kind": "x"},
+		},
+		{
+			name:   "keep first",
+			policy: OverwriteKeepFirst,
+			want:   XMLMap{"/root/note": "first", "/root/item/@id": "1", "/root/item/@kind": "x"},
+		},
+		{
+			name:    "error",
+			policy:  OverwriteError,
+			wantErr: ErrDuplicatePath,
+		},
+		{
+			name:   "collect",
+			policy: OverwriteCollect,
+			want:   XMLMap{"/root/note": "second", "/root/item/@id": "2", "/root/item/@kind": "x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseToMap(strings.NewReader(input), WithNamespaces(false), WithOverwritePolicy(tt.policy))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseToMap() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseToMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithCollectedValues(t *testing.T) {
+	input := `<root xmlns:a="urn:a" xmlns:b="urn:b">
+	<note>a<!--c-->b</note><note>c</note>
+	<item a:id="1" b:id="2"/>
+</root>`
+
+	collected := make(map[string][]string)
+	got, err := ParseToMap(strings.NewReader(input), WithNamespaces(false),
+		WithCollectedValues(func(path string, values []string) {
+			collected[path] = values
+		}))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+
+	// The map only holds valid paths of real elements
+	want := XMLMap{"/root/note[1]": "b", "/root/note[2]": "c", "/root/item/@id": "2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseToMap() = %v, want %v", got, want)
+	}
+	if err := got.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	wantCollected := map[string][]string{
+		"/root/note[1]":  {"a", "b"},
+		"/root/item/@id": {"1", "2"},
+	}
+	if !reflect.DeepEqual(collected, wantCollected) {
+		t.Errorf("collected values = %v, want %v", collected, wantCollected)
+	}
+}
+
+func TestWithOverwritePolicyNoDuplicates(t *testing.T) {
+	input := `<root><a id="1">x</a><a id="2">y</a></root>`
+	want, err := ParseToMap(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+	for _, policy := range []OverwritePolicy{OverwriteKeepFirst, OverwriteError, OverwriteCollect} {
+		got, err := ParseToMap(strings.NewReader(input), WithOverwritePolicy(policy))
+		if err != nil {
+			t.Fatalf("policy %d: ParseToMap() error = %v", policy, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("policy %d: ParseToMap() = %v, want %v", policy, got, want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package xmlsurf
+
+import (
+	"fmt"
+)
+
+// PathValue is a path of an XMLMap with its value
+type PathValue struct {
+	Path  string
+	Value string
+}
+
+// NewFromPairs builds an XMLMap from path and value pairs, e.g. rows of a
+// "path<TAB>value" fixture file. It fails if a path occurs more than once.
+func NewFromPairs(pairs []PathValue) (XMLMap, error) {
+	result := make(XMLMap, len(pairs))
+	for i, pair := range pairs {
+		if _, exists := result[pair.Path]; exists {
+			return nil, fmt.Errorf("pair %d: %w %s", i+1, ErrDuplicatePath, pair.Path)
+		}
+		result[pair.Path] = pair.Value
+	}
+	return result, nil
+}
+
+// ToPairs returns the paths and values of the map ordered by path depth and then
+// by name and index, the same stable order WriteLines uses
+func (m XMLMap) ToPairs() []PathValue {
+	pairs := make([]PathValue, 0, len(m))
+	for path, value := range m {
+		pairs = append(pairs, PathValue{Path: path, Value: value})
+	}
+	sortByPath(pairs, func(pair PathValue) string { return pair.Path })
+	return pairs
+}
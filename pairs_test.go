@@ -0,0 +1,42 @@
+package xmlsurf
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPairs(t *testing.T) {
+	m := XMLMap{
+		"/root/items/item[10]": "j",
+		"/root/items/item[2]":  "b",
+		"/root/@id":            "1",
+		"/root/name":           "n",
+	}
+
+	pairs := m.ToPairs()
+	want := []PathValue{
+		{"/root/@id", "1"},
+		{"/root/name", "n"},
+		{"/root/items/item[2]", "b"},
+		{"/root/items/item[10]", "j"},
+	}
+	if !reflect.DeepEqual(pairs, want) {
+		t.Errorf("ToPairs() = %v, want %v", pairs, want)
+	}
+
+	back, err := NewFromPairs(pairs)
+	if err != nil {
+		t.Fatalf("NewFromPairs() error = %v", err)
+	}
+	if !reflect.DeepEqual(back, m) {
+		t.Errorf("NewFromPairs(ToPairs()) = %v, want %v", back, m)
+	}
+}
+
+func TestNewFromPairsDuplicate(t *testing.T) {
+	_, err := NewFromPairs([]PathValue{{"/root/a", "1"}, {"/root/a", "2"}})
+	if !errors.Is(err, ErrDuplicatePath) {
+		t.Errorf("NewFromPairs() error = %v, want ErrDuplicatePath", err)
+	}
+}
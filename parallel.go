@@ -0,0 +1,88 @@
+package xmlsurf
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// DocumentError reports a parsing failure of a single document in a batch
+type DocumentError struct {
+	Index int   // Position of the document in the input slice
+	Err   error // The parsing error
+}
+
+// Error returns the error message including the document index
+func (e *DocumentError) Error() string {
+	return fmt.Sprintf("document %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying parsing error
+func (e *DocumentError) Unwrap() error {
+	return e.Err
+}
+
+// ParseErrors collects the per-document errors of a batch parse
+type ParseErrors []*DocumentError
+
+// Error returns all document errors joined into a single message
+func (e ParseErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ParseManyToMaps parses multiple XML documents concurrently using a pool of workers.
+// Options are evaluated once and shared by all documents, so the functions they
+// hold are called from several goroutines at once and must be safe for concurrent
+// use: those of WithValueTransform, WithProgress, WithCollectedValues, WithMetrics,
+// WithTraceHook and WithBinaryPaths.
+// The returned slice has one entry per reader in the same order. Documents that
+// fail to parse have a nil entry and are reported in the returned ParseErrors.
+func ParseManyToMaps(readers []io.Reader, opts ...Option) ([]XMLMap, error) {
+	options := DefaultParseOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	results := make([]XMLMap, len(readers))
+	errs := make([]error, len(readers))
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(readers) {
+		workers = len(readers)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], errs[i] = parseWithOptions(readers[i], options)
+			}
+		}()
+	}
+	for i := range readers {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var parseErrs ParseErrors
+	for i, err := range errs {
+		if err != nil {
+			parseErrs = append(parseErrs, &DocumentError{Index: i, Err: err})
+		}
+	}
+	if len(parseErrs) > 0 {
+		return results, parseErrs
+	}
+
+	return results, nil
+}
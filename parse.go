@@ -1,11 +1,12 @@
 package xmlsurf
 
 import (
+	"bytes"
 	"encoding/xml"
-	"errors"
 	"fmt"
 	"io"
 	"strings"
+	"time"
 )
 
 // ParseToMap parses XML from the reader and returns a map of XPath expressions to values.
@@ -19,132 +20,481 @@ func ParseToMap(reader io.Reader, opts ...Option) (XMLMap, error) {
 		opt(options)
 	}
 
-	decoder := xml.NewDecoder(reader)
-	// Pre-allocate the map with a reasonable size to avoid rehashing
-	result := make(XMLMap, 50)
-	pathStack := make([]string, 0, 10)
-	var currentPath string
-	elementCounts := make(map[string]int, 10)
-	namespaces := make(map[string]string, 5)
-	var rootSeen bool
+	return parseWithOptions(reader, options)
+}
+
+// ParseToMapOrdered parses XML like ParseToMap and additionally returns the paths
+// of the resulting map in document order, for use with WithDocumentOrder.
+func ParseToMapOrdered(reader io.Reader, opts ...Option) (XMLMap, []string, error) {
+	options := DefaultParseOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	order := make([]string, 0, 50)
+	result, err := parseDocument(reader, options, &order)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, order, nil
+}
+
+// parseWithOptions parses XML from the reader using already evaluated options
+func parseWithOptions(reader io.Reader, options *ParseOptions) (XMLMap, error) {
+	return parseDocument(reader, options, nil)
+}
+
+// parseDocument parses XML from the reader. If order is not nil, the paths of
+// the result are appended to it in document order.
+func parseDocument(reader io.Reader, options *ParseOptions, order *[]string) (XMLMap, error) {
+	return parseDocumentWith(reader, options, order, nil, newParseState(options.SizeHint))
+}
+
+// parseDocumentWith parses XML from the reader using the given parser state,
+// which must be empty. If positions is not nil, the position of every path is stored in it.
+func parseDocumentWith(reader io.Reader, options *ParseOptions, order *[]string, positions map[string]Position, state *parseState) (result XMLMap, err error) {
+	decoder := newDecoder(reader, options)
+	progress := progressReporter{report: options.Progress}
+	if options.Metrics != nil {
+		// Deferred first, so panics have been converted to errors when it runs
+		start := time.Now()
+		defer func() {
+			options.Metrics.ObserveParse(ParseStats{
+				Bytes:     decoder.InputOffset(),
+				Elements:  len(state.nodes),
+				Paths:     len(result),
+				Duration:  time.Since(start),
+				Err:       err,
+				ErrorKind: classifyError(err),
+			})
+		}()
+	}
+	defer recoverPanic("parse", &err)
 
-	// Reuse path builder for better performance
-	pathBuilder := getPathBuilder()
-	defer putPathBuilder(pathBuilder)
+	builder := newDocumentBuilder(options, state)
+	defer builder.close()
+	trace := tracer{hook: options.TraceHook}
+	var starts []Position
 
 	for {
+		// The decoder stops in front of each token, so this is where the next token starts
+		var start Position
+		if positions != nil || trace.enabled() {
+			start = decoderPosition(decoder)
+		}
 		token, err := decoder.Token()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return nil, err
+			return nil, trace.error(decodeError(decoder, err), start)
 		}
-
-		switch t := token.(type) {
-		case xml.StartElement:
-			// Check for multiple roots
-			if len(pathStack) == 0 {
-				if rootSeen {
-					return nil, fmt.Errorf("XML syntax error: multiple root elements")
+		if err := builder.add(token); err != nil {
+			return nil, trace.error(decodeError(decoder, err), start)
+		}
+		trace.token(token, start)
+		if startElement, ok := token.(xml.StartElement); ok {
+			progress.element(decoder.InputOffset())
+			if builder.skipping {
+				builder.skipping = false
+				if err := decoder.Skip(); err != nil {
+					return nil, trace.error(decodeError(decoder, err), start)
 				}
-				rootSeen = true
+				if trace.enabled() {
+					trace.token(startElement.End(), decoderPosition(decoder))
+				}
+				continue
+			}
+			if positions != nil {
+				starts = append(starts, start)
 			}
+		}
+		if builder.stopped {
+			break
+		}
+	}
+	progress.done(decoder.InputOffset())
 
-			// Process namespace declarations
-			processNamespaces(t.Attr, namespaces)
+	result, err = builder.result(order)
+	if err != nil {
+		return nil, err
+	}
+	if positions != nil {
+		builder.positions(starts, result, positions)
+	}
+	return result, nil
+}
 
-			// Build element name with namespace if needed
-			elementName := buildElementName(t.Name.Local, t.Name.Space, namespaces, options.IncludeNamespaces, pathBuilder)
+// Progress is reported every progressElements elements or progressBytes bytes
+const (
+	progressElements = 1024
+	progressBytes    = 1 << 20
+)
 
-			// Build current path
-			newPath := buildPath(currentPath, elementName, pathBuilder)
+// progressReporter calls a WithProgress function at the configured intervals
+type progressReporter struct {
+	report       func(bytesRead int64, elements int)
+	elements     int
+	lastReported int64
+}
 
-			// Track element counts at each level and update indices if needed
-			basePath := newPath
-			elementCounts[basePath]++
-			count := elementCounts[basePath]
+// element counts a parsed element and reports progress when an interval is reached
+func (p *progressReporter) element(offset int64) {
+	if p.report == nil {
+		return
+	}
+	p.elements++
+	if p.elements%progressElements == 0 || offset-p.lastReported >= progressBytes {
+		p.lastReported = offset
+		p.report(offset, p.elements)
+	}
+}
 
-			// If we've seen this element before at this level, add indices
-			if count > 1 {
-				keysToUpdate, indexedPath := updateElementIndices(basePath, count, result, pathBuilder)
+// done reports the final progress
+func (p *progressReporter) done(offset int64) {
+	if p.report != nil {
+		p.report(offset, p.elements)
+	}
+}
 
-				// Apply the updates (only needed when count == 2)
-				for oldKey, newKey := range keysToUpdate {
-					v := result[oldKey]
-					delete(result, oldKey)
-					result[newKey] = v
-				}
+// documentBuilder collects the tokens of a document into an XMLMap.
+// Elements are recorded in document order and paths are assigned in a
+// single pass once sibling counts are known, so repeated elements never
+// require rewriting keys that were already stored.
+type documentBuilder struct {
+	options      *ParseOptions
+	state        *parseState
+	rootSeen     bool
+	pathBuilder  *strings.Builder
+	binaryCounts map[string]int  // Elements selected by WithBinaryPaths per index-free path
+	spilled      []string        // Temporary files written by WithSpillover, removed if parsing fails
+	stopFound    map[string]bool // Paths of WithStopAfter found so far
+	ended        int             // Elements read completely, counted for WithStopAfterElements
+	stopped      bool            // Whether the requested content was read and parsing can stop
+	skip         *subtreeMatcher // Compiled patterns of WithSkipSubtrees, nil without them
+	skipping     bool            // Whether the last start element was left out and its subtree must be skipped
+}
 
-				newPath = indexedPath
+// newDocumentBuilder returns a builder using the given empty parser state
+func newDocumentBuilder(options *ParseOptions, state *parseState) *documentBuilder {
+	return &documentBuilder{
+		options:     options,
+		state:       state,
+		pathBuilder: getPathBuilder(),
+		skip:        compileSubtreeMatcher(options.SkipSubtrees),
+	}
+}
+
+// close releases the resources of the builder, including the writers of binary
+// values left open and the spilled values of a failed parse
+func (b *documentBuilder) close() {
+	for _, i := range b.state.nodeStack {
+		if binary := b.state.nodes[i].binary; binary != nil {
+			binary.close()
+			b.state.nodes[i].binary = nil
+		}
+	}
+	b.removeSpilled()
+	putPathBuilder(b.pathBuilder)
+}
+
+// add processes the next token of the document
+func (b *documentBuilder) add(token xml.Token) error {
+	state, options := b.state, b.options
+
+	switch t := token.(type) {
+	case xml.StartElement:
+		// Check for multiple roots; a fragment may have any number of top-level elements
+		if len(state.nodeStack) == 0 && options.FragmentRoot == "" {
+			if b.rootSeen {
+				return ErrMultipleRoots
 			}
+			b.rootSeen = true
+		}
 
-			// Process attributes
-			for _, attr := range t.Attr {
-				attrPath, attrValue := processAttribute(attr, newPath, namespaces, options, pathBuilder)
-				if attrPath != "" {
-					result[attrPath] = attrValue
-				}
+		if err := checkNames(t); err != nil {
+			return err
+		}
+
+		// Open the namespace scope of the element
+		state.namespaces.push(t.Attr)
+
+		// Build element name with namespace if needed
+		elementName := buildElementName(t.Name.Local, t.Name.Space, &state.namespaces, options.IncludeNamespaces, options.DefaultNamespacePrefix, b.pathBuilder)
+
+		parent := -1
+		if len(state.nodeStack) > 0 {
+			parent = state.nodeStack[len(state.nodeStack)-1]
+		}
+
+		// Select the element by its index-free path for WithBinaryPaths, WithStopAfter and WithSkipSubtrees
+		var basePath string
+		if options.BinaryPaths != nil || options.StopAfter != nil || b.skip != nil {
+			basePath = options.FragmentRoot + "/" + elementName
+			if parent >= 0 {
+				basePath = state.nodes[parent].basePath + "/" + elementName
 			}
+		}
 
-			// Store the current path for nested elements
-			currentPath = newPath
-			pathStack = append(pathStack, currentPath)
+		// Leave out subtrees selected by WithSkipSubtrees; the caller skips their tokens
+		if b.skip != nil && b.skip.match(basePath, elementName) {
+			state.namespaces.pop()
+			b.skipping = true
+			return nil
+		}
 
-		case xml.EndElement:
-			if len(pathStack) > 0 {
-				pathStack = pathStack[:len(pathStack)-1]
-				if len(pathStack) > 0 {
-					currentPath = pathStack[len(pathStack)-1]
-				} else {
-					currentPath = ""
+		// Count siblings with the same name under the same parent
+		if parent >= 0 {
+			state.nodes[parent].hasChildren = true
+		}
+		key := siblingKey{parent: parent, name: elementName}
+		state.siblingCounts[key]++
+
+		node := parseNode{
+			parent:   parent,
+			name:     elementName,
+			position: state.siblingCounts[key],
+			basePath: basePath,
+		}
+		if options.BinaryPaths != nil {
+			binary, err := b.openBinary(basePath)
+			if err != nil {
+				return err
+			}
+			node.binary = binary
+		}
+
+		// Process attributes
+		for _, attr := range t.Attr {
+			if options.schemaTypes != nil && attr.Name.Space == xsiNamespaceURI && attr.Name.Local == "type" {
+				node.schemaType = resolveSchemaType(attr.Value, &state.namespaces)
+			}
+			attrName, attrValue, ok := processAttribute(attr, &state.namespaces, options, b.pathBuilder)
+			if ok {
+				node.attrs = append(node.attrs, parseAttr{name: attrName, value: attrValue})
+				if options.StopAfter != nil {
+					b.reached(node.basePath + "/@" + attrName)
 				}
 			}
+		}
 
-		case xml.CharData:
-			value := strings.TrimSpace(string(t))
-			if len(value) > 0 {
-				if options.ValueTransform != nil {
-					value = options.ValueTransform(value)
+		state.nodes = append(state.nodes, node)
+		state.nodeStack = append(state.nodeStack, len(state.nodes)-1)
+
+	case xml.EndElement:
+		if len(state.nodeStack) > 0 {
+			node := &state.nodes[state.nodeStack[len(state.nodeStack)-1]]
+			if node.binary != nil {
+				// The decoded content went to the writer; the map keeps the element with an empty value
+				binary := node.binary
+				node.binary, node.hasValue = nil, true
+				if err := binary.finish(); err != nil {
+					return err
 				}
-				result[currentPath] = value
 			}
+			if options.StopAfter != nil {
+				b.reached(node.basePath)
+			}
+			b.elementEnded()
+			state.nodeStack = state.nodeStack[:len(state.nodeStack)-1]
+			state.namespaces.pop()
+		}
+
+	case xml.CharData:
+		if len(state.nodeStack) == 0 {
+			return nil
 		}
+		if binary := state.nodes[state.nodeStack[len(state.nodeStack)-1]].binary; binary != nil {
+			return binary.write(t)
+		}
+		var value string
+		if options.SpillLimit > 0 && len(t) > options.SpillLimit && len(bytes.TrimSpace(t)) > 0 {
+			// Large text goes to a temporary file without being copied into a string
+			reference, err := b.spill(t)
+			if err != nil {
+				return err
+			}
+			value = reference
+		} else {
+			value = string(t)
+			if strings.TrimSpace(value) == "" {
+				return nil
+			}
+			if options.TrimValues {
+				value = strings.TrimSpace(value)
+			}
+			if options.ValueTransform != nil {
+				value = options.ValueTransform(value)
+			}
+		}
+		node := &state.nodes[state.nodeStack[len(state.nodeStack)-1]]
+		if node.hasValue && options.OverwritePolicy != OverwriteKeepLast {
+			node.earlierValues = append(node.earlierValues, node.value)
+		} else if node.hasValue {
+			// The replaced text is not stored anywhere
+			if file, ok := SpilledFile(node.value); ok {
+				removeSpilledFile(file)
+			}
+		}
+		node.value = value
+		node.hasValue = true
+	}
+	return nil
+}
+
+// result assigns the paths of all recorded elements and returns the map.
+// If order is not nil, the paths are appended to it in document order.
+func (b *documentBuilder) result(order *[]string) (XMLMap, error) {
+	nodes := b.state.nodes
+	result := make(XMLMap, max(len(nodes), b.options.SizeHint))
+	if err := assignPaths(nodes, b.state.siblingCounts, b.options, result, order, b.pathBuilder); err != nil {
+		return nil, err
 	}
 
 	if len(result) == 0 {
-		return nil, errors.New("EOF")
+		return nil, ErrEmptyDocument
 	}
 
+	if b.options.UnwrapNested {
+		result = result.unwrapNested(b.options)
+	}
+	// Nothing else refers to the spilled files of a new map
+	result.redact(b.options.RedactPatterns, true)
+
+	// The spilled values belong to the result now
+	b.spilled = nil
 	return result, nil
 }
 
-// processNamespaces handles XML namespace processing
-func processNamespaces(attrs []xml.Attr, namespaces map[string]string) {
-	for _, attr := range attrs {
-		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
-			prefix := attr.Name.Local
-			if prefix == "xmlns" {
-				prefix = ""
+// parseState holds the structures used while parsing a document
+type parseState struct {
+	nodes         []parseNode
+	nodeStack     []int
+	siblingCounts map[siblingKey]int
+	namespaces    namespaceScopes
+}
+
+// newParseState returns an empty parser state sized for about sizeHint elements
+func newParseState(sizeHint int) *parseState {
+	if sizeHint < 50 {
+		sizeHint = 50
+	}
+	return &parseState{
+		nodes:         make([]parseNode, 0, sizeHint),
+		nodeStack:     make([]int, 0, 10),
+		siblingCounts: make(map[siblingKey]int, sizeHint/5),
+	}
+}
+
+// reset empties the state for reuse, dropping references to parsed strings
+func (s *parseState) reset() {
+	clear(s.nodes)
+	s.nodes = s.nodes[:0]
+	s.nodeStack = s.nodeStack[:0]
+	clear(s.siblingCounts)
+	s.namespaces.reset()
+}
+
+// parseNode is an element recorded during parsing before its path is known
+type parseNode struct {
+	parent      int // Index of the parent node, -1 for the root
+	name        string
+	position    int // 1-based position among siblings with the same name
+	value       string
+	hasValue    bool
+	hasChildren bool // Whether the element contains child elements
+	attrs       []parseAttr
+	path        string
+	// schemaType is the resolved xsi:type, only recorded for ParseToTypedMap
+	schemaType string
+	// earlierValues holds text replaced by later text of the element; it is only
+	// recorded when the overwrite policy is not OverwriteKeepLast
+	earlierValues []string
+	// basePath is the index-free path, only recorded with WithBinaryPaths, WithStopAfter or WithSkipSubtrees
+	basePath string
+	// binary decodes the text of an element selected by WithBinaryPaths while it is open
+	binary *binaryValue
+}
+
+// parseAttr is an attribute recorded during parsing
+type parseAttr struct {
+	name  string
+	value string
+}
+
+// siblingKey identifies a group of same-named siblings
+type siblingKey struct {
+	parent int
+	name   string
+}
+
+// assignPaths computes the final path of every node and stores values and attributes.
+// Nodes are in document order, so parents are always resolved before their children.
+func assignPaths(nodes []parseNode, siblingCounts map[siblingKey]int, options *ParseOptions, result XMLMap, order *[]string, pathBuilder *strings.Builder) error {
+	for i := range nodes {
+		node := &nodes[i]
+
+		// Top-level elements of a fragment are placed under the synthetic root
+		parentPath := options.FragmentRoot
+		if node.parent >= 0 {
+			parentPath = nodes[node.parent].path
+		}
+		path := buildPath(parentPath, node.name, pathBuilder)
+
+		// Add an index only when the element has same-named siblings
+		if siblingCounts[siblingKey{parent: node.parent, name: node.name}] > 1 {
+			path = buildIndexedPath(path, options.index(node.position), options.IndexStyle, pathBuilder)
+		}
+		node.path = path
+		if node.schemaType != "" {
+			options.schemaTypes[path] = node.schemaType
+		}
+
+		if len(node.earlierValues) > 0 {
+			values := append(node.earlierValues, node.value)
+			if err := storeOverwritten(result, order, path, values, options); err != nil {
+				return err
+			}
+			removeUnstored(values, result[path])
+		} else if node.hasValue || (options.EmptyElements && !node.hasChildren) {
+			result[path] = node.value
+			if order != nil {
+				*order = append(*order, path)
+			}
+		}
+		if options.OverwritePolicy != OverwriteKeepLast && hasDuplicateAttrs(node.attrs) {
+			if err := storeAttrs(result, order, path, node.attrs, options); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, attr := range node.attrs {
+			pathBuilder.Reset()
+			pathBuilder.WriteString(path)
+			pathBuilder.WriteString("/@")
+			pathBuilder.WriteString(attr.name)
+			attrPath := pathBuilder.String()
+			result[attrPath] = attr.value
+			if order != nil {
+				*order = append(*order, attrPath)
 			}
-			namespaces[prefix] = attr.Value
 		}
 	}
+	return nil
 }
 
-// buildElementName creates an element name with namespace if needed
-func buildElementName(elementName string, space string, namespaces map[string]string, includeNamespaces bool, pathBuilder *strings.Builder) string {
+// buildElementName creates an element name with namespace if needed, using the
+// prefix bound to the namespace URI in the current scope.
+// Names in the default namespace get defaultPrefix, if set.
+func buildElementName(elementName string, space string, namespaces *namespaceScopes, includeNamespaces bool, defaultPrefix string, pathBuilder *strings.Builder) string {
 	if !includeNamespaces || space == "" {
 		return elementName
 	}
 
 	// Find prefix for namespace URI
-	prefix := ""
-	for p, uri := range namespaces {
-		if uri == space {
-			prefix = p
-			break
-		}
+	prefix, _ := namespaces.prefix(space, true)
+	if prefix == "" {
+		prefix = defaultPrefix
 	}
 
 	// Build name with namespace
@@ -154,7 +504,7 @@ func buildElementName(elementName string, space string, namespaces map[string]st
 		pathBuilder.WriteString(":")
 		pathBuilder.WriteString(elementName)
 	} else {
-		// For default namespace (no prefix), just return the element name
+		// For default namespace without a synthetic prefix, just return the element name
 		pathBuilder.WriteString(elementName)
 	}
 	return pathBuilder.String()
@@ -174,68 +524,62 @@ func buildPath(currentPath, elementName string, pathBuilder *strings.Builder) st
 	return pathBuilder.String()
 }
 
-// updateElementIndices handles indexing of repeated elements
-func updateElementIndices(basePath string, count int, result XMLMap, pathBuilder *strings.Builder) (map[string]string, string) {
-	// For the first repeat (count == 2), update the existing paths
-	keysToUpdate := make(map[string]string)
-
-	if count == 2 {
-		// Prefixes for faster prefix checking
-		basePathPrefix := basePath + "/"
-		basePathAttrPrefix := basePath + "/@"
-
-		// Create a list of keys to update
-		for k := range result {
-			if k == basePath || strings.HasPrefix(k, basePathPrefix) || strings.HasPrefix(k, basePathAttrPrefix) {
-				pathBuilder.Reset()
-				pathBuilder.WriteString(basePath)
-				pathBuilder.WriteString("[1]")
-				if strings.HasPrefix(k, basePathPrefix) {
-					pathBuilder.WriteString(k[len(basePath):])
-				} else if strings.HasPrefix(k, basePathAttrPrefix) {
-					pathBuilder.WriteString(k[len(basePath):])
-				}
-				keysToUpdate[k] = pathBuilder.String()
-			}
+// buildIndexedPath appends an index in the style to a path
+func buildIndexedPath(path string, index int, style IndexStyle, pathBuilder *strings.Builder) string {
+	pathBuilder.Reset()
+	pathBuilder.WriteString(path)
+	style.appendIndex(pathBuilder, index)
+	return pathBuilder.String()
+}
+
+// checkNames rejects element and attribute names that encoding/xml accepts although
+// they are not qualified names, e.g. a:0. They would turn into paths that
+// cannot be written back as XML.
+func checkNames(start xml.StartElement) error {
+	if err := checkName(start.Name); err != nil {
+		return err
+	}
+	for _, attr := range start.Attr {
+		if err := checkName(attr.Name); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	// Create the new path with index
-	pathBuilder.Reset()
-	pathBuilder.WriteString(basePath)
-	pathBuilder.WriteString("[")
-	pathBuilder.WriteString(fmt.Sprint(count))
-	pathBuilder.WriteString("]")
-	newPath := pathBuilder.String()
-
-	return keysToUpdate, newPath
+// checkName checks the local part of a name; its prefix is checked by the decoder
+func checkName(name xml.Name) error {
+	if validatePathName(name.Local) != nil || strings.Contains(name.Local, ":") {
+		return fmt.Errorf("%w: %s", errInvalidName, name.Local)
+	}
+	return nil
 }
 
-// processAttribute handles an attribute and adds it to the result map
-func processAttribute(attr xml.Attr, path string, namespaces map[string]string, options *ParseOptions, pathBuilder *strings.Builder) (string, string) {
+// processAttribute builds the name and value of an attribute.
+// It reports false for namespace declarations, which are not stored.
+func processAttribute(attr xml.Attr, namespaces *namespaceScopes, options *ParseOptions, pathBuilder *strings.Builder) (string, string, bool) {
 	// Skip namespace declarations
 	if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
-		return "", ""
+		return "", "", false
 	}
 
-	// Build attribute name with namespace if needed
+	// Build attribute name with namespace if needed; attributes are never in the default namespace
 	attrName := attr.Name.Local
 	if options.IncludeNamespaces && attr.Name.Space != "" {
-		attrName = buildElementName(attrName, attr.Name.Space, namespaces, true, pathBuilder)
+		if prefix, ok := namespaces.prefix(attr.Name.Space, false); ok {
+			pathBuilder.Reset()
+			pathBuilder.WriteString(prefix)
+			pathBuilder.WriteString(":")
+			pathBuilder.WriteString(attrName)
+			attrName = pathBuilder.String()
+		}
 	}
 
-	// Build full path to the attribute
-	pathBuilder.Reset()
-	pathBuilder.WriteString(path)
-	pathBuilder.WriteString("/@")
-	pathBuilder.WriteString(attrName)
-	attrPath := pathBuilder.String()
-
 	// Apply value transformation if specified
 	value := attr.Value
 	if options.ValueTransform != nil {
 		value = options.ValueTransform(value)
 	}
 
-	return attrPath, value
+	return attrName, value, true
 }
@@ -1,7 +1,13 @@
 package xmlsurf
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -60,6 +66,70 @@ func TestParseXMLToMap(t *testing.T) {
 				"/root/items/item[3]": "three",
 			},
 		},
+		{
+			name: "repeated elements interleaved with other siblings",
+			xml: `<root>
+				<item id="1">one</item>
+				<other>x</other>
+				<item id="2">two</item>
+				<group><item>nested</item></group>
+				<item>three</item>
+			</root>`,
+			expected: XMLMap{
+				"/root/item[1]":     "one",
+				"/root/item[1]/@id": "1",
+				"/root/other":       "x",
+				"/root/item[2]":     "two",
+				"/root/item[2]/@id": "2",
+				"/root/group/item":  "nested",
+				"/root/item[3]":     "three",
+			},
+		},
+		{
+			name: "trimming disabled keeps surrounding whitespace",
+			xml: `<root>
+				<item>  padded  </item>
+				<empty>   </empty>
+			</root>`,
+			options: []Option{WithTrimValues(false), WithValueTransform(func(s string) string {
+				return "[" + s + "]"
+			})},
+			expected: XMLMap{
+				"/root/item": "[  padded  ]",
+			},
+		},
+		{
+			name: "default namespace with synthetic prefix",
+			xml: `<root xmlns="urn:orders">
+				<order id="1">
+					<plain xmlns="">text</plain>
+					<x:item xmlns:x="urn:items">a</x:item>
+				</order>
+			</root>`,
+			options: []Option{WithDefaultNamespacePrefix("d")},
+			expected: XMLMap{
+				"/d:root/d:order/@id":    "1",
+				"/d:root/d:order/plain":  "text",
+				"/d:root/d:order/x:item": "a",
+			},
+		},
+		{
+			name: "empty elements",
+			xml: `<root>
+				<flag/>
+				<note>  </note>
+				<item id="1"/>
+				<group><child/></group>
+			</root>`,
+			options: []Option{WithEmptyElements(true)},
+			expected: XMLMap{
+				"/root/flag":        "",
+				"/root/note":        "",
+				"/root/item":        "",
+				"/root/item/@id":    "1",
+				"/root/group/child": "",
+			},
+		},
 		{
 			name: "list items with nested elements",
 			xml: `<root>
@@ -316,22 +386,51 @@ func TestParseXMLToMapErrors(t *testing.T) {
 		name        string
 		xml         string
 		expectedErr string
+		is          error
+		line        int // Line of the *SyntaxError, not checked if 0
 	}{
 		{
 			name:        "empty input",
 			xml:         "",
-			expectedErr: "EOF",
+			expectedErr: "empty document",
+			is:          ErrEmptyDocument,
+		},
+		{
+			name:        "no values",
+			xml:         "<root></root>",
+			expectedErr: "empty document",
+			is:          ErrEmptyDocument,
 		},
 		{
 			name:        "invalid xml",
 			xml:         "<root>",
 			expectedErr: "XML syntax error on line 1: unexpected EOF",
+			line:        1,
 		},
 		{
 			name:        "multiple root elements",
 			xml:         "<root1></root1><root2></root2>",
 			expectedErr: "XML syntax error: multiple root elements",
 		},
+		{
+			name:        "multiple root elements on a later line",
+			xml:         "<root1></root1>\n<root2></root2>",
+			expectedErr: "XML syntax error: multiple root elements",
+			is:          ErrMultipleRoots,
+			line:        2,
+		},
+		{
+			name:        "local name starting with a digit",
+			xml:         "<root>\n<b a:0=\"1\">x</b></root>",
+			expectedErr: "XML syntax error on line 2: invalid XML name: 0",
+			line:        2,
+		},
+		{
+			name:        "invalid namespace prefix",
+			xml:         `<s:root xmlns:0="urn:s"><x>1</x></s:root>`,
+			expectedErr: "XML syntax error on line 1: invalid XML name: 0",
+			line:        1,
+		},
 	}
 
 	for _, tt := range tests {
@@ -345,10 +444,142 @@ func TestParseXMLToMapErrors(t *testing.T) {
 			if err.Error() != tt.expectedErr {
 				t.Errorf("ParseToMap() error = %q, want %q", err.Error(), tt.expectedErr)
 			}
+			if tt.is != nil && !errors.Is(err, tt.is) {
+				t.Errorf("errors.Is(%v, %v) = false", err, tt.is)
+			}
+			if tt.line == 0 {
+				return
+			}
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("errors.As(%v, *SyntaxError) = false", err)
+			}
+			if syntaxErr.Line != tt.line {
+				t.Errorf("SyntaxError.Line = %d, want %d", syntaxErr.Line, tt.line)
+			}
+		})
+	}
+}
+
+func TestParseFragment(t *testing.T) {
+	tests := []struct {
+		name    string
+		xml     string
+		root    string
+		want    XMLMap
+		wantErr error
+	}{
+		{
+			name: "several top-level elements",
+			xml:  `<a>1</a><b x="y">2</b>`,
+			root: "/fragment",
+			want: XMLMap{"/fragment/a": "1", "/fragment/b": "2", "/fragment/b/@x": "y"},
+		},
+		{
+			name: "repeated top-level elements",
+			xml:  "<item>1</item>\n<item><id>2</id></item>",
+			root: "/fragment",
+			want: XMLMap{"/fragment/item[1]": "1", "/fragment/item[2]/id": "2"},
+		},
+		{
+			name: "root path with several steps",
+			xml:  `<a>1</a><b>2</b>`,
+			root: "db/snippet/",
+			want: XMLMap{"/db/snippet/a": "1", "/db/snippet/b": "2"},
+		},
+		{
+			name: "single root element",
+			xml:  `<?xml version="1.0"?><root><a>1</a></root>`,
+			root: "/fragment",
+			want: XMLMap{"/fragment/root/a": "1"},
+		},
+		{
+			name: "text outside elements is ignored",
+			xml:  `text<a>1</a>more`,
+			root: "/fragment",
+			want: XMLMap{"/fragment/a": "1"},
+		},
+		{
+			name:    "no elements",
+			xml:     `text`,
+			root:    "/fragment",
+			wantErr: ErrEmptyDocument,
+		},
+		{
+			name:    "empty root requires a single root element",
+			xml:     `<a>1</a><b>2</b>`,
+			root:    "",
+			wantErr: ErrMultipleRoots,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseToMap(strings.NewReader(tt.xml), WithAllowFragment(tt.root))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseToMap() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(m, tt.want) {
+				t.Errorf("ParseToMap() = %v, want %v", m, tt.want)
+			}
 		})
 	}
 }
 
+func TestParseFragmentRoundTrip(t *testing.T) {
+	report, err := Fidelity(strings.NewReader(`<a>1</a><b>2</b>`), WithAllowFragment("/fragment"))
+	if err != nil {
+		t.Fatalf("Fidelity() error = %v", err)
+	}
+	if !report.Lossless() {
+		t.Errorf("Fidelity() = %+v, want lossless", report)
+	}
+
+	record, err := NewStream(strings.NewReader(`<feed><order><id>1</id></order></feed>`), WithAllowFragment("/fragment")).Next()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if want := (XMLMap{"/order/id": "1"}); !reflect.DeepEqual(record.Map, want) {
+		t.Errorf("Next() map = %v, want %v; streams ignore WithAllowFragment", record.Map, want)
+	}
+}
+
+func TestWhitespaceOnlyElementsRoundTrip(t *testing.T) {
+	input := "<root><note> </note><blank>\n\t</blank><value>1</value></root>"
+
+	for _, trim := range []bool{true, false} {
+		m, err := ParseToMap(strings.NewReader(input), WithEmptyElements(true), WithTrimValues(trim))
+		if err != nil {
+			t.Fatalf("ParseToMap() error = %v", err)
+		}
+		want := XMLMap{"/root/note": "", "/root/blank": "", "/root/value": "1"}
+		if !reflect.DeepEqual(m, want) {
+			t.Errorf("ParseToMap(trim %v) = %v, want %v", trim, m, want)
+		}
+
+		var buf strings.Builder
+		if err := m.ToXML(&buf, false); err != nil {
+			t.Fatalf("ToXML() error = %v", err)
+		}
+		reparsed, err := ParseToMap(strings.NewReader(buf.String()), WithEmptyElements(true))
+		if err != nil {
+			t.Fatalf("ParseToMap() error = %v", err)
+		}
+		if !reflect.DeepEqual(reparsed, m) {
+			t.Errorf("round trip = %v, want %v", reparsed, m)
+		}
+	}
+
+	// Without the option the elements are absent
+	m, err := ParseToMap(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+	if _, ok := m["/root/note"]; ok {
+		t.Error("ParseToMap() without WithEmptyElements stored /root/note")
+	}
+}
+
 func BenchmarkParseToMap(b *testing.B) {
 	xml := `<?xml version="1.0" encoding="UTF-8"?>
 	<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"
@@ -393,3 +624,184 @@ func BenchmarkParseToMap(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkParseToMapManySiblings(b *testing.B) {
+	var builder strings.Builder
+	builder.WriteString("<root><items>")
+	for i := 0; i < 10000; i++ {
+		builder.WriteString(`<item id="x"><name>name</name><price>1</price></item>`)
+	}
+	builder.WriteString("</items></root>")
+	xml := builder.String()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader := strings.NewReader(xml)
+		_, err := ParseToMap(reader)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParseManyToMaps(t *testing.T) {
+	readers := []io.Reader{
+		strings.NewReader(`<root><item>one</item></root>`),
+		strings.NewReader(`<root>`),
+		strings.NewReader(`<root><item>three</item></root>`),
+	}
+
+	results, err := ParseManyToMaps(readers, WithValueTransform(strings.ToUpper))
+	if len(results) != len(readers) {
+		t.Fatalf("ParseManyToMaps() returned %d results, want %d", len(results), len(readers))
+	}
+
+	var parseErrs ParseErrors
+	if !errors.As(err, &parseErrs) {
+		t.Fatalf("ParseManyToMaps() error = %v, want ParseErrors", err)
+	}
+	if len(parseErrs) != 1 || parseErrs[0].Index != 1 {
+		t.Errorf("ParseManyToMaps() errors = %v, want a single error for document 1", parseErrs)
+	}
+
+	if !results[0].Equal(XMLMap{"/root/item": "ONE"}) {
+		t.Errorf("ParseManyToMaps() result[0] = %v", results[0])
+	}
+	if results[1] != nil {
+		t.Errorf("ParseManyToMaps() result[1] = %v, want nil", results[1])
+	}
+	if !results[2].Equal(XMLMap{"/root/item": "THREE"}) {
+		t.Errorf("ParseManyToMaps() result[2] = %v", results[2])
+	}
+}
+
+func TestParser(t *testing.T) {
+	parser := NewParser(WithSizeHint(100), WithValueTransform(strings.ToUpper))
+	documents := []string{
+		`<root xmlns:a="urn:a"><a:item>one</a:item><a:item>two</a:item></root>`,
+		`<other><item>three</item></other>`,
+		`<root><item x="1">four</item></root>`,
+	}
+
+	// Reused state must not leak sibling counts or namespaces between documents
+	for round := 0; round < 2; round++ {
+		for _, doc := range documents {
+			expected, err := ParseToMap(strings.NewReader(doc), WithValueTransform(strings.ToUpper))
+			if err != nil {
+				t.Fatalf("ParseToMap() error = %v", err)
+			}
+			result, err := parser.Parse(strings.NewReader(doc))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !result.Equal(expected) {
+				t.Errorf("Parse() = %v, want %v", result, expected)
+			}
+		}
+	}
+
+	if _, err := parser.Parse(strings.NewReader(`<root>`)); err == nil {
+		t.Errorf("Parse() expected error for malformed input")
+	}
+
+	result, order, err := parser.ParseOrdered(strings.NewReader(`<root><b>1</b><a>2</a></root>`))
+	if err != nil {
+		t.Fatalf("ParseOrdered() error = %v", err)
+	}
+	if !reflect.DeepEqual(order, []string{"/root/b", "/root/a"}) || result["/root/a"] != "2" {
+		t.Errorf("ParseOrdered() = %v, %v", result, order)
+	}
+}
+
+func TestNew(t *testing.T) {
+	parser := New(WithValueTransform(strings.ToUpper))
+	result, err := parser.ParseToMap(strings.NewReader(`<root><b>x</b><a>y</a></root>`))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+	if !result.Equal(XMLMap{"/root/b": "X", "/root/a": "Y"}) {
+		t.Errorf("ParseToMap() = %v", result)
+	}
+
+	result, order, err := parser.ParseToMapOrdered(strings.NewReader(`<root><b>x</b><a>y</a></root>`))
+	if err != nil {
+		t.Fatalf("ParseToMapOrdered() error = %v", err)
+	}
+	if !reflect.DeepEqual(order, []string{"/root/b", "/root/a"}) || result["/root/b"] != "X" {
+		t.Errorf("ParseToMapOrdered() = %v, %v", result, order)
+	}
+}
+
+func TestParserConcurrent(t *testing.T) {
+	parser := NewParser()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				doc := fmt.Sprintf(`<root><item>%d</item><item>%d</item></root>`, i, j)
+				result, err := parser.Parse(strings.NewReader(doc))
+				if err != nil || result["/root/item[1]"] != strconv.Itoa(i) || result["/root/item[2]"] != strconv.Itoa(j) {
+					t.Errorf("Parse() = %v, %v", result, err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func BenchmarkParserManySiblings(b *testing.B) {
+	var builder strings.Builder
+	builder.WriteString("<root><items>")
+	for i := 0; i < 10000; i++ {
+		builder.WriteString(`<item id="x"><name>name</name><price>1</price></item>`)
+	}
+	builder.WriteString("</items></root>")
+	xml := builder.String()
+	parser := NewParser(WithSizeHint(40000))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader := strings.NewReader(xml)
+		_, err := parser.Parse(reader)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParseToMapWithProgress(t *testing.T) {
+	var builder strings.Builder
+	builder.WriteString("<root>")
+	for i := 0; i < 3000; i++ {
+		builder.WriteString("<item>x</item>")
+	}
+	builder.WriteString("</root>")
+	xml := builder.String()
+
+	type report struct {
+		bytesRead int64
+		elements  int
+	}
+	var reports []report
+	_, err := ParseToMap(strings.NewReader(xml), WithProgress(func(bytesRead int64, elements int) {
+		reports = append(reports, report{bytesRead, elements})
+	}))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+
+	if len(reports) != 3 {
+		t.Fatalf("progress reported %d times, want 3: %v", len(reports), reports)
+	}
+	if reports[0].elements != 1024 || reports[1].elements != 2048 {
+		t.Errorf("progress reports = %v, want reports at 1024 and 2048 elements", reports)
+	}
+	if last := reports[len(reports)-1]; last.elements != 3001 || last.bytesRead != int64(len(xml)) {
+		t.Errorf("final progress = %v, want 3001 elements and %d bytes", last, len(xml))
+	}
+}
@@ -0,0 +1,80 @@
+package xmlsurf
+
+import (
+	"io"
+	"sync"
+)
+
+// maxPooledNodes limits the size of parser states kept for reuse, so a single
+// huge document does not pin its memory in the pool
+const maxPooledNodes = 1 << 16
+
+// Parser parses documents with options that are evaluated once, including chained
+// value transforms, and reuses its internal structures across calls to reduce
+// allocations in high-throughput code. A Parser is safe for concurrent use if the
+// functions of its options are, as concurrent calls share them.
+type Parser struct {
+	options *ParseOptions
+	states  sync.Pool
+}
+
+// New returns a Parser using the given options
+func New(opts ...Option) *Parser {
+	return NewParser(opts...)
+}
+
+// NewParser returns a Parser using the given options, like New
+func NewParser(opts ...Option) *Parser {
+	options := DefaultParseOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	p := &Parser{options: options}
+	p.states.New = func() interface{} {
+		return newParseState(options.SizeHint)
+	}
+	return p
+}
+
+// ParseToMap parses XML from the reader like the package function ParseToMap,
+// using the options given to NewParser
+func (p *Parser) ParseToMap(reader io.Reader) (XMLMap, error) {
+	state := p.states.Get().(*parseState)
+	defer p.putState(state)
+
+	return parseDocumentWith(reader, p.options, nil, nil, state)
+}
+
+// ParseToMapOrdered parses XML from the reader like the package function ParseToMapOrdered,
+// using the options given to NewParser
+func (p *Parser) ParseToMapOrdered(reader io.Reader) (XMLMap, []string, error) {
+	state := p.states.Get().(*parseState)
+	defer p.putState(state)
+
+	order := make([]string, 0, max(50, p.options.SizeHint))
+	result, err := parseDocumentWith(reader, p.options, &order, nil, state)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, order, nil
+}
+
+// Parse is shorthand for ParseToMap
+func (p *Parser) Parse(reader io.Reader) (XMLMap, error) {
+	return p.ParseToMap(reader)
+}
+
+// ParseOrdered is shorthand for ParseToMapOrdered
+func (p *Parser) ParseOrdered(reader io.Reader) (XMLMap, []string, error) {
+	return p.ParseToMapOrdered(reader)
+}
+
+// putState returns a state to the pool unless it grew too large
+func (p *Parser) putState(state *parseState) {
+	if cap(state.nodes) > maxPooledNodes {
+		return
+	}
+	state.reset()
+	p.states.Put(state)
+}
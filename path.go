@@ -1,6 +1,8 @@
 package xmlsurf
 
 import (
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -23,100 +25,270 @@ func putPathBuilder(b *strings.Builder) {
 	pathBuilderPool.Put(b)
 }
 
-// comparePaths compares two XML paths for ordering
+// comparePaths compares two XML paths for ordering.
+// Paths are ordered by depth first and then segment by segment using compareSegments.
+// It does not allocate; to sort many paths, sortByPath splits each path only once.
 func comparePaths(pathI, pathJ string) bool {
-	partsI := strings.Split(pathI, "/")
-	partsJ := strings.Split(pathJ, "/")
-	depthI := len(partsI)
-	depthJ := len(partsJ)
+	depthI := strings.Count(pathI, "/")
+	depthJ := strings.Count(pathJ, "/")
 
 	// Compare by depth first
 	if depthI != depthJ {
 		return depthI < depthJ
 	}
 
-	// Compare each part of the path
-	for k := 0; k < depthI; k++ {
-		if partsI[k] != partsJ[k] {
-			// Special handling for SOAP and common XML elements
-			specialElements := map[string]int{
-				"Header":   1,
-				"Body":     2,
-				"Username": 1,
-				"Token":    2,
-				"child":    1,
-				"another":  2,
-			}
-
-			// Check for special elements
-			rankI := getElementRank(partsI[k], specialElements)
-			rankJ := getElementRank(partsJ[k], specialElements)
-
-			if rankI > 0 && rankJ > 0 {
-				return rankI < rankJ
-			}
-
-			// Default to lexicographical order
-			return partsI[k] < partsJ[k]
+	// Compare each segment of the path
+	for pathI != "" || pathJ != "" {
+		segmentI, restI := cutSegment(pathI)
+		segmentJ, restJ := cutSegment(pathJ)
+		if segmentI != segmentJ {
+			return compareSegments(segmentI, segmentJ)
 		}
+		pathI, pathJ = restI, restJ
+	}
+	return false
+}
+
+// cutSegment splits the first segment off a path
+func cutSegment(path string) (string, string) {
+	if i := strings.IndexByte(path, '/'); i != -1 {
+		return path[:i], path[i+1:]
 	}
+	return path, ""
+}
 
-	return pathI < pathJ
+// segmentKey is a path segment split into name and index once for sorting
+type segmentKey struct {
+	segment string
+	name    string
+	index   int
 }
 
-// getElementRank returns the rank of an element or 0 if not a special element
-func getElementRank(part string, specialElements map[string]int) int {
-	// Check for exact matches
-	if rank, ok := specialElements[part]; ok {
-		return rank
+// sortByPath sorts items stably by their paths in the order of comparePaths.
+// Each path is split into segments once up front instead of on every comparison.
+func sortByPath[T any](items []T, path func(T) string) {
+	if len(items) < 2 {
+		return
+	}
+
+	total := 0
+	for _, item := range items {
+		total += strings.Count(path(item), "/") + 1
 	}
+	segments := make([]segmentKey, 0, total)
 
-	// Check for contains matches
-	for name, rank := range specialElements {
-		if strings.Contains(part, name) {
-			return rank
+	type keyed struct {
+		item     T
+		segments []segmentKey
+	}
+	keys := make([]keyed, len(items))
+	for i, item := range items {
+		start := len(segments)
+		for rest, more := path(item), true; more; {
+			var segment string
+			more = strings.IndexByte(rest, '/') != -1
+			segment, rest = cutSegment(rest)
+			name, index := splitIndex(segment)
+			segments = append(segments, segmentKey{segment: segment, name: name, index: index})
 		}
+		keys[i] = keyed{item: item, segments: segments[start:len(segments):len(segments)]}
 	}
 
-	return 0
+	sort.SliceStable(keys, func(i, j int) bool {
+		segmentsI, segmentsJ := keys[i].segments, keys[j].segments
+		if len(segmentsI) != len(segmentsJ) {
+			return len(segmentsI) < len(segmentsJ)
+		}
+		for k := range segmentsI {
+			a, b := &segmentsI[k], &segmentsJ[k]
+			if a.segment == b.segment {
+				continue
+			}
+			if a.name != b.name {
+				return a.name < b.name
+			}
+			if a.index != b.index {
+				return a.index < b.index
+			}
+			return a.segment < b.segment
+		}
+		return false
+	})
+
+	for i := range keys {
+		items[i] = keys[i].item
+	}
 }
 
-// extractBasePath extracts the base path without indices from an XPath
-func extractBasePath(path string, builder *strings.Builder) string {
-	builder.Reset()
+// compareSegments orders path segments by element name and then numerically by index
+func compareSegments(segmentI, segmentJ string) bool {
+	nameI, indexI := splitIndex(segmentI)
+	nameJ, indexJ := splitIndex(segmentJ)
 
-	// Split path into segments
-	parts := strings.Split(path, "/")
+	if nameI != nameJ {
+		return nameI < nameJ
+	}
 
-	// Skip empty first part if it exists
-	start := 0
-	if len(parts) > 0 && parts[0] == "" {
-		start = 1
-		builder.WriteString("/")
+	if indexI != indexJ {
+		return indexI < indexJ
 	}
+	return segmentI < segmentJ
+}
 
-	// Process each part
-	for i := start; i < len(parts); i++ {
-		part := parts[i]
+// pathSegment is a step of a path as found by forEachSegment
+type pathSegment struct {
+	text    string // The whole segment, e.g. item[2]
+	name    string // The segment without its index, or the whole segment if it has none
+	base    string // The segment without any bracket groups, as in index-free paths
+	index   int    // The index, 1 if the segment has none
+	indexed bool   // Whether the segment ends in a numeric index
+	start   int    // The offset of the segment in the path
+	end     int    // The offset after the segment, where its separator starts
+	nested  bool   // Whether NestedSeparator follows the segment
+}
 
-		// Skip empty parts
-		if part == "" {
-			continue
+// forEachSegment calls fn for every segment of the path, the steps between "/" and
+// NestedSeparator, until fn returns false. Brackets are matched as groups, so "/",
+// NestedSeparator and "]" inside the quotes of an attribute predicate stay part of the
+// segment; only a numeric group at the end of a segment is its index.
+func forEachSegment(path string, fn func(segment pathSegment) bool) {
+	start := 0
+	if strings.HasPrefix(path, "/") {
+		start = 1
+	}
+	for start < len(path) {
+		segment := scanSegment(path, start)
+		if !fn(segment) || segment.end == len(path) {
+			return
+		}
+		start = segment.end + 1
+		if segment.nested {
+			// The embedded document has a path of its own
+			start = segment.end + len(NestedSeparator)
+			if strings.HasPrefix(path[start:], "/") {
+				start++
+			}
 		}
+	}
+}
 
-		// Remove index from part if present
-		if idx := strings.Index(part, "["); idx != -1 {
-			builder.WriteString(part[:idx])
-		} else {
-			builder.WriteString(part)
+// scanSegment returns the segment of the path starting at start
+func scanSegment(path string, start int) pathSegment {
+	firstOpen, lastOpen, lastClose := -1, -1, -1
+	depth := 0
+	var quote byte
+	end := start
+scan:
+	for ; end < len(path); end++ {
+		c := path[end]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case depth > 0 && (c == '\'' || c == '"'):
+			quote = c
+		case c == '[':
+			if depth == 0 {
+				lastOpen = end
+				if firstOpen == -1 {
+					firstOpen = end
+				}
+			}
+			depth++
+		case c == ']' && depth > 0:
+			depth--
+			if depth == 0 {
+				lastClose = end
+			}
+		case c == '/' || strings.HasPrefix(path[end:], NestedSeparator):
+			break scan
 		}
+	}
 
-		// Add separator unless it's the last part
-		if i < len(parts)-1 {
-			builder.WriteString("/")
+	segment := pathSegment{text: path[start:end], start: start, end: end, index: 1}
+	segment.nested = end < len(path) && path[end] != '/'
+	segment.name, segment.base = segment.text, segment.text
+	if firstOpen != -1 {
+		segment.base = path[start:firstOpen]
+	}
+	if lastOpen != -1 && lastClose == end-1 {
+		if index, err := strconv.Atoi(path[lastOpen+1 : lastClose]); err == nil {
+			segment.name, segment.index, segment.indexed = path[start:lastOpen], index, true
 		}
 	}
+	return segment
+}
+
+// rewriteSegments returns the path with every segment written by write, keeping the
+// separators between them
+func rewriteSegments(path string, b *strings.Builder, write func(b *strings.Builder, segment pathSegment)) string {
+	b.Reset()
+	b.Grow(len(path))
+	written := 0
+	forEachSegment(path, func(segment pathSegment) bool {
+		b.WriteString(path[written:segment.start])
+		write(b, segment)
+		written = segment.end
+		return true
+	})
+	b.WriteString(path[written:])
+	return b.String()
+}
+
+// lastPathSegment returns the last segment of the path
+func lastPathSegment(path string) pathSegment {
+	var last pathSegment
+	forEachSegment(path, func(segment pathSegment) bool {
+		last = segment
+		return true
+	})
+	return last
+}
+
+// extractBasePath extracts the base path without indices from an XPath
+func extractBasePath(path string, builder *strings.Builder) string {
+	if strings.IndexByte(path, '[') == -1 {
+		// Nothing to remove; return the path without copying it
+		return path
+	}
+	return rewriteSegments(path, builder, func(b *strings.Builder, segment pathSegment) {
+		b.WriteString(segment.base)
+	})
+}
+
+// appendBasePath appends the base path of path to dst like extractBasePath, for
+// looking up base paths in maps without allocating
+func appendBasePath(dst []byte, path string) []byte {
+	if strings.IndexByte(path, '[') == -1 {
+		return append(dst, path...)
+	}
+	written := 0
+	forEachSegment(path, func(segment pathSegment) bool {
+		dst = append(dst, path[written:segment.start]...)
+		dst = append(dst, segment.base...)
+		written = segment.end
+		return true
+	})
+	return append(dst, path[written:]...)
+}
 
-	result := builder.String()
-	return result
+// splitIndex splits a path segment like "item[2]" into its name and index.
+// Segments without an index are reported with index 1.
+func splitIndex(segment string) (string, int) {
+	if !strings.HasSuffix(segment, "]") {
+		return segment, 1
+	}
+	parsed := scanSegment(segment, 0)
+	return parsed.name, parsed.index
+}
+
+// isPathOrBelow reports whether path equals prefix or lies in its subtree,
+// including documents embedded in its value
+func isPathOrBelow(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || path[len(prefix)] == '/' ||
+		strings.HasPrefix(path[len(prefix):], NestedSeparator)
 }
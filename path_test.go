@@ -0,0 +1,153 @@
+package xmlsurf
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestComparePaths(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"/root", "/root/a", true},
+		{"/root/a", "/root", false},
+		{"/root/a", "/root/b", true},
+		{"/root/item[2]", "/root/item[10]", true},
+		{"/root/item[10]", "/root/item[2]", false},
+		{"/root/item", "/root/item[2]", true},
+		{"/s:Envelope/s:Body", "/s:Envelope/s:Header", true},
+		{"/root/a/z", "/root/b/a", true},
+		{"/root/a/", "/root/a/b", true},
+		{"/root/a", "/root/a", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			if got := comparePaths(tt.a, tt.b); got != tt.want {
+				t.Errorf("comparePaths(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortByPath(t *testing.T) {
+	paths := []string{
+		"/root/item[10]/name", "/root/item[2]/name", "/root/item[2]/@id", "/root",
+		"/s:Envelope/s:Body/x", "/s:Envelope/s:Header/y", "/root/item/name",
+		"/root/b", "/root/a[3]", "/root/a", "/root/a[x]", "",
+	}
+
+	want := make([]string, len(paths))
+	copy(want, paths)
+	sort.SliceStable(want, func(i, j int) bool {
+		return comparePaths(want[i], want[j])
+	})
+
+	got := make([]string, len(paths))
+	copy(got, paths)
+	sortByPath(got, func(path string) string { return path })
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("sortByPath() = %v, want %v", got, want)
+	}
+}
+
+func BenchmarkSortByPath(b *testing.B) {
+	paths := make([]string, 0, 50000)
+	for i := 50000; i > 0; i-- {
+		paths = append(paths, fmt.Sprintf("/catalog/items/item[%d]/name", i))
+	}
+	sorted := make([]string, len(paths))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		copy(sorted, paths)
+		sortByPath(sorted, func(path string) string { return path })
+	}
+}
+
+func TestForEachSegment(t *testing.T) {
+	tests := []struct {
+		path string
+		want []pathSegment
+	}{
+		{
+			path: "/a/item[2]/@id",
+			want: []pathSegment{
+				{text: "a", name: "a", base: "a", index: 1, start: 1, end: 2},
+				{text: "item[2]", name: "item", base: "item", index: 2, indexed: true, start: 3, end: 10},
+				{text: "@id", name: "@id", base: "@id", index: 1, start: 11, end: 14},
+			},
+		},
+		{
+			path: "/r/p[3]!/order/x",
+			want: []pathSegment{
+				{text: "r", name: "r", base: "r", index: 1, start: 1, end: 2},
+				{text: "p[3]", name: "p", base: "p", index: 3, indexed: true, start: 3, end: 7, nested: true},
+				{text: "order", name: "order", base: "order", index: 1, start: 9, end: 14},
+				{text: "x", name: "x", base: "x", index: 1, start: 15, end: 16},
+			},
+		},
+		{
+			// Separators and brackets inside predicate quotes belong to the segment
+			path: `/a/b[@href='x/y]!'][4]`,
+			want: []pathSegment{
+				{text: "a", name: "a", base: "a", index: 1, start: 1, end: 2},
+				{text: `b[@href='x/y]!'][4]`, name: `b[@href='x/y]!']`, base: "b", index: 4, indexed: true, start: 3, end: 22},
+			},
+		},
+		{
+			path: "/a/item[*]",
+			want: []pathSegment{
+				{text: "a", name: "a", base: "a", index: 1, start: 1, end: 2},
+				{text: "item[*]", name: "item[*]", base: "item", index: 1, start: 3, end: 10},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		var got []pathSegment
+		forEachSegment(tt.path, func(segment pathSegment) bool {
+			got = append(got, segment)
+			return true
+		})
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("forEachSegment(%q) = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSegmentRewrites(t *testing.T) {
+	path := `/r/p[2]!/o/b[@k='1]'][3]/@id`
+	var b strings.Builder
+
+	if got := extractBasePath(path, &b); got != "/r/p!/o/b/@id" {
+		t.Errorf("extractBasePath() = %q", got)
+	}
+	if got := string(appendBasePath(nil, path)); got != "/r/p!/o/b/@id" {
+		t.Errorf("appendBasePath() = %q", got)
+	}
+	if got, indices := pathTemplate(path); got != `/r/p[*]!/o/b[@k='1]'][*]/@id` || !reflect.DeepEqual(indices, []int{2, 3}) {
+		t.Errorf("pathTemplate() = %q, %v", got, indices)
+	}
+	if got := rebaseIndices(path, -1, &b); got != `/r/p[1]!/o/b[@k='1]'][2]/@id` {
+		t.Errorf("rebaseIndices() = %q", got)
+	}
+	if got := formatIndices(path, IndexDot, &b); got != `/r/p.2!/o/b[@k='1]'].3/@id` {
+		t.Errorf("formatIndices() = %q", got)
+	}
+	if got := bracketIndices("/r/p.2!/o/b.3/@id", IndexDot, &b); got != "/r/p[2]!/o/b[3]/@id" {
+		t.Errorf("bracketIndices() = %q", got)
+	}
+	if got := leafName("/r/p[2]!/o/ns:b[3]"); got != "b" {
+		t.Errorf("leafName() = %q", got)
+	}
+	if got, ok := recordOf("/r/p[2]!/o/b", "/r/p"); !ok || got != "/r/p[2]" {
+		t.Errorf("recordOf() = %q, %v", got, ok)
+	}
+}
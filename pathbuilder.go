@@ -0,0 +1,201 @@
+package xmlsurf
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// PathSegment is one element step of a Path
+type PathSegment struct {
+	Name  string
+	Index int // 1-based index, 0 when the element is not indexed
+}
+
+// Path builds and decomposes XMLMap paths, e.g.
+// P("root").Child("items").Index(2).Attr("id").String() == "/root/items[2]/@id".
+// Paths are immutable: every method returns a new Path. Invalid names or indices
+// are recorded and reported by Err; the first error is kept.
+// A Path addresses a single document; use JoinNestedPath and SplitNestedPath for
+// paths into embedded documents.
+type Path struct {
+	segments []PathSegment
+	attr     string
+	err      error
+}
+
+// P starts a path at the root element with the given name
+func P(root string) Path {
+	return Path{}.Child(root)
+}
+
+// Child returns the path of the child element with the given name
+func (p Path) Child(name string) Path {
+	if p.attr != "" {
+		return p.withErr(fmt.Errorf("path %s: child %q of an attribute", p, name))
+	}
+	if err := validatePathName(name); err != nil {
+		return p.withErr(fmt.Errorf("path %s: child: %w", p, err))
+	}
+	segments := make([]PathSegment, len(p.segments), len(p.segments)+1)
+	copy(segments, p.segments)
+	p.segments = append(segments, PathSegment{Name: name})
+	return p
+}
+
+// Index returns the path with the 1-based index i applied to its last element
+func (p Path) Index(i int) Path {
+	switch {
+	case len(p.segments) == 0:
+		return p.withErr(fmt.Errorf("path: index %d without an element", i))
+	case p.attr != "":
+		return p.withErr(fmt.Errorf("path %s: index %d of an attribute", p, i))
+	case i < 1:
+		return p.withErr(fmt.Errorf("path %s: index %d is not positive", p, i))
+	}
+	segments := make([]PathSegment, len(p.segments))
+	copy(segments, p.segments)
+	segments[len(segments)-1].Index = i
+	p.segments = segments
+	return p
+}
+
+// Attr returns the path of the attribute with the given name on the element
+func (p Path) Attr(name string) Path {
+	switch {
+	case len(p.segments) == 0:
+		return p.withErr(fmt.Errorf("path: attribute %q without an element", name))
+	case p.attr != "":
+		return p.withErr(fmt.Errorf("path %s: attribute %q of an attribute", p, name))
+	}
+	if err := validatePathName(name); err != nil {
+		return p.withErr(fmt.Errorf("path %s: attribute: %w", p, err))
+	}
+	p.attr = name
+	return p
+}
+
+// Parent returns the path of the enclosing element. The parent of an attribute
+// is the element it belongs to; the parent of the root is an empty Path.
+func (p Path) Parent() Path {
+	if p.attr != "" {
+		p.attr = ""
+		return p
+	}
+	if len(p.segments) > 0 {
+		p.segments = p.segments[: len(p.segments)-1 : len(p.segments)-1]
+	}
+	return p
+}
+
+// Segments returns a copy of the element steps of the path
+func (p Path) Segments() []PathSegment {
+	segments := make([]PathSegment, len(p.segments))
+	copy(segments, p.segments)
+	return segments
+}
+
+// Attribute returns the attribute name, or "" if the path addresses an element
+func (p Path) Attribute() string {
+	return p.attr
+}
+
+// IsAttr reports whether the path addresses an attribute
+func (p Path) IsAttr() bool {
+	return p.attr != ""
+}
+
+// Err returns the first error recorded while building the path
+func (p Path) Err() error {
+	return p.err
+}
+
+// String returns the path in XMLMap key form
+func (p Path) String() string {
+	pathBuilder := getPathBuilder()
+	defer putPathBuilder(pathBuilder)
+
+	for _, segment := range p.segments {
+		pathBuilder.WriteString("/")
+		pathBuilder.WriteString(segment.Name)
+		if segment.Index > 0 {
+			pathBuilder.WriteString("[")
+			pathBuilder.WriteString(strconv.Itoa(segment.Index))
+			pathBuilder.WriteString("]")
+		}
+	}
+	if p.attr != "" {
+		pathBuilder.WriteString("/@")
+		pathBuilder.WriteString(p.attr)
+	}
+	return pathBuilder.String()
+}
+
+// withErr returns p with err recorded, unless an earlier error is already recorded
+func (p Path) withErr(err error) Path {
+	if p.err == nil {
+		p.err = err
+	}
+	return p
+}
+
+// ParsePath decomposes and validates a path in XMLMap key form, e.g. "/root/items[2]/@id".
+// It does not accept paths into embedded documents or wildcard patterns.
+func ParsePath(path string) (Path, error) {
+	if !strings.HasPrefix(path, "/") {
+		return Path{}, fmt.Errorf("path %q: must start with /", path)
+	}
+
+	var p Path
+	steps := strings.Split(path[1:], "/")
+	for i, step := range steps {
+		if strings.HasPrefix(step, "@") {
+			if i != len(steps)-1 {
+				return Path{}, fmt.Errorf("path %q: attribute %s must be the last step", path, step)
+			}
+			if err := validatePathName(step[1:]); err != nil {
+				return Path{}, fmt.Errorf("path %q: %w", path, err)
+			}
+			if i == 0 {
+				return Path{}, fmt.Errorf("path %q: attribute %s without an element", path, step)
+			}
+			return p.Attr(step[1:]), nil
+		}
+
+		name, index := step, 0
+		if open := strings.Index(step, "["); open != -1 {
+			n, err := strconv.Atoi(strings.TrimSuffix(step[open+1:], "]"))
+			if err != nil || !strings.HasSuffix(step, "]") || n < 1 {
+				return Path{}, fmt.Errorf("path %q: malformed index in %q", path, step)
+			}
+			name, index = step[:open], n
+		}
+		if err := validatePathName(name); err != nil {
+			return Path{}, fmt.Errorf("path %q: %w", path, err)
+		}
+
+		p = p.Child(name)
+		if index > 0 {
+			p = p.Index(index)
+		}
+	}
+	return p, nil
+}
+
+// validatePathName checks that name can be used as an element or attribute name in a path
+func validatePathName(name string) error {
+	if name == "" {
+		return errors.New("empty name")
+	}
+	for i, r := range name {
+		switch {
+		case r == '/' || r == '[' || r == ']' || r == '@' || r == '!' || r == '*' || unicode.IsSpace(r):
+			return fmt.Errorf("invalid character %q in name %q", r, name)
+		case i == 0 && (unicode.IsDigit(r) || r == '-' || r == '.'):
+			return fmt.Errorf("name %q must not start with %q", name, r)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPathBuilder(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     Path
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "element with index and attribute",
+			path:     P("root").Child("items").Index(2).Attr("id"),
+			expected: "/root/items[2]/@id",
+		},
+		{
+			name:     "namespaced names",
+			path:     P("soap:Envelope").Child("soap:Body").Attr("xml:lang"),
+			expected: "/soap:Envelope/soap:Body/@xml:lang",
+		},
+		{
+			name:     "parent of attribute and element",
+			path:     P("root").Child("a").Index(3).Attr("id").Parent().Parent(),
+			expected: "/root",
+		},
+		{
+			name:    "invalid name",
+			path:    P("root").Child("a/b"),
+			wantErr: true,
+		},
+		{
+			name:    "zero index",
+			path:    P("root").Child("a").Index(0),
+			wantErr: true,
+		},
+		{
+			name:    "child of attribute",
+			path:    P("root").Attr("id").Child("a"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if (tt.path.Err() != nil) != tt.wantErr {
+				t.Fatalf("Err() = %v, wantErr %v", tt.path.Err(), tt.wantErr)
+			}
+			if !tt.wantErr && tt.path.String() != tt.expected {
+				t.Errorf("String() = %q, want %q", tt.path.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestPathBuilderImmutable(t *testing.T) {
+	base := P("root").Child("items")
+	first := base.Child("a")
+	second := base.Child("b")
+	indexed := base.Index(2)
+
+	if first.String() != "/root/items/a" || second.String() != "/root/items/b" {
+		t.Errorf("derived paths share storage: %s, %s", first, second)
+	}
+	if base.String() != "/root/items" || indexed.String() != "/root/items[2]" {
+		t.Errorf("Index() modified the original path: %s, %s", base, indexed)
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path     string
+		segments []PathSegment
+		attr     string
+		wantErr  bool
+	}{
+		{
+			path:     "/root/items[2]/@id",
+			segments: []PathSegment{{Name: "root"}, {Name: "items", Index: 2}},
+			attr:     "id",
+		},
+		{
+			path:     "/s:Envelope/s:Body/item",
+			segments: []PathSegment{{Name: "s:Envelope"}, {Name: "s:Body"}, {Name: "item"}},
+		},
+		{path: "root/a", wantErr: true},
+		{path: "/root//a", wantErr: true},
+		{path: "/root/a[0]", wantErr: true},
+		{path: "/root/a[x]", wantErr: true},
+		{path: "/root/a[1", wantErr: true},
+		{path: "/root/@id/a", wantErr: true},
+		{path: "/root/a[*]", wantErr: true},
+		{path: "/root/@", wantErr: true},
+		{path: "/@id", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			p, err := ParsePath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(p.Segments(), tt.segments) || p.Attribute() != tt.attr {
+				t.Errorf("ParsePath() = %v @%q, want %v @%q", p.Segments(), p.Attribute(), tt.segments, tt.attr)
+			}
+			if p.String() != tt.path {
+				t.Errorf("ParsePath().String() = %q, want %q", p.String(), tt.path)
+			}
+		})
+	}
+}
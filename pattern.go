@@ -0,0 +1,183 @@
+package xmlsurf
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// MatchAny is a pattern value matching any value
+	MatchAny = "*"
+	// MatchNonEmpty is a pattern value matching any non-empty value
+	MatchNonEmpty = "*non-empty*"
+	// MatchRegexpPrefix starts a pattern value holding a regular expression, e.g. "re:^[0-9]+$"
+	MatchRegexpPrefix = "re:"
+	// MatchLiteralPrefix starts a pattern value compared literally, e.g. `\*` matches "*"
+	MatchLiteralPrefix = `\`
+)
+
+// ErrInvalidPattern is returned by ValidatePattern for a pattern value that cannot be compiled
+var ErrInvalidPattern = errors.New("invalid pattern value")
+
+// MatchLiteral returns a pattern value matching exactly value. Values that would be
+// read as a matcher, such as "*" or "re:x", are escaped with MatchLiteralPrefix.
+func MatchLiteral(value string) string {
+	if value == MatchAny || value == MatchNonEmpty ||
+		strings.HasPrefix(value, MatchRegexpPrefix) || strings.HasPrefix(value, MatchLiteralPrefix) {
+		return MatchLiteralPrefix + value
+	}
+	return value
+}
+
+// ValidatePattern checks that every value of the pattern map can be compiled. It
+// returns an error wrapping ErrInvalidPattern for the first invalid regular
+// expression in path order, which PatternDiffs would otherwise report as a mismatch.
+func ValidatePattern(pattern XMLMap) error {
+	paths := make([]string, 0, len(pattern))
+	for path := range pattern {
+		paths = append(paths, path)
+	}
+	sortByPath(paths, func(path string) string { return path })
+	for _, path := range paths {
+		if _, err := compileValuePattern(pattern[path]); err != nil {
+			return fmt.Errorf("%w at %s: %v", ErrInvalidPattern, path, err)
+		}
+	}
+	return nil
+}
+
+// MatchesPattern returns true if m matches every entry of the pattern map.
+// See PatternDiffs for the pattern syntax.
+func (m XMLMap) MatchesPattern(pattern XMLMap, opts ...CompareOption) bool {
+	return len(m.PatternDiffs(pattern, opts...)) == 0
+}
+
+// PatternDiffs returns the differences between m and the entries of the pattern map.
+// Pattern paths may contain wildcards: "[*]" matches any index or no index, a "*"
+// segment matches any element and "@*" matches any attribute. Pattern values may be
+// MatchAny, MatchNonEmpty, a regular expression prefixed with MatchRegexpPrefix, or a
+// literal value, escaped with MatchLiteralPrefix if it looks like a matcher (see
+// MatchLiteral). Every path matching a pattern path must match its value; a pattern
+// path matching no path is reported as DiffMissing and a mismatching value as
+// DiffValue with the pattern value as RightValue. Invalid regular expressions never
+// match; use ValidatePattern to report them.
+func (m XMLMap) PatternDiffs(pattern XMLMap, opts ...CompareOption) []Diff {
+	options := newCompareOptions(opts)
+	m, pattern = prepareCompare(m, pattern, options)
+
+	diffs := make([]Diff, 0)
+	for patternPath, patternValue := range pattern {
+		pathMatcher := compilePathPattern(patternPath)
+		valueMatcher, _ := compileValuePattern(patternValue)
+
+		matched := false
+		for path, value := range m {
+			if !pathMatcher.match(path) {
+				continue
+			}
+			matched = true
+			if !valueMatcher(value) {
+				diffs = append(diffs, Diff{
+					Path:       path,
+					LeftValue:  value,
+					RightValue: patternValue,
+					Type:       DiffValue,
+				})
+			}
+		}
+		if !matched {
+			diffs = append(diffs, Diff{
+				Path:       patternPath,
+				RightValue: patternValue,
+				Type:       DiffMissing,
+			})
+		}
+	}
+
+	// Sort diffs by path for consistent output
+	if len(diffs) > 0 {
+		sort.Slice(diffs, func(i, j int) bool {
+			return diffs[i].Path < diffs[j].Path
+		})
+	}
+
+	return diffs
+}
+
+// pathPattern is a compiled path that may contain wildcard segments
+type pathPattern struct {
+	pattern  string
+	segments []string
+	wildcard bool
+}
+
+// compilePathPattern compiles a path pattern
+func compilePathPattern(pattern string) pathPattern {
+	return pathPattern{
+		pattern:  pattern,
+		segments: strings.Split(pattern, "/"),
+		wildcard: strings.Contains(pattern, "*"),
+	}
+}
+
+// match reports whether the path matches the pattern
+func (p pathPattern) match(path string) bool {
+	if !p.wildcard {
+		return path == p.pattern
+	}
+
+	segments := strings.Split(path, "/")
+	if len(segments) != len(p.segments) {
+		return false
+	}
+	for i, segment := range segments {
+		if !matchSegment(p.segments[i], segment) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchSegment reports whether a single path segment matches a pattern segment
+func matchSegment(pattern, segment string) bool {
+	switch pattern {
+	case segment:
+		return true
+	case "*":
+		return segment != "" && !strings.HasPrefix(segment, "@")
+	case "@*":
+		return strings.HasPrefix(segment, "@")
+	}
+
+	if strings.HasSuffix(pattern, "[*]") {
+		name := strings.TrimSuffix(pattern, "[*]")
+		segmentName, _ := splitIndex(segment)
+		return matchSegment(name, segmentName)
+	}
+	return false
+}
+
+// compileValuePattern compiles a pattern value into a matcher function. For an
+// invalid regular expression it returns the error with a matcher matching nothing.
+func compileValuePattern(pattern string) (func(string) bool, error) {
+	switch {
+	case pattern == MatchAny:
+		return func(string) bool { return true }, nil
+	case pattern == MatchNonEmpty:
+		return func(value string) bool { return value != "" }, nil
+	case strings.HasPrefix(pattern, MatchRegexpPrefix):
+		re, err := regexp.Compile(pattern[len(MatchRegexpPrefix):])
+		if err != nil {
+			return func(string) bool { return false }, err
+		}
+		return re.MatchString, nil
+	case strings.HasPrefix(pattern, MatchLiteralPrefix):
+		literal := pattern[len(MatchLiteralPrefix):]
+		return func(value string) bool { return value == literal }, nil
+	default:
+		return func(value string) bool { return value == pattern }, nil
+	}
+}
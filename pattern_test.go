@@ -0,0 +1,121 @@
+package xmlsurf
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestXMLMapPatternDiffs(t *testing.T) {
+	actual := XMLMap{
+		"/root/id":                "42",
+		"/root/items/item[1]/id":  "a1",
+		"/root/items/item[1]/@no": "1",
+		"/root/items/item[2]/id":  "",
+		"/root/items/item[2]/@no": "2",
+		"/root/single/item/id":    "s1",
+		"/root/meta/created":      "2024-01-01",
+		"/root/flags/mask":        "*",
+		"/root/flags/expr":        "re:x",
+	}
+
+	tests := []struct {
+		name     string
+		pattern  XMLMap
+		expected []Diff
+	}{
+		{
+			name: "literal and regexp values",
+			pattern: XMLMap{
+				"/root/id":           "42",
+				"/root/meta/created": `re:^\d{4}-\d{2}-\d{2}$`,
+			},
+			expected: []Diff{},
+		},
+		{
+			name: "index wildcard matches indexed and unindexed elements",
+			pattern: XMLMap{
+				"/root/items/item[*]/@no": "re:^[0-9]+$",
+				"/root/single/item[*]/id": MatchNonEmpty,
+			},
+			expected: []Diff{},
+		},
+		{
+			name: "every matched path must match the value",
+			pattern: XMLMap{
+				"/root/items/item[*]/id": MatchNonEmpty,
+			},
+			expected: []Diff{
+				{Path: "/root/items/item[2]/id", LeftValue: "", RightValue: MatchNonEmpty, Type: DiffValue},
+			},
+		},
+		{
+			name: "segment wildcards",
+			pattern: XMLMap{
+				"/root/*/created":        MatchAny,
+				"/root/items/item[1]/@*": "1",
+			},
+			expected: []Diff{},
+		},
+		{
+			name: "escaped literal values",
+			pattern: XMLMap{
+				"/root/flags/mask": `\*`,
+				"/root/flags/expr": `\re:x`,
+				"/root/id":         `\*`,
+			},
+			expected: []Diff{
+				{Path: "/root/id", LeftValue: "42", RightValue: `\*`, Type: DiffValue},
+			},
+		},
+		{
+			name: "unmatched path and invalid regexp",
+			pattern: XMLMap{
+				"/root/missing[*]": MatchAny,
+				"/root/id":         "re:(",
+			},
+			expected: []Diff{
+				{Path: "/root/id", LeftValue: "42", RightValue: "re:(", Type: DiffValue},
+				{Path: "/root/missing[*]", RightValue: MatchAny, Type: DiffMissing},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs := actual.PatternDiffs(tt.pattern)
+			if !reflect.DeepEqual(diffs, tt.expected) {
+				t.Errorf("PatternDiffs() = %v, want %v", diffs, tt.expected)
+			}
+			if matches := actual.MatchesPattern(tt.pattern); matches != (len(tt.expected) == 0) {
+				t.Errorf("MatchesPattern() = %v, want %v", matches, len(tt.expected) == 0)
+			}
+		})
+	}
+}
+
+func TestMatchLiteral(t *testing.T) {
+	for _, value := range []string{"plain", "", MatchAny, MatchNonEmpty, "re:^a$", `\x`, "*a"} {
+		pattern := XMLMap{"/root": MatchLiteral(value)}
+		if !(XMLMap{"/root": value}).MatchesPattern(pattern) {
+			t.Errorf("MatchLiteral(%q) = %q does not match the value", value, pattern["/root"])
+		}
+		if value != "" && (XMLMap{"/root": value + "x"}).MatchesPattern(pattern) {
+			t.Errorf("MatchLiteral(%q) = %q matches another value", value, pattern["/root"])
+		}
+	}
+	if got := MatchLiteral("plain"); got != "plain" {
+		t.Errorf("MatchLiteral() = %q, want the plain value unescaped", got)
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	if err := ValidatePattern(XMLMap{"/root/a": "re:^[0-9]+$", "/root/b": `\re:(`}); err != nil {
+		t.Errorf("ValidatePattern() error = %v, want nil", err)
+	}
+	err := ValidatePattern(XMLMap{"/root/a": "re:(", "/root/b": "re:["})
+	if !errors.Is(err, ErrInvalidPattern) || !strings.Contains(err.Error(), "/root/a") {
+		t.Errorf("ValidatePattern() error = %v, want ErrInvalidPattern at /root/a", err)
+	}
+}
@@ -0,0 +1,60 @@
+package xmlsurf
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Position is a location in the parsed input
+type Position struct {
+	Line   int   // 1-based line number
+	Column int   // 1-based column, counted in bytes
+	Offset int64 // 0-based byte offset
+}
+
+// String returns the position as line:column
+func (p Position) String() string {
+	return strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+}
+
+// ParseToMapWithPositions parses XML like ParseToMap and additionally returns the
+// position of every path in the input. Element paths point at the start tag of the
+// element; attribute paths point at the start tag of their element, and paths into
+// embedded documents at the element holding the document.
+func ParseToMapWithPositions(reader io.Reader, opts ...Option) (XMLMap, map[string]Position, error) {
+	options := DefaultParseOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	positions := make(map[string]Position)
+	result, err := parseDocumentWith(reader, options, nil, positions, newParseState(options.SizeHint))
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, positions, nil
+}
+
+// decoderPosition returns the current position of the decoder
+func decoderPosition(decoder *xml.Decoder) Position {
+	line, column := decoder.InputPos()
+	return Position{Line: line, Column: column, Offset: decoder.InputOffset()}
+}
+
+// positions stores the position of every path of result, given the start
+// position of each recorded element in document order
+func (b *documentBuilder) positions(starts []Position, result XMLMap, positions map[string]Position) {
+	byElement := make(map[string]Position, len(b.state.nodes))
+	for i, node := range b.state.nodes {
+		byElement[node.path] = starts[i]
+	}
+	for path := range result {
+		element := SplitNestedPath(path)[0]
+		if isAttributePath(element) {
+			element = element[:strings.LastIndex(element, "/")]
+		}
+		positions[path] = byElement[element]
+	}
+}
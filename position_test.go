@@ -0,0 +1,59 @@
+package xmlsurf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseToMapWithPositions(t *testing.T) {
+	input := `<?xml version="1.0"?>
+<root>
+  <item id="1">a</item>
+  <item id="2">
+    b
+  </item>
+	<payload>&lt;order&gt;&lt;id&gt;7&lt;/id&gt;&lt;/order&gt;</payload>
+</root>`
+
+	m, positions, err := ParseToMapWithPositions(strings.NewReader(input), WithUnwrapNested(true))
+	if err != nil {
+		t.Fatalf("ParseToMapWithPositions() error = %v", err)
+	}
+	if len(positions) != len(m) {
+		t.Errorf("got %d positions for %d paths", len(positions), len(m))
+	}
+
+	tests := []struct {
+		path string
+		want Position
+	}{
+		{"/root/item[1]", Position{Line: 3, Column: 3, Offset: 31}},
+		{"/root/item[1]/@id", Position{Line: 3, Column: 3, Offset: 31}},
+		{"/root/item[2]", Position{Line: 4, Column: 3, Offset: 55}},
+		{"/root/payload!/order/id", Position{Line: 7, Column: 2, Offset: 86}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := positions[tt.path]
+			if !ok {
+				t.Fatalf("no position for %s", tt.path)
+			}
+			if got != tt.want {
+				t.Errorf("position = %#v, want %#v", got, tt.want)
+			}
+			if input[got.Offset] != '<' {
+				t.Errorf("offset %d points at %q, want a start tag", got.Offset, input[got.Offset])
+			}
+		})
+	}
+
+	if got := positions["/root/item[2]"].String(); got != "4:3" {
+		t.Errorf("String() = %q, want 4:3", got)
+	}
+}
+
+func TestParseToMapWithPositionsError(t *testing.T) {
+	if _, _, err := ParseToMapWithPositions(strings.NewReader("<root><a></root>")); err == nil {
+		t.Error("expected an error for malformed XML")
+	}
+}
@@ -0,0 +1,8 @@
+module github.com/bmcszk/xmlsurf/protomap
+
+go 1.22
+
+require (
+	github.com/bmcszk/xmlsurf v0.0.0-20261017235040-003663345f00
+	google.golang.org/protobuf v1.36.6
+)
@@ -0,0 +1,466 @@
+// Package protomap converts xmlsurf maps to and from protobuf messages described at
+// runtime by a message descriptor, e.g. to translate SOAP payloads for gRPC services.
+package protomap
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bmcszk/xmlsurf"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func init() {
+	xmlsurf.RegisterModule("protomap")
+}
+
+// Option configures the conversion
+type Option func(*options)
+
+// options holds the evaluated conversion options
+type options struct {
+	attributePrefix string
+	textField       string
+	discardUnknown  bool
+}
+
+// WithAttributePrefix returns an Option that maps attributes to fields named with the
+// prefix, e.g. attribute id to field attr_id for the prefix "attr_". Without a prefix
+// attributes map to fields named like them, and FromMessage writes all fields as elements.
+func WithAttributePrefix(prefix string) Option {
+	return func(o *options) {
+		o.attributePrefix = prefix
+	}
+}
+
+// WithTextField returns an Option that names the field holding the value of an element
+// that is converted to a message; the default is "value"
+func WithTextField(name string) Option {
+	return func(o *options) {
+		o.textField = name
+	}
+}
+
+// WithDiscardUnknown returns an Option that skips elements and attributes without a
+// matching field instead of failing
+func WithDiscardUnknown(discard bool) Option {
+	return func(o *options) {
+		o.discardUnknown = discard
+	}
+}
+
+// newOptions evaluates options on top of the defaults
+func newOptions(opts []Option) *options {
+	o := &options{textField: "value"}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// ToMessage converts the map into a new message of the given type. The root element
+// corresponds to the message; child elements and attributes set the fields of the same
+// name, compared case-insensitively and ignoring underscores, hyphens and namespace prefixes,
+// so <OrderId> sets order_id. Repeated elements fill repeated fields in index order
+// and elements with children fill message fields. Scalar values are parsed according
+// to the field kind; bytes are base64 encoded and enums are given by name or number.
+// Map fields are not supported.
+func ToMessage(m xmlsurf.XMLMap, desc protoreflect.MessageDescriptor, opts ...Option) (*dynamicpb.Message, error) {
+	o := newOptions(opts)
+
+	wrapped, err := m.WrapNested()
+	if err != nil {
+		return nil, err
+	}
+	root, rootPath, err := buildTree(wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := dynamicpb.NewMessage(desc)
+	if err := o.fillMessage(msg, root, rootPath); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// FromMessage converts a message into a map with the given root element name.
+// Set fields are written in declaration order as child elements named like the field;
+// repeated fields with more than one item get indices, as ParseToMap assigns them.
+// Fields named with the attribute prefix become attributes, the text field becomes
+// the element value, and a set message field without set fields becomes an empty element.
+func FromMessage(msg protoreflect.Message, root string, opts ...Option) (xmlsurf.XMLMap, error) {
+	o := newOptions(opts)
+	result := make(xmlsurf.XMLMap)
+	if err := o.writeMessage(result, "/"+root, msg); err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		result["/"+root] = ""
+	}
+	return result, nil
+}
+
+// element is an element of the map arranged as a tree
+type element struct {
+	value    string
+	attrs    map[string]string
+	children map[string]map[int]*element // By name and 1-based index, 0 if not indexed
+	names    []string                    // Child names in first-seen order
+}
+
+// child returns the child element with the name and index, creating it if needed
+func (e *element) child(name string, index int) *element {
+	if e.children == nil {
+		e.children = make(map[string]map[int]*element)
+	}
+	if e.children[name] == nil {
+		e.children[name] = make(map[int]*element)
+		e.names = append(e.names, name)
+	}
+	child := e.children[name][index]
+	if child == nil {
+		child = &element{}
+		e.children[name][index] = child
+	}
+	return child
+}
+
+// instances returns the elements with the name in index order
+func (e *element) instances(name string) []*element {
+	indices := make([]int, 0, len(e.children[name]))
+	for index := range e.children[name] {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+	result := make([]*element, 0, len(indices))
+	for _, index := range indices {
+		result = append(result, e.children[name][index])
+	}
+	return result
+}
+
+// buildTree arranges the paths of the map as a tree below the root element
+func buildTree(m xmlsurf.XMLMap) (*element, string, error) {
+	if len(m) == 0 {
+		return nil, "", errors.New("empty XMLMap")
+	}
+
+	paths := make([]string, 0, len(m))
+	for path := range m {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	root := &element{}
+	rootName := ""
+	for _, path := range paths {
+		p, err := xmlsurf.ParsePath(path)
+		if err != nil {
+			return nil, "", err
+		}
+		segments := p.Segments()
+		if rootName == "" {
+			rootName = segments[0].Name
+		} else if segments[0].Name != rootName {
+			return nil, "", fmt.Errorf("multiple root elements: %s and %s", rootName, segments[0].Name)
+		}
+
+		e := root
+		for _, segment := range segments[1:] {
+			e = e.child(segment.Name, segment.Index)
+		}
+		if p.IsAttr() {
+			if e.attrs == nil {
+				e.attrs = make(map[string]string)
+			}
+			e.attrs[p.Attribute()] = m[path]
+		} else {
+			e.value = m[path]
+		}
+	}
+	return root, "/" + rootName, nil
+}
+
+// normalizeName returns the form in which element and field names are compared
+func normalizeName(name string) string {
+	if idx := strings.LastIndex(name, ":"); idx != -1 {
+		name = name[idx+1:]
+	}
+	name = strings.NewReplacer("_", "", "-", "").Replace(name)
+	return strings.ToLower(name)
+}
+
+// fieldByName returns the field of the message matching an element or attribute name
+func fieldByName(desc protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	normalized := normalizeName(name)
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if normalizeName(string(fd.Name())) == normalized {
+			return fd
+		}
+	}
+	return nil
+}
+
+// fillMessage sets the fields of msg from the element at path
+func (o *options) fillMessage(msg protoreflect.Message, e *element, path string) error {
+	desc := msg.Descriptor()
+
+	if e.value != "" {
+		fd := fieldByName(desc, o.textField)
+		if fd == nil || fd.IsList() || isMessage(fd) {
+			if !o.discardUnknown {
+				return fmt.Errorf("%s: value without a scalar %q field in %s", path, o.textField, desc.FullName())
+			}
+		} else if err := o.setScalar(msg, fd, e.value, path); err != nil {
+			return err
+		}
+	}
+
+	attrNames := make([]string, 0, len(e.attrs))
+	for name := range e.attrs {
+		attrNames = append(attrNames, name)
+	}
+	sort.Strings(attrNames)
+	for _, name := range attrNames {
+		attrPath := path + "/@" + name
+		fd := fieldByName(desc, o.attributePrefix+localName(name))
+		if fd == nil || fd.IsList() || isMessage(fd) {
+			if o.discardUnknown {
+				continue
+			}
+			return fmt.Errorf("%s: no scalar field for attribute in %s", attrPath, desc.FullName())
+		}
+		if err := o.setScalar(msg, fd, e.attrs[name], attrPath); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range e.names {
+		childPath := path + "/" + name
+		fd := fieldByName(desc, name)
+		if fd == nil {
+			if o.discardUnknown {
+				continue
+			}
+			return fmt.Errorf("%s: no field for element in %s", childPath, desc.FullName())
+		}
+		if fd.IsMap() {
+			return fmt.Errorf("%s: map field %s is not supported", childPath, fd.FullName())
+		}
+
+		instances := e.instances(name)
+		if !fd.IsList() && len(instances) > 1 {
+			return fmt.Errorf("%s: repeated element for singular field %s", childPath, fd.FullName())
+		}
+
+		var list protoreflect.List
+		if fd.IsList() {
+			list = msg.Mutable(fd).List()
+		}
+		for i, instance := range instances {
+			instancePath := childPath
+			if len(instances) > 1 {
+				instancePath += "[" + strconv.Itoa(i+1) + "]"
+			}
+			value, err := o.fieldValue(msg, list, fd, instance, instancePath)
+			if err != nil {
+				return err
+			}
+			if list != nil {
+				list.Append(value)
+			} else {
+				msg.Set(fd, value)
+			}
+		}
+	}
+	return nil
+}
+
+// fieldValue converts the element at path into a value of the field
+func (o *options) fieldValue(msg protoreflect.Message, list protoreflect.List, fd protoreflect.FieldDescriptor, e *element, path string) (protoreflect.Value, error) {
+	if isMessage(fd) {
+		var value protoreflect.Value
+		if list != nil {
+			value = list.NewElement()
+		} else {
+			value = msg.NewField(fd)
+		}
+		if err := o.fillMessage(value.Message(), e, path); err != nil {
+			return protoreflect.Value{}, err
+		}
+		return value, nil
+	}
+
+	if len(e.children) > 0 || len(e.attrs) > 0 {
+		return protoreflect.Value{}, fmt.Errorf("%s: element with children or attributes for scalar field %s", path, fd.FullName())
+	}
+	return parseScalar(fd, e.value, path)
+}
+
+// setScalar parses value and sets the scalar field
+func (o *options) setScalar(msg protoreflect.Message, fd protoreflect.FieldDescriptor, value, path string) error {
+	v, err := parseScalar(fd, value, path)
+	if err != nil {
+		return err
+	}
+	msg.Set(fd, v)
+	return nil
+}
+
+// parseScalar converts a string value at path into a value of the scalar field
+func parseScalar(fd protoreflect.FieldDescriptor, value, path string) (protoreflect.Value, error) {
+	trimmed := strings.TrimSpace(value)
+	var (
+		v   protoreflect.Value
+		err error
+	)
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(value), nil
+	case protoreflect.BytesKind:
+		var b []byte
+		b, err = base64.StdEncoding.DecodeString(trimmed)
+		v = protoreflect.ValueOfBytes(b)
+	case protoreflect.BoolKind:
+		var b bool
+		b, err = strconv.ParseBool(trimmed)
+		v = protoreflect.ValueOfBool(b)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		var n int64
+		n, err = strconv.ParseInt(trimmed, 10, 32)
+		v = protoreflect.ValueOfInt32(int32(n))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		var n int64
+		n, err = strconv.ParseInt(trimmed, 10, 64)
+		v = protoreflect.ValueOfInt64(n)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		var n uint64
+		n, err = strconv.ParseUint(trimmed, 10, 32)
+		v = protoreflect.ValueOfUint32(uint32(n))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		var n uint64
+		n, err = strconv.ParseUint(trimmed, 10, 64)
+		v = protoreflect.ValueOfUint64(n)
+	case protoreflect.FloatKind:
+		var f float64
+		f, err = strconv.ParseFloat(trimmed, 32)
+		v = protoreflect.ValueOfFloat32(float32(f))
+	case protoreflect.DoubleKind:
+		var f float64
+		f, err = strconv.ParseFloat(trimmed, 64)
+		v = protoreflect.ValueOfFloat64(f)
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(trimmed)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		var n int64
+		n, err = strconv.ParseInt(trimmed, 10, 32)
+		v = protoreflect.ValueOfEnum(protoreflect.EnumNumber(n))
+	default:
+		return protoreflect.Value{}, fmt.Errorf("%s: unsupported field kind %s", path, fd.Kind())
+	}
+
+	if err != nil {
+		return protoreflect.Value{}, fmt.Errorf("%s: invalid %s value %q for field %s", path, fd.Kind(), value, fd.FullName())
+	}
+	return v, nil
+}
+
+// writeMessage stores the set fields of msg below the element at path
+func (o *options) writeMessage(result xmlsurf.XMLMap, path string, msg protoreflect.Message) error {
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !msg.Has(fd) {
+			continue
+		}
+		if fd.IsMap() {
+			return fmt.Errorf("%s: map field %s is not supported", path, fd.FullName())
+		}
+
+		name := string(fd.Name())
+		value := msg.Get(fd)
+		switch {
+		case fd.IsList():
+			list := value.List()
+			for j := 0; j < list.Len(); j++ {
+				childPath := path + "/" + name
+				if list.Len() > 1 {
+					childPath += "[" + strconv.Itoa(j+1) + "]"
+				}
+				if err := o.writeValue(result, childPath, fd, list.Get(j)); err != nil {
+					return err
+				}
+			}
+		case isMessage(fd):
+			if err := o.writeValue(result, path+"/"+name, fd, value); err != nil {
+				return err
+			}
+		case name == o.textField:
+			result[path] = formatScalar(fd, value)
+		case o.attributePrefix != "" && strings.HasPrefix(name, o.attributePrefix):
+			result[path+"/@"+strings.TrimPrefix(name, o.attributePrefix)] = formatScalar(fd, value)
+		default:
+			result[path+"/"+name] = formatScalar(fd, value)
+		}
+	}
+	return nil
+}
+
+// writeValue stores a field value as the element at path
+func (o *options) writeValue(result xmlsurf.XMLMap, path string, fd protoreflect.FieldDescriptor, value protoreflect.Value) error {
+	if !isMessage(fd) {
+		result[path] = formatScalar(fd, value)
+		return nil
+	}
+	before := len(result)
+	if err := o.writeMessage(result, path, value.Message()); err != nil {
+		return err
+	}
+	if len(result) == before {
+		// Keep the presence of an empty message
+		result[path] = ""
+	}
+	return nil
+}
+
+// formatScalar converts a scalar field value into its string form
+func formatScalar(fd protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(value.Bytes())
+	case protoreflect.FloatKind:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 32)
+	case protoreflect.DoubleKind:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 64)
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(value.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return strconv.FormatInt(int64(value.Enum()), 10)
+	default:
+		return value.String()
+	}
+}
+
+// isMessage reports whether the field holds messages
+func isMessage(fd protoreflect.FieldDescriptor) bool {
+	return fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind
+}
+
+// localName returns a name without its namespace prefix
+func localName(name string) string {
+	if idx := strings.LastIndex(name, ":"); idx != -1 {
+		return name[idx+1:]
+	}
+	return name
+}
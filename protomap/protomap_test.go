@@ -0,0 +1,205 @@
+package protomap
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bmcszk/xmlsurf"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// orderDescriptor returns the descriptor of
+//
+//	message Order {
+//	  string attr_id = 1;
+//	  string customer_name = 2;
+//	  repeated Line line = 3;
+//	  Status status = 4;
+//	  bool paid = 5;
+//	  Note note = 6;
+//	  message Line { string sku = 1; int32 qty = 2; double price = 3; }
+//	  message Note { string attr_lang = 1; string value = 2; }
+//	  enum Status { UNKNOWN = 0; OPEN = 1; SHIPPED = 2; }
+//	}
+func orderDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, label descriptorpb.FieldDescriptorProto_Label, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Type:   typ.Enum(),
+			Label:  label.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	str := descriptorpb.FieldDescriptorProto_TYPE_STRING
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("order.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Order"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("attr_id", 1, str, optional, ""),
+				field("customer_name", 2, str, optional, ""),
+				field("line", 3, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, repeated, ".test.Order.Line"),
+				field("status", 4, descriptorpb.FieldDescriptorProto_TYPE_ENUM, optional, ".test.Order.Status"),
+				field("paid", 5, descriptorpb.FieldDescriptorProto_TYPE_BOOL, optional, ""),
+				field("note", 6, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, optional, ".test.Order.Note"),
+			},
+			NestedType: []*descriptorpb.DescriptorProto{
+				{
+					Name: proto.String("Line"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("sku", 1, str, optional, ""),
+						field("qty", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, optional, ""),
+						field("price", 3, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, optional, ""),
+					},
+				},
+				{
+					Name: proto.String("Note"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("attr_lang", 1, str, optional, ""),
+						field("value", 2, str, optional, ""),
+					},
+				},
+			},
+			EnumType: []*descriptorpb.EnumDescriptorProto{{
+				Name: proto.String("Status"),
+				Value: []*descriptorpb.EnumValueDescriptorProto{
+					{Name: proto.String("UNKNOWN"), Number: proto.Int32(0)},
+					{Name: proto.String("OPEN"), Number: proto.Int32(1)},
+					{Name: proto.String("SHIPPED"), Number: proto.Int32(2)},
+				},
+			}},
+		}},
+	}
+
+	fd, err := protodesc.NewFile(file, nil)
+	if err != nil {
+		t.Fatalf("protodesc.NewFile() error = %v", err)
+	}
+	return fd.Messages().ByName("Order")
+}
+
+func TestToMessage(t *testing.T) {
+	desc := orderDescriptor(t)
+	input := `<ns:Order xmlns:ns="urn:orders" id="42">
+	<ns:CustomerName>Ann</ns:CustomerName>
+	<ns:Line><ns:Sku>A</ns:Sku><ns:Qty>2</ns:Qty><ns:Price>1.5</ns:Price></ns:Line>
+	<ns:Line><ns:Sku>B</ns:Sku><ns:Qty>1</ns:Qty></ns:Line>
+	<ns:Status>SHIPPED</ns:Status>
+	<ns:Paid>true</ns:Paid>
+	<ns:Note lang="en">Leave at door</ns:Note>
+</ns:Order>`
+	m, err := xmlsurf.ParseToMap(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+
+	msg, err := ToMessage(m, desc, WithAttributePrefix("attr_"))
+	if err != nil {
+		t.Fatalf("ToMessage() error = %v", err)
+	}
+
+	fields := desc.Fields()
+	if got := msg.Get(fields.ByName("attr_id")).String(); got != "42" {
+		t.Errorf("attr_id = %q, want 42", got)
+	}
+	if got := msg.Get(fields.ByName("customer_name")).String(); got != "Ann" {
+		t.Errorf("customer_name = %q, want Ann", got)
+	}
+	lines := msg.Get(fields.ByName("line")).List()
+	if lines.Len() != 2 {
+		t.Fatalf("got %d lines, want 2", lines.Len())
+	}
+	lineFields := desc.Messages().ByName("Line").Fields()
+	if got := lines.Get(0).Message().Get(lineFields.ByName("price")).Float(); got != 1.5 {
+		t.Errorf("line[0].price = %v, want 1.5", got)
+	}
+	if got := lines.Get(1).Message().Get(lineFields.ByName("sku")).String(); got != "B" {
+		t.Errorf("line[1].sku = %q, want B", got)
+	}
+	if got := msg.Get(fields.ByName("status")).Enum(); got != 2 {
+		t.Errorf("status = %d, want 2", got)
+	}
+	note := msg.Get(fields.ByName("note")).Message()
+	if got := note.Get(note.Descriptor().Fields().ByName("value")).String(); got != "Leave at door" {
+		t.Errorf("note.value = %q", got)
+	}
+
+	// And back, with proto field names as element names
+	back, err := FromMessage(msg, "Order", WithAttributePrefix("attr_"))
+	if err != nil {
+		t.Fatalf("FromMessage() error = %v", err)
+	}
+	want := xmlsurf.XMLMap{
+		"/Order/@id":           "42",
+		"/Order/customer_name": "Ann",
+		"/Order/line[1]/sku":   "A",
+		"/Order/line[1]/qty":   "2",
+		"/Order/line[1]/price": "1.5",
+		"/Order/line[2]/sku":   "B",
+		"/Order/line[2]/qty":   "1",
+		"/Order/status":        "SHIPPED",
+		"/Order/paid":          "true",
+		"/Order/note/@lang":    "en",
+		"/Order/note":          "Leave at door",
+	}
+	if !reflect.DeepEqual(back, want) {
+		t.Errorf("FromMessage() = %v, want %v", back, want)
+	}
+}
+
+func TestToMessageErrors(t *testing.T) {
+	desc := orderDescriptor(t)
+
+	tests := []struct {
+		name string
+		m    xmlsurf.XMLMap
+		want string
+	}{
+		{"unknown element", xmlsurf.XMLMap{"/Order/Unknown": "x"}, "no field for element"},
+		{"unknown attribute", xmlsurf.XMLMap{"/Order/@other": "x"}, "no scalar field for attribute"},
+		{"invalid number", xmlsurf.XMLMap{"/Order/line/qty": "many"}, `invalid int32 value "many"`},
+		{"repeated singular", xmlsurf.XMLMap{"/Order/paid[1]": "true", "/Order/paid[2]": "false"}, "repeated element for singular field"},
+		{"children for scalar", xmlsurf.XMLMap{"/Order/paid/x": "1"}, "children or attributes for scalar field"},
+		{"multiple roots", xmlsurf.XMLMap{"/Order/paid": "true", "/Other/paid": "true"}, "multiple root elements"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ToMessage(tt.m, desc, WithAttributePrefix("attr_"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ToMessage() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	msg, err := ToMessage(xmlsurf.XMLMap{"/Order/Unknown": "x", "/Order/paid": "1"}, desc, WithDiscardUnknown(true))
+	if err != nil {
+		t.Fatalf("ToMessage() with WithDiscardUnknown error = %v", err)
+	}
+	if !msg.Get(desc.Fields().ByName("paid")).Bool() {
+		t.Error("paid = false, want true")
+	}
+}
+
+func TestModuleRegistered(t *testing.T) {
+	for _, module := range xmlsurf.Capabilities().Modules {
+		if module == "protomap" {
+			return
+		}
+	}
+	t.Errorf("Capabilities().Modules = %v, want protomap", xmlsurf.Capabilities().Modules)
+}
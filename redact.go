@@ -0,0 +1,59 @@
+package xmlsurf
+
+import "strings"
+
+// RedactedValue replaces the values masked by Redact and WithRedactPatterns
+const RedactedValue = "[REDACTED]"
+
+// Redact replaces the values at the paths, and everything below them, with
+// RedactedValue, so the map can be logged without exposing secrets. Paths may
+// contain the wildcards of PatternDiffs, e.g. "/Envelope/Header/*/Password" or
+// "/users/user[*]/@token". Redact modifies the map; Clone it first to keep the
+// original values. The temporary files of values spilled by WithSpillover are kept,
+// as clones may still refer to them; RemoveSpilled removes them.
+func (m XMLMap) Redact(paths ...string) {
+	m.redact(paths, false)
+}
+
+// redact replaces the values at the paths with RedactedValue, removing the temporary
+// files of spilled values if removeSpilled is set, for maps no other map shares files with
+func (m XMLMap) redact(paths []string, removeSpilled bool) {
+	if len(paths) == 0 {
+		return
+	}
+	patterns := compilePathPatterns(paths)
+	for path := range m {
+		if matchesPathOrAncestor(patterns, path) {
+			if file, ok := SpilledFile(m[path]); ok && removeSpilled {
+				removeSpilledFile(file)
+			}
+			m[path] = RedactedValue
+		}
+	}
+}
+
+// compilePathPatterns compiles the paths given to Redact or WithAllowedPaths
+func compilePathPatterns(paths []string) []pathPattern {
+	patterns := make([]pathPattern, len(paths))
+	for i, path := range paths {
+		patterns[i] = compilePathPattern(path)
+	}
+	return patterns
+}
+
+// matchesPathOrAncestor reports whether a pattern matches the path or one of its ancestors,
+// including the element holding an embedded document
+func matchesPathOrAncestor(patterns []pathPattern, path string) bool {
+	for {
+		for _, pattern := range patterns {
+			if pattern.match(path) {
+				return true
+			}
+		}
+		end := strings.LastIndexAny(path, "/"+NestedSeparator)
+		if end <= 0 {
+			return false
+		}
+		path = path[:end]
+	}
+}
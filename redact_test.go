@@ -0,0 +1,124 @@
+package xmlsurf
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	m := XMLMap{
+		"/Envelope/Header/Security/UsernameToken/Username": "alice",
+		"/Envelope/Header/Security/UsernameToken/Password": "secret",
+		"/Envelope/Header/Security/@mustUnderstand":        "1",
+		"/Envelope/Header/MessageID":                       "42",
+		"/Envelope/Body/users/user[1]/@token":              "t1",
+		"/Envelope/Body/users/user[2]/@token":              "t2",
+		"/Envelope/Body/users/user[2]/name":                "bob",
+		"/Envelope/Body/payload!/inner/apiKey":             "k",
+		"/Envelope/Body/SecurityNote":                      "public",
+	}
+
+	tests := []struct {
+		name  string
+		paths []string
+		want  []string
+	}{
+		{name: "none"},
+		{
+			name:  "subtree",
+			paths: []string{"/Envelope/Header/Security"},
+			want: []string{
+				"/Envelope/Header/Security/UsernameToken/Username",
+				"/Envelope/Header/Security/UsernameToken/Password",
+				"/Envelope/Header/Security/@mustUnderstand",
+			},
+		},
+		{
+			name:  "wildcards",
+			paths: []string{"/Envelope/Header/*/*/Password", "/Envelope/Body/users/user[*]/@*"},
+			want: []string{
+				"/Envelope/Header/Security/UsernameToken/Password",
+				"/Envelope/Body/users/user[1]/@token",
+				"/Envelope/Body/users/user[2]/@token",
+			},
+		},
+		{
+			name:  "embedded document",
+			paths: []string{"/Envelope/Body/payload"},
+			want:  []string{"/Envelope/Body/payload!/inner/apiKey"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := m.Clone()
+			got.Redact(tt.paths...)
+
+			want := m.Clone()
+			for _, path := range tt.want {
+				want[path] = RedactedValue
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Redact(%v) = %v, want %v", tt.paths, got, want)
+			}
+		})
+	}
+}
+
+func TestWithRedactPatterns(t *testing.T) {
+	input := `<Envelope><Header><Security><Password>secret</Password></Security></Header>` +
+		`<Body><payload>&lt;auth&gt;&lt;key&gt;k&lt;/key&gt;&lt;/auth&gt;</payload><id>7</id></Body></Envelope>`
+
+	m, err := ParseToMap(strings.NewReader(input), WithUnwrapNested(true),
+		WithRedactPatterns("/Envelope/Header/Security"), WithRedactPatterns("/Envelope/Body/payload!/auth/key"))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+	want := XMLMap{
+		"/Envelope/Header/Security/Password": RedactedValue,
+		"/Envelope/Body/payload!/auth/key":   RedactedValue,
+		"/Envelope/Body/id":                  "7",
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("ParseToMap() = %v, want %v", m, want)
+	}
+}
+
+func TestWithRedactPatternsRemovesSpilledFiles(t *testing.T) {
+	dir := t.TempDir()
+	secret := strings.Repeat("s", 100)
+	input := `<login><password>` + secret + `</password><photo>` + strings.Repeat("p", 100) + `</photo></login>`
+
+	m, err := ParseToMap(strings.NewReader(input), WithSpillover(10, dir), WithRedactPatterns("/login/password"))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+	defer m.RemoveSpilled()
+	if got := m["/login/password"]; got != RedactedValue {
+		t.Errorf("password = %q, want %q", got, RedactedValue)
+	}
+
+	// Only the file of the value that is kept is left
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("spilled files = %v, want only the file of /login/photo", entries)
+	}
+	if got, err := m.ReadValue("/login/photo"); err != nil || got != strings.Repeat("p", 100) {
+		t.Errorf("ReadValue(/login/photo) = %q, %v", got, err)
+	}
+
+	// Redacting a clone keeps the file the original refers to
+	c := m.Clone()
+	c.Redact("/login/photo")
+	if got := c["/login/photo"]; got != RedactedValue {
+		t.Errorf("clone photo = %q, want %q", got, RedactedValue)
+	}
+	if got, err := m.ReadValue("/login/photo"); err != nil || got != strings.Repeat("p", 100) {
+		t.Errorf("ReadValue(/login/photo) after redacting a clone = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,100 @@
+package xmlsurf
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// reindexGroup identifies same-named sibling elements by the original path of their parent
+type reindexGroup struct {
+	parent string
+	name   string
+}
+
+// Reindex renumbers the indices of repeated elements in place so they are
+// contiguous and start at 1, keeping their relative order: item[1] and item[3]
+// become item[1] and item[2]. Elements without same-named siblings lose their
+// index, so a single remaining item[2] becomes item. An element written both
+// with and without index, such as item and item[1], is treated as one element.
+// Paths inside embedded documents are renumbered as well.
+func (m XMLMap) Reindex() {
+	// Collect the original indices of every group of same-named siblings
+	groups := make(map[reindexGroup][]int)
+	for path := range m {
+		walkElementSegments(path, func(parent, name string, index int) {
+			key := reindexGroup{parent: parent, name: name}
+			groups[key] = append(groups[key], index)
+		})
+	}
+
+	// Rank the indices of each group; 0 marks a group written without indices
+	ranks := make(map[reindexGroup]map[int]int, len(groups))
+	for key, indices := range groups {
+		sort.Ints(indices)
+		rank := make(map[int]int, len(indices))
+		for _, index := range indices {
+			if _, seen := rank[index]; !seen {
+				rank[index] = len(rank) + 1
+			}
+		}
+		if len(rank) == 1 {
+			for index := range rank {
+				rank[index] = 0
+			}
+		}
+		ranks[key] = rank
+	}
+
+	reindexed := make(XMLMap, len(m))
+	pathBuilder := getPathBuilder()
+	defer putPathBuilder(pathBuilder)
+
+	for path, value := range m {
+		pathBuilder.Reset()
+		walkElementSegments(path, func(parent, name string, index int) {
+			if strings.HasSuffix(parent, NestedSeparator) {
+				pathBuilder.WriteString(NestedSeparator)
+			}
+			pathBuilder.WriteString("/")
+			pathBuilder.WriteString(name)
+			if newIndex := ranks[reindexGroup{parent: parent, name: name}][index]; newIndex > 0 {
+				pathBuilder.WriteString("[")
+				pathBuilder.WriteString(strconv.Itoa(newIndex))
+				pathBuilder.WriteString("]")
+			}
+		})
+		if isAttributePath(path) {
+			pathBuilder.WriteString(path[strings.LastIndex(path, "/"):])
+		}
+		reindexed[pathBuilder.String()] = value
+	}
+
+	for path := range m {
+		delete(m, path)
+	}
+	for path, value := range reindexed {
+		m[path] = value
+	}
+}
+
+// walkElementSegments calls fn for every element step of path with the path of
+// its parent, its name and its index (1 when the step has no index).
+// The parent path carries an index on every step, so item and item[1] share
+// children, and ends in NestedSeparator for the root of an embedded document.
+// Attribute steps are skipped.
+func walkElementSegments(path string, fn func(parent, name string, index int)) {
+	parent := ""
+	forEachSegment(path, func(segment pathSegment) bool {
+		if strings.HasPrefix(segment.text, "@") {
+			return true
+		}
+		fn(parent, segment.name, segment.index)
+
+		parent += "/" + segment.name + "[" + strconv.Itoa(segment.index) + "]"
+		if segment.nested {
+			parent += NestedSeparator
+		}
+		return true
+	})
+}
@@ -0,0 +1,88 @@
+package xmlsurf
+
+import (
+	"testing"
+)
+
+func TestXMLMapReindex(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        XMLMap
+		expected XMLMap
+	}{
+		{
+			name: "close gaps after deletion",
+			m: XMLMap{
+				"/root/item[1]":     "a",
+				"/root/item[3]":     "c",
+				"/root/item[3]/@id": "3",
+				"/root/item[10]":    "j",
+			},
+			expected: XMLMap{
+				"/root/item[1]":     "a",
+				"/root/item[2]":     "c",
+				"/root/item[2]/@id": "3",
+				"/root/item[3]":     "j",
+			},
+		},
+		{
+			name: "single remaining element loses its index",
+			m: XMLMap{
+				"/root/item[2]/name": "b",
+				"/root/item[2]/@id":  "2",
+				"/root/other[1]":     "x",
+			},
+			expected: XMLMap{
+				"/root/item/name": "b",
+				"/root/item/@id":  "2",
+				"/root/other":     "x",
+			},
+		},
+		{
+			name: "nested groups are renumbered per parent",
+			m: XMLMap{
+				"/root/order[2]/line[2]": "a",
+				"/root/order[2]/line[5]": "b",
+				"/root/order[4]/line[3]": "c",
+			},
+			expected: XMLMap{
+				"/root/order[1]/line[1]": "a",
+				"/root/order[1]/line[2]": "b",
+				"/root/order[2]/line":    "c",
+			},
+		},
+		{
+			name: "embedded documents",
+			m: XMLMap{
+				"/root/payload[3]!/order/item[2]": "a",
+				"/root/payload[3]!/order/item[4]": "b",
+			},
+			expected: XMLMap{
+				"/root/payload!/order/item[1]": "a",
+				"/root/payload!/order/item[2]": "b",
+			},
+		},
+		{
+			name: "contiguous map is unchanged",
+			m: XMLMap{
+				"/root/item[1]": "a",
+				"/root/item[2]": "b",
+				"/root/single":  "c",
+			},
+			expected: XMLMap{
+				"/root/item[1]": "a",
+				"/root/item[2]": "b",
+				"/root/single":  "c",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.m.Reindex()
+			if !tt.m.Equal(tt.expected) {
+				t.Errorf("Reindex() = %v, want %v", tt.m, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package xmlsurf
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DiffGroup collects diffs of the same type whose paths differ only in their indices
+type DiffGroup struct {
+	Template string   // Path with every index replaced by [*]
+	Type     DiffType // Type of the grouped differences
+	Indices  [][]int  // Indices of each grouped diff, one entry per indexed path segment
+	Diffs    []Diff   // The grouped differences
+}
+
+// String returns a one-line summary of the group
+func (g DiffGroup) String() string {
+	if len(g.Diffs) == 1 && len(g.Indices[0]) == 0 {
+		return g.Diffs[0].String()
+	}
+
+	indices := make([]string, len(g.Indices))
+	for i, tuple := range g.Indices {
+		parts := make([]string, len(tuple))
+		for j, index := range tuple {
+			parts[j] = strconv.Itoa(index)
+		}
+		indices[i] = strings.Join(parts, ".")
+	}
+
+	var what string
+	switch g.Type {
+	case DiffMissing:
+		what = "Missing path"
+	case DiffExtra:
+		what = "Extra path"
+	case DiffValue:
+		what = "Value mismatch at"
+	default:
+		what = "Unknown diff type at"
+	}
+	return fmt.Sprintf("%s %s for indices %s", what, g.Template, strings.Join(indices, ","))
+}
+
+// GroupDiffs collapses diffs whose paths differ only in their indices into groups,
+// e.g. value mismatches at /root/item[2]/price and /root/item[5]/price become one
+// group with the template /root/item[*]/price and indices 2 and 5.
+// Groups are sorted by template and type; diffs within a group keep their order.
+func GroupDiffs(diffs []Diff) []DiffGroup {
+	type groupKey struct {
+		template string
+		diffType DiffType
+	}
+
+	groups := make(map[groupKey]*DiffGroup)
+	keys := make([]groupKey, 0)
+	for _, diff := range diffs {
+		template, indices := pathTemplate(diff.Path)
+		key := groupKey{template: template, diffType: diff.Type}
+		group, ok := groups[key]
+		if !ok {
+			group = &DiffGroup{Template: template, Type: diff.Type}
+			groups[key] = group
+			keys = append(keys, key)
+		}
+		group.Indices = append(group.Indices, indices)
+		group.Diffs = append(group.Diffs, diff)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].template != keys[j].template {
+			return keys[i].template < keys[j].template
+		}
+		return keys[i].diffType < keys[j].diffType
+	})
+
+	result := make([]DiffGroup, len(keys))
+	for i, key := range keys {
+		result[i] = *groups[key]
+	}
+	return result
+}
+
+// pathTemplate replaces every index in the path with [*] and returns the removed indices
+func pathTemplate(path string) (string, []int) {
+	var builder strings.Builder
+	indices := make([]int, 0)
+	template := rewriteSegments(path, &builder, func(b *strings.Builder, segment pathSegment) {
+		if !segment.indexed {
+			b.WriteString(segment.text)
+			return
+		}
+		b.WriteString(segment.name)
+		b.WriteString("[*]")
+		indices = append(indices, segment.index)
+	})
+	return template, indices
+}
@@ -0,0 +1,38 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupDiffs(t *testing.T) {
+	diffs := []Diff{
+		{Path: "/root/items/item[2]/price", LeftValue: "1", RightValue: "2", Type: DiffValue},
+		{Path: "/root/items/item[5]/price", LeftValue: "1", RightValue: "3", Type: DiffValue},
+		{Path: "/root/items/item[9]/price", LeftValue: "4", RightValue: "2", Type: DiffValue},
+		{Path: "/root/items/item[9]/name", RightValue: "x", Type: DiffMissing},
+		{Path: "/root/list[1]/entry[3]", LeftValue: "a", Type: DiffExtra},
+		{Path: "/root/list[2]/entry[1]", LeftValue: "b", Type: DiffExtra},
+		{Path: "/root/status", LeftValue: "OK", RightValue: "FAIL", Type: DiffValue},
+	}
+
+	groups := GroupDiffs(diffs)
+
+	expected := []string{
+		"Missing path /root/items/item[*]/name for indices 9",
+		"Value mismatch at /root/items/item[*]/price for indices 2,5,9",
+		"Extra path /root/list[*]/entry[*] for indices 1.3,2.1",
+		`Value mismatch at /root/status: "OK" != "FAIL"`,
+	}
+	summaries := make([]string, len(groups))
+	for i, group := range groups {
+		summaries[i] = group.String()
+	}
+	if !reflect.DeepEqual(summaries, expected) {
+		t.Errorf("GroupDiffs() = %q, want %q", summaries, expected)
+	}
+
+	if len(groups[1].Diffs) != 3 || !reflect.DeepEqual(groups[1].Indices, [][]int{{2}, {5}, {9}}) {
+		t.Errorf("GroupDiffs() price group = %+v", groups[1])
+	}
+}
@@ -0,0 +1,38 @@
+package xmlsurf
+
+import "strings"
+
+// subtreeMatcher selects the elements of WithSkipSubtrees
+type subtreeMatcher struct {
+	paths []pathPattern
+	names map[string]bool // Element names of "//name" patterns, matched at any depth
+}
+
+// compileSubtreeMatcher compiles the patterns of WithSkipSubtrees, returning nil without patterns
+func compileSubtreeMatcher(patterns []string) *subtreeMatcher {
+	if len(patterns) == 0 {
+		return nil
+	}
+	matcher := &subtreeMatcher{names: make(map[string]bool)}
+	for _, pattern := range patterns {
+		if name, ok := strings.CutPrefix(pattern, "//"); ok {
+			matcher.names[name] = true
+		} else {
+			matcher.paths = append(matcher.paths, compilePathPattern(pattern))
+		}
+	}
+	return matcher
+}
+
+// match reports whether the element with the index-free path and name is skipped
+func (m *subtreeMatcher) match(basePath, name string) bool {
+	if m.names[name] {
+		return true
+	}
+	for _, pattern := range m.paths {
+		if pattern.match(basePath) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithSkipSubtrees(t *testing.T) {
+	input := `<Envelope xmlns:ds="urn:dsig"><Header><ds:Signature><ds:Value>abc</ds:Value></ds:Signature></Header>` +
+		`<Body><Order><Payload size="big"><A:0>x</A:0></Payload><Item>a</Item><Payload/><Item>b</Item></Order>` +
+		`<ds:Signature>def</ds:Signature></Body></Envelope>`
+
+	tests := []struct {
+		name     string
+		patterns []string
+		want     XMLMap
+	}{
+		{
+			name:     "name at any depth and wildcard path",
+			patterns: []string{"//ds:Signature", "/Envelope/Body/*/Payload"},
+			want: XMLMap{
+				"/Envelope/Header":             "",
+				"/Envelope/Body/Order/Item[1]": "a",
+				"/Envelope/Body/Order/Item[2]": "b",
+			},
+		},
+		{
+			name:     "elements left without children",
+			patterns: []string{"//ds:Signature", "/Envelope/Body/Order/Payload", "/Envelope/Body/Order/Item"},
+			want:     XMLMap{"/Envelope/Header": "", "/Envelope/Body/Order": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseToMap(strings.NewReader(input), WithSkipSubtrees(tt.patterns...), WithEmptyElements(true))
+			if err != nil {
+				t.Fatalf("ParseToMap() error = %v", err)
+			}
+			if !reflect.DeepEqual(m, tt.want) {
+				t.Errorf("ParseToMap() = %v, want %v", m, tt.want)
+			}
+		})
+	}
+
+	// Skipping the root leaves nothing
+	if _, err := ParseToMap(strings.NewReader(input), WithSkipSubtrees("/Envelope")); err != ErrEmptyDocument {
+		t.Errorf("ParseToMap() error = %v, want ErrEmptyDocument", err)
+	}
+}
+
+func TestWithSkipSubtreesPositionsAndTrace(t *testing.T) {
+	input := "<root>\n<skip><a>1</a></skip>\n<keep>2</keep>\n</root>"
+
+	_, positions, err := ParseToMapWithPositions(strings.NewReader(input), WithSkipSubtrees("/root/skip"))
+	if err != nil {
+		t.Fatalf("ParseToMapWithPositions() error = %v", err)
+	}
+	if got := positions["/root/keep"]; got.Line != 3 {
+		t.Errorf("position of /root/keep = %+v, want line 3", got)
+	}
+
+	depth := 0
+	hook := func(event TraceEvent) {
+		switch event.Kind {
+		case TraceStartElement:
+			depth++
+		case TraceEndElement:
+			depth--
+		}
+	}
+	if _, err := ParseToMap(strings.NewReader(input), WithSkipSubtrees("/root/skip"), WithTraceHook(hook)); err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+	if depth != 0 {
+		t.Errorf("trace depth after parsing = %d, want balanced start and end events", depth)
+	}
+}
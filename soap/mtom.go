@@ -0,0 +1,169 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"strings"
+
+	"github.com/bmcszk/xmlsurf"
+)
+
+var (
+	// ErrNotMultipart is returned when an MTOM message is not multipart/related
+	ErrNotMultipart = errors.New("not a multipart/related message")
+	// ErrNoRootPart is returned when an MTOM message has no root part
+	ErrNoRootPart = errors.New("no root part found")
+)
+
+// Attachment is a binary part of an MTOM message
+type Attachment struct {
+	ContentID   string // Content-ID without angle brackets
+	ContentType string
+	Data        []byte
+}
+
+// MTOMMessage is a SOAP message sent as MTOM/XOP: an XML root part whose binary
+// content is moved to attachments referenced by xop:Include elements
+type MTOMMessage struct {
+	Envelope    xmlsurf.XMLMap         // Root part, parsed with the given options
+	Attachments map[string]*Attachment // Other parts by Content-ID without angle brackets
+}
+
+// ParseMTOM parses a multipart/related MTOM message. contentType is the Content-Type
+// header of the message, which holds the boundary and the Content-ID of the root part
+// in its start parameter; without it, the first part is the root part.
+// Parts encoded with base64 or quoted-printable are decoded.
+func ParseMTOM(r io.Reader, contentType string, opts ...xmlsurf.Option) (*MTOMMessage, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	if mediaType != "multipart/related" || params["boundary"] == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNotMultipart, mediaType)
+	}
+	start := contentID(params["start"])
+
+	message := &MTOMMessage{Attachments: make(map[string]*Attachment)}
+	var root []byte
+	rootFound := false
+	reader := multipart.NewReader(r, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := readPart(part)
+		if err != nil {
+			return nil, err
+		}
+
+		id := contentID(part.Header.Get("Content-ID"))
+		if !rootFound && (start == "" || id == start) {
+			root, rootFound = data, true
+			continue
+		}
+		message.Attachments[id] = &Attachment{
+			ContentID:   id,
+			ContentType: part.Header.Get("Content-Type"),
+			Data:        data,
+		}
+	}
+	if !rootFound {
+		return nil, ErrNoRootPart
+	}
+
+	message.Envelope, err = xmlsurf.ParseToMap(bytes.NewReader(root), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// readPart returns the decoded content of a part; quoted-printable is decoded by multipart
+func readPart(part *multipart.Part) ([]byte, error) {
+	var r io.Reader = part
+	if strings.EqualFold(part.Header.Get("Content-Transfer-Encoding"), "base64") {
+		r = base64.NewDecoder(base64.StdEncoding, newlineFilter{part})
+	}
+	return io.ReadAll(r)
+}
+
+// newlineFilter drops the line breaks of base64 encoded content
+type newlineFilter struct {
+	r io.Reader
+}
+
+// Read returns the content without CR and LF
+func (f newlineFilter) Read(p []byte) (int, error) {
+	for {
+		n, err := f.r.Read(p)
+		kept := 0
+		for _, b := range p[:n] {
+			if b != '\r' && b != '\n' {
+				p[kept] = b
+				kept++
+			}
+		}
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
+
+// contentID normalizes a Content-ID header or start parameter by removing the angle brackets
+func contentID(id string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(id), "<"), ">")
+}
+
+// Attachment returns the attachment referenced by ref, either a cid: URL as used in the
+// href of xop:Include or a plain Content-ID with or without angle brackets
+func (m *MTOMMessage) Attachment(ref string) (*Attachment, bool) {
+	id := ref
+	if len(ref) > 4 && strings.EqualFold(ref[:4], "cid:") {
+		// cid: URLs escape the Content-ID
+		unescaped, err := url.PathUnescape(ref[4:])
+		if err != nil {
+			return nil, false
+		}
+		id = unescaped
+	}
+	attachment, ok := m.Attachments[contentID(id)]
+	return attachment, ok
+}
+
+// References returns the paths of the elements whose content was moved to an attachment,
+// mapped to the href of their xop:Include element, e.g. /Envelope/Body/upload/data to
+// cid:file@example.com. Include elements are matched by local name.
+func (m *MTOMMessage) References() map[string]string {
+	references := make(map[string]string)
+	for path, value := range m.Envelope {
+		element, attr, ok := strings.Cut(path, "/@")
+		if !ok || localName(attr) != "href" {
+			continue
+		}
+		slash := strings.LastIndex(element, "/")
+		if slash <= 0 || localName(element[slash+1:]) != "Include" {
+			continue
+		}
+		references[element[:slash]] = value
+	}
+	return references
+}
+
+// AttachmentAt returns the attachment holding the content of the element at path
+func (m *MTOMMessage) AttachmentAt(path string) (*Attachment, bool) {
+	ref, ok := m.References()[path]
+	if !ok {
+		return nil, false
+	}
+	return m.Attachment(ref)
+}
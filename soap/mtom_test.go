@@ -0,0 +1,145 @@
+package soap
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/bmcszk/xmlsurf"
+)
+
+// mtomPart is a part of a test message
+type mtomPart struct {
+	id       string
+	encoding string
+	body     string
+}
+
+// buildMTOM returns a multipart/related message with the parts and its content type
+func buildMTOM(t *testing.T, start string, parts ...mtomPart) (string, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, part := range parts {
+		header := textproto.MIMEHeader{"Content-Id": {part.id}, "Content-Type": {"application/octet-stream"}}
+		if part.encoding != "" {
+			header.Set("Content-Transfer-Encoding", part.encoding)
+		}
+		pw, err := w.CreatePart(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pw.Write([]byte(part.body))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	contentType := `multipart/related; type="application/xop+xml"; boundary="` + w.Boundary() + `"`
+	if start != "" {
+		contentType += `; start="` + start + `"`
+	}
+	return buf.String(), contentType
+}
+
+const mtomEnvelope = `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body>` +
+	`<upload><name>report.pdf</name><data><xop:Include xmlns:xop="http://www.w3.org/2004/08/xop/include" href="cid:file%401"/></data></upload>` +
+	`</s:Body></s:Envelope>`
+
+func TestParseMTOM(t *testing.T) {
+	tests := []struct {
+		name  string
+		start string
+		parts []mtomPart
+	}{
+		{
+			name:  "root part first",
+			parts: []mtomPart{{id: "<root@1>", body: mtomEnvelope}, {id: "<file@1>", encoding: "binary", body: "%PDF\x00\x01"}},
+		},
+		{
+			name:  "root part named by start",
+			start: "<root@1>",
+			parts: []mtomPart{{id: "<file@1>", body: "%PDF\x00\x01"}, {id: "<root@1>", body: mtomEnvelope}},
+		},
+		{
+			name:  "base64 attachment",
+			parts: []mtomPart{{id: "<root@1>", body: mtomEnvelope}, {id: "<file@1>", encoding: "base64", body: "JVBE\r\nRgAB"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := buildMTOM(t, tt.start, tt.parts...)
+			message, err := ParseMTOM(strings.NewReader(body), contentType)
+			if err != nil {
+				t.Fatalf("ParseMTOM() error = %v", err)
+			}
+
+			if got := message.Envelope["/s:Envelope/s:Body/upload/name"]; got != "report.pdf" {
+				t.Errorf("Envelope name = %q, want report.pdf (envelope %v)", got, message.Envelope)
+			}
+			wantRefs := map[string]string{"/s:Envelope/s:Body/upload/data": "cid:file%401"}
+			if refs := message.References(); len(refs) != 1 || refs["/s:Envelope/s:Body/upload/data"] != "cid:file%401" {
+				t.Errorf("References() = %v, want %v", refs, wantRefs)
+			}
+
+			attachment, ok := message.AttachmentAt("/s:Envelope/s:Body/upload/data")
+			if !ok {
+				t.Fatalf("AttachmentAt() found no attachment, attachments %v", message.Attachments)
+			}
+			if string(attachment.Data) != "%PDF\x00\x01" || attachment.ContentID != "file@1" {
+				t.Errorf("AttachmentAt() = %+v", attachment)
+			}
+			for _, ref := range []string{"cid:file%401", "CID:file@1", "file@1", "<file@1>"} {
+				if got, ok := message.Attachment(ref); !ok || got != attachment {
+					t.Errorf("Attachment(%q) = %v, %v", ref, got, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestParseMTOMOptions(t *testing.T) {
+	body, contentType := buildMTOM(t, "", mtomPart{id: "<root@1>", body: mtomEnvelope})
+	message, err := ParseMTOM(strings.NewReader(body), contentType, xmlsurf.WithNamespaces(false))
+	if err != nil {
+		t.Fatalf("ParseMTOM() error = %v", err)
+	}
+	if _, ok := message.References()["/Envelope/Body/upload/data"]; !ok {
+		t.Errorf("References() = %v, want the data element without prefixes", message.References())
+	}
+	if _, ok := message.AttachmentAt("/Envelope/Body/upload/data"); ok {
+		t.Error("AttachmentAt() found a missing attachment")
+	}
+}
+
+func TestParseMTOMErrors(t *testing.T) {
+	body, contentType := buildMTOM(t, "<missing@1>", mtomPart{id: "<root@1>", body: mtomEnvelope})
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		want        error
+	}{
+		{name: "not multipart", body: mtomEnvelope, contentType: "application/soap+xml", want: ErrNotMultipart},
+		{name: "no boundary", body: mtomEnvelope, contentType: "multipart/related", want: ErrNotMultipart},
+		{name: "start names no part", body: body, contentType: contentType, want: ErrNoRootPart},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseMTOM(strings.NewReader(tt.body), tt.contentType); !errors.Is(err, tt.want) {
+				t.Errorf("ParseMTOM() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	body, contentType = buildMTOM(t, "", mtomPart{id: "<root@1>", body: "<a>"})
+	var syntaxErr *xmlsurf.SyntaxError
+	if _, err := ParseMTOM(strings.NewReader(body), contentType); !errors.As(err, &syntaxErr) {
+		t.Errorf("ParseMTOM() with malformed root part error = %v, want a *SyntaxError", err)
+	}
+}
@@ -0,0 +1,168 @@
+// Package soap provides helpers for working with SOAP envelopes represented as xmlsurf.XMLMap.
+package soap
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bmcszk/xmlsurf"
+)
+
+func init() {
+	xmlsurf.RegisterModule("soap")
+}
+
+// Version identifies a SOAP protocol version
+type Version int
+
+const (
+	// SOAP11 is SOAP 1.1 (http://schemas.xmlsoap.org/soap/envelope/)
+	SOAP11 Version = iota
+	// SOAP12 is SOAP 1.2 (http://www.w3.org/2003/05/soap-envelope)
+	SOAP12
+)
+
+const (
+	// NamespaceSOAP11 is the envelope namespace URI of SOAP 1.1
+	NamespaceSOAP11 = "http://schemas.xmlsoap.org/soap/envelope/"
+	// NamespaceSOAP12 is the envelope namespace URI of SOAP 1.2
+	NamespaceSOAP12 = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+var (
+	// ErrNoEnvelope is returned when the map is not a SOAP envelope
+	ErrNoEnvelope = errors.New("no SOAP envelope found")
+	// ErrNoBody is returned when the envelope has no body
+	ErrNoBody = errors.New("no SOAP body found")
+)
+
+// Fault represents a SOAP fault of either protocol version
+type Fault struct {
+	Code   string         // faultcode (1.1) or Code/Value (1.2)
+	String string         // faultstring (1.1) or Reason/Text (1.2)
+	Actor  string         // faultactor (1.1) or Role (1.2)
+	Detail xmlsurf.XMLMap // Contents of detail (1.1) or Detail (1.2), relative to it
+}
+
+// Error returns the fault code and string
+func (f *Fault) Error() string {
+	return "SOAP fault " + f.Code + ": " + f.String
+}
+
+// WrapSOAPBody wraps the map into the body of a SOAP envelope of the given version.
+// The envelope uses the "soap" prefix and declares its namespace on the root element.
+func WrapSOAPBody(m xmlsurf.XMLMap, version Version) xmlsurf.XMLMap {
+	namespace := NamespaceSOAP11
+	if version == SOAP12 {
+		namespace = NamespaceSOAP12
+	}
+
+	result := make(xmlsurf.XMLMap, len(m)+1)
+	result["/soap:Envelope/@xmlns:soap"] = namespace
+	for path, value := range m {
+		result["/soap:Envelope/soap:Body"+path] = value
+	}
+	return result
+}
+
+// ExtractSOAPBody returns the contents of the SOAP body with paths relative to the body.
+// Envelope and body elements are matched by local name, so maps parsed with or
+// without namespaces are supported.
+func ExtractSOAPBody(m xmlsurf.XMLMap) (xmlsurf.XMLMap, error) {
+	bodyPrefix, err := findBody(m)
+	if err != nil {
+		return nil, err
+	}
+	return subtree(m, bodyPrefix), nil
+}
+
+// ExtractSOAPFault returns the fault contained in the SOAP body.
+// It returns nil without error when the body contains no fault.
+func ExtractSOAPFault(m xmlsurf.XMLMap) (*Fault, error) {
+	body, err := ExtractSOAPBody(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var faultPrefix string
+	for path := range body {
+		parts := strings.Split(path, "/")
+		if len(parts) > 1 && localName(parts[1]) == "Fault" {
+			faultPrefix = "/" + parts[1]
+			break
+		}
+	}
+	if faultPrefix == "" {
+		return nil, nil
+	}
+
+	fault := &Fault{}
+	for path, value := range subtree(body, faultPrefix) {
+		parts := strings.Split(path, "/")
+		names := make([]string, 0, len(parts))
+		for _, part := range parts[1:] {
+			names = append(names, localName(part))
+		}
+
+		switch strings.Join(names, "/") {
+		case "faultcode", "Code/Value":
+			fault.Code = value
+		case "faultstring", "Reason/Text":
+			fault.String = value
+		case "faultactor", "Role":
+			fault.Actor = value
+		}
+	}
+
+	for path := range body {
+		parts := strings.Split(path, "/")
+		if len(parts) > 2 && (localName(parts[2]) == "detail" || localName(parts[2]) == "Detail") {
+			fault.Detail = subtree(subtree(body, faultPrefix), "/"+parts[2])
+			break
+		}
+	}
+
+	return fault, nil
+}
+
+// findBody returns the path prefix of the SOAP body element
+func findBody(m xmlsurf.XMLMap) (string, error) {
+	envelopeFound := false
+	for path := range m {
+		parts := strings.Split(path, "/")
+		if len(parts) < 2 || localName(parts[1]) != "Envelope" {
+			continue
+		}
+		envelopeFound = true
+		if len(parts) > 2 && localName(parts[2]) == "Body" {
+			return "/" + parts[1] + "/" + parts[2], nil
+		}
+	}
+	if !envelopeFound {
+		return "", ErrNoEnvelope
+	}
+	return "", ErrNoBody
+}
+
+// subtree returns the paths below prefix, made relative to it
+func subtree(m xmlsurf.XMLMap, prefix string) xmlsurf.XMLMap {
+	result := make(xmlsurf.XMLMap)
+	prefix += "/"
+	for path, value := range m {
+		if strings.HasPrefix(path, prefix) && !strings.HasPrefix(path, prefix+"@") {
+			result[path[len(prefix)-1:]] = value
+		}
+	}
+	return result
+}
+
+// localName strips the namespace prefix and index from a path segment
+func localName(segment string) string {
+	if idx := strings.Index(segment, "["); idx != -1 {
+		segment = segment[:idx]
+	}
+	if idx := strings.Index(segment, ":"); idx != -1 {
+		segment = segment[idx+1:]
+	}
+	return segment
+}
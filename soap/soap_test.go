@@ -0,0 +1,177 @@
+package soap
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bmcszk/xmlsurf"
+)
+
+func TestWrapSOAPBody(t *testing.T) {
+	body := xmlsurf.XMLMap{
+		"/GetOrder/id": "42",
+	}
+
+	envelope := WrapSOAPBody(body, SOAP12)
+	expected := xmlsurf.XMLMap{
+		"/soap:Envelope/@xmlns:soap":           NamespaceSOAP12,
+		"/soap:Envelope/soap:Body/GetOrder/id": "42",
+	}
+	if !envelope.Equal(expected) {
+		t.Errorf("WrapSOAPBody() = %v, want %v", envelope, expected)
+	}
+
+	var builder strings.Builder
+	if err := envelope.ToXML(&builder, false); err != nil {
+		t.Fatalf("ToXML() error = %v", err)
+	}
+	parsed, err := xmlsurf.ParseToMap(strings.NewReader(builder.String()))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+	extracted, err := ExtractSOAPBody(parsed)
+	if err != nil {
+		t.Fatalf("ExtractSOAPBody() error = %v", err)
+	}
+	if !extracted.Equal(body) {
+		t.Errorf("ExtractSOAPBody() after round-trip = %v, want %v", extracted, body)
+	}
+}
+
+func TestExtractSOAPBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       xmlsurf.XMLMap
+		expected    xmlsurf.XMLMap
+		expectedErr error
+	}{
+		{
+			name: "prefixed envelope",
+			input: xmlsurf.XMLMap{
+				"/soap:Envelope/soap:Header/auth:Token":            "abc",
+				"/soap:Envelope/soap:Body/ns:GetOrder/ns:id":       "42",
+				"/soap:Envelope/soap:Body/ns:GetOrder/@version":    "2",
+				"/soap:Envelope/soap:Body/ns:GetOrder/ns:items[1]": "a",
+			},
+			expected: xmlsurf.XMLMap{
+				"/ns:GetOrder/ns:id":       "42",
+				"/ns:GetOrder/@version":    "2",
+				"/ns:GetOrder/ns:items[1]": "a",
+			},
+		},
+		{
+			name: "envelope parsed without namespaces",
+			input: xmlsurf.XMLMap{
+				"/Envelope/Body/GetOrder/id": "42",
+			},
+			expected: xmlsurf.XMLMap{
+				"/GetOrder/id": "42",
+			},
+		},
+		{
+			name: "not an envelope",
+			input: xmlsurf.XMLMap{
+				"/root/Body/id": "42",
+			},
+			expectedErr: ErrNoEnvelope,
+		},
+		{
+			name: "envelope without body",
+			input: xmlsurf.XMLMap{
+				"/soap:Envelope/soap:Header/Token": "abc",
+			},
+			expectedErr: ErrNoBody,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ExtractSOAPBody(tt.input)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("ExtractSOAPBody() error = %v, want %v", err, tt.expectedErr)
+			}
+			if tt.expectedErr == nil && !result.Equal(tt.expected) {
+				t.Errorf("ExtractSOAPBody() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExtractSOAPFault(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    xmlsurf.XMLMap
+		expected *Fault
+	}{
+		{
+			name: "SOAP 1.1 fault",
+			input: xmlsurf.XMLMap{
+				"/soap:Envelope/soap:Body/soap:Fault/faultcode":          "soap:Server",
+				"/soap:Envelope/soap:Body/soap:Fault/faultstring":        "Internal error",
+				"/soap:Envelope/soap:Body/soap:Fault/faultactor":         "http://example.com",
+				"/soap:Envelope/soap:Body/soap:Fault/detail/err:Message": "boom",
+			},
+			expected: &Fault{
+				Code:   "soap:Server",
+				String: "Internal error",
+				Actor:  "http://example.com",
+				Detail: xmlsurf.XMLMap{"/err:Message": "boom"},
+			},
+		},
+		{
+			name: "SOAP 1.2 fault",
+			input: xmlsurf.XMLMap{
+				"/env:Envelope/env:Body/env:Fault/env:Code/env:Value":        "env:Sender",
+				"/env:Envelope/env:Body/env:Fault/env:Reason/env:Text":       "Bad request",
+				"/env:Envelope/env:Body/env:Fault/env:Reason/env:Text/@lang": "en",
+				"/env:Envelope/env:Body/env:Fault/env:Detail/code":           "E1",
+			},
+			expected: &Fault{
+				Code:   "env:Sender",
+				String: "Bad request",
+				Detail: xmlsurf.XMLMap{"/code": "E1"},
+			},
+		},
+		{
+			name: "no fault",
+			input: xmlsurf.XMLMap{
+				"/soap:Envelope/soap:Body/GetOrderResponse/id": "42",
+			},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fault, err := ExtractSOAPFault(tt.input)
+			if err != nil {
+				t.Fatalf("ExtractSOAPFault() error = %v", err)
+			}
+			if tt.expected == nil {
+				if fault != nil {
+					t.Errorf("ExtractSOAPFault() = %+v, want nil", fault)
+				}
+				return
+			}
+			if fault == nil {
+				t.Fatalf("ExtractSOAPFault() = nil, want %+v", tt.expected)
+			}
+			if fault.Code != tt.expected.Code || fault.String != tt.expected.String || fault.Actor != tt.expected.Actor {
+				t.Errorf("ExtractSOAPFault() = %+v, want %+v", fault, tt.expected)
+			}
+			if !fault.Detail.Equal(tt.expected.Detail) {
+				t.Errorf("ExtractSOAPFault() detail = %v, want %v", fault.Detail, tt.expected.Detail)
+			}
+		})
+	}
+}
+
+func TestModuleRegistered(t *testing.T) {
+	for _, module := range xmlsurf.Capabilities().Modules {
+		if module == "soap" {
+			return
+		}
+	}
+	t.Errorf("Capabilities().Modules = %v, want soap", xmlsurf.Capabilities().Modules)
+}
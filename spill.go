@@ -0,0 +1,116 @@
+package xmlsurf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// SpillPrefix starts the value stored in place of element text that WithSpillover
+// wrote to a temporary file; the name of the file follows it
+const SpillPrefix = "xmlsurf-spill:"
+
+// spilledFiles holds the names of the temporary files written by WithSpillover that
+// have not been removed. Documents can hold text starting with SpillPrefix, so only
+// references to these files are followed.
+var spilledFiles sync.Map
+
+// SpilledFile returns the name of the temporary file holding a value spilled by
+// WithSpillover, and false for any other value. Values starting with SpillPrefix that
+// name any other file, such as text of the document, are not spilled values.
+func SpilledFile(value string) (string, bool) {
+	file, ok := strings.CutPrefix(value, SpillPrefix)
+	if !ok {
+		return "", false
+	}
+	if _, spilled := spilledFiles.Load(file); !spilled {
+		return "", false
+	}
+	return file, true
+}
+
+// ReadValue returns the value at path, reading it from its temporary file if it was
+// spilled by WithSpillover. It returns an error wrapping ErrPathNotFound if the map
+// holds no value at path.
+func (m XMLMap) ReadValue(path string) (string, error) {
+	value, ok := m[path]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrPathNotFound, path)
+	}
+	file, ok := SpilledFile(value)
+	if !ok {
+		return value, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// RemoveSpilled removes the temporary files of the values spilled by WithSpillover.
+// The references stay in the map, so call it once the values are no longer needed.
+func (m XMLMap) RemoveSpilled() error {
+	var errs []error
+	for _, value := range m {
+		if file, ok := SpilledFile(value); ok {
+			if err := removeSpilledFile(file); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// removeSpilledFile removes a file written by WithSpillover and forgets it
+func removeSpilledFile(file string) error {
+	err := os.Remove(file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	spilledFiles.Delete(file)
+	return nil
+}
+
+// removeUnstored removes the temporary files of the spilled values of a path that
+// were not stored, once the overwrite policy has chosen the stored value
+func removeUnstored(values []string, stored string) {
+	for _, value := range values {
+		if file, ok := SpilledFile(value); ok && value != stored {
+			removeSpilledFile(file)
+		}
+	}
+}
+
+// spill writes element text longer than the spillover limit to a temporary file and
+// returns the reference to store in its place
+func (b *documentBuilder) spill(text []byte) (string, error) {
+	if b.options.TrimValues {
+		text = bytes.TrimSpace(text)
+	}
+	file, err := os.CreateTemp(b.options.SpillDir, "xmlsurf-spill-*")
+	if err != nil {
+		return "", err
+	}
+	b.spilled = append(b.spilled, file.Name())
+	spilledFiles.Store(file.Name(), struct{}{})
+	if _, err := file.Write(text); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	return SpillPrefix + file.Name(), nil
+}
+
+// removeSpilled removes the temporary files written for a document that failed to parse
+func (b *documentBuilder) removeSpilled() {
+	for _, file := range b.spilled {
+		removeSpilledFile(file)
+	}
+	b.spilled = nil
+}
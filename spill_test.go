@@ -0,0 +1,167 @@
+package xmlsurf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWithSpillover(t *testing.T) {
+	dir := t.TempDir()
+	payload := strings.Repeat("QUJD", 100)
+	input := `<upload><name>report.pdf</name><data id="1">` + "\n  " + payload + "\n" + `</data><blank>      </blank></upload>`
+
+	m, err := ParseToMap(strings.NewReader(input), WithSpillover(64, dir))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+	if got := m["/upload/name"]; got != "report.pdf" {
+		t.Errorf("small value = %q, want report.pdf", got)
+	}
+	if got := m["/upload/data/@id"]; got != "1" {
+		t.Errorf("attribute = %q, want 1", got)
+	}
+	if _, ok := m["/upload/blank"]; ok {
+		t.Error("whitespace-only element was spilled")
+	}
+
+	file, ok := SpilledFile(m["/upload/data"])
+	if !ok || !strings.HasPrefix(file, dir) {
+		t.Fatalf("large value = %q, want a reference to a file in %s", m["/upload/data"], dir)
+	}
+	for path, want := range map[string]string{"/upload/data": payload, "/upload/name": "report.pdf"} {
+		if got, err := m.ReadValue(path); err != nil || got != want {
+			t.Errorf("ReadValue(%s) = %q, %v, want %q", path, got, err, want)
+		}
+	}
+	if _, err := m.ReadValue("/upload/missing"); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("ReadValue() of a missing path error = %v, want ErrPathNotFound", err)
+	}
+
+	if err := m.RemoveSpilled(); err != nil {
+		t.Fatalf("RemoveSpilled() error = %v", err)
+	}
+	if _, err := os.Stat(file); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("spilled file still exists after RemoveSpilled(): %v", err)
+	}
+	if err := m.RemoveSpilled(); err != nil {
+		t.Errorf("second RemoveSpilled() error = %v", err)
+	}
+}
+
+func TestWithSpilloverRemovesFilesOnError(t *testing.T) {
+	dir := t.TempDir()
+	payload := strings.Repeat("x", 100)
+
+	for _, input := range []string{
+		`<upload><data>` + payload + `</data>`,
+		`<upload><data>` + payload + `<!-- split -->` + payload + `</data></upload>`,
+	} {
+		_, err := ParseToMap(strings.NewReader(input), WithSpillover(10, dir), WithOverwritePolicy(OverwriteError))
+		if err == nil {
+			t.Errorf("ParseToMap(%.30s...) error = nil", input)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		t.Errorf("spilled file %s left after a failed parse", entry.Name())
+	}
+}
+
+func TestSpilledFileIgnoresForeignReferences(t *testing.T) {
+	victim := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(victim, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Text of the document that looks like a reference is an ordinary value
+	input := `<upload><data>` + SpillPrefix + victim + `</data></upload>`
+	m, err := ParseToMap(strings.NewReader(input), WithSpillover(1024, t.TempDir()))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+	if _, ok := SpilledFile(m["/upload/data"]); ok {
+		t.Errorf("SpilledFile(%q) = true for a file not written by WithSpillover", m["/upload/data"])
+	}
+	if got, err := m.ReadValue("/upload/data"); err != nil || got != SpillPrefix+victim {
+		t.Errorf("ReadValue() = %q, %v, want the text of the document", got, err)
+	}
+	if err := m.RemoveSpilled(); err != nil {
+		t.Fatalf("RemoveSpilled() error = %v", err)
+	}
+	if _, err := os.Stat(victim); err != nil {
+		t.Errorf("RemoveSpilled() removed a file not written by WithSpillover: %v", err)
+	}
+}
+
+func TestWithSpilloverRemovesReplacedValues(t *testing.T) {
+	first, last := strings.Repeat("a", 100), strings.Repeat("b", 100)
+	input := `<upload><data>` + first + `<!-- split -->` + last + `</data></upload>`
+
+	registered := func() int {
+		n := 0
+		spilledFiles.Range(func(_, _ any) bool { n++; return true })
+		return n
+	}
+	before := registered()
+
+	var collected []string
+	collect := func(_ string, values []string) {
+		for _, value := range values {
+			file, _ := SpilledFile(value)
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Errorf("collected value %q: %v", value, err)
+			}
+			collected = append(collected, string(data))
+		}
+	}
+
+	tests := []struct {
+		name string
+		opt  Option
+		want string
+	}{
+		{name: "keep last", opt: WithOverwritePolicy(OverwriteKeepLast), want: last},
+		{name: "keep first", opt: WithOverwritePolicy(OverwriteKeepFirst), want: first},
+		{name: "collect", opt: WithCollectedValues(collect), want: last},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			m, err := ParseToMap(strings.NewReader(input), WithSpillover(10, dir), tt.opt)
+			if err != nil {
+				t.Fatalf("ParseToMap() error = %v", err)
+			}
+			if got, err := m.ReadValue("/upload/data"); err != nil || got != tt.want {
+				t.Errorf("ReadValue() = %.10q, %v, want %.10q", got, err, tt.want)
+			}
+
+			// Only the file of the stored value is left
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("spilled files = %d, want 1", len(entries))
+			}
+
+			if err := m.RemoveSpilled(); err != nil {
+				t.Fatalf("RemoveSpilled() error = %v", err)
+			}
+			if n := registered(); n != before {
+				t.Errorf("registered spilled files = %d after RemoveSpilled(), want %d", n, before)
+			}
+		})
+	}
+
+	if len(collected) != 2 || collected[0] != first || collected[1] != last {
+		t.Errorf("collected %d values, want the first and last text", len(collected))
+	}
+}
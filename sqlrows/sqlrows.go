@@ -0,0 +1,191 @@
+// Package sqlrows turns repeated elements of xmlsurf maps into rows of
+// parameterized SQL INSERT statements and loads them with database/sql.
+package sqlrows
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bmcszk/xmlsurf"
+)
+
+func init() {
+	xmlsurf.RegisterModule("sqlrows")
+}
+
+// Column maps a table column to a path relative to a record, e.g. "@id" or "customer/name".
+// An empty Path or "." selects the record's own value.
+type Column struct {
+	Name string
+	Path string
+}
+
+// Statement is a parameterized SQL statement with its arguments
+type Statement struct {
+	Query string
+	Args  []any
+}
+
+// Dialect selects placeholder and identifier quoting syntax
+type Dialect int
+
+const (
+	// PostgreSQL uses $1, $2, ... placeholders and "double quoted" identifiers
+	PostgreSQL Dialect = iota
+	// MySQL uses ? placeholders and `backquoted` identifiers
+	MySQL
+	// SQLite uses ? placeholders and "double quoted" identifiers
+	SQLite
+)
+
+// placeholder returns the placeholder of the n-th (1-based) argument
+func (d Dialect) placeholder(n int) string {
+	if d == PostgreSQL {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// quote quotes an identifier, quoting each part of a qualified name such as schema.table
+func (d Dialect) quote(identifier string) string {
+	quote := `"`
+	if d == MySQL {
+		quote = "`"
+	}
+	parts := strings.Split(identifier, ".")
+	for i, part := range parts {
+		parts[i] = quote + strings.ReplaceAll(part, quote, quote+quote) + quote
+	}
+	return strings.Join(parts, ".")
+}
+
+// Options configures statement generation
+type Options struct {
+	// Dialect selects the SQL syntax, PostgreSQL by default
+	Dialect Dialect
+	// BatchSize is the maximum number of rows per INSERT statement
+	BatchSize int
+}
+
+// Option is a function that configures Options
+type Option func(*Options)
+
+// WithDialect returns an Option that selects the SQL dialect
+func WithDialect(dialect Dialect) Option {
+	return func(o *Options) {
+		o.Dialect = dialect
+	}
+}
+
+// WithBatchSize returns an Option that sets the maximum number of rows per statement
+func WithBatchSize(size int) Option {
+	return func(o *Options) {
+		o.BatchSize = size
+	}
+}
+
+// DefaultOptions returns the default options: PostgreSQL syntax and 100 rows per statement
+func DefaultOptions() *Options {
+	return &Options{
+		Dialect:   PostgreSQL,
+		BatchSize: 100,
+	}
+}
+
+// Inserts returns INSERT statements for the elements at the index-free recordPath,
+// one row per element in index order (see xmlsurf.XMLMap.GetAllSubtrees).
+// Values missing from a record are inserted as NULL. The recordPath must be absolute.
+func Inserts(m xmlsurf.XMLMap, table, recordPath string, columns []Column, opts ...Option) ([]Statement, error) {
+	options := DefaultOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("sqlrows: no columns")
+	}
+	if options.BatchSize < 1 {
+		return nil, fmt.Errorf("sqlrows: invalid batch size %d", options.BatchSize)
+	}
+	if !strings.HasPrefix(recordPath, "/") || len(recordPath) == 1 {
+		return nil, fmt.Errorf("sqlrows: record path %q is not absolute", recordPath)
+	}
+
+	prefix := "INSERT INTO " + options.Dialect.quote(table) + " (" + quotedNames(columns, options.Dialect) + ") VALUES "
+	root := recordPath[strings.LastIndex(recordPath, "/"):]
+
+	records := m.GetAllSubtrees(recordPath)
+	statements := make([]Statement, 0, (len(records)+options.BatchSize-1)/options.BatchSize)
+	for start := 0; start < len(records); start += options.BatchSize {
+		batch := records[start:min(start+options.BatchSize, len(records))]
+
+		var query strings.Builder
+		query.WriteString(prefix)
+		args := make([]any, 0, len(batch)*len(columns))
+		for i, record := range batch {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+			query.WriteString("(")
+			for j, column := range columns {
+				if j > 0 {
+					query.WriteString(", ")
+				}
+				args = append(args, columnValue(record, root, column.Path))
+				query.WriteString(options.Dialect.placeholder(len(args)))
+			}
+			query.WriteString(")")
+		}
+		statements = append(statements, Statement{Query: query.String(), Args: args})
+	}
+	return statements, nil
+}
+
+// Execer executes statements; *sql.DB, *sql.Tx and *sql.Conn implement it
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Load inserts the elements at recordPath into table in batches, see Inserts.
+// It returns the number of inserted rows. Use a *sql.Tx to load all batches atomically.
+func Load(ctx context.Context, db Execer, m xmlsurf.XMLMap, table, recordPath string, columns []Column, opts ...Option) (int64, error) {
+	statements, err := Inserts(m, table, recordPath, columns, opts...)
+	if err != nil {
+		return 0, err
+	}
+
+	var inserted int64
+	for i, statement := range statements {
+		result, err := db.ExecContext(ctx, statement.Query, statement.Args...)
+		if err != nil {
+			return inserted, fmt.Errorf("sqlrows: batch %d: %w", i+1, err)
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			inserted += n
+		}
+	}
+	return inserted, nil
+}
+
+// quotedNames returns the quoted, comma separated column names
+func quotedNames(columns []Column, dialect Dialect) string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = dialect.quote(column.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// columnValue returns the value at the relative path of a record, or nil if it is missing
+func columnValue(record xmlsurf.XMLMap, root, path string) any {
+	if path != "" && path != "." {
+		root += "/" + strings.TrimPrefix(path, "/")
+	}
+	if value, ok := record[root]; ok {
+		return value
+	}
+	return nil
+}
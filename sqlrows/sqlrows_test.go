@@ -0,0 +1,121 @@
+package sqlrows
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bmcszk/xmlsurf"
+)
+
+var orders = xmlsurf.XMLMap{
+	"/feed/order[1]/@id":           "1",
+	"/feed/order[1]/customer/name": "Alice",
+	"/feed/order[2]/@id":           "2",
+	"/feed/order[3]/@id":           "3",
+	"/feed/order[3]/customer/name": "Carol",
+}
+
+var orderColumns = []Column{
+	{Name: "id", Path: "@id"},
+	{Name: "customer", Path: "customer/name"},
+}
+
+func TestInserts(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected []Statement
+	}{
+		{
+			name: "postgres in one batch",
+			expected: []Statement{{
+				Query: `INSERT INTO "staging"."orders" ("id", "customer") VALUES ($1, $2), ($3, $4), ($5, $6)`,
+				Args:  []any{"1", "Alice", "2", nil, "3", "Carol"},
+			}},
+		},
+		{
+			name: "mysql in batches of two",
+			opts: []Option{WithDialect(MySQL), WithBatchSize(2)},
+			expected: []Statement{
+				{
+					Query: "INSERT INTO `staging`.`orders` (`id`, `customer`) VALUES (?, ?), (?, ?)",
+					Args:  []any{"1", "Alice", "2", nil},
+				},
+				{
+					Query: "INSERT INTO `staging`.`orders` (`id`, `customer`) VALUES (?, ?)",
+					Args:  []any{"3", "Carol"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statements, err := Inserts(orders, "staging.orders", "/feed/order", orderColumns, tt.opts...)
+			if err != nil {
+				t.Fatalf("Inserts() error = %v", err)
+			}
+			if !reflect.DeepEqual(statements, tt.expected) {
+				t.Errorf("Inserts() = %#v, want %#v", statements, tt.expected)
+			}
+		})
+	}
+
+	if _, err := Inserts(orders, "orders", "/feed/order", nil); err == nil {
+		t.Errorf("Inserts() expected error without columns")
+	}
+	for _, recordPath := range []string{"order", "", "/"} {
+		if _, err := Inserts(orders, "orders", recordPath, orderColumns); err == nil {
+			t.Errorf("Inserts() expected error for record path %q", recordPath)
+		}
+	}
+}
+
+// recordingExecer records executed statements and fails after failAfter calls if set
+type recordingExecer struct {
+	statements []Statement
+	failAfter  int
+}
+
+func (e *recordingExecer) ExecContext(_ context.Context, query string, args ...any) (sql.Result, error) {
+	if e.failAfter > 0 && len(e.statements) == e.failAfter {
+		return nil, errors.New("connection lost")
+	}
+	e.statements = append(e.statements, Statement{Query: query, Args: args})
+	return driverResult(len(args) / 2), nil
+}
+
+// driverResult reports a fixed number of affected rows
+type driverResult int64
+
+func (r driverResult) LastInsertId() (int64, error) { return 0, errors.New("not supported") }
+func (r driverResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+func TestLoad(t *testing.T) {
+	db := &recordingExecer{}
+	inserted, err := Load(context.Background(), db, orders, "orders", "/feed/order", orderColumns, WithBatchSize(2))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if inserted != 3 || len(db.statements) != 2 {
+		t.Errorf("Load() inserted %d rows in %d statements, want 3 rows in 2", inserted, len(db.statements))
+	}
+
+	failing := &recordingExecer{failAfter: 1}
+	inserted, err = Load(context.Background(), failing, orders, "orders", "/feed/order", orderColumns, WithBatchSize(2))
+	if err == nil || inserted != 2 {
+		t.Errorf("Load() = %d, %v, want 2 rows and an error for batch 2", inserted, err)
+	}
+}
+
+func TestModuleRegistered(t *testing.T) {
+	for _, module := range xmlsurf.Capabilities().Modules {
+		if module == "sqlrows" {
+			return
+		}
+	}
+	t.Errorf("Capabilities().Modules = %v, want sqlrows", xmlsurf.Capabilities().Modules)
+}
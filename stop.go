@@ -0,0 +1,25 @@
+package xmlsurf
+
+// reached records that the element or attribute at the index-free path was read
+// and stops parsing once all paths of WithStopAfter were read
+func (b *documentBuilder) reached(path string) {
+	if !b.options.StopAfter[path] || b.stopFound[path] {
+		return
+	}
+	if b.stopFound == nil {
+		b.stopFound = make(map[string]bool, len(b.options.StopAfter))
+	}
+	b.stopFound[path] = true
+	if len(b.stopFound) == len(b.options.StopAfter) {
+		b.stopped = true
+	}
+}
+
+// elementEnded counts an element read up to its end tag and stops parsing once
+// the limit of WithStopAfterElements is reached
+func (b *documentBuilder) elementEnded() {
+	b.ended++
+	if b.options.StopAfterElements > 0 && b.ended >= b.options.StopAfterElements {
+		b.stopped = true
+	}
+}
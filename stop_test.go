@@ -0,0 +1,81 @@
+package xmlsurf
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// failingReader fails every read, standing in for input that must not be read
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("read past the requested content")
+}
+
+func TestWithStopAfter(t *testing.T) {
+	head := `<Envelope><Header><MessageID>42</MessageID><To id="7">svc</To><From>a</From></Header>`
+
+	tests := []struct {
+		name string
+		opts []Option
+		want XMLMap
+	}{
+		{
+			name: "element",
+			opts: []Option{WithStopAfter("/Envelope/Header/MessageID")},
+			want: XMLMap{"/Envelope/Header/MessageID": "42"},
+		},
+		{
+			name: "element and attribute",
+			opts: []Option{WithStopAfter("/Envelope/Header/To/@id", "/Envelope/Header/MessageID")},
+			want: XMLMap{"/Envelope/Header/MessageID": "42", "/Envelope/Header/To/@id": "7"},
+		},
+		{
+			name: "element count",
+			opts: []Option{WithStopAfterElements(2)},
+			want: XMLMap{"/Envelope/Header/MessageID": "42", "/Envelope/Header/To/@id": "7", "/Envelope/Header/To": "svc"},
+		},
+		{
+			name: "enclosing element",
+			opts: []Option{WithStopAfter("/Envelope/Header")},
+			want: XMLMap{
+				"/Envelope/Header/MessageID": "42", "/Envelope/Header/To/@id": "7",
+				"/Envelope/Header/To": "svc", "/Envelope/Header/From": "a",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseToMap(io.MultiReader(strings.NewReader(head), failingReader{}), tt.opts...)
+			if err != nil {
+				t.Fatalf("ParseToMap() error = %v", err)
+			}
+			if !reflect.DeepEqual(m, tt.want) {
+				t.Errorf("ParseToMap() = %v, want %v", m, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithStopAfterNotFound(t *testing.T) {
+	input := `<order><item>a</item><item>b</item></order>`
+
+	// Paths that never all appear read the whole document
+	m, err := ParseToMap(strings.NewReader(input), WithStopAfter("/order/item", "/order/missing"))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+	want := XMLMap{"/order/item[1]": "a", "/order/item[2]": "b"}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("ParseToMap() = %v, want %v", m, want)
+	}
+
+	// The error of content read before stopping is reported
+	if _, err := ParseToMap(strings.NewReader(`<order><a>1</b>`), WithStopAfter("/order/a")); err == nil {
+		t.Error("ParseToMap() error = nil, want a syntax error")
+	}
+}
@@ -0,0 +1,310 @@
+package xmlsurf
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Record is a child element of the root read by a Stream
+type Record struct {
+	// Path is the path of the element in the document, e.g. /feed/order[3].
+	// The index counts the elements with the same name read so far and is always present,
+	// because a stream cannot know whether more elements with the same name follow.
+	Path string
+	// Map holds the subtree of the element, rooted at the element: /order/@id
+	Map XMLMap
+}
+
+// Checkpoint is the serializable state of a Stream after the last returned record.
+// Store it (e.g. as JSON) and pass it to ResumeStream to continue after a restart.
+type Checkpoint struct {
+	// Offset is the byte offset in the input just after the last returned record
+	Offset int64 `json:"offset"`
+	// Root is the open root element
+	Root CheckpointElement `json:"root"`
+	// Counts holds the number of records read per element name, for record indices
+	Counts map[string]int `json:"counts"`
+	// Records is the total number of records read
+	Records int `json:"records"`
+}
+
+// CheckpointElement describes an open element of a checkpointed stream
+type CheckpointElement struct {
+	Space      string                `json:"space,omitempty"` // Namespace URI as reported by encoding/xml
+	Local      string                `json:"local"`
+	Namespaces []CheckpointNamespace `json:"namespaces,omitempty"` // Declarations on the element
+}
+
+// CheckpointNamespace is a namespace declaration; an empty Prefix declares the default namespace
+type CheckpointNamespace struct {
+	Prefix string `json:"prefix,omitempty"`
+	URI    string `json:"uri"`
+}
+
+// Stream reads a document one child element of the root at a time, so documents
+// larger than memory can be processed record by record. Each record is returned
+// as soon as its end tag has been read, so a Stream also works on never-ending
+// XML streams over network connections, such as XMPP, where the root element
+// stays open for the lifetime of the connection.
+type Stream struct {
+	decoder *xml.Decoder
+	input   *bomReader
+	options *ParseOptions
+	// base is added to decoder offsets to get offsets in the original input
+	base     int64
+	root     *CheckpointElement
+	rootPath string
+	// header holds the attributes of the root element
+	header XMLMap
+	// namespaces holds the declarations of the root element
+	namespaces *namespaceScopes
+	counts     map[string]int
+	records    int
+	offset     int64
+	done       bool
+}
+
+// NewStream returns a Stream reading records from r
+func NewStream(r io.Reader, opts ...Option) *Stream {
+	parsed := DefaultParseOptions()
+	for _, opt := range opts {
+		opt(parsed)
+	}
+	// Records are children of the root element, so WithAllowFragment does not apply,
+	// the caller decides when to stop reading them and which of them to skip. Their
+	// text is handled as in a whole document.
+	options := parsed.innerOptions()
+	options.SizeHint = parsed.SizeHint
+	options.CollectValues = parsed.CollectValues
+	options.BinaryPaths, options.BinarySink = parsed.BinaryPaths, parsed.BinarySink
+	options.SpillLimit, options.SpillDir = parsed.SpillLimit, parsed.SpillDir
+	options.RedactPatterns = parsed.RedactPatterns
+	options.AutoDecompress = parsed.AutoDecompress
+	input := &bomReader{source: r, decompress: options.AutoDecompress}
+	return &Stream{
+		decoder: newBOMDecoder(input),
+		input:   input,
+		options: options,
+		counts:  make(map[string]int),
+	}
+}
+
+// ResumeStream continues a stream from a checkpoint. r must be positioned at
+// checkpoint.Offset of the original input, e.g. a file after Seek(checkpoint.Offset, io.SeekStart).
+// The options must be the same as those of the checkpointed stream.
+func ResumeStream(r io.Reader, checkpoint Checkpoint, opts ...Option) *Stream {
+	if checkpoint.Root.Local == "" {
+		// Checkpointed before the root element was read
+		return NewStream(r, opts...)
+	}
+
+	// Re-open the root element so the decoder knows its namespace declarations
+	// and accepts its end tag
+	prefix := checkpoint.Root.startTag()
+
+	s := NewStream(io.MultiReader(strings.NewReader(prefix), r), opts...)
+	s.base = checkpoint.Offset - int64(len(prefix))
+	s.offset = checkpoint.Offset
+	s.records = checkpoint.Records
+	for name, count := range checkpoint.Counts {
+		s.counts[name] = count
+	}
+	return s
+}
+
+// Header reads the input up to the start tag of the root element, if that has not
+// happened yet, and returns the attributes of the root element, e.g. /stream:stream/@id.
+// Protocols like XMPP need them before the first record arrives.
+// After ResumeStream the header holds no attributes.
+func (s *Stream) Header() (_ XMLMap, err error) {
+	defer recoverPanic("stream", &err)
+
+	for s.root == nil {
+		token, err := s.decoder.Token()
+		if err == io.EOF {
+			return nil, ErrEmptyDocument
+		}
+		if err != nil {
+			return nil, decodeError(s.decoder, err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			if err := s.openRoot(start); err != nil {
+				return nil, decodeError(s.decoder, err)
+			}
+		}
+	}
+	return s.header.Clone(), nil
+}
+
+// Next returns the next record. It returns io.EOF after the end tag of the root element.
+func (s *Stream) Next() (_ Record, err error) {
+	defer recoverPanic("stream", &err)
+
+	if s.done {
+		return Record{}, io.EOF
+	}
+	if _, err := s.Header(); err != nil {
+		return Record{}, err
+	}
+
+	for {
+		token, err := s.decoder.Token()
+		if err != nil {
+			return Record{}, decodeError(s.decoder, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			return s.readRecord(t)
+		case xml.EndElement:
+			s.done = true
+			return Record{}, io.EOF
+		}
+	}
+}
+
+// Namespaces returns the namespace declarations of the root element as prefixes
+// mapped to URIs, e.g. for writing records with WithNamespaceDeclarations.
+// It returns nil before the root element has been read.
+func (s *Stream) Namespaces() map[string]string {
+	if s.namespaces == nil {
+		return nil
+	}
+	return s.namespaces.inScope()
+}
+
+// Checkpoint returns the state of the stream after the last returned record.
+// Offsets only count bytes of UTF-8 input read as it is, so it fails with
+// ErrConvertedInput for UTF-16 input and for input decompressed by WithAutoDecompress.
+func (s *Stream) Checkpoint() (Checkpoint, error) {
+	if s.input.converted {
+		return Checkpoint{}, ErrConvertedInput
+	}
+	counts := make(map[string]int, len(s.counts))
+	for name, count := range s.counts {
+		counts[name] = count
+	}
+	checkpoint := Checkpoint{
+		Offset:  s.offset,
+		Counts:  counts,
+		Records: s.records,
+	}
+	if s.root != nil {
+		checkpoint.Root = *s.root
+	}
+	return checkpoint, nil
+}
+
+// openRoot records the root element
+func (s *Stream) openRoot(start xml.StartElement) error {
+	if err := checkNames(start); err != nil {
+		return err
+	}
+	root := &CheckpointElement{Space: start.Name.Space, Local: start.Name.Local}
+	s.namespaces = &namespaceScopes{}
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			prefix := attr.Name.Local
+			if attr.Name.Space == "" {
+				prefix = ""
+			}
+			root.Namespaces = append(root.Namespaces, CheckpointNamespace{Prefix: prefix, URI: attr.Value})
+		}
+	}
+	s.namespaces.push(start.Attr)
+	s.root = root
+
+	pathBuilder := getPathBuilder()
+	defer putPathBuilder(pathBuilder)
+	s.rootPath = "/" + buildElementName(start.Name.Local, start.Name.Space, s.namespaces,
+		s.options.IncludeNamespaces, s.options.DefaultNamespacePrefix, pathBuilder)
+
+	s.header = make(XMLMap, len(start.Attr))
+	for _, attr := range start.Attr {
+		if name, value, ok := processAttribute(attr, s.namespaces, s.options, pathBuilder); ok {
+			s.header[s.rootPath+"/@"+name] = value
+		}
+	}
+	return nil
+}
+
+// readRecord reads the element started by start up to its end tag
+func (s *Stream) readRecord(start xml.StartElement) (Record, error) {
+	builder := newDocumentBuilder(s.options, newParseState(s.options.SizeHint))
+	defer builder.close()
+
+	// Elements of the record resolve prefixes declared on the root
+	for _, binding := range s.namespaces.bindings {
+		builder.state.namespaces.declare(binding.prefix, binding.uri)
+	}
+
+	if err := builder.add(start); err != nil {
+		return Record{}, decodeError(s.decoder, err)
+	}
+	for depth := 1; depth > 0; {
+		token, err := s.decoder.Token()
+		if err != nil {
+			return Record{}, decodeError(s.decoder, err)
+		}
+		switch token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+		if err := builder.add(token); err != nil {
+			return Record{}, decodeError(s.decoder, err)
+		}
+	}
+
+	name := builder.state.nodes[0].name
+	m, err := builder.result(nil)
+	if errors.Is(err, ErrEmptyDocument) {
+		// An empty element without attributes has no paths of its own
+		m = XMLMap{}
+	}
+
+	s.counts[name]++
+	s.records++
+	s.offset = s.base + s.decoder.InputOffset()
+	var path strings.Builder
+	path.WriteString(s.rootPath)
+	path.WriteString("/")
+	path.WriteString(name)
+	s.options.IndexStyle.appendIndex(&path, s.options.index(s.counts[name]))
+	return Record{Path: path.String(), Map: m}, nil
+}
+
+// startTag returns a start tag re-declaring the element and its namespaces
+func (e CheckpointElement) startTag() string {
+	var b strings.Builder
+	b.WriteString("<")
+	name := e.Local
+	if e.Space != "" {
+		name = e.Space + ":" + e.Local
+		for _, ns := range e.Namespaces {
+			if ns.URI == e.Space {
+				name = e.Local
+				if ns.Prefix != "" {
+					name = ns.Prefix + ":" + e.Local
+				}
+				break
+			}
+		}
+	}
+	b.WriteString(name)
+	for _, ns := range e.Namespaces {
+		b.WriteString(" xmlns")
+		if ns.Prefix != "" {
+			b.WriteString(":")
+			b.WriteString(ns.Prefix)
+		}
+		b.WriteString(`="`)
+		xml.EscapeText(&b, []byte(ns.URI))
+		b.WriteString(`"`)
+	}
+	b.WriteString(">")
+	return b.String()
+}
@@ -0,0 +1,198 @@
+package xmlsurf
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const streamDocument = `<?xml version="1.0"?>
+<f:feed xmlns:f="urn:feed" xmlns="urn:default" version="1">
+	<f:order id="1"><total>10</total></f:order>
+	<f:order id="2"><total>20</total></f:order>
+	<note>text</note>
+	<f:order id="3"><total>30</total></f:order>
+</f:feed>`
+
+// readAll reads the remaining records of a stream
+func readAll(t *testing.T, s *Stream) []Record {
+	t.Helper()
+	var records []Record
+	for {
+		record, err := s.Next()
+		if err == io.EOF {
+			return records
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		records = append(records, record)
+	}
+}
+
+func TestStream(t *testing.T) {
+	records := readAll(t, NewStream(strings.NewReader(streamDocument)))
+
+	expected := []Record{
+		{Path: "/f:feed/f:order[1]", Map: XMLMap{"/f:order/@id": "1", "/f:order/total": "10"}},
+		{Path: "/f:feed/f:order[2]", Map: XMLMap{"/f:order/@id": "2", "/f:order/total": "20"}},
+		{Path: "/f:feed/note[1]", Map: XMLMap{"/note": "text"}},
+		{Path: "/f:feed/f:order[3]", Map: XMLMap{"/f:order/@id": "3", "/f:order/total": "30"}},
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("records = %v, want %v", records, expected)
+	}
+
+	if _, err := NewStream(strings.NewReader(`<feed><a>1</a>`)).Next(); err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	s := NewStream(strings.NewReader(`<feed><a>1</a>`))
+	s.Next()
+	if _, err := s.Next(); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("Next() on truncated input error = %v, want a syntax error", err)
+	}
+}
+
+func TestStreamCheckpoint(t *testing.T) {
+	all := readAll(t, NewStream(strings.NewReader(streamDocument)))
+
+	for processed := 0; processed <= len(all); processed++ {
+		s := NewStream(strings.NewReader(streamDocument))
+		for i := 0; i < processed; i++ {
+			if _, err := s.Next(); err != nil {
+				t.Fatalf("Next() error = %v", err)
+			}
+		}
+
+		// Simulate a restart: persist the checkpoint and resume from its offset
+		checkpoint, err := s.Checkpoint()
+		if err != nil {
+			t.Fatalf("Checkpoint() error = %v", err)
+		}
+		data, err := json.Marshal(checkpoint)
+		if err != nil {
+			t.Fatalf("json.Marshal() error = %v", err)
+		}
+		checkpoint = Checkpoint{}
+		if err := json.Unmarshal(data, &checkpoint); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+
+		resumed := ResumeStream(strings.NewReader(streamDocument[checkpoint.Offset:]), checkpoint)
+		rest := readAll(t, resumed)
+		if len(rest) != len(all)-processed || (len(rest) > 0 && !reflect.DeepEqual(rest, all[processed:])) {
+			t.Errorf("after %d records, resumed records = %v, want %v", processed, rest, all[processed:])
+		}
+		if checkpoint.Records != processed {
+			t.Errorf("Checkpoint().Records = %d, want %d", checkpoint.Records, processed)
+		}
+	}
+}
+
+func TestStreamCheckpointConvertedInput(t *testing.T) {
+	gzipWriter := func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }
+	tests := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{name: "UTF-8", input: streamDocument},
+		{name: "UTF-16", input: encodeUTF16(streamDocument, false), want: ErrConvertedInput},
+		{name: "gzip", input: compress(t, streamDocument, gzipWriter), want: ErrConvertedInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStream(strings.NewReader(tt.input), WithAutoDecompress(true))
+			if _, err := s.Next(); err != nil {
+				t.Fatalf("Next() error = %v", err)
+			}
+			if _, err := s.Checkpoint(); !errors.Is(err, tt.want) {
+				t.Errorf("Checkpoint() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamUnbounded(t *testing.T) {
+	// The writer end never closes the root element until the end of the test,
+	// like an XMPP connection
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	writes := make(chan string)
+	go func() {
+		for s := range writes {
+			if _, err := io.WriteString(pw, s); err != nil {
+				return
+			}
+		}
+		pw.Close()
+	}()
+	defer close(writes)
+
+	stream := NewStream(pr)
+	writes <- `<stream:stream xmlns:stream="http://etherx.jabber.org/streams" xmlns="jabber:client" id="abc" from="example.com">`
+	header, err := stream.Header()
+	if err != nil {
+		t.Fatalf("Header() error = %v", err)
+	}
+	if got := header["/stream:stream/@id"]; got != "abc" {
+		t.Errorf("header id = %q, want abc (header %v)", got, header)
+	}
+
+	for i, stanza := range []string{
+		`<message to="a"><body>one</body></message>`,
+		`<presence/><message to="b"><body>two</body></message>`,
+	} {
+		writes <- stanza
+		record, err := stream.Next()
+		if err != nil {
+			t.Fatalf("Next() #%d error = %v", i, err)
+		}
+		if i == 1 {
+			// The empty presence element has no paths but is still a record
+			if record.Path != "/stream:stream/presence[1]" {
+				t.Errorf("Next() #%d path = %q", i, record.Path)
+			}
+			if record, err = stream.Next(); err != nil {
+				t.Fatalf("Next() #%d error = %v", i, err)
+			}
+		}
+		want := XMLMap{"/message/@to": string(rune('a' + i)), "/message/body": []string{"one", "two"}[i]}
+		if !reflect.DeepEqual(record.Map, want) {
+			t.Errorf("Next() #%d map = %v, want %v", i, record.Map, want)
+		}
+	}
+
+	writes <- `</stream:stream>`
+	if _, err := stream.Next(); err != io.EOF {
+		t.Errorf("Next() after close error = %v, want io.EOF", err)
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	if _, err := NewStream(strings.NewReader("")).Next(); !errors.Is(err, ErrEmptyDocument) {
+		t.Errorf("Next() on empty input error = %v, want ErrEmptyDocument", err)
+	}
+
+	stream := NewStream(strings.NewReader("<feed>\n<order><id>1</id></order>\n<order><id>2</order></feed>"))
+	if _, err := stream.Next(); err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	_, err := stream.Next()
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Line != 3 {
+		t.Errorf("Next() error = %v, want a *SyntaxError on line 3", err)
+	}
+
+	for _, input := range []string{`<feed x:0="1"><order/></feed>`, `<feed><order><b a:0="1"/></order></feed>`} {
+		_, err := NewStream(strings.NewReader(input)).Next()
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Next() on %s error = %v, want a *SyntaxError", input, err)
+		}
+	}
+}
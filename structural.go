@@ -0,0 +1,221 @@
+package xmlsurf
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// structuralNode is an element of a map viewed as a tree
+type structuralNode struct {
+	path     string   // Path of the element, e.g. /order/items/item[2]
+	name     string   // Element name without index
+	index    int      // 1-based index, 1 for an element without index
+	lines    []string // Own values as suffix and value, e.g. "/@id=7" or "=text"
+	children []*structuralNode
+	digest   [sha256.Size]byte // Digest of the subtree ignoring the order of repeated elements
+	sequence [sha256.Size]byte // Digest of the subtree including the order of repeated elements
+}
+
+// matchStructurally renumbers the instances of repeated elements in right so that
+// each instance gets the index of an instance in left with an identical subtree.
+// Instances without an identical partner are paired with the remaining left
+// instances in document order, so their differences are reported field by field;
+// surplus instances are numbered after the instances of left.
+func matchStructurally(left, right XMLMap) XMLMap {
+	leftTree, _ := left.structuralTree()
+	rightTree, elementOf := right.structuralTree()
+
+	return right.renamed(elementOf, leftTree, rightTree, pairBySubtree)
+}
+
+// renamed pairs the trees of left and m and returns m with the paths of the paired elements
+func (m XMLMap) renamed(elementOf map[string]string, leftTree, tree *structuralNode, pair pairFunc) XMLMap {
+	renamed := make(map[string]string, len(elementOf))
+	pairTrees(leftTree, tree, renamed, pair)
+
+	result := make(XMLMap, len(m))
+	for path, value := range m {
+		element := elementOf[path]
+		result[renamed[element]+path[len(element):]] = value
+	}
+	return result
+}
+
+// structuralTree builds the element tree of m below a virtual top node with
+// computed subtree digests, and the element path of every path of m
+func (m XMLMap) structuralTree() (*structuralNode, map[string]string) {
+	top := &structuralNode{}
+	nodes := map[string]*structuralNode{"": top}
+	elementOf := make(map[string]string, len(m))
+
+	var nodeAt func(path string) *structuralNode
+	nodeAt = func(path string) *structuralNode {
+		if node, ok := nodes[path]; ok {
+			return node
+		}
+		slash := strings.LastIndex(path, "/")
+		parent := nodeAt(path[:slash])
+		name, index := splitIndex(path[slash+1:])
+		node := &structuralNode{path: path, name: name, index: index}
+		parent.children = append(parent.children, node)
+		nodes[path] = node
+		return node
+	}
+
+	for path, value := range m {
+		element := path
+		if nested := strings.Index(element, NestedSeparator); nested != -1 {
+			element = element[:nested]
+		}
+		if attr := strings.LastIndex(element, "/@"); attr != -1 {
+			element = element[:attr]
+		}
+		elementOf[path] = element
+		node := nodeAt(element)
+		node.lines = append(node.lines, path[len(element):]+"="+value)
+	}
+
+	top.computeDigest()
+	return top, elementOf
+}
+
+// computeDigest sets the digests of the node and its descendants. Both cover the own
+// values and, per child name, the digests of the children; for digest they are sorted,
+// so it does not depend on the order of repeated elements at any depth.
+func (n *structuralNode) computeDigest() {
+	sort.Slice(n.children, func(i, j int) bool {
+		if n.children[i].name != n.children[j].name {
+			return n.children[i].name < n.children[j].name
+		}
+		return n.children[i].index < n.children[j].index
+	})
+	sort.Strings(n.lines)
+
+	unordered, sequence := sha256.New(), sha256.New()
+	writeString := func(h hash.Hash, s string) {
+		var size [binary.MaxVarintLen64]byte
+		h.Write(size[:binary.PutUvarint(size[:], uint64(len(s)))])
+		h.Write([]byte(s))
+	}
+	for _, line := range n.lines {
+		writeString(unordered, line)
+		writeString(sequence, line)
+	}
+
+	for start := 0; start < len(n.children); {
+		end := start
+		for end < len(n.children) && n.children[end].name == n.children[start].name {
+			n.children[end].computeDigest()
+			end++
+		}
+		digests := make([]string, 0, end-start)
+		writeString(sequence, "/"+n.children[start].name)
+		for _, child := range n.children[start:end] {
+			digests = append(digests, string(child.digest[:]))
+			writeString(sequence, string(child.sequence[:]))
+		}
+		sort.Strings(digests)
+		writeString(unordered, "/"+n.children[start].name)
+		for _, digest := range digests {
+			writeString(unordered, digest)
+		}
+		start = end
+	}
+	unordered.Sum(n.digest[:0])
+	sequence.Sum(n.sequence[:0])
+}
+
+// pairFunc returns the partner in left of each instance in right, or nil for
+// instances without a partner. Both lists are instances of the same element
+// ordered by index.
+type pairFunc func(left, right []*structuralNode) []*structuralNode
+
+// pairTrees records the new paths of the descendants of right, which is paired with
+// left. Instances without a partner are numbered after the instances of left.
+func pairTrees(left, right *structuralNode, renamed map[string]string, pair pairFunc) {
+	leftGroups := make(map[string][]*structuralNode)
+	for _, child := range left.children {
+		leftGroups[child.name] = append(leftGroups[child.name], child)
+	}
+
+	for start := 0; start < len(right.children); {
+		end := start
+		for end < len(right.children) && right.children[end].name == right.children[start].name {
+			end++
+		}
+		name := right.children[start].name
+		candidates := leftGroups[name]
+		partners := pair(candidates, right.children[start:end])
+
+		next := 1
+		for _, candidate := range candidates {
+			next = max(next, candidate.index+1)
+		}
+		for i, child := range right.children[start:end] {
+			if partners[i] != nil {
+				renamed[child.path] = partners[i].path
+				pairTrees(partners[i], child, renamed, pair)
+				continue
+			}
+			child.renameSubtree(right.newPath(renamed)+"/"+name+"["+strconv.Itoa(next)+"]", renamed)
+			next++
+		}
+		start = end
+	}
+}
+
+// pairBySubtree pairs identical subtrees first, then the remaining instances in document order
+func pairBySubtree(left, right []*structuralNode) []*structuralNode {
+	partners := make([]*structuralNode, len(right))
+	used := make([]bool, len(left))
+
+	// Queue the unused instances of every subtree digest in document order
+	queues := make(map[[sha256.Size]byte][]int, len(left))
+	for j, candidate := range left {
+		queues[candidate.digest] = append(queues[candidate.digest], j)
+	}
+	for i, child := range right {
+		queue := queues[child.digest]
+		if len(queue) == 0 {
+			continue
+		}
+		j := queue[0]
+		queues[child.digest] = queue[1:]
+		partners[i], used[j] = left[j], true
+	}
+
+	j := 0
+	for i := range right {
+		if partners[i] != nil {
+			continue
+		}
+		for j < len(left) && used[j] {
+			j++
+		}
+		if j == len(left) {
+			break
+		}
+		partners[i], used[j] = left[j], true
+	}
+	return partners
+}
+
+// newPath returns the path the node is renamed to; the virtual top node has an empty path
+func (n *structuralNode) newPath(renamed map[string]string) string {
+	if n.path == "" {
+		return ""
+	}
+	return renamed[n.path]
+}
+
+// renameSubtree records path as the new path of the node and moves its descendants along
+func (n *structuralNode) renameSubtree(path string, renamed map[string]string) {
+	renamed[n.path] = path
+	for _, child := range n.children {
+		child.renameSubtree(path+child.path[len(n.path):], renamed)
+	}
+}
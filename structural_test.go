@@ -0,0 +1,140 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestWithStructuralOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		left  XMLMap
+		right XMLMap
+		want  []Diff
+	}{
+		{
+			name: "reordered subtrees",
+			left: XMLMap{
+				"/r/item[1]/a": "1", "/r/item[1]/b": "2",
+				"/r/item[2]/a": "3", "/r/item[2]/b": "4",
+			},
+			right: XMLMap{
+				"/r/item[1]/a": "3", "/r/item[1]/b": "4",
+				"/r/item[2]/a": "1", "/r/item[2]/b": "2",
+			},
+			want: []Diff{},
+		},
+		{
+			name:  "values swapped between fields",
+			left:  XMLMap{"/r/item/a": "1", "/r/item/b": "2"},
+			right: XMLMap{"/r/item/a": "2", "/r/item/b": "1"},
+			want: []Diff{
+				{Path: "/r/item/a", LeftValue: "1", RightValue: "2", Type: DiffValue},
+				{Path: "/r/item/b", LeftValue: "2", RightValue: "1", Type: DiffValue},
+			},
+		},
+		{
+			name: "values swapped between instances",
+			left: XMLMap{
+				"/r/item[1]/a": "1", "/r/item[1]/b": "2",
+				"/r/item[2]/a": "3", "/r/item[2]/b": "4",
+			},
+			right: XMLMap{
+				"/r/item[1]/a": "3", "/r/item[1]/b": "2",
+				"/r/item[2]/a": "1", "/r/item[2]/b": "4",
+			},
+			want: []Diff{
+				{Path: "/r/item[1]/a", LeftValue: "1", RightValue: "3", Type: DiffValue},
+				{Path: "/r/item[2]/a", LeftValue: "3", RightValue: "1", Type: DiffValue},
+			},
+		},
+		{
+			name: "changed instance paired after identical ones",
+			left: XMLMap{
+				"/r/item[1]/@id": "a", "/r/item[1]/qty": "1",
+				"/r/item[2]/@id": "b", "/r/item[2]/qty": "2",
+			},
+			right: XMLMap{
+				"/r/item[1]/@id": "b", "/r/item[1]/qty": "5",
+				"/r/item[2]/@id": "a", "/r/item[2]/qty": "1",
+			},
+			want: []Diff{
+				{Path: "/r/item[2]/qty", LeftValue: "2", RightValue: "5", Type: DiffValue},
+			},
+		},
+		{
+			name: "nested repeated elements in another order",
+			left: XMLMap{
+				"/r/order[1]/line[1]": "x", "/r/order[1]/line[2]": "y",
+				"/r/order[2]/line": "z",
+			},
+			right: XMLMap{
+				"/r/order[1]/line":    "z",
+				"/r/order[2]/line[1]": "y", "/r/order[2]/line[2]": "x",
+			},
+			want: []Diff{},
+		},
+		{
+			name:  "surplus instances",
+			left:  XMLMap{"/r/item[1]/a": "1", "/r/item[2]/a": "2"},
+			right: XMLMap{"/r/item[1]/a": "3", "/r/item[2]/a": "2", "/r/item[3]/a": "1"},
+			want: []Diff{
+				{Path: "/r/item[3]/a", RightValue: "3", Type: DiffMissing},
+			},
+		},
+		{
+			name: "identical subtrees pair in document order",
+			left: XMLMap{
+				"/r/item[1]/a": "1", "/r/item[2]/a": "2", "/r/item[3]/a": "1", "/r/item[4]/a": "3",
+			},
+			right: XMLMap{
+				"/r/item[1]/a": "4", "/r/item[2]/a": "1", "/r/item[3]/a": "2", "/r/item[4]/a": "1",
+			},
+			want: []Diff{
+				{Path: "/r/item[4]/a", LeftValue: "3", RightValue: "4", Type: DiffValue},
+			},
+		},
+		{
+			name:  "embedded documents are part of the subtree",
+			left:  XMLMap{"/r/doc[1]!/x/y": "1", "/r/doc[2]!/x/y": "2"},
+			right: XMLMap{"/r/doc[1]!/x/y": "2", "/r/doc[2]!/x/y": "1"},
+			want:  []Diff{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.left.DiffsIgnoreOrder(tt.right, WithStructuralOrder(true))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffsIgnoreOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithStructuralOrderDetectsCrossFieldSwap(t *testing.T) {
+	left := XMLMap{"/r/item[1]/a": "1", "/r/item[1]/b": "2", "/r/item[2]/a": "2", "/r/item[2]/b": "1"}
+	right := XMLMap{"/r/item[1]/a": "2", "/r/item[1]/b": "2", "/r/item[2]/a": "1", "/r/item[2]/b": "1"}
+
+	// Values pooled per path are the same on both sides
+	if !left.EqualIgnoreOrder(right) {
+		t.Error("EqualIgnoreOrder() = false without structural order")
+	}
+	if left.EqualIgnoreOrder(right, WithStructuralOrder(true)) {
+		t.Error("EqualIgnoreOrder() = true with structural order")
+	}
+}
+
+func BenchmarkWithStructuralOrderManyDuplicates(b *testing.B) {
+	left, right := make(XMLMap), make(XMLMap)
+	for i := 1; i <= 5000; i++ {
+		value := strconv.Itoa(i % 10)
+		left["/r/item["+strconv.Itoa(i)+"]/a"] = value
+		right["/r/item["+strconv.Itoa(5001-i)+"]/a"] = value
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		left.EqualIgnoreOrder(right, WithStructuralOrder(true))
+	}
+}
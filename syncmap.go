@@ -0,0 +1,99 @@
+package xmlsurf
+
+import (
+	"io"
+	"sync"
+)
+
+// SyncXMLMap is an XMLMap guarded by a read-write mutex, for maps that are
+// modified while other goroutines read them, such as templates shared by HTTP handlers.
+// The zero value is an empty map ready to use.
+type SyncXMLMap struct {
+	mu sync.RWMutex
+	m  XMLMap
+}
+
+// NewSyncXMLMap returns a SyncXMLMap holding a copy of m
+func NewSyncXMLMap(m XMLMap) *SyncXMLMap {
+	return &SyncXMLMap{m: m.Clone()}
+}
+
+// Get returns the value at path and whether it exists
+func (s *SyncXMLMap) Get(path string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.m[path]
+	return value, ok
+}
+
+// Set stores the value at path
+func (s *SyncXMLMap) Set(path, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m == nil {
+		s.m = make(XMLMap)
+	}
+	s.m[path] = value
+}
+
+// Delete removes the value at path
+func (s *SyncXMLMap) Delete(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, path)
+}
+
+// Len returns the number of paths
+func (s *SyncXMLMap) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.m)
+}
+
+// Snapshot returns a copy of the current map that can be used without locking
+func (s *SyncXMLMap) Snapshot() XMLMap {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.Clone()
+}
+
+// Update calls fn with the map while holding the write lock, so several
+// changes are applied atomically. fn must not keep the map after returning.
+func (s *SyncXMLMap) Update(fn func(m XMLMap)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m == nil {
+		s.m = make(XMLMap)
+	}
+	fn(s.m)
+}
+
+// Merge merges other into the map, see XMLMap.Merge
+func (s *SyncXMLMap) Merge(other XMLMap, strategy MergeStrategy) error {
+	var err error
+	s.Update(func(m XMLMap) {
+		err = m.Merge(other, strategy)
+	})
+	return err
+}
+
+// Diffs compares the current map with other, see XMLMap.Diffs
+func (s *SyncXMLMap) Diffs(other XMLMap, opts ...CompareOption) []Diff {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.Diffs(other, opts...)
+}
+
+// Equal reports whether the current map equals other, see XMLMap.Equal
+func (s *SyncXMLMap) Equal(other XMLMap, opts ...CompareOption) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.Equal(other, opts...)
+}
+
+// ToXML writes the current map as XML, see XMLMap.ToXML. The map is copied under
+// the read lock and written without holding it, so a slow writer does not block
+// writers of the map.
+func (s *SyncXMLMap) ToXML(w io.Writer, indent bool) error {
+	return s.Snapshot().ToXML(w, indent)
+}
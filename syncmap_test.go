@@ -0,0 +1,102 @@
+package xmlsurf
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSyncXMLMap(t *testing.T) {
+	template := XMLMap{"/root/name": "template"}
+	s := NewSyncXMLMap(template)
+
+	s.Set("/root/name", "changed")
+	if template["/root/name"] != "template" {
+		t.Errorf("NewSyncXMLMap() shares storage with the original map")
+	}
+	if value, ok := s.Get("/root/name"); !ok || value != "changed" {
+		t.Errorf("Get() = %q, %v, want %q, true", value, ok, "changed")
+	}
+
+	s.Merge(XMLMap{"/root/extra": "1"}, MergeOverwrite)
+	s.Delete("/root/name")
+	if !s.Equal(XMLMap{"/root/extra": "1"}) {
+		t.Errorf("Snapshot() = %v", s.Snapshot())
+	}
+
+	var zero SyncXMLMap
+	zero.Set("/root", "v")
+	if zero.Len() != 1 {
+		t.Errorf("zero value Len() = %d, want 1", zero.Len())
+	}
+}
+
+// blockingWriter blocks every write until release is closed
+type blockingWriter struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	close(w.entered)
+	<-w.release
+	return len(p), nil
+}
+
+func TestSyncXMLMapToXMLDoesNotHoldLock(t *testing.T) {
+	s := NewSyncXMLMap(XMLMap{"/root/a": "1"})
+	w := &blockingWriter{entered: make(chan struct{}), release: make(chan struct{})}
+	defer close(w.release)
+
+	go func() {
+		_ = s.ToXML(w, false)
+	}()
+	<-w.entered
+
+	set := make(chan struct{})
+	go func() {
+		s.Set("/root/b", "2")
+		close(set)
+	}()
+	select {
+	case <-set:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Set() blocked while ToXML() was writing")
+	}
+}
+
+// TestSyncXMLMapConcurrentAccess mixes writers and readers. Run with -race.
+func TestSyncXMLMapConcurrentAccess(t *testing.T) {
+	s := NewSyncXMLMap(XMLMap{"/root/count": "0"})
+	expected := XMLMap{"/root/count": "0"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				s.Set(fmt.Sprintf("/root/w%d/item[%d]", i, j+1), "x")
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				var buf bytes.Buffer
+				if err := s.ToXML(&buf, false); err != nil {
+					t.Errorf("ToXML() error = %v", err)
+					return
+				}
+				s.Diffs(expected)
+				s.Get("/root/count")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if s.Len() != 1+8*50 {
+		t.Errorf("Len() = %d, want %d", s.Len(), 1+8*50)
+	}
+}
@@ -0,0 +1,55 @@
+package xmlsurf
+
+import "encoding/xml"
+
+// Tokens returns the XML of the map as tokens: start elements, character data and
+// end elements, in the order WriteXML writes them. Element orders and namespace
+// declarations are applied; options that only affect formatting, such as indentation
+// or WithSelfClosing, are ignored. Qualified names are kept as local names like
+// "soap:Body", so encoding the tokens writes the prefixes of the map.
+func (m XMLMap) Tokens(opts ...WriteOption) (_ []xml.Token, err error) {
+	defer recoverPanic("write XML", &err)
+
+	options := DefaultWriteOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+	root, err := m.writeTree(options, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	compareFn := options.elementOrder()
+	var tokens []xml.Token
+	var appendNode func(node *xmlNode)
+	appendNode = func(node *xmlNode) {
+		start := node.startElement()
+		tokens = append(tokens, start)
+		if node.value != "" {
+			tokens = append(tokens, xml.CharData(node.value))
+		}
+		sortChildren(node, compareFn)
+		for _, child := range node.children {
+			appendNode(child)
+		}
+		tokens = append(tokens, start.End())
+	}
+	appendNode(root)
+	return tokens, nil
+}
+
+// EncodeTokens encodes the XML of the map with enc, so it can be embedded in a larger
+// document, e.g. as the body of a hand-written envelope. It encodes the tokens returned
+// by Tokens; like any use of EncodeToken, the caller flushes the encoder.
+func (m XMLMap) EncodeTokens(enc *xml.Encoder, opts ...WriteOption) error {
+	tokens, err := m.Tokens(opts...)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if err := enc.EncodeToken(token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package xmlsurf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokens(t *testing.T) {
+	m := XMLMap{
+		"/order/@id":         "7",
+		"/order/item[1]":     "a",
+		"/order/item[2]/qty": "2",
+		"/order/note":        "x < y",
+	}
+
+	tokens, err := m.Tokens(WithSequence("/order", "note", "item"))
+	if err != nil {
+		t.Fatalf("Tokens() error = %v", err)
+	}
+	start := func(name string, attrs ...xml.Attr) xml.StartElement {
+		return xml.StartElement{Name: xml.Name{Local: name}, Attr: attrs}
+	}
+	end := func(name string) xml.EndElement { return xml.EndElement{Name: xml.Name{Local: name}} }
+	want := []xml.Token{
+		start("order", xml.Attr{Name: xml.Name{Local: "id"}, Value: "7"}),
+		start("note"), xml.CharData("x < y"), end("note"),
+		start("item"), xml.CharData("a"), end("item"),
+		start("item"), start("qty"), xml.CharData("2"), end("qty"), end("item"),
+		end("order"),
+	}
+	if !reflect.DeepEqual(tokens, want) {
+		t.Errorf("Tokens() = %v, want %v", tokens, want)
+	}
+
+	if _, err := (XMLMap{}).Tokens(); err == nil {
+		t.Error("Tokens() of an empty map error = nil")
+	}
+}
+
+func TestEncodeTokens(t *testing.T) {
+	body := XMLMap{
+		"/m:GetOrder/m:id":      "42",
+		"/m:GetOrder/m:note[1]": "a&b",
+		"/m:GetOrder/m:note[2]": "c",
+	}
+
+	// A hand-written envelope around the map
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	envelope := xml.StartElement{Name: xml.Name{Local: "soap:Envelope"}, Attr: []xml.Attr{
+		{Name: xml.Name{Local: "xmlns:soap"}, Value: "urn:soap"},
+	}}
+	bodyStart := xml.StartElement{Name: xml.Name{Local: "soap:Body"}}
+	if err := enc.EncodeToken(envelope); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.EncodeToken(bodyStart); err != nil {
+		t.Fatal(err)
+	}
+	if err := body.EncodeTokens(enc, WithNamespaceDeclarations(map[string]string{"m": "urn:m"})); err != nil {
+		t.Fatalf("EncodeTokens() error = %v", err)
+	}
+	if err := enc.EncodeToken(bodyStart.End()); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.EncodeToken(envelope.End()); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<soap:Envelope xmlns:soap="urn:soap"><soap:Body><m:GetOrder xmlns:m="urn:m"><m:id>42</m:id>` +
+		`<m:note>a&amp;b</m:note><m:note>c</m:note></m:GetOrder></soap:Body></soap:Envelope>`
+	if buf.String() != want {
+		t.Errorf("encoded = %s, want %s", buf.String(), want)
+	}
+
+	// The embedded body is written like WriteXML writes it
+	var written strings.Builder
+	if err := body.WriteXML(&written, WithNamespaceDeclarations(map[string]string{"m": "urn:m"})); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), written.String()) {
+		t.Errorf("encoded = %s, want it to contain %s", buf.String(), written.String())
+	}
+}
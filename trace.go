@@ -0,0 +1,129 @@
+package xmlsurf
+
+import (
+	"context"
+	"encoding/xml"
+	"log/slog"
+)
+
+// TraceKind identifies the kind of a TraceEvent
+type TraceKind int
+
+const (
+	// TraceStartElement is emitted for every start tag
+	TraceStartElement TraceKind = iota + 1
+	// TraceEndElement is emitted for every end tag
+	TraceEndElement
+	// TraceNamespace is emitted for every namespace declaration, after the start tag declaring it
+	TraceNamespace
+	// TraceError is emitted when parsing fails at a token
+	TraceError
+)
+
+// String returns the name of the kind, e.g. "start_element"
+func (k TraceKind) String() string {
+	switch k {
+	case TraceStartElement:
+		return "start_element"
+	case TraceEndElement:
+		return "end_element"
+	case TraceNamespace:
+		return "namespace"
+	case TraceError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// TraceEvent is a structured event emitted while parsing, see WithTraceHook
+type TraceEvent struct {
+	Kind TraceKind
+	// Position is where the token starts
+	Position Position
+	// Depth is the nesting depth of the element, 1 for the root
+	Depth int
+	// Name is the element name; Space holds the namespace URI, not the prefix
+	Name xml.Name
+	// Prefix and URI describe a namespace declaration; an empty Prefix is the default namespace
+	Prefix, URI string
+	// Err is the error of a TraceError event
+	Err error
+}
+
+// LogValue returns the event as a group of attributes, so events can be logged
+// with log/slog directly
+func (e TraceEvent) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("kind", e.Kind.String()),
+		slog.String("position", e.Position.String()),
+		slog.Int("depth", e.Depth),
+	}
+	if e.Name.Local != "" {
+		attrs = append(attrs, slog.String("name", e.Name.Local))
+		if e.Name.Space != "" {
+			attrs = append(attrs, slog.String("space", e.Name.Space))
+		}
+	}
+	if e.Kind == TraceNamespace {
+		attrs = append(attrs, slog.String("prefix", e.Prefix), slog.String("uri", e.URI))
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// SlogTraceHook returns a trace hook for WithTraceHook logging every event to logger
+// at the given level, and errors at slog.LevelError
+func SlogTraceHook(logger *slog.Logger, level slog.Level) func(TraceEvent) {
+	return func(event TraceEvent) {
+		eventLevel := level
+		if event.Kind == TraceError {
+			eventLevel = slog.LevelError
+		}
+		logger.LogAttrs(context.Background(), eventLevel, "xmlsurf "+event.Kind.String(), slog.Any("event", event))
+	}
+}
+
+// tracer emits the trace events of one parse
+type tracer struct {
+	hook  func(TraceEvent)
+	depth int
+}
+
+// enabled reports whether events are emitted
+func (t *tracer) enabled() bool {
+	return t.hook != nil
+}
+
+// token emits the events of a token read at position
+func (t *tracer) token(token xml.Token, position Position) {
+	if t.hook == nil {
+		return
+	}
+	switch tok := token.(type) {
+	case xml.StartElement:
+		t.depth++
+		t.hook(TraceEvent{Kind: TraceStartElement, Position: position, Depth: t.depth, Name: tok.Name})
+		for _, attr := range tok.Attr {
+			switch {
+			case attr.Name.Space == "xmlns":
+				t.hook(TraceEvent{Kind: TraceNamespace, Position: position, Depth: t.depth, Prefix: attr.Name.Local, URI: attr.Value})
+			case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+				t.hook(TraceEvent{Kind: TraceNamespace, Position: position, Depth: t.depth, URI: attr.Value})
+			}
+		}
+	case xml.EndElement:
+		t.hook(TraceEvent{Kind: TraceEndElement, Position: position, Depth: t.depth, Name: tok.Name})
+		t.depth--
+	}
+}
+
+// error emits a TraceError event and returns err
+func (t *tracer) error(err error, position Position) error {
+	if t.hook != nil {
+		t.hook(TraceEvent{Kind: TraceError, Position: position, Depth: t.depth, Err: err})
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package xmlsurf
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestWithTraceHook(t *testing.T) {
+	input := "<root xmlns:a=\"urn:a\">\n  <a:item>1</a:item>\n</root>"
+
+	var events []TraceEvent
+	_, err := ParseToMap(strings.NewReader(input), WithTraceHook(func(event TraceEvent) {
+		events = append(events, event)
+	}))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+
+	want := []struct {
+		kind     TraceKind
+		name     string
+		depth    int
+		position string
+	}{
+		{TraceStartElement, "root", 1, "1:1"},
+		{TraceNamespace, "", 1, "1:1"},
+		{TraceStartElement, "item", 2, "2:3"},
+		{TraceEndElement, "item", 2, "2:12"},
+		{TraceEndElement, "root", 1, "3:1"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %v", len(events), len(want), events)
+	}
+	for i, w := range want {
+		got := events[i]
+		if got.Kind != w.kind || got.Name.Local != w.name || got.Depth != w.depth || got.Position.String() != w.position {
+			t.Errorf("event %d = %v %q depth %d at %s, want %v %q depth %d at %s",
+				i, got.Kind, got.Name.Local, got.Depth, got.Position, w.kind, w.name, w.depth, w.position)
+		}
+	}
+	if events[1].Prefix != "a" || events[1].URI != "urn:a" {
+		t.Errorf("namespace event = %q=%q, want a=urn:a", events[1].Prefix, events[1].URI)
+	}
+	if events[2].Name.Space != "urn:a" {
+		t.Errorf("item namespace = %q, want urn:a", events[2].Name.Space)
+	}
+}
+
+func TestWithTraceHookError(t *testing.T) {
+	var last TraceEvent
+	_, err := ParseToMap(strings.NewReader("<root>\n<a></b>"), WithTraceHook(func(event TraceEvent) {
+		last = event
+	}))
+
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("ParseToMap() error = %v, want a *SyntaxError", err)
+	}
+	if last.Kind != TraceError || last.Err != err {
+		t.Errorf("last event = %v with %v, want the parse error", last.Kind, last.Err)
+	}
+}
+
+func TestSlogTraceHook(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	_, err := ParseToMap(strings.NewReader(`<root>1</root>`), WithTraceHook(SlogTraceHook(logger, slog.LevelDebug)))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{
+		`level=DEBUG msg="xmlsurf start_element" event.kind=start_element event.position=1:1 event.depth=1 event.name=root`,
+		`msg="xmlsurf end_element"`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("log output %q does not contain %q", output, want)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package xmlsurf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Rule is a step of a Transform. Exactly one of Rename, Move, Drop and Map is set:
+//
+//   - Rename renames the elements or attributes matching the pattern to the name To,
+//     keeping their indices and everything below them
+//   - Move moves the elements at the path, with everything below them, to the path To,
+//     keeping the index of every instance
+//   - Drop removes the paths matching the patterns and everything below them
+//   - Map replaces the values at the paths matching the pattern that are keys of Values;
+//     other values are kept
+//
+// Patterns may contain the wildcards of PatternDiffs. Like Move, a pattern whose last
+// segment has no index selects every instance of a repeated element.
+type Rule struct {
+	Rename string            `json:"rename,omitempty" yaml:"rename,omitempty"`
+	Move   string            `json:"move,omitempty" yaml:"move,omitempty"`
+	To     string            `json:"to,omitempty" yaml:"to,omitempty"`
+	Drop   []string          `json:"drop,omitempty" yaml:"drop,omitempty"`
+	Map    string            `json:"map,omitempty" yaml:"map,omitempty"`
+	Values map[string]string `json:"values,omitempty" yaml:"values,omitempty"`
+}
+
+// RenameRule returns a Rule renaming the elements or attributes matching the pattern to name
+func RenameRule(pattern, name string) Rule {
+	return Rule{Rename: pattern, To: name}
+}
+
+// MoveRule returns a Rule moving the elements at the path from to the path to
+func MoveRule(from, to string) Rule {
+	return Rule{Move: from, To: to}
+}
+
+// DropRule returns a Rule removing the paths matching the patterns
+func DropRule(patterns ...string) Rule {
+	return Rule{Drop: patterns}
+}
+
+// MapValuesRule returns a Rule replacing the values at the paths matching the pattern
+func MapValuesRule(pattern string, values map[string]string) Rule {
+	return Rule{Map: pattern, Values: values}
+}
+
+// Transform rewrites maps by applying rules in order, e.g. to convert the responses of
+// one vendor to the format of another without hard-coding the rewrites
+type Transform struct {
+	rules []Rule
+}
+
+// NewTransform returns a Transform applying the rules in order. It fails if a rule
+// sets none or several of Rename, Move, Drop and Map, or lacks what its kind needs.
+func NewTransform(rules ...Rule) (*Transform, error) {
+	for i, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("transform rule %d: %w", i+1, err)
+		}
+	}
+	return &Transform{rules: rules}, nil
+}
+
+// LoadTransform reads a Transform from a JSON document listing its rules under
+// "rules", e.g.
+//
+//	{"rules": [
+//	  {"rename": "/resp/order/custId", "to": "customerId"},
+//	  {"move": "/resp/order/lines/line", "to": "/order/items/item"},
+//	  {"drop": ["/resp/debug", "/resp/order/@internal"]},
+//	  {"map": "/order/status", "values": {"S": "shipped", "O": "open"}}
+//	]}
+//
+// Unknown keys are rejected, so typos do not silently disable a rule. The yaml
+// subpackage loads the same rules from YAML.
+func LoadTransform(r io.Reader) (*Transform, error) {
+	var config struct {
+		Rules []Rule `json:"rules"`
+	}
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("load transform: empty document")
+		}
+		return nil, fmt.Errorf("load transform: %w", err)
+	}
+	return NewTransform(config.Rules...)
+}
+
+// Apply returns a copy of the map with the rules applied in order; m is not modified.
+// It fails with an error wrapping ErrDuplicatePath if a rule rewrites a path onto
+// another path of the map.
+func (t *Transform) Apply(m XMLMap) (XMLMap, error) {
+	result := m.Clone()
+	for i, rule := range t.rules {
+		var err error
+		if result, err = rule.apply(result); err != nil {
+			return nil, fmt.Errorf("transform rule %d: %w", i+1, err)
+		}
+	}
+	return result, nil
+}
+
+// validate checks that the rule has exactly one kind and what the kind needs
+func (r Rule) validate() error {
+	kinds := 0
+	for _, set := range []bool{r.Rename != "", r.Move != "", len(r.Drop) > 0, r.Map != ""} {
+		if set {
+			kinds++
+		}
+	}
+	switch {
+	case kinds == 0:
+		return errors.New("one of rename, move, drop and map is required")
+	case kinds > 1:
+		return errors.New("only one of rename, move, drop and map may be set")
+	case r.Rename != "":
+		if r.To == "" || strings.ContainsAny(r.To, "/"+NestedSeparator) {
+			return fmt.Errorf("rename %s: to must be a name, got %q", r.Rename, r.To)
+		}
+		attribute := strings.HasPrefix(r.Rename[strings.LastIndexByte(r.Rename, '/')+1:], "@")
+		if attribute != strings.HasPrefix(r.To, "@") {
+			return fmt.Errorf("rename %s: cannot rename between element and attribute %s", r.Rename, r.To)
+		}
+	case r.Move != "":
+		if !strings.HasPrefix(r.To, "/") {
+			return fmt.Errorf("move %s: to must be a path, got %q", r.Move, r.To)
+		}
+	case r.Map != "":
+		if len(r.Values) == 0 {
+			return fmt.Errorf("map %s: values are required", r.Map)
+		}
+	}
+	if r.To != "" && r.Rename == "" && r.Move == "" {
+		return errors.New("to is only allowed with rename and move")
+	}
+	if len(r.Values) > 0 && r.Map == "" {
+		return errors.New("values are only allowed with map")
+	}
+	return nil
+}
+
+// apply returns the map rewritten by the rule
+func (r Rule) apply(m XMLMap) (XMLMap, error) {
+	switch {
+	case len(r.Drop) > 0:
+		patterns := compilePathPatterns(r.Drop)
+		for path := range m {
+			if matchesPathOrAncestor(patterns, path) {
+				delete(m, path)
+			}
+		}
+		return m, nil
+	case r.Map != "":
+		pattern := compilePathPattern(r.Map)
+		for path, value := range m {
+			if !matchesInstance(pattern, path) {
+				continue
+			}
+			if mapped, ok := r.Values[value]; ok {
+				m[path] = mapped
+			}
+		}
+		return m, nil
+	case r.Rename != "":
+		pattern := compilePathPattern(r.Rename)
+		return rewritePaths(m, func(path string) string {
+			end, ok := matchedPrefix(pattern, path)
+			if !ok {
+				return path
+			}
+			// Replace the name of the last segment of the prefix, keeping its index
+			start := strings.LastIndexByte(path[:end], '/') + 1
+			name, _ := splitIndex(path[start:end])
+			return path[:start] + r.To + path[start+len(name):]
+		})
+	default:
+		from := strings.TrimSuffix(r.Move, "/")
+		return rewritePaths(m, func(path string) string {
+			record, ok := recordOf(path, from)
+			if !ok {
+				return path
+			}
+			return r.To + record[len(from):] + path[len(record):]
+		})
+	}
+}
+
+// rewritePaths returns a map with every path replaced by rewrite, failing if two paths
+// end up the same
+func rewritePaths(m XMLMap, rewrite func(path string) string) (XMLMap, error) {
+	result := make(XMLMap, len(m))
+	for path, value := range m {
+		rewritten := rewrite(path)
+		if _, exists := result[rewritten]; exists {
+			return nil, fmt.Errorf("%w %s", ErrDuplicatePath, rewritten)
+		}
+		result[rewritten] = value
+	}
+	return result, nil
+}
+
+// matchedPrefix returns the length of the shortest prefix of the path, ending at a
+// segment boundary, that is an instance matching the pattern
+func matchedPrefix(pattern pathPattern, path string) (int, bool) {
+	for end := 1; end <= len(path); end++ {
+		if end < len(path) && path[end] != '/' && !strings.HasPrefix(path[end:], NestedSeparator) {
+			continue
+		}
+		if matchesInstance(pattern, path[:end]) {
+			return end, true
+		}
+	}
+	return 0, false
+}
+
+// matchesInstance reports whether the pattern matches the path, or the path without
+// the index of its last segment
+func matchesInstance(pattern pathPattern, path string) bool {
+	if pattern.match(path) {
+		return true
+	}
+	if !strings.HasSuffix(path, "]") {
+		return false
+	}
+	open := strings.LastIndexByte(path, '[')
+	return open > strings.LastIndexByte(path, '/') && pattern.match(path[:open])
+}
@@ -0,0 +1,166 @@
+package xmlsurf
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTransformApply(t *testing.T) {
+	m := XMLMap{
+		"/resp/order/@id":                  "42",
+		"/resp/order/@internal":            "x",
+		"/resp/order/custId":               "c1",
+		"/resp/order/status":               "S",
+		"/resp/order/lines/line[1]/sku":    "a",
+		"/resp/order/lines/line[1]/@state": "O",
+		"/resp/order/lines/line[2]/sku":    "b",
+		"/resp/order/lines/line[2]/@state": "X",
+		"/resp/debug/trace":                "t",
+		"/resp/order/payload!/doc/custId":  "c2",
+	}
+
+	tests := []struct {
+		name    string
+		rules   []Rule
+		want    XMLMap
+		wantErr error
+	}{
+		{
+			name:  "rename element and attribute",
+			rules: []Rule{RenameRule("/resp/order/custId", "customerId"), RenameRule("/resp/order/lines/line[*]/@state", "@status")},
+			want: XMLMap{
+				"/resp/order/@id":                   "42",
+				"/resp/order/@internal":             "x",
+				"/resp/order/customerId":            "c1",
+				"/resp/order/status":                "S",
+				"/resp/order/lines/line[1]/sku":     "a",
+				"/resp/order/lines/line[1]/@status": "O",
+				"/resp/order/lines/line[2]/sku":     "b",
+				"/resp/order/lines/line[2]/@status": "X",
+				"/resp/debug/trace":                 "t",
+				"/resp/order/payload!/doc/custId":   "c2",
+			},
+		},
+		{
+			name: "move, drop and map",
+			rules: []Rule{
+				DropRule("/resp/debug", "/resp/*/@internal", "/resp/order/payload"),
+				MoveRule("/resp/order/lines/line", "/resp/order/items/item"),
+				RenameRule("/resp", "order"),
+				MoveRule("/order/order", "/order/header"),
+				MapValuesRule("/order/header/status", map[string]string{"S": "shipped", "O": "open"}),
+				MapValuesRule("/order/header/items/item[*]/@state", map[string]string{"O": "open"}),
+			},
+			want: XMLMap{
+				"/order/header/@id":                  "42",
+				"/order/header/custId":               "c1",
+				"/order/header/status":               "shipped",
+				"/order/header/items/item[1]/sku":    "a",
+				"/order/header/items/item[1]/@state": "open",
+				"/order/header/items/item[2]/sku":    "b",
+				"/order/header/items/item[2]/@state": "X",
+			},
+		},
+		{
+			name:  "rename inside embedded document",
+			rules: []Rule{RenameRule("/resp/order/payload!/doc/custId", "customer"), DropRule("/resp/order/lines", "/resp/debug")},
+			want: XMLMap{
+				"/resp/order/@id":                   "42",
+				"/resp/order/@internal":             "x",
+				"/resp/order/custId":                "c1",
+				"/resp/order/status":                "S",
+				"/resp/order/payload!/doc/customer": "c2",
+			},
+		},
+		{
+			name:  "rename every instance",
+			rules: []Rule{RenameRule("/resp/order/lines/line", "entry"), DropRule("/resp/order/@*", "/resp/order/custId", "/resp/order/status", "/resp/order/payload", "/resp/debug")},
+			want: XMLMap{
+				"/resp/order/lines/entry[1]/sku":    "a",
+				"/resp/order/lines/entry[1]/@state": "O",
+				"/resp/order/lines/entry[2]/sku":    "b",
+				"/resp/order/lines/entry[2]/@state": "X",
+			},
+		},
+		{
+			name:    "collision",
+			rules:   []Rule{RenameRule("/resp/order/status", "custId")},
+			wantErr: ErrDuplicatePath,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transform, err := NewTransform(tt.rules...)
+			if err != nil {
+				t.Fatalf("NewTransform() error = %v", err)
+			}
+			original := m.Clone()
+			got, err := transform.Apply(m)
+			if !reflect.DeepEqual(m, original) {
+				t.Errorf("Apply() modified its input")
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Apply() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Apply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTransformInvalidRules(t *testing.T) {
+	tests := []struct {
+		rule Rule
+		want string
+	}{
+		{rule: Rule{}, want: "one of rename, move, drop and map is required"},
+		{rule: Rule{Rename: "/a/b", Drop: []string{"/a/c"}}, want: "only one of"},
+		{rule: RenameRule("/a/b", "x/y"), want: "to must be a name"},
+		{rule: RenameRule("/a/@b", "c"), want: "between element and attribute"},
+		{rule: MoveRule("/a/b", "c"), want: "to must be a path"},
+		{rule: Rule{Map: "/a/b"}, want: "values are required"},
+		{rule: Rule{Drop: []string{"/a"}, To: "/b"}, want: "to is only allowed"},
+	}
+
+	for _, tt := range tests {
+		_, err := NewTransform(DropRule("/x"), tt.rule)
+		if err == nil || !strings.Contains(err.Error(), "rule 2: ") || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("NewTransform(%+v) error = %v, want %q", tt.rule, err, tt.want)
+		}
+	}
+}
+
+func TestLoadTransform(t *testing.T) {
+	m := XMLMap{"/a/old": "1", "/a/status": "S", "/a/debug": "d"}
+	want := XMLMap{"/b/new": "1", "/b/status": "shipped"}
+
+	transform, err := LoadTransform(strings.NewReader(`{"rules": [
+  {"rename": "/a/old", "to": "new"},
+  {"drop": ["/a/debug"]},
+  {"map": "/a/status", "values": {"S": "shipped"}},
+  {"move": "/a", "to": "/b"}
+]}`))
+	if err != nil {
+		t.Fatalf("LoadTransform() error = %v", err)
+	}
+	got, err := transform.Apply(m)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Apply() = %v, %v, want %v", got, err, want)
+	}
+
+	for _, config := range []string{"", `{"rules": [{"renam": "/a", "to": "b"}]}`, `{"rules": [{"move": "/a"}]}`} {
+		if _, err := LoadTransform(strings.NewReader(config)); err == nil {
+			t.Errorf("LoadTransform(%q) error = nil", config)
+		}
+	}
+}
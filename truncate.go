@@ -0,0 +1,89 @@
+package xmlsurf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Attributes added by Truncate to elements whose content was cut off. They are
+// valid attribute steps, so truncated views pass Validate and can be written as XML.
+const (
+	// TruncatedChildren holds the number of distinct child elements that were cut off
+	TruncatedChildren = "@xmlsurf-children"
+	// TruncatedHash holds a digest of all cut off paths and values, so truncated
+	// views of two documents differ exactly when the cut off content differs
+	TruncatedHash = "@xmlsurf-hash"
+)
+
+// Truncate returns a copy of the map limited to elements at most depth steps deep,
+// e.g. /root/order has depth 2. Attributes are kept with their element. Content
+// below the limit is summarized at the deepest kept element by the attributes
+// <element>/@xmlsurf-children and <element>/@xmlsurf-hash (see TruncatedChildren
+// and TruncatedHash).
+// A depth below 1 is treated as 1.
+func (m XMLMap) Truncate(depth int) XMLMap {
+	if depth < 1 {
+		depth = 1
+	}
+
+	result := make(XMLMap)
+	children := make(map[string]map[string]bool)
+	cutOff := make(map[string][]string)
+
+	for path, value := range m {
+		cut := elementDepthCut(path, depth)
+		if cut == -1 {
+			result[path] = value
+			continue
+		}
+
+		element := strings.TrimSuffix(path[:cut], NestedSeparator)
+		rest := path[cut+1:]
+		child := rest
+		if idx := strings.Index(rest, "/"); idx != -1 {
+			child = rest[:idx]
+		}
+		child = strings.TrimSuffix(child, NestedSeparator)
+
+		if children[element] == nil {
+			children[element] = make(map[string]bool)
+		}
+		children[element][child] = true
+		cutOff[element] = append(cutOff[element], path[len(element):]+"\x00"+value)
+	}
+
+	for element, entries := range cutOff {
+		sort.Strings(entries)
+		hash := sha256.New()
+		for _, entry := range entries {
+			hash.Write([]byte(entry))
+			hash.Write([]byte{0})
+		}
+		result[element+"/"+TruncatedChildren] = strconv.Itoa(len(children[element]))
+		result[element+"/"+TruncatedHash] = hex.EncodeToString(hash.Sum(nil)[:8])
+	}
+	return result
+}
+
+// elementDepthCut returns the position of the slash that starts the first element
+// step deeper than depth, or -1 if the path is not deeper than depth.
+// Attributes belong to their element and never start a cut.
+func elementDepthCut(path string, depth int) int {
+	slashes := 0
+	for i := 0; i < len(path); i++ {
+		if path[i] != '/' {
+			continue
+		}
+		slashes++
+		if slashes == depth+1 {
+			if strings.HasPrefix(path[i+1:], "@") {
+				return -1
+			}
+			return i
+		}
+	}
+	return -1
+}
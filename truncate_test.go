@@ -0,0 +1,69 @@
+package xmlsurf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestXMLMapTruncate(t *testing.T) {
+	m := XMLMap{
+		"/root/@version":              "1",
+		"/root/header/id":             "42",
+		"/root/orders/order[1]/@id":   "1",
+		"/root/orders/order[1]/total": "10",
+		"/root/orders/order[2]/@id":   "2",
+		"/root/orders/order[2]/total": "20",
+		"/root/orders/@count":         "2",
+		"/root/status":                "ok",
+	}
+
+	truncated := m.Truncate(2)
+	expectedPaths := map[string]string{
+		"/root/@version":                 "1",
+		"/root/orders/@count":            "2",
+		"/root/status":                   "ok",
+		"/root/header/@xmlsurf-children": "1",
+		"/root/orders/@xmlsurf-children": "2",
+	}
+	for path, value := range expectedPaths {
+		if truncated[path] != value {
+			t.Errorf("Truncate(2)[%s] = %q, want %q", path, truncated[path], value)
+		}
+	}
+	if _, ok := truncated["/root/orders/@xmlsurf-hash"]; !ok {
+		t.Errorf("Truncate(2) has no hash for /root/orders")
+	}
+	if len(truncated) != len(expectedPaths)+2 {
+		t.Errorf("Truncate(2) = %v", truncated)
+	}
+
+	// The summaries are attributes, so the view is a valid map that can be written
+	if err := truncated.Validate(); err != nil {
+		t.Errorf("Validate() of truncated view error = %v", err)
+	}
+	var builder strings.Builder
+	if err := truncated.WriteXML(&builder); err != nil {
+		t.Fatalf("WriteXML() of truncated view error = %v", err)
+	}
+	if !strings.Contains(builder.String(), `<orders count="2" xmlsurf-children="2" xmlsurf-hash="`) {
+		t.Errorf("WriteXML() of truncated view = %s", builder.String())
+	}
+
+	// A change below the limit only changes the hash of its truncated ancestor
+	changed := m.Clone()
+	changed["/root/orders/order[2]/total"] = "25"
+	diffs := truncated.Diffs(changed.Truncate(2))
+	if len(diffs) != 1 || diffs[0].Path != "/root/orders/@xmlsurf-hash" {
+		t.Errorf("Diffs of truncated views = %v, want only /root/orders/@xmlsurf-hash", diffs)
+	}
+
+	// Nothing is cut off when the limit exceeds the document depth
+	if full := m.Truncate(10); !full.Equal(m) {
+		t.Errorf("Truncate(10) = %v, want %v", full, m)
+	}
+
+	root := m.Truncate(0)
+	if root["/root/@xmlsurf-children"] != "3" || root["/root/@version"] != "1" {
+		t.Errorf("Truncate(0) = %v", root)
+	}
+}
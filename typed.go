@@ -0,0 +1,188 @@
+package xmlsurf
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TypedXMLMap holds the values of an XMLMap converted to Go types: int64, float64,
+// bool, time.Time or string
+type TypedXMLMap map[string]any
+
+const (
+	// xsiNamespaceURI is the namespace of the xsi:type attribute
+	xsiNamespaceURI = "http://www.w3.org/2001/XMLSchema-instance"
+	// xsdNamespaceURI is the namespace of the built-in XML Schema types
+	xsdNamespaceURI = "http://www.w3.org/2001/XMLSchema"
+)
+
+// dateTimeLocal is the layout of an xs:dateTime without a timezone
+const dateTimeLocal = "2006-01-02T15:04:05.999999999"
+
+// ParseToTypedMap parses XML like ParseToMap and converts the values like XMLMap.Typed.
+// Type attributes are recognized by their namespace rather than their prefix, so
+// i:type="s:int" works when i and s are declared, as does WithNamespaces(false).
+func ParseToTypedMap(reader io.Reader, opts ...Option) (TypedXMLMap, error) {
+	options := DefaultParseOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+	options.schemaTypes = make(map[string]string)
+
+	m, err := parseWithOptions(reader, options)
+	if err != nil {
+		return nil, err
+	}
+	return m.typed(options.schemaTypes), nil
+}
+
+// Typed returns the values of the map converted to Go types.
+// An element with an xsi:type attribute is converted according to that XML Schema type,
+// e.g. xs:int, xs:decimal, xs:boolean, xs:date or xs:dateTime, and stays a string
+// if the value does not match it. Other values are inferred: integers without
+// leading zeros become int64, decimal numbers float64, true and false bool, and
+// ISO-8601 dates (2006-01-02) and timestamps (RFC 3339) time.Time. Anything else,
+// including attribute values, stays a string.
+//
+// The map holds no namespace declarations, so only attributes named xsi:type are
+// recognized; use ParseToTypedMap for documents binding other prefixes.
+func (m XMLMap) Typed() TypedXMLMap {
+	schemaTypes := make(map[string]string)
+	for path, value := range m {
+		if element, ok := strings.CutSuffix(path, "/@xsi:type"); ok {
+			schemaTypes[element] = value[strings.LastIndexByte(value, ':')+1:]
+		}
+	}
+	return m.typed(schemaTypes)
+}
+
+// typed returns the values of the map converted to Go types, using the XML Schema
+// type of the elements in schemaTypes
+func (m XMLMap) typed(schemaTypes map[string]string) TypedXMLMap {
+	result := make(TypedXMLMap, len(m))
+	for path, value := range m {
+		if isAttributePath(path) {
+			result[path] = value
+			continue
+		}
+		if schemaType, ok := schemaTypes[path]; ok {
+			result[path] = schemaValue(value, schemaType)
+			continue
+		}
+		result[path] = inferValue(value)
+	}
+	return result
+}
+
+// resolveSchemaType returns the local name of an xsi:type value naming a built-in
+// XML Schema type. A type in another namespace is returned as written, so it matches
+// no built-in type; an undeclared prefix is assumed to be the schema namespace.
+func resolveSchemaType(value string, namespaces *namespaceScopes) string {
+	value = strings.TrimSpace(value)
+	prefix, local, found := strings.Cut(value, ":")
+	if !found {
+		prefix, local = "", value
+	}
+	if uri, ok := namespaces.uri(prefix); ok && uri != xsdNamespaceURI {
+		return value
+	}
+	return local
+}
+
+// schemaValue converts a value according to the local name of an XML Schema type
+func schemaValue(value, xsiType string) any {
+	trimmed := strings.TrimSpace(value)
+
+	switch xsiType {
+	case "int", "integer", "long", "short", "byte", "nonNegativeInteger", "nonPositiveInteger",
+		"positiveInteger", "negativeInteger", "unsignedInt", "unsignedShort", "unsignedByte", "unsignedLong":
+		if n, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+			return n
+		}
+	case "decimal", "float", "double":
+		if f, err := strconv.ParseFloat(trimmed, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		switch trimmed {
+		case "true", "1":
+			return true
+		case "false", "0":
+			return false
+		}
+	case "date":
+		if t, err := time.Parse(time.DateOnly, trimmed); err == nil {
+			return t
+		}
+	case "dateTime":
+		if t, err := time.Parse(time.RFC3339Nano, trimmed); err == nil {
+			return t
+		}
+		// The timezone is optional in XML Schema; a time without one is taken as UTC
+		if t, err := time.Parse(dateTimeLocal, trimmed); err == nil {
+			return t
+		}
+	}
+	return value
+}
+
+// inferValue converts a value without type information to the type it looks like
+func inferValue(value string) any {
+	if value == "" {
+		return value
+	}
+
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	if isPlainNumber(value) {
+		if !strings.ContainsAny(value, ".eE") {
+			// Leading zeros suggest an identifier such as a postal code
+			digits := strings.TrimPrefix(value, "-")
+			if len(digits) > 1 && digits[0] == '0' {
+				return value
+			}
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return n
+			}
+			return value
+		}
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+		return value
+	}
+
+	if len(value) == len(time.DateOnly) {
+		if t, err := time.Parse(time.DateOnly, value); err == nil {
+			return t
+		}
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t
+	}
+	return value
+}
+
+// isPlainNumber reports whether a value is written as a decimal number,
+// excluding forms such as NaN, Inf, hexadecimal or underscores that strconv accepts
+func isPlainNumber(value string) bool {
+	digits := false
+	for i, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = true
+		case r == '-' && i == 0, r == '.', r == 'e', r == 'E':
+		case (r == '-' || r == '+') && i > 0 && (value[i-1] == 'e' || value[i-1] == 'E'):
+		default:
+			return false
+		}
+	}
+	return digits
+}
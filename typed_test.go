@@ -0,0 +1,141 @@
+package xmlsurf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTyped(t *testing.T) {
+	input := `<order xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<id>42</id>
+	<zip>01234</zip>
+	<total>19.99</total>
+	<paid>true</paid>
+	<date>2024-03-01</date>
+	<created>2024-03-01T10:00:00Z</created>
+	<name>Widget</name>
+	<nan>NaN</nan>
+	<code xsi:type="xs:string">123</code>
+	<flag xsi:type="xs:boolean">1</flag>
+	<count xsi:type="xs:int">007</count>
+	<bad xsi:type="xs:int">many</bad>
+	<price currency="10">5e2</price>
+</order>`
+
+	got, err := ParseToTypedMap(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseToTypedMap() error = %v", err)
+	}
+
+	want := TypedXMLMap{
+		"/order/id":              int64(42),
+		"/order/zip":             "01234",
+		"/order/total":           19.99,
+		"/order/paid":            true,
+		"/order/date":            time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		"/order/created":         time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		"/order/name":            "Widget",
+		"/order/nan":             "NaN",
+		"/order/code":            "123",
+		"/order/code/@xsi:type":  "xs:string",
+		"/order/flag":            true,
+		"/order/flag/@xsi:type":  "xs:boolean",
+		"/order/count":           int64(7),
+		"/order/count/@xsi:type": "xs:int",
+		"/order/bad":             "many",
+		"/order/bad/@xsi:type":   "xs:int",
+		"/order/price":           500.0,
+		"/order/price/@currency": "10",
+	}
+	if !reflect.DeepEqual(got, want) {
+		for path, value := range want {
+			if !reflect.DeepEqual(got[path], value) {
+				t.Errorf("%s = %#v, want %#v", path, got[path], value)
+			}
+		}
+		if len(got) != len(want) {
+			t.Errorf("got %d paths, want %d", len(got), len(want))
+		}
+	}
+}
+
+func TestInferValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  any
+	}{
+		{"", ""},
+		{"0", int64(0)},
+		{"-5", int64(-5)},
+		{"-05", "-05"},
+		{"99999999999999999999", "99999999999999999999"},
+		{"1.5", 1.5},
+		{"-1.5e-3", -1.5e-3},
+		{"0x10", "0x10"},
+		{"1_000", "1_000"},
+		{"Inf", "Inf"},
+		{"True", "True"},
+		{"2024-13-01", "2024-13-01"},
+		{"-", "-"},
+	}
+	for _, tt := range tests {
+		if got := inferValue(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("inferValue(%q) = %#v, want %#v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseToTypedMapResolvesPrefixes(t *testing.T) {
+	input := `<order xmlns:i="http://www.w3.org/2001/XMLSchema-instance" xmlns:s="http://www.w3.org/2001/XMLSchema" xmlns:t="urn:types">
+	<count i:type="s:int">007</count>
+	<created i:type="s:dateTime">2024-03-01T10:00:00.5</created>
+	<code i:type="t:int">007</code>
+	<plain type="s:int">007</plain>
+</order>`
+
+	tests := []struct {
+		name   string
+		opts   []Option
+		prefix string
+	}{
+		{name: "with namespaces", prefix: "i:"},
+		{name: "without namespaces", opts: []Option{WithNamespaces(false)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseToTypedMap(strings.NewReader(input), tt.opts...)
+			if err != nil {
+				t.Fatalf("ParseToTypedMap() error = %v", err)
+			}
+			want := map[string]any{
+				"/order/count":   int64(7),
+				"/order/created": time.Date(2024, 3, 1, 10, 0, 0, 5e8, time.UTC),
+				// A type in another namespace is not a built-in type
+				"/order/code": "007",
+				// Without the xsi namespace the attribute is an ordinary one
+				"/order/plain": "007",
+			}
+			for path, value := range want {
+				if !reflect.DeepEqual(got[path], value) {
+					t.Errorf("%s = %#v, want %#v", path, got[path], value)
+				}
+			}
+			if got["/order/count/@"+tt.prefix+"type"] != "s:int" {
+				t.Errorf("type attribute missing: %v", got)
+			}
+		})
+	}
+}
+
+func TestTypedDateTimeWithoutTimezone(t *testing.T) {
+	m := XMLMap{
+		"/root/at":           "2024-03-01T10:00:00",
+		"/root/at/@xsi:type": "xs:dateTime",
+	}
+	want := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	if got := m.Typed()["/root/at"]; got != want {
+		t.Errorf("Typed() = %#v, want %#v", got, want)
+	}
+}
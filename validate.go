@@ -0,0 +1,61 @@
+package xmlsurf
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Validate checks that every key of the map is a well-formed path: it starts with /,
+// names are not empty and hold no reserved characters, indices are positive integers
+// in brackets, an attribute is the last step and belongs to an element, and paths into
+// embedded documents are split at NestedSeparator after an element.
+// It also checks that all paths share one root element. All problems are reported,
+// ordered by path, joined with errors.Join.
+func (m XMLMap) Validate() error {
+	paths := make([]string, 0, len(m))
+	for path := range m {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var errs []error
+	roots := make(map[string]string)
+	for _, path := range paths {
+		parts := SplitNestedPath(path)
+		valid := true
+		for i, part := range parts {
+			p, err := ParsePath(part)
+			if err != nil {
+				errs = append(errs, err)
+				valid = false
+				break
+			}
+			if i < len(parts)-1 && p.IsAttr() {
+				errs = append(errs, fmt.Errorf("path %q: attribute %s holds an embedded document", path, p.Attribute()))
+				valid = false
+				break
+			}
+		}
+		if !valid {
+			continue
+		}
+
+		root, _ := ParsePath(parts[0])
+		name := root.Segments()[0].Name
+		if _, ok := roots[name]; !ok {
+			roots[name] = path
+		}
+	}
+
+	if len(roots) > 1 {
+		names := make([]string, 0, len(roots))
+		for name := range roots {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		errs = append(errs, fmt.Errorf("multiple root elements: %v, e.g. %q and %q",
+			names, roots[names[0]], roots[names[1]]))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,62 @@
+package xmlsurf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       XMLMap
+		wantErr []string // Substrings of the error, nil if the map is valid
+	}{
+		{
+			name: "valid",
+			m: XMLMap{
+				"/root/items/item[1]/@id": "1",
+				"/root/items/item[2]":     "b",
+				"/root/payload!/order/id": "7",
+				"/root/s:Body":            "x",
+			},
+		},
+		{name: "empty map", m: XMLMap{}},
+		{name: "missing leading slash", m: XMLMap{"root/a": "1"}, wantErr: []string{"must start with /"}},
+		{name: "dangling @", m: XMLMap{"/root/@": "1"}, wantErr: []string{"empty name"}},
+		{name: "@ inside a name", m: XMLMap{"/root/a@b": "1"}, wantErr: []string{"invalid character '@'"}},
+		{name: "zero index", m: XMLMap{"/root/a[0]": "1"}, wantErr: []string{"malformed index"}},
+		{name: "unclosed index", m: XMLMap{"/root/a[1": "1"}, wantErr: []string{"malformed index"}},
+		{name: "orphan attribute", m: XMLMap{"/@id": "1"}, wantErr: []string{"without an element"}},
+		{name: "attribute not last", m: XMLMap{"/root/@id/a": "1"}, wantErr: []string{"must be the last step"}},
+		{name: "empty step", m: XMLMap{"/root//a": "1"}, wantErr: []string{"empty name"}},
+		{name: "wildcard", m: XMLMap{"/root/*": "1"}, wantErr: []string{"invalid character '*'"}},
+		{name: "embedded document in attribute", m: XMLMap{"/root/@data!/a": "1"}, wantErr: []string{"holds an embedded document"}},
+		{name: "embedded path without slash", m: XMLMap{"/root/data!a": "1"}, wantErr: []string{"must start with /"}},
+		{name: "multiple roots", m: XMLMap{"/a/x": "1", "/b/y": "2"}, wantErr: []string{"multiple root elements: [a b]"}},
+		{
+			name:    "all problems are reported",
+			m:       XMLMap{"root/a": "1", "/root/b[x]": "2", "/root/c": "3"},
+			wantErr: []string{`"/root/b[x]"`, `"root/a"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.m.Validate()
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() error = nil, want %v", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+// Package xlsx exports xmlsurf maps and diffs as Excel workbooks (Office Open XML).
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/bmcszk/xmlsurf"
+)
+
+func init() {
+	xmlsurf.RegisterModule("xlsx")
+}
+
+// Sheet names of the workbook written by Write
+const (
+	MapSheet   = "Map"
+	DiffsSheet = "Diffs"
+)
+
+// Cell styles defined in styles.xml
+const (
+	styleDefault = iota
+	styleHeader
+	styleMissing
+	styleExtra
+	styleValue
+)
+
+// maxCellLength is the maximum number of characters Excel accepts in a cell
+const maxCellLength = 32767
+
+// Write writes a workbook with two sheets: MapSheet lists the paths and values of m,
+// DiffsSheet lists the diffs with their left and right values, highlighted by type
+// (missing red, extra green, value mismatch yellow). Values longer than Excel's
+// cell limit are truncated.
+func Write(w io.Writer, m xmlsurf.XMLMap, diffs []xmlsurf.Diff) error {
+	zw := zip.NewWriter(w)
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", workbook},
+		{"xl/_rels/workbook.xml.rels", workbookRels},
+		{"xl/styles.xml", styles},
+		{"xl/worksheets/sheet1.xml", mapSheet(m)},
+		{"xl/worksheets/sheet2.xml", diffsSheet(diffs)},
+	}
+	for _, part := range parts {
+		fw, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, part.content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// mapSheet returns the worksheet listing the map in path order
+func mapSheet(m xmlsurf.XMLMap) string {
+	paths := make([]string, 0, len(m))
+	for path := range m {
+		paths = append(paths, path)
+	}
+	sortPaths(paths)
+
+	sheet := newSheetWriter([]int{60, 60})
+	sheet.row(styleHeader, "Path", "Value")
+	for _, path := range paths {
+		sheet.row(styleDefault, path, m[path])
+	}
+	return sheet.close()
+}
+
+// diffsSheet returns the worksheet listing the diffs
+func diffsSheet(diffs []xmlsurf.Diff) string {
+	sheet := newSheetWriter([]int{16, 60, 40, 40})
+	sheet.row(styleHeader, "Type", "Path", "Left", "Right")
+	for _, diff := range diffs {
+		label, style := "Unknown", styleDefault
+		switch diff.Type {
+		case xmlsurf.DiffMissing:
+			label, style = "Missing", styleMissing
+		case xmlsurf.DiffExtra:
+			label, style = "Extra", styleExtra
+		case xmlsurf.DiffValue:
+			label, style = "Value mismatch", styleValue
+		}
+		sheet.row(style, label, diff.Path, diff.LeftValue, diff.RightValue)
+	}
+	return sheet.close()
+}
+
+// sortPaths orders paths by element names and numeric indices, so item[2] comes before item[10].
+// Paths that ParsePath rejects are ordered as plain strings.
+func sortPaths(paths []string) {
+	parsed := make(map[string][]xmlsurf.PathSegment, len(paths))
+	for _, path := range paths {
+		if p, err := xmlsurf.ParsePath(path); err == nil {
+			segments := p.Segments()
+			if p.IsAttr() {
+				segments = append(segments, xmlsurf.PathSegment{Name: "@" + p.Attribute()})
+			}
+			parsed[path] = segments
+		}
+	}
+
+	sort.Slice(paths, func(i, j int) bool {
+		a, okA := parsed[paths[i]]
+		b, okB := parsed[paths[j]]
+		if !okA || !okB {
+			return paths[i] < paths[j]
+		}
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k].Name != b[k].Name {
+				return a[k].Name < b[k].Name
+			}
+			if a[k].Index != b[k].Index {
+				return a[k].Index < b[k].Index
+			}
+		}
+		return len(a) < len(b)
+	})
+}
+
+// sheetWriter builds the XML of a worksheet with inline string cells
+type sheetWriter struct {
+	buf  bytes.Buffer
+	rows int
+}
+
+// newSheetWriter starts a worksheet with the given column widths
+func newSheetWriter(widths []int) *sheetWriter {
+	s := &sheetWriter{}
+	s.buf.WriteString(xml.Header)
+	s.buf.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	s.buf.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	s.buf.WriteString(`<cols>`)
+	for i, width := range widths {
+		column := strconv.Itoa(i + 1)
+		s.buf.WriteString(`<col min="` + column + `" max="` + column + `" width="` + strconv.Itoa(width) + `" customWidth="1"/>`)
+	}
+	s.buf.WriteString(`</cols><sheetData>`)
+	return s
+}
+
+// row appends a row of string cells with the given style
+func (s *sheetWriter) row(style int, values ...string) {
+	s.rows++
+	rowNumber := strconv.Itoa(s.rows)
+	s.buf.WriteString(`<row r="` + rowNumber + `">`)
+	for i, value := range values {
+		if len([]rune(value)) > maxCellLength {
+			value = string([]rune(value)[:maxCellLength])
+		}
+		s.buf.WriteString(`<c r="` + string(rune('A'+i)) + rowNumber + `" t="inlineStr"`)
+		if style != styleDefault {
+			s.buf.WriteString(` s="` + strconv.Itoa(style) + `"`)
+		}
+		s.buf.WriteString(`><is><t xml:space="preserve">`)
+		xml.EscapeText(&s.buf, []byte(value))
+		s.buf.WriteString(`</t></is></c>`)
+	}
+	s.buf.WriteString(`</row>`)
+}
+
+// close ends the worksheet and returns its XML
+func (s *sheetWriter) close() string {
+	s.buf.WriteString(`</sheetData></worksheet>`)
+	return s.buf.String()
+}
+
+const contentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet2.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`</Types>`
+
+const rootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const workbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` +
+	`<sheet name="` + MapSheet + `" sheetId="1" r:id="rId1"/>` +
+	`<sheet name="` + DiffsSheet + `" sheetId="2" r:id="rId2"/>` +
+	`</sheets></workbook>`
+
+const workbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/>` +
+	`<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// styles defines the cell formats referenced by the style constants, in order
+const styles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="6">` +
+	`<fill><patternFill patternType="none"/></fill>` +
+	`<fill><patternFill patternType="gray125"/></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FFD9D9D9"/></patternFill></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FFF4CCCC"/></patternFill></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FFD9EAD3"/></patternFill></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FFFFF2CC"/></patternFill></fill>` +
+	`</fills>` +
+	`<borders count="1"><border/></borders>` +
+	`<cellStyleXfs count="1"><xf/></cellStyleXfs>` +
+	`<cellXfs count="5">` +
+	`<xf/>` +
+	`<xf fontId="1" fillId="2" applyFont="1" applyFill="1"/>` +
+	`<xf fillId="3" applyFill="1"/>` +
+	`<xf fillId="4" applyFill="1"/>` +
+	`<xf fillId="5" applyFill="1"/>` +
+	`</cellXfs>` +
+	`</styleSheet>`
@@ -0,0 +1,119 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/bmcszk/xmlsurf"
+)
+
+// worksheet is the part of a worksheet read back by the tests
+type worksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Style string `xml:"s,attr"`
+			Text  string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readWorksheet(t *testing.T, files map[string]*zip.File, name string) ([][]string, []string) {
+	t.Helper()
+	file, ok := files[name]
+	if !ok {
+		t.Fatalf("workbook has no part %s", name)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	var sheet worksheet
+	if err := xml.NewDecoder(rc).Decode(&sheet); err != nil {
+		t.Fatalf("%s is not well-formed: %v", name, err)
+	}
+	rows := make([][]string, len(sheet.Rows))
+	styles := make([]string, len(sheet.Rows))
+	for i, row := range sheet.Rows {
+		for _, cell := range row.Cells {
+			rows[i] = append(rows[i], cell.Text)
+		}
+		if len(row.Cells) > 0 {
+			styles[i] = row.Cells[0].Style
+		}
+	}
+	return rows, styles
+}
+
+func TestWrite(t *testing.T) {
+	m := xmlsurf.XMLMap{
+		"/root/item[10]": "j",
+		"/root/item[2]":  "b & <c>",
+		"/root/@id":      "1",
+	}
+	diffs := []xmlsurf.Diff{
+		{Path: "/root/a", RightValue: "x", Type: xmlsurf.DiffMissing},
+		{Path: "/root/b", LeftValue: "1", RightValue: "2", Type: xmlsurf.DiffValue},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, m, diffs); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Write() output is not a zip archive: %v", err)
+	}
+	files := make(map[string]*zip.File)
+	for _, file := range zr.File {
+		files[file.Name] = file
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if err := xml.Unmarshal(data, new(struct{})); err != nil {
+			t.Errorf("%s is not well-formed: %v", file.Name, err)
+		}
+	}
+
+	rows, _ := readWorksheet(t, files, "xl/worksheets/sheet1.xml")
+	expectedRows := [][]string{
+		{"Path", "Value"},
+		{"/root/@id", "1"},
+		{"/root/item[2]", "b & <c>"},
+		{"/root/item[10]", "j"},
+	}
+	if !reflect.DeepEqual(rows, expectedRows) {
+		t.Errorf("map sheet rows = %v, want %v", rows, expectedRows)
+	}
+
+	rows, styles := readWorksheet(t, files, "xl/worksheets/sheet2.xml")
+	expectedRows = [][]string{
+		{"Type", "Path", "Left", "Right"},
+		{"Missing", "/root/a", "", "x"},
+		{"Value mismatch", "/root/b", "1", "2"},
+	}
+	if !reflect.DeepEqual(rows, expectedRows) {
+		t.Errorf("diffs sheet rows = %v, want %v", rows, expectedRows)
+	}
+	if !reflect.DeepEqual(styles, []string{"1", "2", "4"}) {
+		t.Errorf("diffs sheet styles = %v", styles)
+	}
+}
+
+func TestModuleRegistered(t *testing.T) {
+	for _, module := range xmlsurf.Capabilities().Modules {
+		if module == "xlsx" {
+			return
+		}
+	}
+	t.Errorf("Capabilities().Modules = %v, want xlsx", xmlsurf.Capabilities().Modules)
+}
@@ -10,7 +10,11 @@ import (
 	"strings"
 )
 
-// XMLMap represents a map of XPath expressions to their values
+// XMLMap represents a map of XPath expressions to their values.
+// Methods that only read the map, including ToXML and all comparison methods,
+// are safe for concurrent use. Merge, Reindex and Redact modify the map and
+// RemoveSpilled removes the files of its spilled values, so they need exclusive
+// access, as do direct writes; SyncXMLMap guards a map that is shared while modified.
 type XMLMap map[string]string
 
 // Diff represents a difference between two XMLMaps
@@ -50,38 +54,61 @@ func (d Diff) String() string {
 // ToXML converts the XMLMap to XML and writes it to the provided writer.
 // The XML will be indented if indent is true.
 func (m XMLMap) ToXML(w io.Writer, indent bool) error {
-	if len(m) == 0 {
-		return errors.New("empty XMLMap")
+	if indent {
+		return m.WriteXML(w, WithIndent("", "  "))
 	}
+	return m.WriteXML(w)
+}
 
-	// Find the root element
-	var rootPath string
-	for path := range m {
-		parts := strings.Split(path, "/")
-		if len(parts) > 1 {
-			rootPath = "/" + parts[1]
-			break
-		}
+// WriteXML converts the XMLMap to XML and writes it to the provided writer.
+// It accepts optional configuration through WriteOption functions.
+func (m XMLMap) WriteXML(w io.Writer, opts ...WriteOption) error {
+	return m.writeXML(w, nil, opts)
+}
+
+// writeXML implements WriteXML, stopping with an error when check reports cancellation
+func (m XMLMap) writeXML(w io.Writer, check *cancelCheck, opts []WriteOption) (err error) {
+	defer recoverPanic("write XML", &err)
+
+	options := DefaultWriteOptions()
+	for _, opt := range opts {
+		opt(options)
 	}
-	if rootPath == "" {
-		return errors.New("no root element found")
+	if options.Declaration && !isUTF8(options.Encoding) {
+		return fmt.Errorf("unsupported encoding %q: output is always UTF-8", options.Encoding)
 	}
 
-	// Build XML tree from map
-	root, _, err := buildXMLTree(m, rootPath)
+	root, err := m.writeTree(options, check)
 	if err != nil {
 		return err
 	}
 
 	// Write XML
 	var buf bytes.Buffer
+	if options.Declaration {
+		buf.WriteString(`<?xml version="1.0" encoding="`)
+		buf.WriteString(options.Encoding)
+		buf.WriteString(`"?>`)
+		buf.WriteString("\n")
+	}
+
 	enc := xml.NewEncoder(&buf)
-	if indent {
-		enc.Indent("", "  ")
+	if options.Prefix != "" || options.Indent != "" {
+		enc.Indent(options.Prefix, options.Indent)
 	}
 
 	// Write the root node and all its children
-	if err := writeXMLNode(root, enc, comparePaths); err != nil {
+	tw := &treeWriter{
+		enc:            enc,
+		buf:            &buf,
+		compareFn:      options.elementOrder(),
+		escapeNewlines: options.Newline != "\n",
+		selfClosing:    options.SelfClosing,
+		rawValues:      options.RawValues,
+		controlChars:   options.ControlChars,
+		check:          check,
+	}
+	if err := writeXMLNode(root, tw); err != nil {
 		return err
 	}
 
@@ -89,85 +116,125 @@ func (m XMLMap) ToXML(w io.Writer, indent bool) error {
 		return err
 	}
 
-	// Copy the buffer to the writer, skipping the XML header
-	output := buf.String()
-	if strings.HasPrefix(output, "<?xml") {
-		if idx := strings.Index(output, "?>"); idx != -1 {
-			output = output[idx+2:]
-		}
+	// Line breaks in values are escaped when converting newlines, so every
+	// remaining line break comes from the declaration or indentation
+	output := bytes.TrimSpace(buf.Bytes())
+	if options.Newline != "\n" {
+		output = bytes.ReplaceAll(output, []byte("\n"), []byte(options.Newline))
 	}
-	_, err = io.WriteString(w, strings.TrimSpace(output))
+	_, err = w.Write(output)
 	return err
 }
 
+// writeTree builds the tree of elements to write, with embedded documents serialized
+// into the values of their outer elements and the namespace declarations added.
+// Building stops with an error when check reports cancellation.
+func (m XMLMap) writeTree(options *WriteOptions, check *cancelCheck) (*xmlNode, error) {
+	if len(m) == 0 {
+		return nil, errors.New("empty XMLMap")
+	}
+	if err := m.checkAllowedPaths(options); err != nil {
+		return nil, err
+	}
+
+	// Serialize embedded documents into the values of their outer elements
+	if m.hasNestedPaths() {
+		wrapped, err := m.WrapNested()
+		if err != nil {
+			return nil, err
+		}
+		m = wrapped
+	}
+
+	// Find the root element
+	rootPath := m.rootPath()
+	if rootPath == "" {
+		return nil, errors.New("no root element found")
+	}
+
+	// Build XML tree from map
+	root, _, err := buildXMLTree(m, rootPath, check)
+	if err != nil {
+		return nil, err
+	}
+	if options.Namespaces != nil {
+		if err := declareNamespaces(root, options.Namespaces); err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+// isUTF8 reports whether the encoding name denotes UTF-8
+func isUTF8(encoding string) bool {
+	return strings.EqualFold(encoding, "UTF-8") || strings.EqualFold(encoding, "UTF8")
+}
+
+// rootPath returns the path of the root element, or "" if there is none
+func (m XMLMap) rootPath() string {
+	for path := range m {
+		parts := strings.SplitN(path, "/", 3)
+		if len(parts) > 1 {
+			return "/" + parts[1]
+		}
+	}
+	return ""
+}
+
 // Equal returns true if two XMLMaps are equal
-func (m XMLMap) Equal(other XMLMap) bool {
-	diffs := m.findDiffs(other)
+func (m XMLMap) Equal(other XMLMap, opts ...CompareOption) bool {
+	diffs := m.Diffs(other, opts...)
 	return len(diffs) == 0
 }
 
 // Diffs returns a list of differences between two XMLMaps
 // It compares exact paths and values, considering element order
-func (m XMLMap) Diffs(other XMLMap) []Diff {
-	return m.findDiffs(other)
+func (m XMLMap) Diffs(other XMLMap, opts ...CompareOption) []Diff {
+	left, right := prepareCompare(m, other, newCompareOptions(opts))
+	diffs, _ := left.findDiffs(right, nil)
+	return diffs
 }
 
 // findDiffs is a helper method that finds differences between two XMLMaps
-// It is used by both Equal and Diffs to avoid code duplication
-func (m XMLMap) findDiffs(other XMLMap) []Diff {
+// It is used by both Equal and Diffs to avoid code duplication.
+// The comparison stops with an error when check reports cancellation.
+func (m XMLMap) findDiffs(other XMLMap, check *cancelCheck) ([]Diff, error) {
 	diffs := make([]Diff, 0)
 
-	// Quick size check
-	if len(m) != len(other) {
-		// Maps have different sizes - find specific differences
-
-		// Find paths in m that are missing or have different values in other
-		for path, value := range m {
-			otherValue, exists := other[path]
-			if !exists {
-				diffs = append(diffs, Diff{
-					Path:      path,
-					LeftValue: value,
-					Type:      DiffExtra,
-				})
-			} else if value != otherValue {
-				diffs = append(diffs, Diff{
-					Path:       path,
-					LeftValue:  value,
-					RightValue: otherValue,
-					Type:       DiffValue,
-				})
-			}
+	// Find paths in m that are missing or have different values in other
+	for path, value := range m {
+		if err := check.err(); err != nil {
+			return nil, err
 		}
+		otherValue, exists := other[path]
+		if !exists {
+			diffs = append(diffs, Diff{
+				Path:      path,
+				LeftValue: value,
+				Type:      DiffExtra,
+			})
+		} else if value != otherValue {
+			diffs = append(diffs, Diff{
+				Path:       path,
+				LeftValue:  value,
+				RightValue: otherValue,
+				Type:       DiffValue,
+			})
+		}
+	}
 
-		// Find paths in other that are missing in m
-		for path, value := range other {
-			if _, exists := m[path]; !exists {
-				diffs = append(diffs, Diff{
-					Path:       path,
-					RightValue: value,
-					Type:       DiffMissing,
-				})
-			}
+	// Find paths in other that are missing in m. Maps of the same size can
+	// still differ in their paths, so this is needed regardless of size.
+	for path, value := range other {
+		if err := check.err(); err != nil {
+			return nil, err
 		}
-	} else {
-		// Maps have same size - just check for differing values
-		for path, value := range m {
-			otherValue, exists := other[path]
-			if !exists {
-				diffs = append(diffs, Diff{
-					Path:      path,
-					LeftValue: value,
-					Type:      DiffExtra,
-				})
-			} else if value != otherValue {
-				diffs = append(diffs, Diff{
-					Path:       path,
-					LeftValue:  value,
-					RightValue: otherValue,
-					Type:       DiffValue,
-				})
-			}
+		if _, exists := m[path]; !exists {
+			diffs = append(diffs, Diff{
+				Path:       path,
+				RightValue: value,
+				Type:       DiffMissing,
+			})
 		}
 	}
 
@@ -178,203 +245,233 @@ func (m XMLMap) findDiffs(other XMLMap) []Diff {
 		})
 	}
 
-	return diffs
+	return diffs, nil
 }
 
-// EqualIgnoreOrder returns true if two XMLMaps are equal ignoring the order of elements
-func (m XMLMap) EqualIgnoreOrder(other XMLMap) bool {
-	diffs := m.findDiffsIgnoreOrder(other)
-	return len(diffs) == 0
+// prepareCompare applies the comparison options that rewrite the compared maps.
+// The input maps are never modified.
+func prepareCompare(left, right XMLMap, options *CompareOptions) (XMLMap, XMLMap) {
+	left, right, _ = prepareCompareContext(left, right, options, nil)
+	return left, right
 }
 
-// DiffsIgnoreOrder returns a list of differences between two XMLMaps, ignoring element order
-func (m XMLMap) DiffsIgnoreOrder(other XMLMap) []Diff {
-	return m.findDiffsIgnoreOrder(other)
+// prepareCompareContext is like prepareCompare but stops with an error when check
+// reports cancellation. Each rewrite counts as a step per path of both maps.
+func prepareCompareContext(left, right XMLMap, options *CompareOptions, check *cancelCheck) (XMLMap, XMLMap, error) {
+	if options.NestedDocuments {
+		if err := check.add(len(left) + len(right)); err != nil {
+			return nil, nil, err
+		}
+		left = left.UnwrapNested()
+		right = right.UnwrapNested()
+	}
+	if len(options.IgnorePaths) > 0 {
+		if err := check.add(len(left) + len(right)); err != nil {
+			return nil, nil, err
+		}
+		left = left.withoutIgnored(options)
+		right = right.withoutIgnored(options)
+	}
+	if options.ListAlignment {
+		if err := check.add(len(left) + len(right)); err != nil {
+			return nil, nil, err
+		}
+		right = alignLists(left, right)
+	}
+	if len(options.MatchKeys) > 0 {
+		if err := check.add(len(left) + len(right)); err != nil {
+			return nil, nil, err
+		}
+		right = matchByKeys(left, right, options.MatchKeys)
+	}
+	return left, right, nil
 }
 
-// findDiffsIgnoreOrder is a helper method that finds differences between two XMLMaps ignoring element order
-// It is used by both EqualIgnoreOrder and DiffsIgnoreOrder to avoid code duplication
-func (m XMLMap) findDiffsIgnoreOrder(other XMLMap) []Diff {
-	diffs := make([]Diff, 0)
-
-	// Create maps of values for each path
-	values1 := make(map[string]map[string]bool, len(m)/2)
-	values2 := make(map[string]map[string]bool, len(m)/2)
-
-	// Maps to keep track of original paths before normalization
-	pathsMap1 := make(map[string][]string)
-	pathsMap2 := make(map[string][]string)
-
-	// Reuse path builder to reduce allocations
-	pathBuilder := getPathBuilder()
-	defer putPathBuilder(pathBuilder)
+// withoutIgnored returns a copy of the map without the ignored paths
+func (m XMLMap) withoutIgnored(options *CompareOptions) XMLMap {
+	result := make(XMLMap, len(m))
+	for path, value := range m {
+		if !options.isIgnored(path) {
+			result[path] = value
+		}
+	}
+	return result
+}
 
-	// Process the first map
-	for k, v := range m {
-		// Extract base path (removing indices)
-		basePath := extractBasePath(k, pathBuilder)
+// EqualIgnoreOrder returns true if two XMLMaps are equal ignoring the order of elements
+func (m XMLMap) EqualIgnoreOrder(other XMLMap, opts ...CompareOption) bool {
+	diffs := m.DiffsIgnoreOrder(other, opts...)
+	return len(diffs) == 0
+}
 
-		// Track the original paths for this base path
-		pathsMap1[basePath] = append(pathsMap1[basePath], k)
+// DiffsIgnoreOrder returns a list of differences between two XMLMaps, ignoring element order
+func (m XMLMap) DiffsIgnoreOrder(other XMLMap, opts ...CompareOption) []Diff {
+	diffs, _ := m.diffsIgnoreOrder(other, newCompareOptions(opts), nil)
+	return diffs
+}
 
-		// Create value map if it doesn't exist
-		if values1[basePath] == nil {
-			values1[basePath] = make(map[string]bool)
+// diffsIgnoreOrder implements DiffsIgnoreOrder, stopping with an error when check
+// reports cancellation
+func (m XMLMap) diffsIgnoreOrder(other XMLMap, options *CompareOptions, check *cancelCheck) ([]Diff, error) {
+	left, right, err := prepareCompareContext(m, other, options, check)
+	if err != nil {
+		return nil, err
+	}
+	if options.StructuralOrder {
+		if err := check.add(len(left) + len(right)); err != nil {
+			return nil, err
 		}
-		values1[basePath][v] = true
+		return left.findDiffs(matchStructurally(left, right), check)
 	}
+	return left.findDiffsIgnoreOrder(right, options.Semantics >= DiffSemanticsV2, check)
+}
 
-	// Process the second map
-	for k, v := range other {
-		// Extract base path (removing indices)
-		basePath := extractBasePath(k, pathBuilder)
+// findDiffsIgnoreOrder is a helper method that finds differences between two XMLMaps ignoring element order
+// It is used by both EqualIgnoreOrder and DiffsIgnoreOrder to avoid code duplication.
+// Values are counted per base path; with multiset set, the number of occurrences
+// of each value must match, otherwise only the presence of each value is compared.
+// The comparison stops with an error when check reports cancellation.
+func (m XMLMap) findDiffsIgnoreOrder(other XMLMap, multiset bool, check *cancelCheck) ([]Diff, error) {
+	diffs := make([]Diff, 0)
 
-		// Track the original paths for this base path
-		pathsMap2[basePath] = append(pathsMap2[basePath], k)
+	if err := check.add(len(m) + len(other)); err != nil {
+		return nil, err
+	}
+	left := groupByBasePath(m)
+	right := groupByBasePath(other)
 
-		// Create value map if it doesn't exist
-		if values2[basePath] == nil {
-			values2[basePath] = make(map[string]bool)
+	for basePath, entries := range left {
+		otherEntries, exists := right[basePath]
+		if !exists {
+			// Base path missing from other
+			for _, entry := range entries {
+				diffs = append(diffs, Diff{Path: entry.path, LeftValue: entry.value, Type: DiffExtra})
+			}
+			continue
 		}
-		values2[basePath][v] = true
-	}
-
-	// Quick size check for optimization
-	if len(values1) != len(values2) {
-		// Different number of base paths - report all differences
-		// Find missing paths and value differences
-		collectDiffsFromValueSets(values1, values2, pathsMap1, pathsMap2, m, other, &diffs)
-	} else {
-		// Same number of base paths - check for value differences
-		for basePath, vals1 := range values1 {
-			vals2, exists := values2[basePath]
-			if !exists || !mapSetsEqual(vals1, vals2) {
-				// Either missing path or different values
-				collectDiffsForBasePath(basePath, vals1, vals2, exists,
-					pathsMap1, pathsMap2, m, other, &diffs)
+		// Sorting both groups dominates the comparison
+		if err := check.add(len(entries) + len(otherEntries)); err != nil {
+			return nil, err
+		}
+		diffs = appendValueDiffs(diffs, entries, otherEntries, multiset)
+	}
+	for basePath, entries := range right {
+		if _, exists := left[basePath]; !exists {
+			// Base path missing from m
+			for _, entry := range entries {
+				diffs = append(diffs, Diff{Path: entry.path, RightValue: entry.value, Type: DiffMissing})
 			}
 		}
 	}
 
 	// Sort diffs by path for consistent output
+	if err := check.add(len(diffs)); err != nil {
+		return nil, err
+	}
 	if len(diffs) > 0 {
 		sort.Slice(diffs, func(i, j int) bool {
 			return diffs[i].Path < diffs[j].Path
 		})
 	}
 
-	return diffs
+	return diffs, nil
 }
 
-// mapSetsEqual checks if two maps containing sets of values are equal
-func mapSetsEqual(set1, set2 map[string]bool) bool {
-	if len(set1) != len(set2) {
-		return false
-	}
+// pathEntry is a path of a map with its value
+type pathEntry struct {
+	path  string
+	value string
+}
 
-	for v := range set1 {
-		if !set2[v] {
-			return false
+// groupByBasePath groups the paths of m by their base path in a single pass
+func groupByBasePath(m XMLMap) map[string][]pathEntry {
+	groups := make(map[string]*[]pathEntry)
+	var basePath []byte
+	for path, value := range m {
+		// Looking up string(basePath) does not allocate; only new groups copy it
+		basePath = appendBasePath(basePath[:0], path)
+		entries := groups[string(basePath)]
+		if entries == nil {
+			entries = new([]pathEntry)
+			groups[string(basePath)] = entries
 		}
+		*entries = append(*entries, pathEntry{path: path, value: value})
 	}
 
-	return true
+	result := make(map[string][]pathEntry, len(groups))
+	for basePath, entries := range groups {
+		result[basePath] = *entries
+	}
+	return result
 }
 
-// collectDiffsFromValueSets collects all differences between two value sets
-// This is used when the number of base paths differs
-func collectDiffsFromValueSets(
-	values1, values2 map[string]map[string]bool,
-	pathsMap1, pathsMap2 map[string][]string,
-	m, other XMLMap,
-	diffs *[]Diff) {
-
-	// Find paths in values1 that are missing or have different values in values2
-	for basePath, vals1 := range values1 {
-		vals2, exists := values2[basePath]
-		if !exists {
-			// Base path missing from other
-			for _, originalPath := range pathsMap1[basePath] {
-				*diffs = append(*diffs, Diff{
-					Path:      originalPath,
-					LeftValue: m[originalPath],
-					Type:      DiffExtra,
-				})
-			}
+// appendValueDiffs compares the values of two groups sharing a base path. Both groups
+// are sorted by value and path and merged, so large groups are compared in
+// O(n log n) without counting values in maps. Surplus occurrences of a value are
+// reported at the last paths holding it.
+func appendValueDiffs(diffs []Diff, left, right []pathEntry, multiset bool) []Diff {
+	sortByValue(left)
+	sortByValue(right)
+
+	i, j := 0, 0
+	for i < len(left) || j < len(right) {
+		// The smallest value not compared yet and its runs on both sides
+		value := ""
+		if j == len(right) || (i < len(left) && left[i].value < right[j].value) {
+			value = left[i].value
 		} else {
-			// Compare values - collect differences
-			collectDiffsForBasePath(basePath, vals1, vals2, exists,
-				pathsMap1, pathsMap2, m, other, diffs)
+			value = right[j].value
+		}
+		endI := i
+		for endI < len(left) && left[endI].value == value {
+			endI++
+		}
+		endJ := j
+		for endJ < len(right) && right[endJ].value == value {
+			endJ++
 		}
-	}
 
-	// Find paths in values2 that are missing in values1
-	for basePath := range values2 {
-		if _, exists := values1[basePath]; !exists {
-			// Base path missing from m
-			for _, originalPath := range pathsMap2[basePath] {
-				*diffs = append(*diffs, Diff{
-					Path:       originalPath,
-					RightValue: other[originalPath],
-					Type:       DiffMissing,
-				})
-			}
+		// Surplus occurrences are the last paths of a run
+		for _, entry := range left[endI-valueSurplus(endI-i, endJ-j, multiset) : endI] {
+			diffs = append(diffs, Diff{Path: entry.path, LeftValue: value, Type: DiffExtra})
+		}
+		for _, entry := range right[endJ-valueSurplus(endJ-j, endI-i, multiset) : endJ] {
+			diffs = append(diffs, Diff{Path: entry.path, RightValue: value, Type: DiffMissing})
 		}
+		i, j = endI, endJ
 	}
+	return diffs
 }
 
-// collectDiffsForBasePath collects all differences for a specific base path
-func collectDiffsForBasePath(
-	basePath string,
-	vals1, vals2 map[string]bool,
-	exists bool,
-	pathsMap1, pathsMap2 map[string][]string,
-	m, other XMLMap,
-	diffs *[]Diff) {
-
-	if !exists {
-		// Path exists in left but not in right
-		for _, originalPath := range pathsMap1[basePath] {
-			*diffs = append(*diffs, Diff{
-				Path:      originalPath,
-				LeftValue: m[originalPath],
-				Type:      DiffExtra,
-			})
-		}
-		return
-	}
-
-	// Check for values in vals1 that don't exist in vals2
-	for val := range vals1 {
-		if !vals2[val] {
-			// Find an original path with this value
-			for _, path := range pathsMap1[basePath] {
-				if m[path] == val {
-					*diffs = append(*diffs, Diff{
-						Path:      path,
-						LeftValue: val,
-						Type:      DiffExtra,
-					})
-					break
-				}
-			}
-		}
+// sortByValue sorts entries by value and then by path
+func sortByValue(entries []pathEntry) {
+	sort.Sort(entriesByValue(entries))
+}
+
+// entriesByValue sorts path entries by value and then by path without the
+// reflection of sort.Slice, which matters for groups of millions of entries
+type entriesByValue []pathEntry
+
+func (e entriesByValue) Len() int      { return len(e) }
+func (e entriesByValue) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+func (e entriesByValue) Less(i, j int) bool {
+	if e[i].value != e[j].value {
+		return e[i].value < e[j].value
 	}
+	return e[i].path < e[j].path
+}
 
-	// Check for values in vals2 that don't exist in vals1
-	for val := range vals2 {
-		if !vals1[val] {
-			// Find an original path with this value
-			for _, path := range pathsMap2[basePath] {
-				if other[path] == val {
-					*diffs = append(*diffs, Diff{
-						Path:       path,
-						RightValue: val,
-						Type:       DiffMissing,
-					})
-					break
-				}
-			}
+// valueSurplus returns how many occurrences of a value should be reported
+// when it occurs count times on one side and otherCount times on the other
+func valueSurplus(count, otherCount int, multiset bool) int {
+	if !multiset {
+		if otherCount == 0 {
+			return 1
 		}
+		return 0
+	}
+	if count > otherCount {
+		return count - otherCount
 	}
+	return 0
 }
@@ -1,6 +1,10 @@
 package xmlsurf
 
 import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -289,6 +293,14 @@ func TestXMLMapToXML(t *testing.T) {
 			},
 			expected: "<root><items><item>one</item><item>two</item><item>three</item></items></root>",
 		},
+		{
+			name: "repeated parent elements",
+			input: XMLMap{
+				"/root/group[1]/item": "a",
+				"/root/group[2]/item": "b",
+			},
+			expected: "<root><group><item>a</item></group><group><item>b</item></group></root>",
+		},
 		{
 			name: "xml with namespaces",
 			input: XMLMap{
@@ -373,6 +385,29 @@ func TestXMLMapDiffs(t *testing.T) {
 			},
 			expected: []Diff{},
 		},
+		{
+			name: "same size with different paths",
+			map1: XMLMap{
+				"/root/a": "1",
+				"/root/b": "2",
+			},
+			map2: XMLMap{
+				"/root/a": "1",
+				"/root/c": "2",
+			},
+			expected: []Diff{
+				{
+					Path:      "/root/b",
+					LeftValue: "2",
+					Type:      DiffExtra,
+				},
+				{
+					Path:       "/root/c",
+					RightValue: "2",
+					Type:       DiffMissing,
+				},
+			},
+		},
 		{
 			name: "missing path in map2",
 			map1: XMLMap{
@@ -578,6 +613,21 @@ func TestXMLMapDiffsIgnoreOrder(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "renamed element group",
+			map1: XMLMap{
+				"/root/items/item[1]": "apple",
+				"/root/old":           "value",
+			},
+			map2: XMLMap{
+				"/root/items/item[1]": "apple",
+				"/root/new":           "value",
+			},
+			expected: []Diff{
+				{Path: "/root/new", RightValue: "value", Type: DiffMissing},
+				{Path: "/root/old", LeftValue: "value", Type: DiffExtra},
+			},
+		},
 		{
 			name: "missing element group",
 			map1: XMLMap{
@@ -711,6 +761,26 @@ func BenchmarkXMLMapToXML(b *testing.B) {
 	}
 }
 
+func BenchmarkXMLMapToXMLLarge(b *testing.B) {
+	// About 50k paths in one long list, the shape of typical export documents
+	xmlMap := make(XMLMap, 50000)
+	for i := 1; i <= 12500; i++ {
+		item := fmt.Sprintf("/catalog/items/item[%d]", i)
+		xmlMap[item+"/@id"] = strconv.Itoa(i)
+		xmlMap[item+"/name"] = "Product " + strconv.Itoa(i)
+		xmlMap[item+"/price"] = "9.99"
+		xmlMap[item+"/tags/tag"] = "sale"
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := xmlMap.ToXML(io.Discard, true); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func BenchmarkXMLMapEqualIgnoreOrder(b *testing.B) {
 	// Create two maps with the same values but in different order
 	map1 := XMLMap{
@@ -746,3 +816,122 @@ func BenchmarkXMLMapEqualIgnoreOrder(b *testing.B) {
 		}
 	}
 }
+
+func TestXMLMapDiffSemantics(t *testing.T) {
+	map1 := XMLMap{
+		"/root/item[1]": "a",
+		"/root/item[2]": "a",
+		"/root/item[3]": "b",
+	}
+	map2 := XMLMap{
+		"/root/item[1]": "a",
+		"/root/item[2]": "b",
+		"/root/item[3]": "b",
+	}
+
+	if !map1.EqualIgnoreOrder(map2) {
+		t.Errorf("EqualIgnoreOrder() with default V1 semantics should compare values as sets")
+	}
+
+	diffs := map1.DiffsIgnoreOrder(map2, WithDiffSemantics(DiffSemanticsV2))
+	expected := []Diff{
+		{Path: "/root/item[2]", LeftValue: "a", Type: DiffExtra},
+		{Path: "/root/item[3]", RightValue: "b", Type: DiffMissing},
+	}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("DiffsIgnoreOrder() = %v, want %v", diffs, expected)
+	}
+}
+
+func TestXMLMapWriteXML(t *testing.T) {
+	input := XMLMap{
+		"/root/item[1]":     "first\nline",
+		"/root/item[2]":     "second",
+		"/root/item[2]/@id": "2",
+	}
+
+	tests := []struct {
+		name     string
+		opts     []WriteOption
+		expected string
+	}{
+		{
+			name:     "defaults",
+			expected: "<root><item>first\nline</item><item id=\"2\">second</item></root>",
+		},
+		{
+			name:     "custom indent",
+			opts:     []WriteOption{WithIndent("> ", "\t")},
+			expected: "> <root>\n> \t<item>first\nline</item>\n> \t<item id=\"2\">second</item>\n> </root>",
+		},
+		{
+			name:     "declaration and windows newlines",
+			opts:     []WriteOption{WithDeclaration("UTF-8"), WithIndent("", " "), WithNewline("\r\n")},
+			expected: "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\r\n<root>\r\n <item>first&#xA;line</item>\r\n <item id=\"2\">second</item>\r\n</root>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var builder strings.Builder
+			if err := input.WriteXML(&builder, tt.opts...); err != nil {
+				t.Fatalf("WriteXML() error = %v", err)
+			}
+			if builder.String() != tt.expected {
+				t.Errorf("WriteXML() = %q, want %q", builder.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestXMLMapWriteXMLDeclarationEncoding(t *testing.T) {
+	input := XMLMap{"/root": "value"}
+	var builder strings.Builder
+	if err := input.WriteXML(&builder, WithDeclaration("ISO-8859-1")); err == nil {
+		t.Errorf("WriteXML() with a non-UTF-8 declaration error = nil, output %q", builder.String())
+	}
+	if err := input.WriteXML(&builder, WithDeclaration("utf-8")); err != nil {
+		t.Errorf("WriteXML() with a lowercase UTF-8 declaration error = %v", err)
+	}
+}
+
+func TestXMLMapWriteXMLSelfClosing(t *testing.T) {
+	input := XMLMap{
+		"/root/empty":    "",
+		"/root/flag/@id": "f",
+		"/root/text":     "value",
+	}
+
+	tests := []struct {
+		name     string
+		opts     []WriteOption
+		expected string
+	}{
+		{
+			name:     "empty elements with end tags",
+			expected: `<root><empty></empty><flag id="f"></flag><text>value</text></root>`,
+		},
+		{
+			name:     "self-closing empty elements",
+			opts:     []WriteOption{WithSelfClosing(true)},
+			expected: `<root><empty/><flag id="f"/><text>value</text></root>`,
+		},
+		{
+			name:     "self-closing with indentation",
+			opts:     []WriteOption{WithSelfClosing(true), WithIndent("", " ")},
+			expected: "<root>\n <empty/>\n <flag id=\"f\"/>\n <text>value</text>\n</root>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var builder strings.Builder
+			if err := input.WriteXML(&builder, tt.opts...); err != nil {
+				t.Fatalf("WriteXML() error = %v", err)
+			}
+			if builder.String() != tt.expected {
+				t.Errorf("WriteXML() = %q, want %q", builder.String(), tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package xmlsurf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// declareNamespaces adds declarations of the namespaces used in the tree to the root element.
+// Prefixes already declared by attributes of the root are left alone.
+func declareNamespaces(root *xmlNode, namespaces map[string]string) error {
+	used := make(map[string]bool)
+	collectPrefixes(root, used)
+
+	for _, attr := range root.attributes {
+		switch {
+		case attr.attrName == "xmlns":
+			delete(used, "")
+		case strings.HasPrefix(attr.attrName, "xmlns:"):
+			delete(used, attr.attrName[len("xmlns:"):])
+		}
+	}
+	// The xml prefix is bound by definition
+	delete(used, "xml")
+
+	prefixes := make([]string, 0, len(used))
+	for prefix := range used {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	declarations := make([]*xmlNode, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		uri, ok := namespaces[prefix]
+		if !ok {
+			if prefix == "" {
+				// Elements without a prefix are valid without a default namespace
+				continue
+			}
+			return fmt.Errorf("no namespace declared for prefix %q", prefix)
+		}
+		name := "xmlns"
+		if prefix != "" {
+			name += ":" + prefix
+		}
+		declarations = append(declarations, &xmlNode{
+			path:     root.path + "/@" + name,
+			name:     name,
+			value:    uri,
+			isAttr:   true,
+			attrName: name,
+		})
+	}
+	root.attributes = append(declarations, root.attributes...)
+	return nil
+}
+
+// collectPrefixes records the prefixes of the element and attribute names in the subtree.
+// The empty prefix stands for elements without a prefix; unprefixed attributes are in no namespace.
+func collectPrefixes(node *xmlNode, used map[string]bool) {
+	used[namePrefix(node.name)] = true
+	for _, attr := range node.attributes {
+		if prefix := namePrefix(attr.attrName); prefix != "" && prefix != "xmlns" {
+			used[prefix] = true
+		}
+	}
+	for _, child := range node.children {
+		collectPrefixes(child, used)
+	}
+}
+
+// namePrefix returns the prefix of a qualified name, or "" if it has none
+func namePrefix(name string) string {
+	if idx := strings.Index(name, ":"); idx != -1 {
+		return name[:idx]
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+package xmlsurf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWithNamespaceDeclarations(t *testing.T) {
+	namespaces := map[string]string{
+		"":    "urn:default",
+		"ord": "urn:orders",
+		"x":   "urn:extra",
+		"xsi": "http://www.w3.org/2001/XMLSchema-instance",
+	}
+
+	tests := []struct {
+		name    string
+		input   XMLMap
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "only used prefixes are declared",
+			input: XMLMap{"/ord:order/ord:id": "1", "/ord:order/@xsi:type": "Big"},
+			want:  `<ord:order xmlns:ord="urn:orders" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="Big"><ord:id>1</ord:id></ord:order>`,
+		},
+		{
+			name:  "default namespace for unprefixed elements",
+			input: XMLMap{"/order/x:note": "a", "/order/@id": "7"},
+			want:  `<order xmlns="urn:default" xmlns:x="urn:extra" id="7"><x:note>a</x:note></order>`,
+		},
+		{
+			name:  "declarations already on the root are kept",
+			input: XMLMap{"/ord:order/ord:id": "1", "/ord:order/@xmlns:ord": "urn:other"},
+			want:  `<ord:order xmlns:ord="urn:other"><ord:id>1</ord:id></ord:order>`,
+		},
+		{
+			name:  "xml prefix is never declared",
+			input: XMLMap{"/ord:order/@xml:lang": "en"},
+			want:  `<ord:order xmlns:ord="urn:orders" xml:lang="en"></ord:order>`,
+		},
+		{
+			name:    "unknown prefix",
+			input:   XMLMap{"/ord:order/y:id": "1"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			err := tt.input.WriteXML(&b, WithNamespaceDeclarations(namespaces))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WriteXML() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && b.String() != tt.want {
+				t.Errorf("WriteXML() =\n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWithNamespaceDeclarationsStreamRecord(t *testing.T) {
+	stream := NewStream(strings.NewReader(streamDocument), WithNamespaces(true))
+	record, err := stream.Next()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+
+	var b strings.Builder
+	if err := record.Map.WriteXML(&b, WithNamespaceDeclarations(stream.Namespaces())); err != nil {
+		t.Fatalf("WriteXML() error = %v", err)
+	}
+
+	// The fragment parses on its own to the same map
+	reparsed, err := ParseToMap(strings.NewReader(b.String()), WithNamespaces(true))
+	if err != nil {
+		t.Fatalf("ParseToMap(%s) error = %v", b.String(), err)
+	}
+	if diffs := record.Map.Diffs(reparsed); len(diffs) > 0 {
+		t.Errorf("reparsed fragment %s differs: %v", b.String(), diffs)
+	}
+}
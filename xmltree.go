@@ -1,7 +1,9 @@
 package xmlsurf
 
 import (
+	"bytes"
 	"encoding/xml"
+	"fmt"
 	"sort"
 	"strings"
 )
@@ -10,6 +12,8 @@ import (
 type xmlNode struct {
 	path       string
 	name       string
+	segment    string // Last segment of the path, e.g. item[2]
+	index      int    // Index of the last segment, 1 for an element without index
 	value      string
 	isAttr     bool
 	attrName   string
@@ -18,255 +22,304 @@ type xmlNode struct {
 	depth      int // Track node depth
 }
 
-// buildXMLTree constructs an XML tree from the map
-func buildXMLTree(m XMLMap, rootPath string) (*xmlNode, map[string]*xmlNode, error) {
-	// Create the root node
+// buildXMLTree constructs an XML tree from the map in a single pass over the paths.
+// Missing parents are created on demand, so the paths need no sorting; paths outside
+// the root element are skipped. Attributes are ordered by name, children are ordered
+// when written. Building stops with an error when check reports cancellation.
+func buildXMLTree(m XMLMap, rootPath string, check *cancelCheck) (*xmlNode, map[string]*xmlNode, error) {
 	root := &xmlNode{
-		path:       rootPath,
-		name:       strings.TrimPrefix(rootPath, "/"),
-		depth:      1,
-		children:   make([]*xmlNode, 0, 4),
-		attributes: make([]*xmlNode, 0, 4),
+		path:  rootPath,
+		name:  strings.TrimPrefix(rootPath, "/"),
+		value: m[rootPath],
+		depth: 1,
 	}
 
-	// Store value for root if exists
-	if val, ok := m[rootPath]; ok {
-		root.value = val
-	}
-
-	// Create a map to track nodes by path
-	nodeMap := make(map[string]*xmlNode)
+	nodeMap := make(map[string]*xmlNode, len(m))
 	nodeMap[rootPath] = root
+	arena := &nodeArena{}
 
-	// Sort paths to ensure consistent processing order
-	paths := make([]string, 0, len(m))
-	for path := range m {
-		if path != rootPath { // Skip root, already processed
-			paths = append(paths, path)
+	for path, value := range m {
+		if err := check.err(); err != nil {
+			return nil, nil, err
+		}
+		if path == rootPath {
+			continue
+		}
+		slash := strings.LastIndexByte(path, '/')
+		if slash == -1 {
+			continue // Skip invalid paths
 		}
-	}
 
-	// Sort paths to ensure parents are created before children
-	sort.Slice(paths, func(i, j int) bool {
-		return comparePaths(paths[i], paths[j])
-	})
+		if last := path[slash+1:]; strings.HasPrefix(last, "@") {
+			parent := elementNode(path[:slash], nodeMap, arena)
+			if parent == nil {
+				continue
+			}
+			attr := arena.new()
+			attr.path = path
+			attr.name = parent.name
+			attr.value = value
+			attr.isAttr = true
+			attr.attrName = last[1:]
+			parent.attributes = append(parent.attributes, attr)
+			continue
+		}
 
-	// Path builder for string operations
-	pathBuilder := getPathBuilder()
-	defer putPathBuilder(pathBuilder)
+		if node := elementNode(path, nodeMap, arena); node != nil {
+			node.value = value
+		}
+	}
 
-	// Process each path
-	for _, path := range paths {
-		processSinglePath(path, m, nodeMap, pathBuilder)
+	for _, node := range nodeMap {
+		if len(node.attributes) > 1 {
+			sort.Slice(node.attributes, func(i, j int) bool {
+				return compareSegments(lastSegment(node.attributes[i].path), lastSegment(node.attributes[j].path))
+			})
+		}
 	}
 
 	return root, nodeMap, nil
 }
 
-// processSinglePath adds a single path to the XML tree
-func processSinglePath(path string, m XMLMap, nodeMap map[string]*xmlNode, pathBuilder *strings.Builder) {
-	parts := strings.Split(path, "/")
-	if len(parts) < 2 {
-		return // Skip invalid paths
+// elementNode returns the node of the element at path, creating it and its missing
+// ancestors. It returns nil for paths outside the tree of the root element.
+func elementNode(path string, nodeMap map[string]*xmlNode, arena *nodeArena) *xmlNode {
+	if node, ok := nodeMap[path]; ok {
+		return node
 	}
-
-	// Parse path information
-	isAttr, parentPath, nodeName, attrName := parsePath(parts, pathBuilder)
-
-	// Get or create parent node
-	parent, exists := nodeMap[parentPath]
-	if !exists {
-		parent = createParentNodes(parts, nodeMap, pathBuilder)
+	slash := strings.LastIndexByte(path, '/')
+	if slash <= 0 {
+		return nil // Another root element
 	}
-
-	// Skip if parent couldn't be created
+	parent := elementNode(path[:slash], nodeMap, arena)
 	if parent == nil {
-		return
+		return nil
 	}
 
-	// Add node to parent
-	if isAttr {
-		addAttributeNode(parent, path, nodeName, attrName, m[path])
-	} else {
-		addElementNode(parent, path, nodeName, m[path], nodeMap)
-	}
+	node := arena.new()
+	node.path = path
+	node.segment = path[slash+1:]
+	node.name, node.index = splitIndex(node.segment)
+	node.depth = parent.depth + 1
+	nodeMap[path] = node
+	parent.children = append(parent.children, node)
+	return node
 }
 
-// parsePath extracts path components from a path string
-func parsePath(parts []string, pathBuilder *strings.Builder) (bool, string, string, string) {
-	isAttr := false
-	attrName := ""
-	nodeName := parts[len(parts)-1]
-
-	// Build parent path
-	pathBuilder.Reset()
-	for i := 0; i < len(parts)-1; i++ {
-		pathBuilder.WriteString(parts[i])
-		if i < len(parts)-2 {
-			pathBuilder.WriteString("/")
-		}
+// nodeArena allocates tree nodes in chunks, so building a large tree does not
+// allocate every node separately
+type nodeArena struct {
+	chunk []xmlNode
+}
+
+// new returns a zeroed node
+func (a *nodeArena) new() *xmlNode {
+	if len(a.chunk) == cap(a.chunk) {
+		// Chunks grow with the tree, so small trees stay small
+		a.chunk = make([]xmlNode, 0, min(max(2*cap(a.chunk), 16), 1024))
 	}
-	parentPath := pathBuilder.String()
+	a.chunk = a.chunk[:len(a.chunk)+1]
+	return &a.chunk[len(a.chunk)-1]
+}
 
-	// Check if this is an attribute
-	if strings.HasPrefix(nodeName, "@") {
-		isAttr = true
-		attrName = strings.TrimPrefix(nodeName, "@")
+// lastSegment returns the part of the path after the last slash
+func lastSegment(path string) string {
+	return path[strings.LastIndexByte(path, '/')+1:]
+}
 
-		// Get the node name from the parent path
-		if len(parts) >= 3 {
-			nodeName = parts[len(parts)-2]
-		}
+// siblingLess orders sibling elements like comparePaths orders their paths,
+// comparing only their last segments
+func siblingLess(a, b *xmlNode) bool {
+	if a.name != b.name {
+		return compareSegments(a.name, b.name)
 	}
-
-	// Remove index from node name if present
-	if idx := strings.Index(nodeName, "["); idx != -1 {
-		nodeName = nodeName[:idx]
+	if a.index != b.index {
+		return a.index < b.index
 	}
-
-	return isAttr, parentPath, nodeName, attrName
+	return a.segment < b.segment
 }
 
-// createParentNodes creates missing parent nodes in the tree
-func createParentNodes(parts []string, nodeMap map[string]*xmlNode, pathBuilder *strings.Builder) *xmlNode {
-	currentPath := ""
-	var currentNode *xmlNode
+// sortChildren orders the children of node with compareFn, or in the default order if it is nil
+func sortChildren(node *xmlNode, compareFn func(string, string) bool) {
+	if len(node.children) < 2 {
+		return
+	}
+	if compareFn == nil {
+		sort.Slice(node.children, func(i, j int) bool {
+			return siblingLess(node.children[i], node.children[j])
+		})
+		return
+	}
+	sort.Slice(node.children, func(i, j int) bool {
+		return compareFn(node.children[i].path, node.children[j].path)
+	})
+}
 
-	// Create each parent node in sequence
-	for i := 1; i < len(parts)-1; i++ {
-		part := parts[i]
+// treeWriter writes an XML tree to an encoder backed by a buffer
+type treeWriter struct {
+	enc *xml.Encoder
+	buf *bytes.Buffer
+	// compareFn orders sibling elements by their paths; nil is the default order
+	compareFn func(string, string) bool
+	// escapeNewlines writes line breaks in values as character references,
+	// so they are not affected by newline conversion of the output
+	escapeNewlines bool
+	// selfClosing writes elements without value and children as <name/>
+	selfClosing bool
+	// check stops writing when the context of a ToXMLContext call is done
+	check *cancelCheck
+	// rawValues writes element values without escaping
+	rawValues bool
+	// controlChars decides how characters not allowed in XML are written
+	controlChars ControlCharPolicy
+}
 
-		// Remove index from part if present
-		if idx := strings.Index(part, "["); idx != -1 {
-			part = part[:idx]
-		}
+// writeText writes the value of the element at path
+func (tw *treeWriter) writeText(path, value string) error {
+	custom := tw.controlChars != ControlCharReplace && hasInvalidChars(value)
+	if !tw.escapeNewlines && !tw.rawValues && !custom {
+		return tw.enc.EncodeToken(xml.CharData(value))
+	}
 
-		// Build path to this node
-		pathBuilder.Reset()
-		if i == 1 {
-			pathBuilder.WriteString("/")
-		} else {
-			pathBuilder.WriteString(currentPath)
-			pathBuilder.WriteString("/")
-		}
-		pathBuilder.WriteString(part)
-		currentPath = pathBuilder.String()
+	// Character data does not affect the encoder's indentation state, so the
+	// escaped text can be written to the buffer directly after flushing
+	if err := tw.enc.Flush(); err != nil {
+		return err
+	}
+	if tw.rawValues {
+		tw.buf.WriteString(value)
+		return nil
+	}
+	if err := escapeValue(tw.buf, value, tw.controlChars, tw.escapeNewlines); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
 
-		// Check if node already exists
-		if node, ok := nodeMap[currentPath]; ok {
-			currentNode = node
-			continue
+// writeStartTag writes the start tag of the element at path. The encoder replaces
+// characters not allowed in XML, so when another policy applies to an attribute value
+// the tag is encoded to keep the encoder's state consistent and then rewritten in the buffer.
+func (tw *treeWriter) writeStartTag(path string, start xml.StartElement) error {
+	custom := false
+	if tw.controlChars != ControlCharReplace {
+		for _, attr := range start.Attr {
+			if hasInvalidChars(attr.Value) {
+				custom = true
+				break
+			}
 		}
+	}
+	if !custom {
+		return tw.enc.EncodeToken(start)
+	}
 
-		// Create a new node
-		depth := strings.Count(currentPath, "/")
-		newNode := &xmlNode{
-			path:       currentPath,
-			name:       part,
-			depth:      depth,
-			children:   make([]*xmlNode, 0, 4),
-			attributes: make([]*xmlNode, 0, 4),
+	var tag bytes.Buffer
+	tag.WriteString("<")
+	tag.WriteString(start.Name.Local)
+	for _, attr := range start.Attr {
+		tag.WriteString(" ")
+		tag.WriteString(attr.Name.Local)
+		tag.WriteString(`="`)
+		if err := escapeValue(&tag, attr.Value, tw.controlChars, true); err != nil {
+			return fmt.Errorf("%s/@%s: %w", path, attr.Name.Local, err)
 		}
-		nodeMap[currentPath] = newNode
+		tag.WriteString(`"`)
+	}
+	tag.WriteString(">")
 
-		if currentNode != nil {
-			currentNode.children = append(currentNode.children, newNode)
-		}
-		currentNode = newNode
+	if err := tw.enc.Flush(); err != nil {
+		return err
+	}
+	before := tw.buf.Len()
+	if err := tw.enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := tw.enc.Flush(); err != nil {
+		return err
 	}
 
-	return currentNode
+	// Keep the indentation written in front of the tag
+	written := tw.buf.Bytes()[before:]
+	open := before + bytes.IndexByte(written, '<')
+	tw.buf.Truncate(open)
+	tw.buf.Write(tag.Bytes())
+	return nil
 }
 
-// addAttributeNode adds an attribute node to a parent node
-func addAttributeNode(parent *xmlNode, path, nodeName, attrName, value string) {
-	attr := &xmlNode{
-		path:     path,
-		name:     nodeName,
-		value:    value,
-		isAttr:   true,
-		attrName: attrName,
+// writeSelfClosingEnd ends an element whose start tag was just written, turning
+// <name></name> into <name/>. The encoder cannot write self-closing tags, so the
+// end tag is encoded to keep its state consistent and then rewritten in the buffer.
+func (tw *treeWriter) writeSelfClosingEnd(end xml.EndElement) error {
+	if err := tw.enc.Flush(); err != nil {
+		return err
 	}
-	parent.attributes = append(parent.attributes, attr)
-}
+	startEnd := tw.buf.Len()
 
-// addElementNode adds an element node to a parent node
-func addElementNode(parent *xmlNode, path, nodeName, value string, nodeMap map[string]*xmlNode) {
-	depth := strings.Count(path, "/")
-	node := &xmlNode{
-		path:       path,
-		name:       nodeName,
-		value:      value,
-		depth:      depth,
-		children:   make([]*xmlNode, 0, 4),
-		attributes: make([]*xmlNode, 0, 4),
+	if err := tw.enc.EncodeToken(end); err != nil {
+		return err
 	}
-	nodeMap[path] = node
-	parent.children = append(parent.children, node)
-}
-
-// writeXMLNode writes a node and its children to the encoder
-func writeXMLNode(node *xmlNode, enc *xml.Encoder, compareFn func(string, string) bool) error {
-	// Split name into prefix and local parts for namespaced elements
-	var prefix, local string
-	if idx := strings.Index(node.name, ":"); idx != -1 {
-		prefix, local = node.name[:idx], node.name[idx+1:]
-	} else {
-		local = node.name
+	if err := tw.enc.Flush(); err != nil {
+		return err
 	}
 
-	// Create start element
-	start := xml.StartElement{
-		Name: xml.Name{Local: local},
-	}
-	if prefix != "" {
-		start.Name.Local = prefix + ":" + local
+	if string(tw.buf.Bytes()[startEnd:]) == "</"+end.Name.Local+">" {
+		tw.buf.Truncate(startEnd - 1)
+		tw.buf.WriteString("/>")
 	}
+	return nil
+}
 
-	// Pre-allocate attribute slice if needed
+// startElement returns the start tag of the element. Qualified names are kept as local
+// names, so the encoder writes the prefixes of the map without declaring namespaces.
+func (node *xmlNode) startElement() xml.StartElement {
+	start := xml.StartElement{Name: xml.Name{Local: node.name}}
 	if len(node.attributes) > 0 {
 		start.Attr = make([]xml.Attr, 0, len(node.attributes))
 	}
-
-	// Add attributes
 	for _, attr := range node.attributes {
-		attrName := attr.attrName
-		if idx := strings.Index(attrName, ":"); idx != -1 {
-			prefix, local := attrName[:idx], attrName[idx+1:]
-			attrName = prefix + ":" + local
-		}
 		start.Attr = append(start.Attr, xml.Attr{
-			Name:  xml.Name{Local: attrName},
+			Name:  xml.Name{Local: attr.attrName},
 			Value: attr.value,
 		})
 	}
+	return start
+}
+
+// writeXMLNode writes a node and its children to the encoder
+func writeXMLNode(node *xmlNode, tw *treeWriter) error {
+	if err := tw.check.err(); err != nil {
+		return err
+	}
+	enc := tw.enc
+	start := node.startElement()
 
 	// Write start element
-	if err := enc.EncodeToken(start); err != nil {
+	if err := tw.writeStartTag(node.path, start); err != nil {
 		return err
 	}
 
 	// Write element value if present
 	if node.value != "" {
-		if err := enc.EncodeToken(xml.CharData(node.value)); err != nil {
+		if err := tw.writeText(node.path, node.value); err != nil {
 			return err
 		}
 	}
 
 	// Sort and write children
-	if len(node.children) > 1 {
-		sort.Slice(node.children, func(i, j int) bool {
-			return compareFn(node.children[i].path, node.children[j].path)
-		})
+	if err := tw.check.add(len(node.children)); err != nil {
+		return err
 	}
-
+	sortChildren(node, tw.compareFn)
 	for _, child := range node.children {
-		if err := writeXMLNode(child, enc, compareFn); err != nil {
+		if err := writeXMLNode(child, tw); err != nil {
 			return err
 		}
 	}
 
 	// Write end element
+	if tw.selfClosing && node.value == "" && len(node.children) == 0 {
+		return tw.writeSelfClosingEnd(start.End())
+	}
 	if err := enc.EncodeToken(start.End()); err != nil {
 		return err
 	}
@@ -0,0 +1,881 @@
+package xmlsurf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// XPathType is the type of the result of an XPath expression
+type XPathType int
+
+const (
+	// XPathNodeSet is a set of elements, attributes or text nodes
+	XPathNodeSet XPathType = iota
+	// XPathString is a string
+	XPathString
+	// XPathNumber is a floating point number
+	XPathNumber
+	// XPathBoolean is a boolean
+	XPathBoolean
+)
+
+// XPathResult is the typed result of Evaluate. The conversion methods follow the
+// XPath string(), number() and boolean() functions, so any result can be read as any type.
+type XPathResult struct {
+	value xpathValue
+}
+
+// Type returns the type of the result
+func (r XPathResult) Type() XPathType {
+	switch r.value.(type) {
+	case []*xpathNode:
+		return XPathNodeSet
+	case string:
+		return XPathString
+	case float64:
+		return XPathNumber
+	default:
+		return XPathBoolean
+	}
+}
+
+// Paths returns the map paths of the nodes of a node-set in document order, nil for
+// other types. An attribute has its own path; a text node has the path of its element.
+func (r XPathResult) Paths() []string {
+	nodes, ok := r.value.([]*xpathNode)
+	if !ok {
+		return nil
+	}
+	paths := make([]string, len(nodes))
+	for i, node := range nodes {
+		paths[i] = node.path
+	}
+	return paths
+}
+
+// Values returns the string values of the nodes of a node-set in document order,
+// nil for other types. The value of an element is all text below it.
+func (r XPathResult) Values() []string {
+	nodes, ok := r.value.([]*xpathNode)
+	if !ok {
+		return nil
+	}
+	values := make([]string, len(nodes))
+	for i, node := range nodes {
+		values[i] = node.stringValue()
+	}
+	return values
+}
+
+// String returns the result converted to a string, e.g. the value of the first node of a node-set
+func (r XPathResult) String() string {
+	return xpathString(r.value)
+}
+
+// Number returns the result converted to a number, NaN if it is not numeric
+func (r XPathResult) Number() float64 {
+	return xpathNumber(r.value)
+}
+
+// Bool returns the result converted to a boolean, e.g. true for a non-empty node-set
+func (r XPathResult) Bool() bool {
+	return xpathBoolean(r.value)
+}
+
+// Evaluate evaluates an XPath 1.0 expression against the document of the map, so
+// assertions like count(//item) = 3 or //order[starts-with(@id, 'A')]/total > 100 can
+// be reused. The map is read as a document whose siblings are in path order and whose
+// elements hold their value as a single text node; embedded documents are children of
+// the elements holding them. Names are matched as written in the paths, including
+// prefixes, and namespace-uri() is always empty. Variables, the namespace axis and
+// id() are not supported; comments and processing instructions never match.
+// Invalid expressions return an error wrapping ErrInvalidXPath.
+func (m XMLMap) Evaluate(expr string) (_ XPathResult, err error) {
+	defer recoverPanic("evaluate XPath", &err)
+
+	e, err := parseXPath(expr)
+	if err != nil {
+		return XPathResult{}, err
+	}
+	root := buildXPathTree(m)
+	value, err := e.eval(xpathContext{node: root, position: 1, size: 1})
+	if err != nil {
+		return XPathResult{}, fmt.Errorf("%w %q: %v", ErrInvalidXPath, expr, err)
+	}
+	return XPathResult{value: value}, nil
+}
+
+// xpathValue is a node-set ([]*xpathNode in document order), string, float64 or bool
+type xpathValue any
+
+// xpathNodeKind is the kind of a node of the tree an expression is evaluated against
+type xpathNodeKind int
+
+const (
+	nodeRoot xpathNodeKind = iota
+	nodeElement
+	nodeAttribute
+	nodeText
+)
+
+// xpathNode is a node of the document built from a map
+type xpathNode struct {
+	kind     xpathNodeKind
+	name     string // Qualified name of an element or attribute
+	segment  string // Path segment of an element, used to order siblings
+	path     string
+	value    string // Value of an attribute or text node
+	parent   *xpathNode
+	children []*xpathNode // Text node first, then elements
+	attrs    []*xpathNode
+	order    int // Position in document order
+	last     int // Order of the last node below, the node's own order if it has none
+	// document holds the root and the nodes below it other than attributes in document
+	// order; it is only set on the root
+	document []*xpathNode
+	// first and end delimit the node and the nodes below it in document, and index
+	// is its position among the children of its parent; attributes have none
+	first, end, index int
+	text              *string // String value of an element, computed on first use
+}
+
+// stringValue returns the XPath string value of the node
+func (n *xpathNode) stringValue() string {
+	switch n.kind {
+	case nodeAttribute, nodeText:
+		return n.value
+	}
+	if n.text != nil {
+		return *n.text
+	}
+	var b strings.Builder
+	var appendText func(n *xpathNode)
+	appendText = func(n *xpathNode) {
+		for _, child := range n.children {
+			if child.kind == nodeText {
+				b.WriteString(child.value)
+			} else {
+				appendText(child)
+			}
+		}
+	}
+	appendText(n)
+	text := b.String()
+	n.text = &text
+	return text
+}
+
+// documentRoot returns the root of the document of the node
+func documentRoot(n *xpathNode) *xpathNode {
+	for n.parent != nil {
+		n = n.parent
+	}
+	return n
+}
+
+// buildXPathTree builds the document of the map, with nodes numbered in document order
+func buildXPathTree(m XMLMap) *xpathNode {
+	root := &xpathNode{kind: nodeRoot, path: "/"}
+	elements := make(map[string]*xpathNode)
+
+	// element returns the element at the path, creating it and its ancestors
+	var element func(path string) *xpathNode
+	element = func(path string) *xpathNode {
+		if n, ok := elements[path]; ok {
+			return n
+		}
+		parent := root
+		segment := path[1:]
+		if end := strings.LastIndexByte(path, '/'); end > 0 {
+			parent = element(strings.TrimSuffix(path[:end], NestedSeparator))
+			segment = path[end+1:]
+		}
+		name, _ := splitIndex(segment)
+		n := &xpathNode{kind: nodeElement, name: name, segment: segment, path: path, parent: parent}
+		parent.children = append(parent.children, n)
+		elements[path] = n
+		return n
+	}
+
+	for path, value := range m {
+		if !strings.HasPrefix(path, "/") {
+			continue
+		}
+		if at := strings.LastIndex(path, "/@"); at != -1 {
+			// An attribute needs an element, the root cannot hold one
+			if at == 0 {
+				continue
+			}
+			parent := element(strings.TrimSuffix(path[:at], NestedSeparator))
+			parent.attrs = append(parent.attrs, &xpathNode{kind: nodeAttribute, name: path[at+2:], path: path, value: value, parent: parent})
+			continue
+		}
+		n := element(path)
+		if value != "" {
+			n.children = append(n.children, &xpathNode{kind: nodeText, path: path, value: value, parent: n})
+		}
+	}
+
+	order := 0
+	var number func(n *xpathNode)
+	number = func(n *xpathNode) {
+		sort.SliceStable(n.children, func(i, j int) bool {
+			a, b := n.children[i], n.children[j]
+			if a.kind != b.kind {
+				return a.kind == nodeText
+			}
+			return compareSegments(a.segment, b.segment)
+		})
+		sort.Slice(n.attrs, func(i, j int) bool { return n.attrs[i].name < n.attrs[j].name })
+
+		n.order = order
+		order++
+		n.first = len(root.document)
+		root.document = append(root.document, n)
+		for _, attr := range n.attrs {
+			attr.order, attr.last = order, order
+			order++
+		}
+		for i, child := range n.children {
+			child.index = i
+			number(child)
+		}
+		n.last = order - 1
+		n.end = len(root.document)
+	}
+	number(root)
+	return root
+}
+
+// xpathContext is the context node with its position in the current node-set
+type xpathContext struct {
+	node     *xpathNode
+	position int
+	size     int
+}
+
+// xpathExpr is a parsed expression
+type xpathExpr interface {
+	eval(ctx xpathContext) (xpathValue, error)
+}
+
+// literalExpr is a string literal
+type literalExpr string
+
+func (e literalExpr) eval(xpathContext) (xpathValue, error) {
+	return string(e), nil
+}
+
+// numberExpr is a number literal
+type numberExpr float64
+
+func (e numberExpr) eval(xpathContext) (xpathValue, error) {
+	return float64(e), nil
+}
+
+// constantExpr is a subexpression whose value does not depend on its context. It is
+// evaluated on first use, so expressions must be parsed for every evaluation.
+type constantExpr struct {
+	expr    xpathExpr
+	done    bool
+	value   xpathValue
+	err     error
+	strings map[string]bool // String values of a node-set value, see stringSet
+}
+
+func (e *constantExpr) eval(ctx xpathContext) (xpathValue, error) {
+	if !e.done {
+		e.value, e.err = e.expr.eval(ctx)
+		e.done = true
+	}
+	return e.value, e.err
+}
+
+// stringSet returns the string values of the nodes of the value, computed once,
+// and false if the value is not a node-set
+func (e *constantExpr) stringSet() (map[string]bool, bool) {
+	nodes, ok := e.value.([]*xpathNode)
+	if !ok {
+		return nil, false
+	}
+	if e.strings == nil {
+		e.strings = make(map[string]bool, len(nodes))
+		for _, n := range nodes {
+			e.strings[n.stringValue()] = true
+		}
+	}
+	return e.strings, true
+}
+
+// lookupConstant compares value with the node-set of constant, an operand of =, by
+// looking up the string values of its nodes. It reports false if constant is not a
+// constant node-set or value is not a node-set.
+func lookupConstant(constant xpathExpr, value xpathValue) (bool, bool) {
+	c, ok := constant.(*constantExpr)
+	if !ok {
+		return false, false
+	}
+	nodes, ok := value.([]*xpathNode)
+	if !ok {
+		return false, false
+	}
+	set, ok := c.stringSet()
+	if !ok {
+		return false, false
+	}
+	for _, n := range nodes {
+		if set[n.stringValue()] {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// negateExpr is a unary minus
+type negateExpr struct {
+	operand xpathExpr
+}
+
+func (e *negateExpr) eval(ctx xpathContext) (xpathValue, error) {
+	value, err := e.operand.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return -xpathNumber(value), nil
+}
+
+// binaryExpr is a logical, comparison, arithmetic or union operator
+type binaryExpr struct {
+	op          string
+	left, right xpathExpr
+}
+
+func (e *binaryExpr) eval(ctx xpathContext) (xpathValue, error) {
+	left, err := e.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// and and or only evaluate the right operand when it decides the result
+	switch e.op {
+	case "and":
+		if !xpathBoolean(left) {
+			return false, nil
+		}
+	case "or":
+		if xpathBoolean(left) {
+			return true, nil
+		}
+	}
+	right, err := e.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "and", "or":
+		return xpathBoolean(right), nil
+	case "=":
+		// A join against a constant node-set looks values up instead of scanning it
+		if found, ok := lookupConstant(e.left, right); ok {
+			return found, nil
+		}
+		if found, ok := lookupConstant(e.right, left); ok {
+			return found, nil
+		}
+		return compareXPathValues(e.op, left, right), nil
+	case "!=", "<", "<=", ">", ">=":
+		return compareXPathValues(e.op, left, right), nil
+	case "|":
+		leftNodes, leftOK := left.([]*xpathNode)
+		rightNodes, rightOK := right.([]*xpathNode)
+		if !leftOK || !rightOK {
+			return nil, errors.New("operands of | must be node-sets")
+		}
+		return sortNodes(append(append([]*xpathNode(nil), leftNodes...), rightNodes...)), nil
+	}
+
+	a, b := xpathNumber(left), xpathNumber(right)
+	switch e.op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "div":
+		return a / b, nil
+	default: // mod
+		return math.Mod(a, b), nil
+	}
+}
+
+// compareXPathValues compares two values with the conversions of XPath 1.0: node-sets
+// are compared node by node, and other values as booleans, numbers or strings
+func compareXPathValues(op string, left, right xpathValue) bool {
+	leftNodes, leftIsNodes := left.([]*xpathNode)
+	rightNodes, rightIsNodes := right.([]*xpathNode)
+	switch {
+	case leftIsNodes && rightIsNodes:
+		// Compare the string values directly, as boxing them for every pair is slow
+		for _, l := range leftNodes {
+			left := l.stringValue()
+			for _, r := range rightNodes {
+				if compareStrings(op, left, r.stringValue()) {
+					return true
+				}
+			}
+		}
+		return false
+	case leftIsNodes || rightIsNodes:
+		nodes, other := leftNodes, right
+		if rightIsNodes {
+			nodes, other = rightNodes, left
+		}
+		if b, ok := other.(bool); ok {
+			l, r := ordered(rightIsNodes, len(nodes) > 0, b)
+			return compareXPathValues(op, l, r)
+		}
+		for _, n := range nodes {
+			var value xpathValue = n.stringValue()
+			if _, ok := other.(float64); ok {
+				value = xpathNumber(value)
+			}
+			if l, r := ordered(rightIsNodes, value, other); compareXPathValues(op, l, r) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch op {
+	case "=", "!=":
+		var equal bool
+		_, leftIsBool := left.(bool)
+		_, rightIsBool := right.(bool)
+		_, leftIsNumber := left.(float64)
+		_, rightIsNumber := right.(float64)
+		switch {
+		case leftIsBool || rightIsBool:
+			equal = xpathBoolean(left) == xpathBoolean(right)
+		case leftIsNumber || rightIsNumber:
+			equal = xpathNumber(left) == xpathNumber(right)
+		default:
+			equal = xpathString(left) == xpathString(right)
+		}
+		return equal == (op == "=")
+	}
+	a, b := xpathNumber(left), xpathNumber(right)
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	default:
+		return a >= b
+	}
+}
+
+// compareStrings compares the string values of two nodes: as strings for = and !=,
+// as numbers otherwise
+func compareStrings(op, left, right string) bool {
+	switch op {
+	case "=":
+		return left == right
+	case "!=":
+		return left != right
+	}
+	return compareXPathValues(op, xpathNumber(left), xpathNumber(right))
+}
+
+// ordered returns value and other as left and right operands, swapped if the
+// node-set they replace was the right operand
+func ordered(swap bool, value, other xpathValue) (xpathValue, xpathValue) {
+	if swap {
+		return other, value
+	}
+	return value, other
+}
+
+// filterExpr is a primary expression with predicates
+type filterExpr struct {
+	primary    xpathExpr
+	predicates []xpathExpr
+}
+
+func (e *filterExpr) eval(ctx xpathContext) (xpathValue, error) {
+	value, err := e.primary.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodes, ok := value.([]*xpathNode)
+	if !ok {
+		return nil, errors.New("predicates can only filter node-sets")
+	}
+	return applyPredicates(nodes, e.predicates)
+}
+
+// pathExpr is a location path, starting at the root, the context node or a filter expression
+type pathExpr struct {
+	absolute bool
+	filter   xpathExpr
+	steps    []*xpathStep
+}
+
+func (e *pathExpr) eval(ctx xpathContext) (xpathValue, error) {
+	nodes := []*xpathNode{ctx.node}
+	switch {
+	case e.absolute:
+		root := ctx.node
+		for root.parent != nil {
+			root = root.parent
+		}
+		nodes = []*xpathNode{root}
+	case e.filter != nil:
+		value, err := e.filter.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if nodes, ok = value.([]*xpathNode); !ok {
+			return nil, errors.New("a location path can only follow a node-set")
+		}
+	}
+
+	for _, step := range e.steps {
+		var err error
+		if nodes, err = step.applyAll(nodes); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// xpathAxis is the direction a step selects nodes in
+type xpathAxis int
+
+const (
+	axisChild xpathAxis = iota
+	axisDescendant
+	axisDescendantOrSelf
+	axisParent
+	axisAncestor
+	axisAncestorOrSelf
+	axisFollowingSibling
+	axisPrecedingSibling
+	axisFollowing
+	axisPreceding
+	axisAttribute
+	axisSelf
+)
+
+// xpathAxes maps the supported axis names to axes
+var xpathAxes = map[string]xpathAxis{
+	"child":              axisChild,
+	"descendant":         axisDescendant,
+	"descendant-or-self": axisDescendantOrSelf,
+	"parent":             axisParent,
+	"ancestor":           axisAncestor,
+	"ancestor-or-self":   axisAncestorOrSelf,
+	"following-sibling":  axisFollowingSibling,
+	"preceding-sibling":  axisPrecedingSibling,
+	"following":          axisFollowing,
+	"preceding":          axisPreceding,
+	"attribute":          axisAttribute,
+	"self":               axisSelf,
+}
+
+// nodeTestKind is the kind of test a step applies to the nodes of its axis
+type nodeTestKind int
+
+const (
+	testName   nodeTestKind = iota // Qualified name
+	testPrefix                     // prefix:*
+	testAny                        // *
+	testNode                       // node()
+	testText                       // text()
+	testNone                       // comment() and processing-instruction()
+)
+
+// nodeTest selects nodes of an axis by kind and name
+type nodeTest struct {
+	kind nodeTestKind
+	name string
+}
+
+// match reports whether the node passes the test on an axis whose principal node
+// type is attribute or element
+func (t nodeTest) match(n *xpathNode, axis xpathAxis) bool {
+	principal := nodeElement
+	if axis == axisAttribute {
+		principal = nodeAttribute
+	}
+	switch t.kind {
+	case testNode:
+		return true
+	case testText:
+		return n.kind == nodeText
+	case testAny:
+		return n.kind == principal
+	case testPrefix:
+		return n.kind == principal && strings.HasPrefix(n.name, t.name)
+	case testName:
+		return n.kind == principal && n.name == t.name
+	}
+	return false
+}
+
+// xpathStep is a step of a location path
+type xpathStep struct {
+	axis       xpathAxis
+	test       nodeTest
+	predicates []xpathExpr
+}
+
+// apply returns the nodes the step selects from the context node
+func (s *xpathStep) apply(n *xpathNode) ([]*xpathNode, error) {
+	var selected []*xpathNode
+	for _, candidate := range axisNodes(n, s.axis) {
+		if s.test.match(candidate, s.axis) {
+			selected = append(selected, candidate)
+		}
+	}
+	return applyPredicates(selected, s.predicates)
+}
+
+// applyAll returns the nodes the step selects from any of the context nodes, which
+// are in document order, in document order without duplicates. Context nodes reached
+// by several of them are only selected once.
+func (s *xpathStep) applyAll(nodes []*xpathNode) ([]*xpathNode, error) {
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+	seen := make([]bool, documentRoot(nodes[0]).last+1)
+	var next []*xpathNode
+	for _, n := range nodes {
+		if len(s.predicates) > 0 {
+			// Positions in predicates count from each context node, so every node needs its own pass
+			selected, err := s.apply(n)
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range selected {
+				if !seen[m.order] {
+					seen[m.order] = true
+					next = append(next, m)
+				}
+			}
+			continue
+		}
+
+		// Without predicates only the union matters, so candidates an earlier context
+		// node reached are skipped; they mark the nodes of the axis rather than the selection
+		switch s.axis {
+		case axisDescendant, axisDescendantOrSelf:
+			// The descendants of a reached node were reached with it
+			if seen[n.order] {
+				continue
+			}
+		case axisFollowing, axisFollowingSibling:
+			// The nodes of these axes end every list they are taken from, so going
+			// backwards the first node reached before starts the reached nodes
+			candidates := axisNodes(n, s.axis)
+			for i := len(candidates) - 1; i >= 0 && !seen[candidates[i].order]; i-- {
+				seen[candidates[i].order] = true
+				if s.test.match(candidates[i], s.axis) {
+					next = append(next, candidates[i])
+				}
+			}
+			continue
+		}
+		for _, m := range axisNodes(n, s.axis) {
+			if !seen[m.order] {
+				seen[m.order] = true
+				if s.test.match(m, s.axis) {
+					next = append(next, m)
+				}
+			}
+		}
+	}
+	sort.Slice(next, func(i, j int) bool { return next[i].order < next[j].order })
+	return next, nil
+}
+
+// axisNodes returns the nodes of the axis from n, in reverse document order for the
+// reverse axes, so positions in predicates count away from n. The result may share
+// memory with the tree and must not be modified.
+func axisNodes(n *xpathNode, axis xpathAxis) []*xpathNode {
+	var nodes []*xpathNode
+	switch axis {
+	case axisChild:
+		return n.children
+	case axisAttribute:
+		return n.attrs
+	case axisSelf:
+		return []*xpathNode{n}
+	case axisDescendantOrSelf, axisDescendant:
+		if n.kind == nodeAttribute {
+			if axis == axisDescendantOrSelf {
+				return []*xpathNode{n}
+			}
+			return nil
+		}
+		document := documentRoot(n).document
+		if axis == axisDescendant {
+			return document[n.first+1 : n.end]
+		}
+		return document[n.first:n.end]
+	case axisParent:
+		if n.parent != nil {
+			nodes = append(nodes, n.parent)
+		}
+	case axisAncestorOrSelf:
+		nodes = append(nodes, n)
+		fallthrough
+	case axisAncestor:
+		for p := n.parent; p != nil; p = p.parent {
+			nodes = append(nodes, p)
+		}
+	case axisFollowingSibling, axisPrecedingSibling:
+		if n.parent == nil || n.kind == nodeAttribute {
+			return nil
+		}
+		siblings := n.parent.children
+		if axis == axisFollowingSibling {
+			return siblings[n.index+1:]
+		}
+		for i := n.index - 1; i >= 0; i-- {
+			nodes = append(nodes, siblings[i])
+		}
+	case axisFollowing, axisPreceding:
+		document := documentRoot(n).document
+		// An attribute is followed by the content of its element
+		element := n
+		if n.kind == nodeAttribute {
+			element = n.parent
+		}
+		if axis == axisFollowing {
+			if n.kind == nodeAttribute {
+				return document[element.first+1:]
+			}
+			return document[element.end:]
+		}
+		for i := element.first - 1; i > 0; i-- {
+			// Ancestors are the nodes before n whose subtree contains it
+			if m := document[i]; m.end <= element.first {
+				nodes = append(nodes, m)
+			}
+		}
+	}
+	return nodes
+}
+
+// applyPredicates filters the nodes, in axis order, by each predicate in turn.
+// A number predicate selects the node at that position.
+func applyPredicates(nodes []*xpathNode, predicates []xpathExpr) ([]*xpathNode, error) {
+	for _, predicate := range predicates {
+		var kept []*xpathNode
+		for i, n := range nodes {
+			value, err := predicate.eval(xpathContext{node: n, position: i + 1, size: len(nodes)})
+			if err != nil {
+				return nil, err
+			}
+			keep := false
+			if number, ok := value.(float64); ok {
+				keep = number == float64(i+1)
+			} else {
+				keep = xpathBoolean(value)
+			}
+			if keep {
+				kept = append(kept, n)
+			}
+		}
+		nodes = kept
+	}
+	return nodes, nil
+}
+
+// sortNodes sorts nodes in document order and removes duplicates
+func sortNodes(nodes []*xpathNode) []*xpathNode {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].order < nodes[j].order })
+	unique := nodes[:0]
+	for i, n := range nodes {
+		if i == 0 || n != nodes[i-1] {
+			unique = append(unique, n)
+		}
+	}
+	return unique
+}
+
+// xpathString converts a value to a string like the XPath string() function
+func xpathString(value xpathValue) string {
+	switch v := value.(type) {
+	case []*xpathNode:
+		if len(v) == 0 {
+			return ""
+		}
+		return v[0].stringValue()
+	case string:
+		return v
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "NaN"
+		case math.IsInf(v, 1):
+			return "Infinity"
+		case math.IsInf(v, -1):
+			return "-Infinity"
+		case v == 0:
+			return "0"
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+// xpathNumber converts a value to a number like the XPath number() function.
+// Strings only convert if they hold an optional minus sign, digits and an optional fraction.
+func xpathNumber(value xpathValue) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	}
+	s := strings.TrimSpace(xpathString(value))
+	digits := strings.TrimPrefix(s, "-")
+	whole, fraction, _ := strings.Cut(digits, ".")
+	if whole == "" && fraction == "" || whole != "" && !isDigits(whole) || fraction != "" && !isDigits(fraction) {
+		return math.NaN()
+	}
+	number, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return number
+}
+
+// xpathBoolean converts a value to a boolean like the XPath boolean() function
+func xpathBoolean(value xpathValue) bool {
+	switch v := value.(type) {
+	case []*xpathNode:
+		return len(v) > 0
+	case string:
+		return v != ""
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case bool:
+		return v
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package xmlsurf
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const xpathTestDocument = `<orders xml:lang="en-GB">
+	<order id="A1" status="shipped"><customer>Alice</customer><total>120.50</total>
+		<item sku="x">pen</item><item sku="y">ink</item><item sku="z">pad</item></order>
+	<order id="B2" status="open"><customer>Bob</customer><total>80</total>
+		<item sku="x">pen</item></order>
+	<order id="A3" status="open"><customer>  Carol   Smith </customer><total>n/a</total>
+		<note>&lt;memo&gt;&lt;text&gt;call&lt;/text&gt;&lt;/memo&gt;</note></order>
+</orders>`
+
+func parseXPathTestDocument(t *testing.T) XMLMap {
+	t.Helper()
+	m, err := ParseToMap(strings.NewReader(xpathTestDocument), WithUnwrapNested(true))
+	if err != nil {
+		t.Fatalf("ParseToMap() error = %v", err)
+	}
+	return m
+}
+
+func TestEvaluateNodeSets(t *testing.T) {
+	m := parseXPathTestDocument(t)
+
+	tests := []struct {
+		expr   string
+		paths  []string
+		values []string
+	}{
+		{expr: "/orders/order[1]/customer", paths: []string{"/orders/order[1]/customer"}, values: []string{"Alice"}},
+		{expr: "//order[@status='open']/@id", paths: []string{"/orders/order[2]/@id", "/orders/order[3]/@id"}, values: []string{"B2", "A3"}},
+		{expr: "//order[starts-with(@id, 'A')][total > 100]/customer", values: []string{"Alice"}},
+		{expr: "//order[1]/item[last()]", values: []string{"pad"}},
+		{expr: "//order[1]/item[position() < 3]/@sku", values: []string{"x", "y"}},
+		{expr: "//item[@sku='x']/..//customer", values: []string{"Alice", "Bob"}},
+		{expr: "//order[count(item) = 1]/customer | //order[not(item)]/customer", values: []string{"Bob", "Carol   Smith"}},
+		{expr: "//order[1]/item[2]/following-sibling::item", values: []string{"pad"}},
+		{expr: "//order[1]/customer/following-sibling::*", values: []string{"pen", "ink", "pad", "120.50"}},
+		{expr: "//order[1]/item[2]/preceding-sibling::*[1]", values: []string{"pen"}},
+		{expr: "//order[2]/preceding::customer", values: []string{"Alice"}},
+		{expr: "//order[2]/following::total", values: []string{"n/a"}},
+		{expr: "//item[@sku='y']/ancestor::*/@id", values: []string{"A1"}},
+		{expr: "//note/memo/text", paths: []string{"/orders/order[3]/note!/memo/text"}, values: []string{"call"}},
+		{expr: "//order[3]/customer/text()", paths: []string{"/orders/order[3]/customer"}},
+		{expr: "(//item)[4]", paths: []string{"/orders/order[2]/item"}},
+		{expr: "//order[lang('en')][1]/@id", values: []string{"A1"}},
+		{expr: "//order[1]/@*", values: []string{"A1", "shipped"}},
+		{expr: "//order[total = 80 or customer = 'Alice']/@id", values: []string{"A1", "B2"}},
+		{expr: "//missing", values: []string{}},
+		// Context nodes whose axes overlap select each node once
+		{expr: "//item/following::total", values: []string{"120.50", "80", "n/a"}},
+		{expr: "//order/*/following-sibling::item", values: []string{"pen", "ink", "pad", "pen"}},
+		{expr: "//@sku/following::customer", paths: []string{"/orders/order[2]/customer", "/orders/order[3]/customer"}},
+		{expr: "//item/following::item[1]", values: []string{"ink", "pad", "pen"}},
+		{expr: "//item/ancestor-or-self::order/@id", values: []string{"A1", "B2"}},
+		// Absolute paths in predicates are evaluated once
+		{expr: "//item[@sku = //order[2]/item/@sku]", paths: []string{"/orders/order[1]/item[1]", "/orders/order[2]/item"}},
+		{expr: "//item[position() = count(//order[2]/item)]", values: []string{"pen", "pen"}},
+		{expr: "//order[@id != //order[1]/@id]/@id", values: []string{"B2", "A3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			result, err := m.Evaluate(tt.expr)
+			if err != nil {
+				t.Fatalf("Evaluate() error = %v", err)
+			}
+			if result.Type() != XPathNodeSet {
+				t.Fatalf("Evaluate().Type() = %v, want XPathNodeSet", result.Type())
+			}
+			if tt.paths != nil && !reflect.DeepEqual(result.Paths(), tt.paths) {
+				t.Errorf("Evaluate().Paths() = %v, want %v", result.Paths(), tt.paths)
+			}
+			if tt.values != nil && !reflect.DeepEqual(result.Values(), tt.values) {
+				t.Errorf("Evaluate().Values() = %q, want %q", result.Values(), tt.values)
+			}
+		})
+	}
+}
+
+func TestEvaluateValues(t *testing.T) {
+	m := parseXPathTestDocument(t)
+
+	tests := []struct {
+		expr string
+		want any
+	}{
+		{expr: "count(//item)", want: 4.0},
+		{expr: "count(//order[@status='open'])", want: 2.0},
+		{expr: "sum(//order[position() < 3]/total)", want: 200.5},
+		{expr: "sum(//total)", want: math.NaN()},
+		{expr: "//order[1]/total * 2 - 1", want: 240.0},
+		{expr: "7 mod 3 + 7 div 2", want: 4.5},
+		{expr: "-(1 + 2)", want: -3.0},
+		{expr: "round(2.5) + floor(-1.5) + ceiling(0.2)", want: 2.0},
+		{expr: "number(//order[3]/total)", want: math.NaN()},
+		{expr: "string-length('héllo')", want: 5.0},
+		{expr: "//order[1]/customer", want: "Alice"},
+		{expr: "string(//order[2]/@id)", want: "B2"},
+		{expr: "concat(//order[1]/@id, '-', //order[2]/@id)", want: "A1-B2"},
+		{expr: "normalize-space(//order[3]/customer)", want: "Carol Smith"},
+		{expr: "substring('12345', 1.5, 2.6)", want: "234"},
+		{expr: "substring('12345', 0 div 0)", want: ""},
+		{expr: "substring-before('2024-01-31', '-')", want: "2024"},
+		{expr: "substring-after('2024-01-31', '-')", want: "01-31"},
+		{expr: "translate('bar', 'abc', 'AB')", want: "BAr"},
+		{expr: "name(//order[1]/@*[2])", want: "status"},
+		{expr: "local-name(/*)", want: "orders"},
+		{expr: "string(1 div 0)", want: "Infinity"},
+		{expr: "string(3.0)", want: "3"},
+		{expr: "contains(//order[1]/customer, 'lic')", want: true},
+		{expr: "//order[1]/total > 100", want: true},
+		{expr: "//total > 100", want: true},
+		{expr: "100 < //total", want: true},
+		{expr: "//total = 'n/a'", want: true},
+		{expr: "//total != 80", want: true},
+		{expr: "//missing = false()", want: true},
+		{expr: "true() and not(//missing)", want: true},
+		{expr: "'1' = 1.0", want: true},
+		{expr: "'abc' = 'abd'", want: false},
+		{expr: "boolean(0 div 0)", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			result, err := m.Evaluate(tt.expr)
+			if err != nil {
+				t.Fatalf("Evaluate() error = %v", err)
+			}
+			switch want := tt.want.(type) {
+			case float64:
+				got := result.Number()
+				if result.Type() != XPathNumber || got != want && !(math.IsNaN(got) && math.IsNaN(want)) {
+					t.Errorf("Evaluate() = %v (type %v), want number %v", got, result.Type(), want)
+				}
+			case string:
+				if result.String() != want {
+					t.Errorf("Evaluate() = %q, want %q", result.String(), want)
+				}
+			case bool:
+				if result.Type() != XPathBoolean || result.Bool() != want {
+					t.Errorf("Evaluate() = %v (type %v), want %v", result.Bool(), result.Type(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	m := XMLMap{"/root/a": "1"}
+
+	for _, expr := range []string{"count('a')", "'a' | /root", "('a')[1]", "sum(1)", "string('a')/b"} {
+		if _, err := m.Evaluate(expr); !errors.Is(err, ErrInvalidXPath) {
+			t.Errorf("Evaluate(%q) error = %v, want ErrInvalidXPath", expr, err)
+		}
+	}
+}
+
+func BenchmarkEvaluateManyItems(b *testing.B) {
+	m := make(XMLMap)
+	for i := 1; i <= 5000; i++ {
+		id := strconv.Itoa(i)
+		m["/root/item["+id+"]/@id"] = id
+		m["/root/item["+id+"]/v"] = id
+	}
+	for _, expr := range []string{
+		"count(//item/following::v)",
+		"count(//item[@id = //v])",
+		"count(//item/following-sibling::item)",
+	} {
+		b.Run(expr, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := m.Evaluate(expr); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
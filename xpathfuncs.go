@@ -0,0 +1,236 @@
+package xmlsurf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// xpathFunction is a function of the XPath core library. Arguments are evaluated
+// before the call; maxArgs is -1 for any number of arguments.
+type xpathFunction struct {
+	minArgs, maxArgs int
+	call             func(ctx xpathContext, args []xpathValue) (xpathValue, error)
+}
+
+// callExpr is a function call
+type callExpr struct {
+	name     string
+	function *xpathFunction
+	args     []xpathExpr
+}
+
+func (e *callExpr) eval(ctx xpathContext) (xpathValue, error) {
+	args := make([]xpathValue, len(e.args))
+	for i, arg := range e.args {
+		value, err := arg.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	value, err := e.function.call(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %w", e.name, err)
+	}
+	return value, nil
+}
+
+// usesContext reports whether the call reads the context node, position or size
+func (e *callExpr) usesContext() bool {
+	switch e.name {
+	case "last", "position", "lang":
+		return true
+	}
+	// Functions with an optional argument default to the context node
+	return len(e.args) == 0 && e.function.maxArgs == 1
+}
+
+// errNotNodeSet is returned for an argument that must be a node-set
+var errNotNodeSet = errors.New("argument is not a node-set")
+
+// nodeSetArg returns the argument as a node-set
+func nodeSetArg(args []xpathValue, i int) ([]*xpathNode, error) {
+	nodes, ok := args[i].([]*xpathNode)
+	if !ok {
+		return nil, errNotNodeSet
+	}
+	return nodes, nil
+}
+
+// contextArg returns the node-set argument, or the context node without arguments
+func contextArg(ctx xpathContext, args []xpathValue) ([]*xpathNode, error) {
+	if len(args) == 0 {
+		return []*xpathNode{ctx.node}, nil
+	}
+	return nodeSetArg(args, 0)
+}
+
+// stringArg returns the argument as a string, or the context node's value without arguments
+func stringArg(ctx xpathContext, args []xpathValue) string {
+	if len(args) == 0 {
+		return ctx.node.stringValue()
+	}
+	return xpathString(args[0])
+}
+
+// nameFunction returns a function reporting a name of the first node of its argument
+func nameFunction(name func(n *xpathNode) string) *xpathFunction {
+	return &xpathFunction{0, 1, func(ctx xpathContext, args []xpathValue) (xpathValue, error) {
+		nodes, err := contextArg(ctx, args)
+		if err != nil || len(nodes) == 0 {
+			return "", err
+		}
+		return name(nodes[0]), nil
+	}}
+}
+
+// stringFunction returns a function of two strings
+func stringFunction(fn func(a, b string) xpathValue) *xpathFunction {
+	return &xpathFunction{2, 2, func(_ xpathContext, args []xpathValue) (xpathValue, error) {
+		return fn(xpathString(args[0]), xpathString(args[1])), nil
+	}}
+}
+
+// numberFunction returns a function of one number
+func numberFunction(fn func(float64) float64) *xpathFunction {
+	return &xpathFunction{1, 1, func(_ xpathContext, args []xpathValue) (xpathValue, error) {
+		return fn(xpathNumber(args[0])), nil
+	}}
+}
+
+// xpathFunctions is the XPath 1.0 core function library, without id()
+var xpathFunctions = map[string]*xpathFunction{
+	// Node-set functions
+	"last": {0, 0, func(ctx xpathContext, _ []xpathValue) (xpathValue, error) {
+		return float64(ctx.size), nil
+	}},
+	"position": {0, 0, func(ctx xpathContext, _ []xpathValue) (xpathValue, error) {
+		return float64(ctx.position), nil
+	}},
+	"count": {1, 1, func(_ xpathContext, args []xpathValue) (xpathValue, error) {
+		nodes, err := nodeSetArg(args, 0)
+		return float64(len(nodes)), err
+	}},
+	"name": nameFunction(func(n *xpathNode) string { return n.name }),
+	"local-name": nameFunction(func(n *xpathNode) string {
+		return n.name[strings.IndexByte(n.name, ':')+1:]
+	}),
+	"namespace-uri": nameFunction(func(*xpathNode) string { return "" }),
+
+	// String functions
+	"string": {0, 1, func(ctx xpathContext, args []xpathValue) (xpathValue, error) {
+		return stringArg(ctx, args), nil
+	}},
+	"concat": {2, -1, func(_ xpathContext, args []xpathValue) (xpathValue, error) {
+		var b strings.Builder
+		for _, arg := range args {
+			b.WriteString(xpathString(arg))
+		}
+		return b.String(), nil
+	}},
+	"starts-with": stringFunction(func(s, prefix string) xpathValue { return strings.HasPrefix(s, prefix) }),
+	"contains":    stringFunction(func(s, substr string) xpathValue { return strings.Contains(s, substr) }),
+	"substring-before": stringFunction(func(s, sep string) xpathValue {
+		before, _, found := strings.Cut(s, sep)
+		if !found {
+			return ""
+		}
+		return before
+	}),
+	"substring-after": stringFunction(func(s, sep string) xpathValue {
+		_, after, _ := strings.Cut(s, sep)
+		return after
+	}),
+	"substring": {2, 3, func(_ xpathContext, args []xpathValue) (xpathValue, error) {
+		// Characters are counted from 1, and positions compared after rounding,
+		// so NaN and infinite arguments select nothing or everything as in XPath
+		start := xpathRound(xpathNumber(args[1]))
+		end := math.Inf(1)
+		if len(args) == 3 {
+			end = start + xpathRound(xpathNumber(args[2]))
+		}
+		var b strings.Builder
+		for i, r := range []rune(xpathString(args[0])) {
+			if p := float64(i + 1); p >= start && p < end {
+				b.WriteRune(r)
+			}
+		}
+		return b.String(), nil
+	}},
+	"string-length": {0, 1, func(ctx xpathContext, args []xpathValue) (xpathValue, error) {
+		return float64(utf8.RuneCountInString(stringArg(ctx, args))), nil
+	}},
+	"normalize-space": {0, 1, func(ctx xpathContext, args []xpathValue) (xpathValue, error) {
+		return strings.Join(strings.Fields(stringArg(ctx, args)), " "), nil
+	}},
+	"translate": {3, 3, func(_ xpathContext, args []xpathValue) (xpathValue, error) {
+		from, to := []rune(xpathString(args[1])), []rune(xpathString(args[2]))
+		return strings.Map(func(r rune) rune {
+			for i, f := range from {
+				if f == r {
+					if i < len(to) {
+						return to[i]
+					}
+					return -1
+				}
+			}
+			return r
+		}, xpathString(args[0])), nil
+	}},
+
+	// Boolean functions
+	"boolean": {1, 1, func(_ xpathContext, args []xpathValue) (xpathValue, error) {
+		return xpathBoolean(args[0]), nil
+	}},
+	"not": {1, 1, func(_ xpathContext, args []xpathValue) (xpathValue, error) {
+		return !xpathBoolean(args[0]), nil
+	}},
+	"true": {0, 0, func(xpathContext, []xpathValue) (xpathValue, error) {
+		return true, nil
+	}},
+	"false": {0, 0, func(xpathContext, []xpathValue) (xpathValue, error) {
+		return false, nil
+	}},
+	"lang": {1, 1, func(ctx xpathContext, args []xpathValue) (xpathValue, error) {
+		lang := strings.ToLower(xpathString(args[0]))
+		for n := ctx.node; n != nil; n = n.parent {
+			for _, attr := range n.attrs {
+				if attr.name == "xml:lang" {
+					value := strings.ToLower(attr.value)
+					return value == lang || strings.HasPrefix(value, lang+"-"), nil
+				}
+			}
+		}
+		return false, nil
+	}},
+
+	// Number functions
+	"number": {0, 1, func(ctx xpathContext, args []xpathValue) (xpathValue, error) {
+		if len(args) == 0 {
+			return xpathNumber(ctx.node.stringValue()), nil
+		}
+		return xpathNumber(args[0]), nil
+	}},
+	"sum": {1, 1, func(_ xpathContext, args []xpathValue) (xpathValue, error) {
+		nodes, err := nodeSetArg(args, 0)
+		sum := 0.0
+		for _, n := range nodes {
+			sum += xpathNumber(n.stringValue())
+		}
+		return sum, err
+	}},
+	"floor":   numberFunction(math.Floor),
+	"ceiling": numberFunction(math.Ceil),
+	"round":   numberFunction(xpathRound),
+}
+
+// xpathRound rounds to the nearest integer, halves towards positive infinity
+func xpathRound(x float64) float64 {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return x
+	}
+	return math.Floor(x + 0.5)
+}
@@ -0,0 +1,616 @@
+package xmlsurf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// xpathTokenKind classifies the tokens of an XPath expression
+type xpathTokenKind int
+
+const (
+	tokenEOF      xpathTokenKind = iota
+	tokenName                    // Name test: a QName, "*" or "prefix:*"
+	tokenNodeType                // node, text, comment or processing-instruction before "("
+	tokenFunction                // Function name before "("
+	tokenAxis                    // Axis name before "::"
+	tokenOperator                // Operator, including "and", "or", "div", "mod" and "*" as multiplication
+	tokenNumber
+	tokenLiteral
+	tokenVariable
+	tokenPunct // One of ( ) [ ] . .. @ , ::
+)
+
+// xpathToken is a token of an XPath expression with its byte offset
+type xpathToken struct {
+	kind   xpathTokenKind
+	text   string
+	offset int
+}
+
+// xpathSyntaxError returns an error wrapping ErrInvalidXPath for the expression
+func xpathSyntaxError(expr string, offset int, format string, args ...any) error {
+	return fmt.Errorf("%w %q at offset %d: %s", ErrInvalidXPath, expr, offset, fmt.Sprintf(format, args...))
+}
+
+// tokenizeXPath splits an expression into tokens
+func tokenizeXPath(expr string) ([]xpathToken, error) {
+	var tokens []xpathToken
+	// An operator name or "*" is an operator unless it starts the expression or
+	// follows @, ::, (, [, a comma or another operator
+	operatorAllowed := func() bool {
+		if len(tokens) == 0 {
+			return false
+		}
+		last := tokens[len(tokens)-1]
+		switch last.kind {
+		case tokenOperator:
+			return false
+		case tokenPunct:
+			return last.text == ")" || last.text == "]" || last.text == "." || last.text == ".."
+		}
+		return true
+	}
+
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end == -1 {
+				return nil, xpathSyntaxError(expr, i, "unterminated string literal")
+			}
+			tokens = append(tokens, xpathToken{kind: tokenLiteral, text: expr[i+1 : i+1+end], offset: start})
+			i += end + 2
+			continue
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(expr) && expr[i+1] >= '0' && expr[i+1] <= '9':
+			for i < len(expr) && (expr[i] >= '0' && expr[i] <= '9' || expr[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, xpathToken{kind: tokenNumber, text: expr[start:i], offset: start})
+			continue
+		case c == '.':
+			text := "."
+			if strings.HasPrefix(expr[i:], "..") {
+				text = ".."
+			}
+			tokens = append(tokens, xpathToken{kind: tokenPunct, text: text, offset: start})
+			i += len(text)
+			continue
+		case c == '$':
+			i++
+			name := scanXPathName(expr[i:])
+			if name == "" {
+				return nil, xpathSyntaxError(expr, start, "missing variable name")
+			}
+			tokens = append(tokens, xpathToken{kind: tokenVariable, text: name, offset: start})
+			i += len(name)
+			continue
+		case c == '*':
+			kind := tokenName
+			if operatorAllowed() {
+				kind = tokenOperator
+			}
+			tokens = append(tokens, xpathToken{kind: kind, text: "*", offset: start})
+			i++
+			continue
+		}
+
+		if op := scanXPathOperator(expr[i:]); op != "" {
+			kind := tokenOperator
+			if op == "::" || op == "(" || op == ")" || op == "[" || op == "]" || op == "@" || op == "," {
+				kind = tokenPunct
+			}
+			tokens = append(tokens, xpathToken{kind: kind, text: op, offset: start})
+			i += len(op)
+			continue
+		}
+
+		name := scanXPathName(expr[i:])
+		if name == "" {
+			return nil, xpathSyntaxError(expr, i, "unexpected character %q", c)
+		}
+		i += len(name)
+		if operatorAllowed() {
+			if name != "and" && name != "or" && name != "div" && name != "mod" {
+				return nil, xpathSyntaxError(expr, start, "expected an operator, found %q", name)
+			}
+			tokens = append(tokens, xpathToken{kind: tokenOperator, text: name, offset: start})
+			continue
+		}
+		// A prefixed wildcard like soap:*
+		if strings.HasSuffix(name, ":") {
+			if i < len(expr) && expr[i] == '*' {
+				tokens = append(tokens, xpathToken{kind: tokenName, text: name + "*", offset: start})
+				i++
+				continue
+			}
+			return nil, xpathSyntaxError(expr, start, "invalid name %q", name)
+		}
+
+		next := i
+		for next < len(expr) && (expr[next] == ' ' || expr[next] == '\t' || expr[next] == '\n' || expr[next] == '\r') {
+			next++
+		}
+		kind := tokenName
+		switch {
+		case strings.HasPrefix(expr[next:], "::"):
+			kind = tokenAxis
+		case strings.HasPrefix(expr[next:], "("):
+			kind = tokenFunction
+			if name == "node" || name == "text" || name == "comment" || name == "processing-instruction" {
+				kind = tokenNodeType
+			}
+		}
+		tokens = append(tokens, xpathToken{kind: kind, text: name, offset: start})
+	}
+	return append(tokens, xpathToken{kind: tokenEOF, offset: len(expr)}), nil
+}
+
+// scanXPathOperator returns the operator or punctuation at the start of s, or ""
+func scanXPathOperator(s string) string {
+	for _, op := range []string{"//", "!=", "<=", ">=", "::", "/", "|", "+", "-", "=", "<", ">", "(", ")", "[", "]", "@", ","} {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// scanXPathName returns the NCName or QName at the start of s, or "". A prefix
+// followed by ":" and no local name is returned with the colon, for "prefix:*".
+func scanXPathName(s string) string {
+	end := scanNCName(s)
+	if end == 0 {
+		return ""
+	}
+	if end < len(s) && s[end] == ':' && !strings.HasPrefix(s[end:], "::") {
+		if local := scanNCName(s[end+1:]); local > 0 {
+			return s[:end+1+local]
+		}
+		return s[:end+1]
+	}
+	return s[:end]
+}
+
+// scanNCName returns the length of the name without colons at the start of s
+func scanNCName(s string) int {
+	i := 0
+	for i < len(s) {
+		r, width := utf8.DecodeRuneInString(s[i:])
+		if !(r == '_' || unicode.IsLetter(r) || i > 0 && (r == '-' || r == '.' || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r))) {
+			break
+		}
+		i += width
+	}
+	return i
+}
+
+// xpathParser builds the expression tree from the tokens of an expression
+type xpathParser struct {
+	expr   string
+	tokens []xpathToken
+	pos    int
+}
+
+// parseXPath parses an XPath 1.0 expression
+func parseXPath(expr string) (xpathExpr, error) {
+	tokens, err := tokenizeXPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &xpathParser{expr: expr, tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return hoist(e), nil
+}
+
+// hoist replaces the subexpressions that do not depend on their context, such as
+// absolute paths in predicates, with constants evaluated once
+func hoist(e xpathExpr) xpathExpr {
+	switch e := e.(type) {
+	case literalExpr, numberExpr, *constantExpr:
+		return e
+	case *negateExpr:
+		e.operand = hoist(e.operand)
+	case *binaryExpr:
+		e.left, e.right = hoist(e.left), hoist(e.right)
+	case *callExpr:
+		for i, arg := range e.args {
+			e.args[i] = hoist(arg)
+		}
+	case *filterExpr:
+		e.primary = hoist(e.primary)
+		hoistAll(e.predicates)
+	case *pathExpr:
+		if e.filter != nil {
+			e.filter = hoist(e.filter)
+		}
+		for _, step := range e.steps {
+			hoistAll(step.predicates)
+		}
+	}
+	if contextFree(e) {
+		return &constantExpr{expr: e}
+	}
+	return e
+}
+
+// hoistAll hoists the subexpressions of each expression
+func hoistAll(exprs []xpathExpr) {
+	for i, e := range exprs {
+		exprs[i] = hoist(e)
+	}
+}
+
+// contextFree reports whether the value of the expression is the same for every context
+func contextFree(e xpathExpr) bool {
+	switch e := e.(type) {
+	case literalExpr, numberExpr, *constantExpr:
+		return true
+	case *negateExpr:
+		return contextFree(e.operand)
+	case *binaryExpr:
+		return contextFree(e.left) && contextFree(e.right)
+	case *callExpr:
+		if e.usesContext() {
+			return false
+		}
+		for _, arg := range e.args {
+			if !contextFree(arg) {
+				return false
+			}
+		}
+		return true
+	case *filterExpr:
+		// Predicates are evaluated against the nodes of the primary expression
+		return contextFree(e.primary)
+	case *pathExpr:
+		return e.absolute || e.filter != nil && contextFree(e.filter)
+	}
+	return false
+}
+
+func (p *xpathParser) peek() xpathToken {
+	return p.tokens[p.pos]
+}
+
+func (p *xpathParser) next() xpathToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it has the kind and text
+func (p *xpathParser) accept(kind xpathTokenKind, text string) bool {
+	if t := p.peek(); t.kind == kind && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expect consumes punctuation or reports it missing
+func (p *xpathParser) expect(text string) error {
+	if !p.accept(tokenPunct, text) {
+		t := p.peek()
+		if t.kind == tokenEOF {
+			return p.errorf(t, "missing %q", text)
+		}
+		return p.errorf(t, "expected %q, found %q", text, t.text)
+	}
+	return nil
+}
+
+func (p *xpathParser) errorf(t xpathToken, format string, args ...any) error {
+	return xpathSyntaxError(p.expr, t.offset, format, args...)
+}
+
+// parseBinary parses a left-associative sequence of operands joined by the operators
+func (p *xpathParser) parseBinary(operand func() (xpathExpr, error), operators ...string) (xpathExpr, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		matched := false
+		for _, op := range operators {
+			if t.kind == tokenOperator && t.text == op {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return left, nil
+		}
+		p.next()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: t.text, left: left, right: right}
+	}
+}
+
+func (p *xpathParser) parseOr() (xpathExpr, error) {
+	return p.parseBinary(p.parseAnd, "or")
+}
+
+func (p *xpathParser) parseAnd() (xpathExpr, error) {
+	return p.parseBinary(p.parseEquality, "and")
+}
+
+func (p *xpathParser) parseEquality() (xpathExpr, error) {
+	return p.parseBinary(p.parseRelational, "=", "!=")
+}
+
+func (p *xpathParser) parseRelational() (xpathExpr, error) {
+	return p.parseBinary(p.parseAdditive, "<", "<=", ">", ">=")
+}
+
+func (p *xpathParser) parseAdditive() (xpathExpr, error) {
+	return p.parseBinary(p.parseMultiplicative, "+", "-")
+}
+
+func (p *xpathParser) parseMultiplicative() (xpathExpr, error) {
+	return p.parseBinary(p.parseUnary, "*", "div", "mod")
+}
+
+func (p *xpathParser) parseUnary() (xpathExpr, error) {
+	if p.accept(tokenOperator, "-") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &negateExpr{operand: operand}, nil
+	}
+	return p.parseBinary(p.parsePath, "|")
+}
+
+// parsePath parses a location path, or a filter expression optionally followed by one
+func (p *xpathParser) parsePath() (xpathExpr, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokenOperator && (t.text == "/" || t.text == "//"):
+		p.next()
+		path := &pathExpr{absolute: true}
+		if t.text == "//" {
+			path.steps = append(path.steps, descendantOrSelfStep())
+		} else if !p.startsStep() {
+			return path, nil
+		}
+		return p.parseSteps(path)
+	case t.kind == tokenLiteral, t.kind == tokenNumber, t.kind == tokenVariable,
+		t.kind == tokenFunction, t.kind == tokenPunct && t.text == "(":
+		filter, err := p.parseFilter()
+		if err != nil {
+			return nil, err
+		}
+		next := p.peek()
+		if next.kind != tokenOperator || next.text != "/" && next.text != "//" {
+			return filter, nil
+		}
+		p.next()
+		path := &pathExpr{filter: filter}
+		if next.text == "//" {
+			path.steps = append(path.steps, descendantOrSelfStep())
+		}
+		return p.parseSteps(path)
+	case p.startsStep():
+		return p.parseSteps(&pathExpr{})
+	case t.kind == tokenEOF:
+		return nil, p.errorf(t, "unexpected end of expression")
+	default:
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+}
+
+// startsStep reports whether the next token can start a location step
+func (p *xpathParser) startsStep() bool {
+	t := p.peek()
+	switch t.kind {
+	case tokenName, tokenNodeType, tokenAxis:
+		return true
+	case tokenPunct:
+		return t.text == "@" || t.text == "." || t.text == ".."
+	}
+	return false
+}
+
+// parseSteps parses steps separated by "/" or "//" into the path
+func (p *xpathParser) parseSteps(path *pathExpr) (xpathExpr, error) {
+	for {
+		step, err := p.parseStep()
+		if err != nil {
+			return nil, err
+		}
+		path.steps = append(path.steps, step)
+
+		t := p.peek()
+		if t.kind != tokenOperator || t.text != "/" && t.text != "//" {
+			return path, nil
+		}
+		p.next()
+		if t.text == "//" {
+			path.steps = append(path.steps, descendantOrSelfStep())
+		}
+	}
+}
+
+// descendantOrSelfStep returns the step "//" abbreviates
+func descendantOrSelfStep() *xpathStep {
+	return &xpathStep{axis: axisDescendantOrSelf, test: nodeTest{kind: testNode}}
+}
+
+// parseStep parses an axis, a node test and predicates, or "." or ".."
+func (p *xpathParser) parseStep() (*xpathStep, error) {
+	if p.accept(tokenPunct, ".") {
+		return &xpathStep{axis: axisSelf, test: nodeTest{kind: testNode}}, nil
+	}
+	if p.accept(tokenPunct, "..") {
+		return &xpathStep{axis: axisParent, test: nodeTest{kind: testNode}}, nil
+	}
+
+	step := &xpathStep{axis: axisChild}
+	if p.accept(tokenPunct, "@") {
+		step.axis = axisAttribute
+	} else if t := p.peek(); t.kind == tokenAxis {
+		p.next()
+		axis, ok := xpathAxes[t.text]
+		if !ok {
+			return nil, p.errorf(t, "unsupported axis %q", t.text)
+		}
+		step.axis = axis
+		if err := p.expect("::"); err != nil {
+			return nil, err
+		}
+	}
+
+	t := p.next()
+	switch t.kind {
+	case tokenName:
+		step.test = nodeTest{kind: testName, name: t.text}
+		if t.text == "*" {
+			step.test.kind = testAny
+		} else if strings.HasSuffix(t.text, ":*") {
+			step.test = nodeTest{kind: testPrefix, name: strings.TrimSuffix(t.text, "*")}
+		}
+	case tokenNodeType:
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		if t.text == "processing-instruction" && p.peek().kind == tokenLiteral {
+			p.next()
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		step.test = nodeTest{kind: testNone}
+		switch t.text {
+		case "node":
+			step.test.kind = testNode
+		case "text":
+			step.test.kind = testText
+		}
+	case tokenEOF:
+		return nil, p.errorf(t, "missing node test")
+	default:
+		return nil, p.errorf(t, "expected a node test, found %q", t.text)
+	}
+
+	predicates, err := p.parsePredicates()
+	if err != nil {
+		return nil, err
+	}
+	step.predicates = predicates
+	return step, nil
+}
+
+// parsePredicates parses any number of bracketed predicates
+func (p *xpathParser) parsePredicates() ([]xpathExpr, error) {
+	var predicates []xpathExpr
+	for p.accept(tokenPunct, "[") {
+		predicate, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, predicate)
+	}
+	return predicates, nil
+}
+
+// parseFilter parses a primary expression followed by predicates
+func (p *xpathParser) parseFilter() (xpathExpr, error) {
+	primary, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	predicates, err := p.parsePredicates()
+	if err != nil {
+		return nil, err
+	}
+	if len(predicates) == 0 {
+		return primary, nil
+	}
+	return &filterExpr{primary: primary, predicates: predicates}, nil
+}
+
+// parsePrimary parses a literal, number, function call or parenthesized expression
+func (p *xpathParser) parsePrimary() (xpathExpr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenLiteral:
+		return literalExpr(t.text), nil
+	case tokenNumber:
+		number, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, p.errorf(t, "invalid number %q", t.text)
+		}
+		return numberExpr(number), nil
+	case tokenVariable:
+		return nil, p.errorf(t, "variables are not supported")
+	case tokenFunction:
+		return p.parseFunctionCall(t)
+	}
+	// The only other token starting a filter expression is "("
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// parseFunctionCall parses the arguments of a call and checks them against the function
+func (p *xpathParser) parseFunctionCall(name xpathToken) (xpathExpr, error) {
+	function, ok := xpathFunctions[name.text]
+	if !ok {
+		return nil, p.errorf(name, "unknown function %s()", name.text)
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	call := &callExpr{name: name.text, function: function}
+	if !p.accept(tokenPunct, ")") {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if p.accept(tokenPunct, ")") {
+				break
+			}
+			if p.peek().kind == tokenEOF {
+				return nil, p.expect(")")
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(call.args) < function.minArgs || function.maxArgs >= 0 && len(call.args) > function.maxArgs {
+		return nil, p.errorf(name, "wrong number of arguments to %s(): %d", name.text, len(call.args))
+	}
+	return call, nil
+}
@@ -0,0 +1,46 @@
+package xmlsurf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseXPath(t *testing.T) {
+	valid := []string{
+		"/", "//a", "a/b", "./a", "../a", "@id", "*", "soap:*", "soap:Body/x",
+		"child::a/descendant-or-self::node()/attribute::id", "a[1][@b]", "a * b", "a*b",
+		"2*3", "div div div", "a and b or c", "- - 1", ".5 + 1.", "(//a)[1]/b",
+		"processing-instruction('x') | comment()", "f-o.o/ba_r", "count( //a )",
+	}
+	for _, expr := range valid {
+		if _, err := parseXPath(expr); err != nil {
+			t.Errorf("parseXPath(%q) error = %v", expr, err)
+		}
+	}
+
+	invalid := []struct {
+		expr string
+		want string
+	}{
+		{expr: "", want: "unexpected end of expression"},
+		{expr: "/a[", want: "unexpected end of expression"},
+		{expr: "/a[1", want: `missing "]"`},
+		{expr: "count(/a", want: `missing ")"`},
+		{expr: "'open", want: "unterminated string literal"},
+		{expr: "a b", want: `expected an operator, found "b"`},
+		{expr: "unknown()", want: "unknown function unknown()"},
+		{expr: "count()", want: "wrong number of arguments to count(): 0"},
+		{expr: "namespace::x", want: `unsupported axis "namespace"`},
+		{expr: "$var", want: "variables are not supported"},
+		{expr: "a/", want: "missing node test"},
+		{expr: "a#b", want: `unexpected character '#'`},
+		{expr: "1.2.3", want: `invalid number "1.2.3"`},
+	}
+	for _, tt := range invalid {
+		_, err := parseXPath(tt.expr)
+		if !errors.Is(err, ErrInvalidXPath) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseXPath(%q) error = %v, want %q", tt.expr, err, tt.want)
+		}
+	}
+}
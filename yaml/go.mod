@@ -0,0 +1,8 @@
+module github.com/bmcszk/xmlsurf/yaml
+
+go 1.22
+
+require (
+	github.com/bmcszk/xmlsurf v0.0.0-20261017235040-003663345f00
+	gopkg.in/yaml.v3 v3.0.1
+)
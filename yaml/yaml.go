@@ -0,0 +1,192 @@
+// Package yaml converts xmlsurf maps to and from YAML documents and loads
+// transforms from YAML, keeping gopkg.in/yaml.v3 out of the core package.
+package yaml
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bmcszk/xmlsurf"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+func init() {
+	xmlsurf.RegisterModule("yaml")
+}
+
+// TextKey is the YAML mapping key holding the value of an element that
+// also has attributes or children
+const TextKey = xmlsurf.NestedTextKey
+
+// Write writes the map as a YAML document with the root element name as its only key.
+// The document has the form of XMLMap.ToNested: an element with only a value becomes a
+// scalar, an element with attributes or children a mapping, with attributes under "@name"
+// keys and its value under TextKey, and repeated elements a sequence under their shared name.
+// Elements are written in the same order as ToXML writes them.
+func Write(w io.Writer, m xmlsurf.XMLMap) error {
+	root, err := m.NestedTree()
+	if err != nil {
+		return err
+	}
+
+	doc := &yamlv3.Node{
+		Kind:    yamlv3.MappingNode,
+		Content: []*yamlv3.Node{yamlString(root.Name), yamlElement(root)},
+	}
+
+	enc := yamlv3.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// yamlElement converts an element into its YAML representation
+func yamlElement(element *xmlsurf.NestedElement) *yamlv3.Node {
+	if len(element.Entries) == 0 {
+		return yamlString(element.Value)
+	}
+
+	mapping := &yamlv3.Node{Kind: yamlv3.MappingNode}
+	for _, entry := range element.Entries {
+		var value *yamlv3.Node
+		switch {
+		case entry.Elements == nil:
+			value = yamlString(entry.Value)
+		case entry.Repeated:
+			value = &yamlv3.Node{Kind: yamlv3.SequenceNode}
+			for _, child := range entry.Elements {
+				value.Content = append(value.Content, yamlElement(child))
+			}
+		default:
+			value = yamlElement(entry.Elements[0])
+		}
+		mapping.Content = append(mapping.Content, yamlString(entry.Key), value)
+	}
+	return mapping
+}
+
+// yamlString returns a scalar node that always decodes as a string
+func yamlString(value string) *yamlv3.Node {
+	return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// Read reads a YAML document in the format written by Write and returns it as an XMLMap.
+// Sequences of more than one item produce indexed paths; a sequence with a single
+// item produces the same path as a plain element, as xmlsurf.ParseToMap does.
+func Read(r io.Reader) (xmlsurf.XMLMap, error) {
+	var doc yamlv3.Node
+	if err := yamlv3.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yamlv3.DocumentNode || len(doc.Content) != 1 {
+		return nil, errors.New("yaml: expected a single document")
+	}
+
+	top := resolveAlias(doc.Content[0])
+	if top.Kind != yamlv3.MappingNode || len(top.Content) != 2 {
+		return nil, errors.New("yaml: expected a mapping with a single root element")
+	}
+
+	result := make(xmlsurf.XMLMap)
+	if err := readElement(result, "/"+top.Content[0].Value, top.Content[1]); err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, errors.New("yaml: document contains no values")
+	}
+	return result, nil
+}
+
+// readElement stores the element at path described by node and its subtree
+func readElement(result xmlsurf.XMLMap, path string, node *yamlv3.Node) error {
+	node = resolveAlias(node)
+
+	switch node.Kind {
+	case yamlv3.ScalarNode:
+		result[path] = scalarValue(node)
+		return nil
+	case yamlv3.MappingNode:
+	default:
+		return fmt.Errorf("yaml: unexpected sequence at %s (line %d)", path, node.Line)
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		value := resolveAlias(node.Content[i+1])
+
+		switch {
+		case key == TextKey || strings.HasPrefix(key, "@"):
+			if value.Kind != yamlv3.ScalarNode {
+				return fmt.Errorf("yaml: %s at %s must be a scalar (line %d)", key, path, value.Line)
+			}
+			if key == TextKey {
+				result[path] = scalarValue(value)
+			} else {
+				result[path+"/"+key] = scalarValue(value)
+			}
+		case value.Kind == yamlv3.SequenceNode:
+			for n, item := range value.Content {
+				childPath := path + "/" + key
+				if len(value.Content) > 1 {
+					childPath = fmt.Sprintf("%s[%d]", childPath, n+1)
+				}
+				if err := readElement(result, childPath, item); err != nil {
+					return err
+				}
+			}
+		default:
+			if err := readElement(result, path+"/"+key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// scalarValue returns the string value of a scalar, treating null as empty
+func scalarValue(node *yamlv3.Node) string {
+	if node.Tag == "!!null" {
+		return ""
+	}
+	return node.Value
+}
+
+// resolveAlias follows alias nodes to the node they refer to
+func resolveAlias(node *yamlv3.Node) *yamlv3.Node {
+	for node.Kind == yamlv3.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// LoadTransform reads a Transform from a YAML document listing its rules under
+// "rules", like xmlsurf.LoadTransform does for JSON, e.g.
+//
+//	rules:
+//	  - rename: /resp/order/custId
+//	    to: customerId
+//	  - move: /resp/order/lines/line
+//	    to: /order/items/item
+//	  - drop: [/resp/debug, /resp/order/@internal]
+//	  - map: /order/status
+//	    values: {S: shipped, O: open}
+//
+// Unknown keys are rejected, so typos do not silently disable a rule.
+func LoadTransform(r io.Reader) (*xmlsurf.Transform, error) {
+	var config struct {
+		Rules []xmlsurf.Rule `yaml:"rules"`
+	}
+	decoder := yamlv3.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("load transform: empty document")
+		}
+		return nil, fmt.Errorf("load transform: %w", err)
+	}
+	return xmlsurf.NewTransform(config.Rules...)
+}
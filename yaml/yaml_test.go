@@ -0,0 +1,143 @@
+package yaml
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bmcszk/xmlsurf"
+)
+
+func TestWrite(t *testing.T) {
+	m := xmlsurf.XMLMap{
+		"/order/@id":              "42",
+		"/order/customer":         "Alice",
+		"/order/items/item[1]":    "pen",
+		"/order/items/item[2]":    "ink",
+		"/order/note":             "fragile: yes",
+		"/order/total":            "10.50",
+		"/order/total/@currency":  "EUR",
+		"/order/shipping/address": "Main St 1",
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, m); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	expected := `order:
+  '@id': "42"
+  customer: Alice
+  items:
+    item:
+      - pen
+      - ink
+  note: 'fragile: yes'
+  shipping:
+    address: Main St 1
+  total:
+    '@currency': EUR
+    '#text': "10.50"
+`
+	if buf.String() != expected {
+		t.Errorf("Write() =\n%s\nwant\n%s", buf.String(), expected)
+	}
+
+	parsed, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if diffs := m.Diffs(parsed); len(diffs) != 0 {
+		t.Errorf("Read(Write()) diffs = %v", diffs)
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		expected xmlsurf.XMLMap
+		wantErr  bool
+	}{
+		{
+			name: "hand-written fixture",
+			yaml: `
+root:
+  "@version": 2
+  count: 3
+  empty:
+  items:
+    item:
+      - id: 1
+      - id: 2
+  single:
+    - only
+`,
+			expected: xmlsurf.XMLMap{
+				"/root/@version":         "2",
+				"/root/count":            "3",
+				"/root/empty":            "",
+				"/root/items/item[1]/id": "1",
+				"/root/items/item[2]/id": "2",
+				"/root/single":           "only",
+			},
+		},
+		{
+			name:    "multiple roots",
+			yaml:    "a: 1\nb: 2\n",
+			wantErr: true,
+		},
+		{
+			name:    "nested sequence",
+			yaml:    "root:\n  item:\n    - [1, 2]\n",
+			wantErr: true,
+		},
+		{
+			name:    "attribute with children",
+			yaml:    "root:\n  \"@id\":\n    x: 1\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Read(strings.NewReader(tt.yaml))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !result.Equal(tt.expected) {
+				t.Errorf("Read() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadTransform(t *testing.T) {
+	m := xmlsurf.XMLMap{"/a/old": "1", "/a/status": "S", "/a/debug": "d"}
+	want := xmlsurf.XMLMap{"/b/new": "1", "/b/status": "shipped"}
+
+	transform, err := LoadTransform(strings.NewReader(`
+rules:
+  - rename: /a/old
+    to: new
+  - drop: [/a/debug]
+  - map: /a/status
+    values: {S: shipped}
+  - move: /a
+    to: /b
+`))
+	if err != nil {
+		t.Fatalf("LoadTransform() error = %v", err)
+	}
+	got, err := transform.Apply(m)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Apply() = %v, %v, want %v", got, err, want)
+	}
+
+	for _, config := range []string{"", "rules:\n  - renam: /a\n    to: b\n", "rules:\n  - move: /a\n"} {
+		if _, err := LoadTransform(strings.NewReader(config)); err == nil {
+			t.Errorf("LoadTransform(%q) error = nil", config)
+		}
+	}
+}